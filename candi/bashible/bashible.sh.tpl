@@ -145,6 +145,59 @@ function current_uptime() {
   cat /proc/uptime | cut -d " " -f1
 }
 
+function chaos_monkey_fault() {
+  # Chaos monkey hook in the node-manager module marks the node with the node.deckhouse.io/chaos-monkey-fault annotation.
+  # Faults which can't be injected from the cluster side are executed here. The annotation is removed before
+  # the fault is injected, so the fault is never executed twice (for example, if bashible can't reach apiserver).
+  local node_data fault duration
+  if ! node_data="$(kubectl_exec get node $(hostname -s) -o json | jq '.metadata.annotations')" ; then
+    return 0
+  fi
+
+  fault="$(jq -r '."node.deckhouse.io/chaos-monkey-fault" // ""' <<< "$node_data")"
+  duration="$(jq -r '."node.deckhouse.io/chaos-monkey-fault-duration" // "300"' <<< "$node_data")"
+
+  case "$fault" in
+    RestartKubelet|RestartCRI|NetworkPartition)
+      ;;
+    *)
+      return 0
+      ;;
+  esac
+
+  if ! kubectl_exec annotate node $(hostname -s) node.deckhouse.io/chaos-monkey-fault- 1> /dev/null ; then
+    >&2 echo "Failed to remove node.deckhouse.io/chaos-monkey-fault annotation, skipping the $fault chaos monkey fault."
+    return 0
+  fi
+
+  >&2 echo "Chaos monkey: injecting $fault fault."
+  case "$fault" in
+    RestartKubelet)
+      systemctl restart kubelet.service || true
+      ;;
+    RestartCRI)
+      if systemctl is-active --quiet containerd.service ; then
+        systemctl restart containerd.service || true
+      elif systemctl is-active --quiet docker.service ; then
+        systemctl restart docker.service || true
+      fi
+      ;;
+    NetworkPartition)
+      # The rollback is scheduled before the partition to be sure that node will be back in any case.
+      systemd-run --unit=d8-chaos-monkey-heal --on-active="${duration}s" /bin/bash -c \
+        "iptables -w -D INPUT -j d8-chaos-monkey; iptables -w -D OUTPUT -j d8-chaos-monkey; iptables -w -F d8-chaos-monkey; iptables -w -X d8-chaos-monkey" || return 0
+      iptables -w -N d8-chaos-monkey 2>/dev/null || iptables -w -F d8-chaos-monkey
+      iptables -w -A d8-chaos-monkey -i lo -j RETURN
+      iptables -w -A d8-chaos-monkey -o lo -j RETURN
+      iptables -w -A d8-chaos-monkey -p tcp --dport 22 -j RETURN
+      iptables -w -A d8-chaos-monkey -p tcp --sport 22 -j RETURN
+      iptables -w -A d8-chaos-monkey -j DROP
+      iptables -w -I INPUT 1 -j d8-chaos-monkey
+      iptables -w -I OUTPUT 1 -j d8-chaos-monkey
+      ;;
+  esac
+}
+
 function main() {
   export PATH="/opt/deckhouse/bin:/usr/local/bin:$PATH"
   export BOOTSTRAP_DIR="/var/lib/bashible"
@@ -217,6 +270,10 @@ function main() {
   fi
 
 {{ if eq .runType "Normal" }}
+  if [ -z "${is_local-}" ] && type kubectl >/dev/null 2>&1 && test -f /etc/kubernetes/kubelet.conf ; then
+    chaos_monkey_fault
+  fi
+
   if [[ -f $CONFIGURATION_CHECKSUM_FILE ]] && [[ "$(<$CONFIGURATION_CHECKSUM_FILE)" == "$CONFIGURATION_CHECKSUM" ]] && [[ -f $UPTIME_FILE ]] && [[ "$(<$UPTIME_FILE)" < "$(current_uptime)" ]] 2>/dev/null; then
     echo "Configuration is in sync, nothing to do."
     annotate_node node.deckhouse.io/configuration-checksum=${CONFIGURATION_CHECKSUM}
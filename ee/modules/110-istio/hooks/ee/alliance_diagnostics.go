@@ -0,0 +1,396 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package ee
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	"github.com/flant/shell-operator/pkg/kube/object_patch"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	eeCrd "github.com/deckhouse/deckhouse/ee/modules/110-istio/hooks/ee/lib/crd"
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	"github.com/deckhouse/deckhouse/go_lib/jwt"
+	"github.com/deckhouse/deckhouse/modules/110-istio/hooks/lib"
+)
+
+const (
+	allianceDiagnosticsMetricsGroup = "alliance_diagnostics"
+	allianceDiagnosticsMetricName   = "d8_istio_alliance_peer_check_error_count"
+
+	allianceCheckMetadataEndpoint = "MetadataEndpoint"
+	allianceCheckAuthentication   = "Authentication"
+	allianceCheckRootCA           = "RootCA"
+	allianceCheckIngressGateways  = "IngressGateways"
+
+	allianceCheckPassed  = "Passed"
+	allianceCheckWarning = "Warning"
+	allianceCheckFailed  = "Failed"
+	allianceCheckSkipped = "Skipped"
+
+	// warn about the remote root CA expiration in advance
+	allianceRootCAExpirationThreshold = 30 * 24 * time.Hour
+)
+
+// allianceGatewayDial checks TCP connectivity to the remote ingress gateway, it is replaced in tests.
+var allianceGatewayDial = func(address string) error {
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+type AlliancePeerDiagnosticsInfo struct {
+	Kind                    string
+	Name                    string
+	TrustDomain             string
+	PublicMetadataEndpoint  string
+	PrivateMetadataEndpoint string
+	Scope                   string
+	IngressGatewayRequired  bool
+}
+
+func (i *AlliancePeerDiagnosticsInfo) SetMetricCheckError(mc go_hook.MetricsCollector, check string, isError float64) {
+	labels := map[string]string{
+		"kind":  i.Kind,
+		"name":  i.Name,
+		"check": check,
+	}
+
+	mc.Set(allianceDiagnosticsMetricName, isError, labels, metrics.WithGroup(allianceDiagnosticsMetricsGroup))
+}
+
+func (i *AlliancePeerDiagnosticsInfo) PatchDiagnostics(pc *object_patch.PatchCollector, diagnostics eeCrd.AllianceDiagnostics) {
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"diagnostics": diagnostics,
+		},
+	}
+
+	pc.MergePatch(patch, "deckhouse.io/v1alpha1", i.Kind, "", i.Name, object_patch.WithSubresource("/status"))
+}
+
+func applyFederationDiagnosticsFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var federation eeCrd.IstioFederation
+
+	err := sdk.FromUnstructured(obj, &federation)
+	if err != nil {
+		return nil, err
+	}
+
+	me := strings.TrimSuffix(federation.Spec.MetadataEndpoint, "/")
+
+	return AlliancePeerDiagnosticsInfo{
+		Kind:                    "IstioFederation",
+		Name:                    federation.GetName(),
+		TrustDomain:             federation.Spec.TrustDomain,
+		PublicMetadataEndpoint:  me + "/public/public.json",
+		PrivateMetadataEndpoint: me + "/private/federation.json",
+		Scope:                   "private-federation",
+		IngressGatewayRequired:  true,
+	}, nil
+}
+
+func applyMulticlusterDiagnosticsFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var multicluster eeCrd.IstioMulticluster
+
+	err := sdk.FromUnstructured(obj, &multicluster)
+	if err != nil {
+		return nil, err
+	}
+
+	me := strings.TrimSuffix(multicluster.Spec.MetadataEndpoint, "/")
+
+	return AlliancePeerDiagnosticsInfo{
+		Kind:                    "IstioMulticluster",
+		Name:                    multicluster.GetName(),
+		PublicMetadataEndpoint:  me + "/public/public.json",
+		PrivateMetadataEndpoint: me + "/private/multicluster.json",
+		Scope:                   "private-multicluster",
+		IngressGatewayRequired:  multicluster.Spec.EnableIngressGateway,
+	}, nil
+}
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	Queue: lib.Queue("alliance-diagnostics"),
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "federations",
+			ApiVersion: "deckhouse.io/v1alpha1",
+			Kind:       "IstioFederation",
+			FilterFunc: applyFederationDiagnosticsFilter,
+		},
+		{
+			Name:       "multiclusters",
+			ApiVersion: "deckhouse.io/v1alpha1",
+			Kind:       "IstioMulticluster",
+			FilterFunc: applyMulticlusterDiagnosticsFilter,
+		},
+	},
+	Schedule: []go_hook.ScheduleConfig{
+		{Name: "cron", Crontab: "* * * * *"},
+	},
+}, dependency.WithExternalDependencies(allianceDiagnostics))
+
+func allianceDiagnostics(input *go_hook.HookInput, dc dependency.Container) error {
+	input.MetricsCollector.Expire(allianceDiagnosticsMetricsGroup)
+
+	if !input.Values.Get("istio.internal.remoteAuthnKeypair.priv").Exists() {
+		return nil
+	}
+
+	var peers = make([]AlliancePeerDiagnosticsInfo, 0)
+	if input.Values.Get("istio.federation.enabled").Bool() {
+		var myTrustDomain = input.Values.Get("global.discovery.clusterDomain").String()
+		for _, federation := range input.Snapshots["federations"] {
+			peer := federation.(AlliancePeerDiagnosticsInfo)
+			if peer.TrustDomain == myTrustDomain {
+				continue
+			}
+			peers = append(peers, peer)
+		}
+	}
+	if input.Values.Get("istio.multicluster.enabled").Bool() {
+		for _, multicluster := range input.Snapshots["multiclusters"] {
+			peers = append(peers, multicluster.(AlliancePeerDiagnosticsInfo))
+		}
+	}
+
+	for _, peer := range peers {
+		diagnostics := diagnoseAlliancePeer(input, dc, peer)
+
+		for _, check := range diagnostics.Checks {
+			switch check.Status {
+			case allianceCheckPassed:
+				peer.SetMetricCheckError(input.MetricsCollector, check.Type, 0)
+			case allianceCheckWarning:
+				input.LogEntry.Warnf("%s %s: %s check warning: %s", peer.Kind, peer.Name, check.Type, check.Message)
+				peer.SetMetricCheckError(input.MetricsCollector, check.Type, 0)
+			case allianceCheckFailed:
+				input.LogEntry.Warnf("%s %s: %s check failed: %s", peer.Kind, peer.Name, check.Type, check.Message)
+				peer.SetMetricCheckError(input.MetricsCollector, check.Type, 1)
+			}
+		}
+
+		peer.PatchDiagnostics(input.PatchCollector, diagnostics)
+	}
+
+	return nil
+}
+
+// diagnoseAlliancePeer runs the checks one after another, the checks which depend on the failed one are skipped.
+func diagnoseAlliancePeer(input *go_hook.HookInput, dc dependency.Container, peer AlliancePeerDiagnosticsInfo) eeCrd.AllianceDiagnostics {
+	checks := map[string]*eeCrd.AllianceDiagnosticsCheck{}
+	for _, checkType := range []string{allianceCheckMetadataEndpoint, allianceCheckAuthentication, allianceCheckRootCA, allianceCheckIngressGateways} {
+		checks[checkType] = &eeCrd.AllianceDiagnosticsCheck{
+			Type:   checkType,
+			Status: allianceCheckSkipped,
+		}
+	}
+	result := func() eeCrd.AllianceDiagnostics {
+		diagnostics := eeCrd.AllianceDiagnostics{
+			Healthy:            true,
+			LastCheckTimestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		for _, checkType := range []string{allianceCheckMetadataEndpoint, allianceCheckAuthentication, allianceCheckRootCA, allianceCheckIngressGateways} {
+			if checks[checkType].Status == allianceCheckFailed {
+				diagnostics.Healthy = false
+			}
+			diagnostics.Checks = append(diagnostics.Checks, *checks[checkType])
+		}
+		return diagnostics
+	}
+	fail := func(checkType, format string, args ...interface{}) {
+		checks[checkType].Status = allianceCheckFailed
+		checks[checkType].Message = fmt.Sprintf(format, args...)
+	}
+	pass := func(checkType, message string) {
+		checks[checkType].Status = allianceCheckPassed
+		checks[checkType].Message = message
+	}
+	// skip sets the reason for all checks which haven't run yet
+	skip := func(reason string) eeCrd.AllianceDiagnostics {
+		for _, check := range checks {
+			if check.Status == allianceCheckSkipped && check.Message == "" {
+				check.Message = reason
+			}
+		}
+		return result()
+	}
+
+	var publicMetadata eeCrd.AlliancePublicMetadata
+	bodyBytes, statusCode, err := lib.HTTPGet(dc.GetHTTPClient(), peer.PublicMetadataEndpoint, "")
+	if err != nil {
+		fail(allianceCheckMetadataEndpoint, "Cannot fetch %s: %v", peer.PublicMetadataEndpoint, err)
+		return skip("The public metadata is unavailable")
+	}
+	if statusCode != http.StatusOK {
+		fail(allianceCheckMetadataEndpoint, "Cannot fetch %s (HTTP Code %d)", peer.PublicMetadataEndpoint, statusCode)
+		return skip("The public metadata is unavailable")
+	}
+	err = json.Unmarshal(bodyBytes, &publicMetadata)
+	if err != nil || publicMetadata.ClusterUUID == "" || publicMetadata.AuthnKeyPub == "" || publicMetadata.RootCA == "" {
+		fail(allianceCheckMetadataEndpoint, "Bad public metadata format in %s", peer.PublicMetadataEndpoint)
+		return skip("The public metadata is unavailable")
+	}
+
+	trustedRootCA := input.Values.Get("istio.internal.remotePublicMetadata." + publicMetadata.ClusterUUID + ".rootCA").String()
+	status, message := checkAllianceRootCA(publicMetadata.RootCA, trustedRootCA, time.Now())
+	checks[allianceCheckRootCA].Status = status
+	checks[allianceCheckRootCA].Message = message
+
+	// the remote cluster verifies our tokens, and we verify the remote ones with its public key
+	keyBlock, _ := pem.Decode([]byte(publicMetadata.AuthnKeyPub))
+	if keyBlock == nil {
+		fail(allianceCheckAuthentication, "The public key of the remote cluster is not a PEM")
+		return skip("The private metadata can't be requested without the public key of the remote cluster")
+	}
+	if _, err := x509.ParsePKIXPublicKey(keyBlock.Bytes); err != nil {
+		fail(allianceCheckAuthentication, "Cannot parse the public key of the remote cluster: %v", err)
+		return skip("The private metadata can't be requested without the public key of the remote cluster")
+	}
+
+	privKey := []byte(input.Values.Get("istio.internal.remoteAuthnKeypair.priv").String())
+	claims := map[string]string{
+		"iss":   "d8-istio",
+		"aud":   publicMetadata.ClusterUUID,
+		"sub":   input.Values.Get("global.discovery.clusterUUID").String(),
+		"scope": peer.Scope,
+	}
+	bearerToken, err := jwt.GenerateJWT(privKey, claims, time.Minute)
+	if err != nil {
+		fail(allianceCheckAuthentication, "Cannot generate the token: %v", err)
+		return skip("The private metadata can't be requested without the token")
+	}
+
+	var privateMetadata struct {
+		IngressGateways *[]eeCrd.FederationIngressGateways `json:"ingressGateways"`
+	}
+	bodyBytes, statusCode, err = lib.HTTPGet(dc.GetHTTPClient(), peer.PrivateMetadataEndpoint, bearerToken)
+	if err != nil {
+		fail(allianceCheckMetadataEndpoint, "Cannot fetch %s: %v", peer.PrivateMetadataEndpoint, err)
+		return skip("The private metadata is unavailable")
+	}
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		pass(allianceCheckMetadataEndpoint, "")
+		fail(allianceCheckAuthentication, "The remote cluster rejected the token (HTTP Code %d), make sure it has the %s resource for this cluster", statusCode, peer.Kind)
+		return skip("The private metadata is unavailable")
+	}
+	if statusCode != http.StatusOK {
+		fail(allianceCheckMetadataEndpoint, "Cannot fetch %s (HTTP Code %d)", peer.PrivateMetadataEndpoint, statusCode)
+		return skip("The private metadata is unavailable")
+	}
+	err = json.Unmarshal(bodyBytes, &privateMetadata)
+	if err != nil {
+		fail(allianceCheckMetadataEndpoint, "Bad private metadata format in %s", peer.PrivateMetadataEndpoint)
+		return skip("The private metadata is unavailable")
+	}
+	pass(allianceCheckMetadataEndpoint, "")
+	pass(allianceCheckAuthentication, "")
+
+	if !peer.IngressGatewayRequired {
+		return skip("The ingress gateway is disabled")
+	}
+	if privateMetadata.IngressGateways == nil || len(*privateMetadata.IngressGateways) == 0 {
+		fail(allianceCheckIngressGateways, "The remote cluster publishes no ingress gateways")
+		return result()
+	}
+
+	var unreachable []string
+	for _, gateway := range *privateMetadata.IngressGateways {
+		address := net.JoinHostPort(gateway.Address, strconv.Itoa(int(gateway.Port)))
+		if err := allianceGatewayDial(address); err != nil {
+			unreachable = append(unreachable, fmt.Sprintf("%s (%v)", address, err))
+		}
+	}
+	if len(unreachable) > 0 {
+		fail(allianceCheckIngressGateways, "Unreachable: %s", strings.Join(unreachable, ", "))
+		return result()
+	}
+	pass(allianceCheckIngressGateways, "")
+
+	return result()
+}
+
+// checkAllianceRootCA verifies that the remote root CA bundle is a valid chain and matches the one trusted by the mesh.
+// The check warns if a certificate of the chain expires soon.
+func checkAllianceRootCA(rootCA, trustedRootCA string, now time.Time) (string, string) {
+	var certs []*x509.Certificate
+	rest := []byte(rootCA)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return allianceCheckFailed, fmt.Sprintf("Cannot parse the root CA certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return allianceCheckFailed, "The root CA contains no certificates"
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	var hasRoot bool
+	for _, cert := range certs {
+		if !cert.IsCA {
+			return allianceCheckFailed, fmt.Sprintf("The certificate %q is not a CA", cert.Subject.String())
+		}
+		if cert.CheckSignatureFrom(cert) == nil {
+			roots.AddCert(cert)
+			hasRoot = true
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	if !hasRoot {
+		return allianceCheckFailed, "The root CA contains no self-signed certificate"
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return allianceCheckFailed, fmt.Sprintf("The root CA chain is not trusted: %v", err)
+	}
+
+	if trustedRootCA != "" && trustedRootCA != rootCA {
+		return allianceCheckFailed, "The root CA differs from the one trusted by the mesh"
+	}
+
+	for _, cert := range certs {
+		if cert.NotAfter.Before(now.Add(allianceRootCAExpirationThreshold)) {
+			return allianceCheckWarning, fmt.Sprintf("The certificate %q expires at %s", cert.Subject.String(), cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	if trustedRootCA == "" {
+		return allianceCheckPassed, "The root CA isn't trusted by the mesh yet"
+	}
+
+	return allianceCheckPassed, ""
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package ee
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+func generateAllianceTestCA(isCA bool, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "remote-root-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+const allianceTestAuthnKeyPub = "-----BEGIN ED25519 PUBLIC KEY-----\nMCowBQYDK2VwAyEAKWjdKDeIIT4xESCMhbol662vNMpq4DxFct8GvJ500Xs=\n-----END ED25519 PUBLIC KEY-----\n"
+
+type allianceTestResponse struct {
+	Code     int
+	Response string
+}
+
+func setAllianceHTTPMock(respMap map[string]allianceTestResponse) {
+	dependency.TestDC.HTTPClient.DoMock.
+		Set(func(req *http.Request) (*http.Response, error) {
+			mockResponse, ok := respMap[req.Host+req.URL.Path]
+			if !ok {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{
+				Header:     map[string][]string{"Content-Type": {"application/json"}},
+				StatusCode: mockResponse.Code,
+				Body:       io.NopCloser(bytes.NewBufferString(mockResponse.Response)),
+			}, nil
+		})
+}
+
+func allianceTestPublicMetadata(clusterUUID, rootCA string) string {
+	data, err := json.Marshal(map[string]string{
+		"clusterUUID": clusterUUID,
+		"authnKeyPub": allianceTestAuthnKeyPub,
+		"rootCA":      rootCA,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return string(data)
+}
+
+var _ = Describe("Istio hooks :: alliance_diagnostics ::", func() {
+	f := HookExecutionConfigInit(`{
+  "global":{
+    "discovery":{
+      "clusterUUID":"deadbeef-mycluster",
+      "clusterDomain": "my.cluster"
+    }
+  },
+  "istio":{"federation":{},"multicluster":{},"internal":{"remotePublicMetadata":{},"remoteAuthnKeypair": {
+    "pub":"-----BEGIN ED25519 PUBLIC KEY-----\nMCowBQYDK2VwAyEAKWjdKDeIIT4xESCMhbol662vNMpq4DxFct8GvJ500Xs=\n-----END ED25519 PUBLIC KEY-----\n",
+    "priv":"-----BEGIN ED25519 PRIVATE KEY-----\nMC4CAQAwBQYDK2VwBCIEIMgNk3rr2AmIIlkKTAM9fG6+hMKvwF+pMAT3ID3M0OFK\n-----END ED25519 PRIVATE KEY-----\n"
+  }}}
+}`, "")
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "IstioFederation", false)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "IstioMulticluster", false)
+
+	rootCA := generateAllianceTestCA(true, time.Now().Add(365*24*time.Hour))
+
+	var dialedAddresses []string
+	BeforeEach(func() {
+		dialedAddresses = nil
+		allianceGatewayDial = func(address string) error {
+			dialedAddresses = append(dialedAddresses, address)
+			if strings.HasPrefix(address, "unreachable") {
+				return errors.New("i/o timeout")
+			}
+			return nil
+		}
+	})
+
+	Context("Empty cluster and minimal settings", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(``))
+			f.RunHook()
+		})
+
+		It("Hook must execute successfully", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(string(f.LogrusOutput.Contents())).To(HaveLen(0))
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(1))
+			Expect(m[0].Action).Should(Equal("expire"))
+		})
+	})
+
+	Context("Healthy federation and local federation", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.federation.enabled", true)
+			f.ValuesSetFromYaml("istio.internal.remotePublicMetadata", []byte(`{"remote-uuid": {"rootCA": `+string(mustJSON(rootCA))+`}}`))
+			setAllianceHTTPMock(map[string]allianceTestResponse{
+				"remote/metadata/public/public.json":      {http.StatusOK, allianceTestPublicMetadata("remote-uuid", rootCA)},
+				"remote/metadata/private/federation.json": {http.StatusOK, `{"ingressGateways":[{"address":"1.2.3.4","port":15443}],"publicServices":[]}`},
+			})
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IstioFederation
+metadata:
+  name: remote
+spec:
+  trustDomain: "remote.cluster"
+  metadataEndpoint: "https://remote/metadata/"
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IstioFederation
+metadata:
+  name: local
+spec:
+  trustDomain: "my.cluster"
+  metadataEndpoint: "https://local/metadata/"
+`))
+			f.RunHook()
+		})
+
+		It("All checks must pass", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(string(f.LogrusOutput.Contents())).To(HaveLen(0))
+			Expect(dialedAddresses).To(Equal([]string{"1.2.3.4:15443"}))
+
+			remote := f.KubernetesGlobalResource("IstioFederation", "remote")
+			Expect(remote.Field("status.diagnostics.healthy").Bool()).To(BeTrue())
+			Expect(remote.Field("status.diagnostics.lastCheckTimestamp").Exists()).To(BeTrue())
+			Expect(remote.Field("status.diagnostics.checks").String()).To(MatchJSON(`[
+				{"type": "MetadataEndpoint", "status": "Passed"},
+				{"type": "Authentication", "status": "Passed"},
+				{"type": "RootCA", "status": "Passed"},
+				{"type": "IngressGateways", "status": "Passed"}
+			]`))
+
+			Expect(f.KubernetesGlobalResource("IstioFederation", "local").Field("status").Exists()).To(BeFalse())
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(5))
+			for _, metric := range m[1:] {
+				Expect(metric.Name).To(Equal("d8_istio_alliance_peer_check_error_count"))
+				Expect(*metric.Value).To(Equal(0.0))
+				Expect(metric.Labels["kind"]).To(Equal("IstioFederation"))
+				Expect(metric.Labels["name"]).To(Equal("remote"))
+			}
+		})
+	})
+
+	Context("Federation with the changed root CA and unreachable gateway", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.federation.enabled", true)
+			f.ValuesSetFromYaml("istio.internal.remotePublicMetadata", []byte(`{"remote-uuid": {"rootCA": "old-root-ca"}}`))
+			setAllianceHTTPMock(map[string]allianceTestResponse{
+				"remote/metadata/public/public.json":      {http.StatusOK, allianceTestPublicMetadata("remote-uuid", rootCA)},
+				"remote/metadata/private/federation.json": {http.StatusOK, `{"ingressGateways":[{"address":"1.2.3.4","port":15443},{"address":"unreachable.host","port":15443}],"publicServices":[]}`},
+			})
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IstioFederation
+metadata:
+  name: remote
+spec:
+  trustDomain: "remote.cluster"
+  metadataEndpoint: "https://remote/metadata/"
+`))
+			f.RunHook()
+		})
+
+		It("RootCA and IngressGateways checks must fail", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			remote := f.KubernetesGlobalResource("IstioFederation", "remote")
+			Expect(remote.Field("status.diagnostics.healthy").Bool()).To(BeFalse())
+			Expect(remote.Field("status.diagnostics.checks").String()).To(MatchJSON(`[
+				{"type": "MetadataEndpoint", "status": "Passed"},
+				{"type": "Authentication", "status": "Passed"},
+				{"type": "RootCA", "status": "Failed", "message": "The root CA differs from the one trusted by the mesh"},
+				{"type": "IngressGateways", "status": "Failed", "message": "Unreachable: unreachable.host:15443 (i/o timeout)"}
+			]`))
+
+			Expect(string(f.LogrusOutput.Contents())).To(ContainSubstring("IstioFederation remote: RootCA check failed"))
+			Expect(string(f.LogrusOutput.Contents())).To(ContainSubstring("IstioFederation remote: IngressGateways check failed"))
+		})
+	})
+
+	Context("Multicluster which rejects the token and unavailable multicluster", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.multicluster.enabled", true)
+			setAllianceHTTPMock(map[string]allianceTestResponse{
+				"remote/metadata/public/public.json":        {http.StatusOK, allianceTestPublicMetadata("remote-uuid", rootCA)},
+				"remote/metadata/private/multicluster.json": {http.StatusForbidden, ``},
+				"down/metadata/public/public.json":          {http.StatusBadGateway, ``},
+			})
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IstioMulticluster
+metadata:
+  name: remote
+spec:
+  metadataEndpoint: "https://remote/metadata/"
+  enableIngressGateway: false
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IstioMulticluster
+metadata:
+  name: down
+spec:
+  metadataEndpoint: "https://down/metadata/"
+  enableIngressGateway: true
+`))
+			f.RunHook()
+		})
+
+		It("Must report the failed checks", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(dialedAddresses).To(BeEmpty())
+
+			remote := f.KubernetesGlobalResource("IstioMulticluster", "remote")
+			Expect(remote.Field("status.diagnostics.healthy").Bool()).To(BeFalse())
+			Expect(remote.Field("status.diagnostics.checks").String()).To(MatchJSON(`[
+				{"type": "MetadataEndpoint", "status": "Passed"},
+				{"type": "Authentication", "status": "Failed", "message": "The remote cluster rejected the token (HTTP Code 403), make sure it has the IstioMulticluster resource for this cluster"},
+				{"type": "RootCA", "status": "Passed", "message": "The root CA isn't trusted by the mesh yet"},
+				{"type": "IngressGateways", "status": "Skipped", "message": "The private metadata is unavailable"}
+			]`))
+
+			down := f.KubernetesGlobalResource("IstioMulticluster", "down")
+			Expect(down.Field("status.diagnostics.healthy").Bool()).To(BeFalse())
+			Expect(down.Field("status.diagnostics.checks").String()).To(MatchJSON(`[
+				{"type": "MetadataEndpoint", "status": "Failed", "message": "Cannot fetch https://down/metadata/public/public.json (HTTP Code 502)"},
+				{"type": "Authentication", "status": "Skipped", "message": "The public metadata is unavailable"},
+				{"type": "RootCA", "status": "Skipped", "message": "The public metadata is unavailable"},
+				{"type": "IngressGateways", "status": "Skipped", "message": "The public metadata is unavailable"}
+			]`))
+
+			var failed []string
+			for _, metric := range f.MetricsCollector.CollectedMetrics()[1:] {
+				if *metric.Value == 1 {
+					failed = append(failed, metric.Labels["name"]+"/"+metric.Labels["check"])
+				}
+			}
+			Expect(failed).To(ConsistOf("remote/Authentication", "down/MetadataEndpoint"))
+		})
+	})
+
+	Context("Root CA checks", func() {
+		It("Must reject a certificate which is not a CA", func() {
+			status, message := checkAllianceRootCA(generateAllianceTestCA(false, time.Now().Add(time.Hour*24*365)), "", time.Now())
+			Expect(status).To(Equal(allianceCheckFailed))
+			Expect(message).To(Equal(`The certificate "CN=remote-root-ca" is not a CA`))
+		})
+
+		It("Must reject an expired chain", func() {
+			status, message := checkAllianceRootCA(rootCA, rootCA, time.Now().Add(2*365*24*time.Hour))
+			Expect(status).To(Equal(allianceCheckFailed))
+			Expect(message).To(ContainSubstring("The root CA chain is not trusted"))
+		})
+
+		It("Must warn about the upcoming expiration", func() {
+			expiring := generateAllianceTestCA(true, time.Now().Add(24*time.Hour))
+			status, message := checkAllianceRootCA(expiring, expiring, time.Now())
+			Expect(status).To(Equal(allianceCheckWarning))
+			Expect(message).To(HavePrefix(`The certificate "CN=remote-root-ca" expires at`))
+		})
+
+		It("Must reject garbage", func() {
+			status, message := checkAllianceRootCA("bad-root-ca", "", time.Now())
+			Expect(status).To(Equal(allianceCheckFailed))
+			Expect(message).To(Equal("The root CA contains no certificates"))
+		})
+	})
+})
+
+func mustJSON(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	return data
+}
@@ -17,6 +17,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
 	"github.com/deckhouse/deckhouse/go_lib/telemetry"
 	"github.com/deckhouse/deckhouse/modules/110-istio/hooks/lib"
 	"github.com/deckhouse/deckhouse/modules/110-istio/hooks/lib/istio_versions"
@@ -142,8 +143,17 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 				},
 			},
 		},
+		{
+			Name:       "dataplane_upgrades",
+			ApiVersion: "deckhouse.io/v1alpha1",
+			Kind:       "IstioDataplaneUpgrade",
+			FilterFunc: applyDataplaneUpgradeFilter,
+		},
 	},
-}, dataplaneHandler)
+	Schedule: []go_hook.ScheduleConfig{
+		{Name: "cron", Crontab: "* * * * *"},
+	},
+}, dependency.WithExternalDependencies(dataplaneHandler))
 
 // Needed to extend v1.Pod with our methods
 type IstioDrivenPod v1.Pod
@@ -246,6 +256,7 @@ type IstioDrivenNamespaceFilterResult struct {
 	RevisionRaw             string
 	Revision                string
 	AutoUpgradeLabelExists  bool
+	Labels                  map[string]string
 }
 
 func applyIstioDrivenNamespaceFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
@@ -254,6 +265,7 @@ func applyIstioDrivenNamespaceFilter(obj *unstructured.Unstructured) (go_hook.Fi
 	var namespaceInfo = IstioDrivenNamespaceFilterResult{
 		Name:                    obj.GetName(),
 		DeletionTimestampExists: deletionTimestampExists,
+		Labels:                  obj.GetLabels(),
 	}
 
 	if revision, ok := obj.GetLabels()[autoUpgradeLabelName]; ok {
@@ -406,7 +418,7 @@ func applyReplicaSetFilter(obj *unstructured.Unstructured) (go_hook.FilterResult
 	return result, nil
 }
 
-func dataplaneHandler(input *go_hook.HookInput) error {
+func dataplaneHandler(input *go_hook.HookInput, dc dependency.Container) error {
 	if !input.Values.Get("istio.internal.globalVersion").Exists() {
 		return nil
 	}
@@ -615,5 +627,6 @@ func dataplaneHandler(input *go_hook.HookInput) error {
 			ignoredNamespace[candidate.namespace] = struct{}{}
 		}
 	}
-	return nil
+
+	return orchestrateDataplaneUpgrades(input, dc, versionMap, globalRevision, istioNamespaceMap)
 }
@@ -6,6 +6,9 @@ Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https
 package ee
 
 import (
+	"bytes"
+	"io"
+	"net/http"
 	"strings"
 
 	"github.com/flant/shell-operator/pkg/metric_storage/operation"
@@ -14,6 +17,7 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/utils/pointer"
 
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
 	"github.com/deckhouse/deckhouse/modules/110-istio/hooks/lib"
 	. "github.com/deckhouse/deckhouse/testing/hooks"
 )
@@ -327,6 +331,7 @@ var hookInitValues = `
 var _ = Describe("Istio hooks :: dataplane_handler :: metrics ::", func() {
 
 	f := HookExecutionConfigInit(hookInitValues, "")
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "IstioDataplaneUpgrade", false)
 	Context("Empty cluster and minimal settings", func() {
 		BeforeEach(func() {
 			f.RunHook()
@@ -1017,6 +1022,7 @@ var _ = Describe("Istio hooks :: dataplane_handler :: metrics ::", func() {
 var _ = Describe("Istio hooks :: dataplane_handler :: dataplane_upgrade ::", func() {
 
 	f := HookExecutionConfigInit(hookInitValues, "")
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "IstioDataplaneUpgrade", false)
 
 	istioNsYAML := generateIstioNsYAML(nsParams{
 		GlobalRevision: true,
@@ -1586,3 +1592,353 @@ var _ = Describe("Istio hooks :: dataplane_handler :: dataplane_upgrade ::", fun
 	})
 
 })
+
+type dataplaneUpgradeParams struct {
+	Name          string
+	Version       string
+	FailurePolicy string
+	MaxErrorRate  string
+	Status        string
+}
+
+const dataplaneUpgradeTemplate = `apiVersion: deckhouse.io/v1alpha1
+kind: IstioDataplaneUpgrade
+metadata:
+  name: {{ .Name }}
+spec:
+  version: "{{ .Version }}"
+  batchSize: 1
+  failurePolicy: {{ .FailurePolicy }}
+  healthChecks:
+    readinessTimeout: 10m
+    observationPeriod: 5m
+    {{- if .MaxErrorRate }}
+    maxErrorRate: {{ .MaxErrorRate }}
+    {{- end }}
+{{- if .Status }}
+status:
+{{ .Status }}
+{{- end }}
+`
+
+func generateDataplaneUpgradeYAML(upgrade dataplaneUpgradeParams) string {
+	if upgrade.FailurePolicy == "" {
+		upgrade.FailurePolicy = "Pause"
+	}
+	return lib.TemplateToYAML(dataplaneUpgradeTemplate, upgrade)
+}
+
+// the batch with the "ns" namespace was started long ago and the Deployment is ready
+const dataplaneUpgradeInProgressStatus = `  phase: Progressing
+  targetRevision: v1x71
+  startedAt: "2020-01-01T00:00:00Z"
+  currentBatch: 1
+  totalBatches: 1
+  batches:
+  - state: InProgress
+    startedAt: "2020-01-01T00:00:00Z"
+    namespaces:
+    - name: ns
+      previousRevision: global`
+
+var _ = Describe("Istio hooks :: dataplane_handler :: istio_dataplane_upgrade ::", func() {
+	f := HookExecutionConfigInit(hookInitValues, "")
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "IstioDataplaneUpgrade", false)
+
+	readyDeployYAML := generateIstioDeploymentYAML(deployParams{
+		Replicas:              2,
+		FullVersionAnnotation: "1.71.71",
+	})
+
+	oldPodYAML := generateIstioPodYAML(podParams{
+		CurrentRevision: "v1x42",
+		FullVersion:     "1.42.42",
+	})
+
+	newPodYAML := generateIstioPodYAML(podParams{
+		CurrentRevision: "v1x71",
+		FullVersion:     "1.71.71",
+	})
+
+	migratedNsYAML := generateIstioNsYAML(nsParams{DefiniteRevision: "v1x71"})
+
+	Context("New upgrade", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				generateIstioNsYAML(nsParams{GlobalRevision: true}),
+				generateIstioNsYAML(nsParams{Name: "ns-current", DefiniteRevision: "v1x71"}),
+				generateIstioDeploymentYAML(deployParams{Replicas: 2}),
+				oldPodYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{Name: "to-1-71", Version: "1.71"}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Must switch the first batch to the target revision", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			ns := f.KubernetesGlobalResource("Namespace", nsName)
+			Expect(ns.Field(`metadata.labels.istio\.io/rev`).String()).To(Equal("v1x71"))
+			Expect(ns.Field(`metadata.labels.istio-injection`).Exists()).To(BeFalse())
+
+			d := f.KubernetesResource("Deployment", nsName, deployName)
+			Expect(d.Field(`spec.template.metadata.annotations.istio\.deckhouse\.io/full-version`).String()).To(Equal("1.71.71"))
+			Expect(d.Field(`spec.template.metadata.annotations.kubectl\.kubernetes\.io/restartedAt`).Exists()).To(BeTrue())
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.phase").String()).To(Equal("Progressing"))
+			Expect(upgrade.Field("status.targetRevision").String()).To(Equal("v1x71"))
+			Expect(upgrade.Field("status.totalBatches").Int()).To(Equal(int64(1)))
+			Expect(upgrade.Field("status.batches").String()).To(MatchJSON(`[{
+				"state": "InProgress",
+				"namespaces": [{"name": "ns", "previousRevision": "global"}],
+				"message": "Restarting workloads with revision v1x71",
+				"startedAt": "` + upgrade.Field("status.batches.0.startedAt").String() + `"
+			}]`))
+		})
+	})
+
+	Context("Two upgrades and a version which is not installed", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				generateIstioNsYAML(nsParams{GlobalRevision: true}),
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{Name: "a-to-1-99", Version: "1.99"}),
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{Name: "b-to-1-71", Version: "1.71"}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Only the first upgrade must be processed", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			first := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "a-to-1-99")
+			Expect(first.Field("status.phase").String()).To(Equal("Pending"))
+			Expect(first.Field("status.message").String()).To(ContainSubstring("Version 1.99 is not installed"))
+
+			second := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "b-to-1-71")
+			Expect(second.Field("status.phase").String()).To(Equal("Pending"))
+			Expect(second.Field("status.message").String()).To(Equal(`Waiting for the IstioDataplaneUpgrade "a-to-1-99" to finish`))
+
+			Expect(f.KubernetesGlobalResource("Namespace", nsName).Field(`metadata.labels.istio\.io/rev`).Exists()).To(BeFalse())
+		})
+	})
+
+	Context("Batch is ready and observed", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				migratedNsYAML,
+				readyDeployYAML,
+				newPodYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{
+					Name:    "to-1-71",
+					Version: "1.71",
+					Status:  dataplaneUpgradeInProgressStatus + "\n    readyAt: \"2020-01-01T00:01:00Z\"",
+				}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Batch must succeed", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.phase").String()).To(Equal("Progressing"))
+			Expect(upgrade.Field("status.batches.0.state").String()).To(Equal("Succeeded"))
+			Expect(upgrade.Field("status.batches.0.finishedAt").Exists()).To(BeTrue())
+		})
+	})
+
+	Context("All batches succeeded", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				migratedNsYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{
+					Name:    "to-1-71",
+					Version: "1.71",
+					Status:  strings.Replace(dataplaneUpgradeInProgressStatus, "state: InProgress", "state: Succeeded", 1),
+				}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Upgrade must succeed", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.phase").String()).To(Equal("Succeeded"))
+			Expect(upgrade.Field("status.finishedAt").Exists()).To(BeTrue())
+		})
+	})
+
+	Context("Pods are not recreated in time, Pause policy", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				migratedNsYAML,
+				readyDeployYAML,
+				oldPodYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{
+					Name:    "to-1-71",
+					Version: "1.71",
+					Status:  dataplaneUpgradeInProgressStatus,
+				}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Upgrade must be paused", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.phase").String()).To(Equal("Paused"))
+			Expect(upgrade.Field("status.batches.0.state").String()).To(Equal("Failed"))
+			Expect(upgrade.Field("status.batches.0.message").String()).To(Equal("Not ready after 10m0s: 1 Pods with the previous revision"))
+
+			Expect(f.KubernetesGlobalResource("Namespace", nsName).Field(`metadata.labels.istio\.io/rev`).String()).To(Equal("v1x71"))
+		})
+	})
+
+	Context("Pods are not recreated in time, Rollback policy", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				migratedNsYAML,
+				readyDeployYAML,
+				oldPodYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{
+					Name:          "to-1-71",
+					Version:       "1.71",
+					FailurePolicy: "Rollback",
+					Status:        dataplaneUpgradeInProgressStatus,
+				}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Batch must be rolled back", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			ns := f.KubernetesGlobalResource("Namespace", nsName)
+			Expect(ns.Field(`metadata.labels.istio\.io/rev`).Exists()).To(BeFalse())
+			Expect(ns.Field(`metadata.labels.istio-injection`).String()).To(Equal("enabled"))
+
+			d := f.KubernetesResource("Deployment", nsName, deployName)
+			Expect(d.Field(`spec.template.metadata.annotations.istio\.deckhouse\.io/full-version`).String()).To(Equal("1.42.42"))
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.phase").String()).To(Equal("RolledBack"))
+			Expect(upgrade.Field("status.batches.0.state").String()).To(Equal("RolledBack"))
+		})
+	})
+
+	Context("Namespace of the pending batch is deleted", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				generateIstioNsYAML(nsParams{GlobalRevision: true}),
+				generateIstioDeploymentYAML(deployParams{Replicas: 2}),
+				oldPodYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{
+					Name:    "to-1-71",
+					Version: "1.71",
+					Status: `  phase: Progressing
+  targetRevision: v1x71
+  startedAt: "2020-01-01T00:00:00Z"
+  currentBatch: 1
+  totalBatches: 1
+  batches:
+  - state: Pending
+    namespaces:
+    - name: deleted
+      previousRevision: global
+    - name: ns
+      previousRevision: global`,
+				}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Must skip the deleted namespace", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.KubernetesGlobalResource("Namespace", "deleted").Exists()).To(BeFalse())
+			Expect(f.KubernetesGlobalResource("Namespace", nsName).Field(`metadata.labels.istio\.io/rev`).String()).To(Equal("v1x71"))
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.batches.0.state").String()).To(Equal("InProgress"))
+		})
+	})
+
+	Context("Namespace of the rolled back batch is deleted", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				migratedNsYAML,
+				readyDeployYAML,
+				oldPodYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{
+					Name:          "to-1-71",
+					Version:       "1.71",
+					FailurePolicy: "Rollback",
+					Status: dataplaneUpgradeInProgressStatus + `
+    - name: deleted
+      previousRevision: global`,
+				}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Must roll back the existing namespaces only", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.KubernetesGlobalResource("Namespace", "deleted").Exists()).To(BeFalse())
+			Expect(f.KubernetesGlobalResource("Namespace", nsName).Field(`metadata.labels.istio-injection`).String()).To(Equal("enabled"))
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.phase").String()).To(Equal("RolledBack"))
+		})
+	})
+
+	Context("Error rate exceeds the threshold", func() {
+		var query string
+
+		BeforeEach(func() {
+			dependency.TestDC.HTTPClient.DoMock.
+				Set(func(req *http.Request) (*http.Response, error) {
+					query = req.URL.Query().Get("query")
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"0.1"]}]}}`)),
+					}, nil
+				})
+
+			f.ValuesSet("istio.internal.globalVersion", "1.42")
+			f.BindingContexts.Set(f.KubeStateSet(strings.Join([]string{
+				migratedNsYAML,
+				readyDeployYAML,
+				newPodYAML,
+				generateDataplaneUpgradeYAML(dataplaneUpgradeParams{
+					Name:         "to-1-71",
+					Version:      "1.71",
+					MaxErrorRate: "5",
+					Status:       dataplaneUpgradeInProgressStatus,
+				}),
+			}, "\n---\n")))
+			f.RunHook()
+		})
+
+		It("Upgrade must be paused", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(query).To(ContainSubstring(`destination_workload_namespace=~"ns"`))
+			Expect(query).To(ContainSubstring(`[300s]`))
+
+			upgrade := f.KubernetesGlobalResource("IstioDataplaneUpgrade", "to-1-71")
+			Expect(upgrade.Field("status.phase").String()).To(Equal("Paused"))
+			Expect(upgrade.Field("status.batches.0.message").String()).To(Equal("Error rate 10.00% exceeds 5.00%"))
+		})
+	})
+})
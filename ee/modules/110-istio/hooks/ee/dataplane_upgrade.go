@@ -0,0 +1,537 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package ee
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/sdk"
+	"github.com/flant/shell-operator/pkg/kube/object_patch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	d8http "github.com/deckhouse/deckhouse/go_lib/dependency/http"
+	"github.com/deckhouse/deckhouse/modules/110-istio/hooks/lib/istio_versions"
+)
+
+const (
+	dataplaneUpgradeRevisionGlobal = "global"
+
+	dataplaneUpgradePhasePending     = "Pending"
+	dataplaneUpgradePhaseProgressing = "Progressing"
+	dataplaneUpgradePhasePaused      = "Paused"
+	dataplaneUpgradePhaseSucceeded   = "Succeeded"
+	dataplaneUpgradePhaseRolledBack  = "RolledBack"
+
+	batchStatePending    = "Pending"
+	batchStateInProgress = "InProgress"
+	batchStateSucceeded  = "Succeeded"
+	batchStateFailed     = "Failed"
+	batchStateRolledBack = "RolledBack"
+
+	failurePolicyRollback = "Rollback"
+
+	restartPatchTemplate = `{ "spec": { "template": { "metadata": { "annotations": { "istio.deckhouse.io/full-version": "%s", "kubectl.kubernetes.io/restartedAt": "%s" } } } } }`
+
+	errorRateQueryTemplate = `sum(rate(istio_requests_total{reporter="destination",destination_workload_namespace=~"%[1]s",response_code=~"5.."}[%[2]s]))` +
+		` / sum(rate(istio_requests_total{reporter="destination",destination_workload_namespace=~"%[1]s"}[%[2]s]))`
+)
+
+type IstioDataplaneUpgrade struct {
+	Name              string
+	Generation        int64
+	CreationTimestamp time.Time
+	Spec              DataplaneUpgradeSpec
+	Status            DataplaneUpgradeStatus
+}
+
+type DataplaneUpgradeSpec struct {
+	Version           string                `json:"version"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	BatchSize         int                   `json:"batchSize"`
+	FailurePolicy     string                `json:"failurePolicy"`
+	Paused            bool                  `json:"paused"`
+	HealthChecks      struct {
+		ReadinessTimeout  string   `json:"readinessTimeout"`
+		ObservationPeriod string   `json:"observationPeriod"`
+		MaxErrorRate      *float64 `json:"maxErrorRate,omitempty"`
+	} `json:"healthChecks"`
+}
+
+type DataplaneUpgradeStatus struct {
+	Phase            string                  `json:"phase,omitempty"`
+	Message          string                  `json:"message,omitempty"`
+	TargetRevision   string                  `json:"targetRevision,omitempty"`
+	CurrentBatch     int                     `json:"currentBatch,omitempty"`
+	TotalBatches     int                     `json:"totalBatches,omitempty"`
+	FailedGeneration int64                   `json:"failedGeneration"`
+	StartedAt        string                  `json:"startedAt,omitempty"`
+	FinishedAt       string                  `json:"finishedAt,omitempty"`
+	Batches          []DataplaneUpgradeBatch `json:"batches,omitempty"`
+}
+
+type DataplaneUpgradeBatch struct {
+	State      string                      `json:"state"`
+	Namespaces []DataplaneUpgradeNamespace `json:"namespaces"`
+	Message    string                      `json:"message,omitempty"`
+	StartedAt  string                      `json:"startedAt,omitempty"`
+	ReadyAt    string                      `json:"readyAt,omitempty"`
+	FinishedAt string                      `json:"finishedAt,omitempty"`
+}
+
+type DataplaneUpgradeNamespace struct {
+	Name             string `json:"name"`
+	PreviousRevision string `json:"previousRevision"`
+}
+
+func applyDataplaneUpgradeFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var upgrade struct {
+		Spec   DataplaneUpgradeSpec   `json:"spec"`
+		Status DataplaneUpgradeStatus `json:"status"`
+	}
+	err := sdk.FromUnstructured(obj, &upgrade)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert IstioDataplaneUpgrade: %v", err)
+	}
+
+	return IstioDataplaneUpgrade{
+		Name:              obj.GetName(),
+		Generation:        obj.GetGeneration(),
+		CreationTimestamp: obj.GetCreationTimestamp().Time,
+		Spec:              upgrade.Spec,
+		Status:            upgrade.Status,
+	}, nil
+}
+
+// dataplaneUpgrader moves namespaces to the target revision batch by batch.
+type dataplaneUpgrader struct {
+	input          *go_hook.HookInput
+	dc             dependency.Container
+	versionMap     istio_versions.IstioVersionsMap
+	globalRevision string
+	namespaces     map[string]IstioDrivenNamespaceFilterResult
+	now            time.Time
+}
+
+func orchestrateDataplaneUpgrades(input *go_hook.HookInput, dc dependency.Container, versionMap istio_versions.IstioVersionsMap, globalRevision string, namespaces map[string]IstioDrivenNamespaceFilterResult) error {
+	upgrades := make([]IstioDataplaneUpgrade, 0, len(input.Snapshots["dataplane_upgrades"]))
+	for _, obj := range input.Snapshots["dataplane_upgrades"] {
+		upgrades = append(upgrades, obj.(IstioDataplaneUpgrade))
+	}
+	sort.Slice(upgrades, func(i, j int) bool {
+		if upgrades[i].CreationTimestamp.Equal(upgrades[j].CreationTimestamp) {
+			return upgrades[i].Name < upgrades[j].Name
+		}
+		return upgrades[i].CreationTimestamp.Before(upgrades[j].CreationTimestamp)
+	})
+
+	u := &dataplaneUpgrader{
+		input:          input,
+		dc:             dc,
+		versionMap:     versionMap,
+		globalRevision: globalRevision,
+		namespaces:     namespaces,
+		now:            time.Now().UTC(),
+	}
+
+	// only the oldest unfinished upgrade is processed, the others wait for it
+	var active string
+	for _, upgrade := range upgrades {
+		status := upgrade.Status
+
+		switch {
+		case status.Phase == dataplaneUpgradePhaseSucceeded || status.Phase == dataplaneUpgradePhaseRolledBack:
+		case active != "":
+			status.Phase = dataplaneUpgradePhasePending
+			status.Message = fmt.Sprintf("Waiting for the IstioDataplaneUpgrade %q to finish", active)
+		default:
+			active = upgrade.Name
+			var err error
+			status, err = u.reconcile(upgrade)
+			if err != nil {
+				return fmt.Errorf("IstioDataplaneUpgrade %s: %v", upgrade.Name, err)
+			}
+		}
+
+		input.PatchCollector.MergePatch(map[string]interface{}{"status": status},
+			"deckhouse.io/v1alpha1", "IstioDataplaneUpgrade", "", upgrade.Name, object_patch.WithSubresource("/status"))
+	}
+
+	return nil
+}
+
+func (u *dataplaneUpgrader) reconcile(upgrade IstioDataplaneUpgrade) (DataplaneUpgradeStatus, error) {
+	status := upgrade.Status
+	spec := upgrade.Spec
+
+	target, ok := u.versionMap[spec.Version]
+	if !ok {
+		status.Phase = dataplaneUpgradePhasePending
+		status.Message = fmt.Sprintf("Version %s is not installed, add it to the istio.additionalVersions parameter", spec.Version)
+		return status, nil
+	}
+	if !u.versionMap.IsFullVersionReady(target.FullVersion) {
+		status.Phase = dataplaneUpgradePhasePending
+		status.Message = fmt.Sprintf("Waiting for the control plane of revision %s to become ready", target.Revision)
+		return status, nil
+	}
+
+	readinessTimeout, err := parseDurationWithDefault(spec.HealthChecks.ReadinessTimeout, 10*time.Minute)
+	if err != nil {
+		return status, fmt.Errorf("parse readinessTimeout: %v", err)
+	}
+	observationPeriod, err := parseDurationWithDefault(spec.HealthChecks.ObservationPeriod, 5*time.Minute)
+	if err != nil {
+		return status, fmt.Errorf("parse observationPeriod: %v", err)
+	}
+
+	if status.Batches == nil {
+		batches, err := u.planBatches(spec, target.Revision)
+		if err != nil {
+			return status, err
+		}
+		status.TargetRevision = target.Revision
+		status.StartedAt = u.now.Format(time.RFC3339)
+		status.TotalBatches = len(batches)
+		status.Batches = batches
+	}
+
+	if spec.Paused {
+		status.Phase = dataplaneUpgradePhasePaused
+		status.Message = "Paused by the spec.paused parameter"
+		return status, nil
+	}
+
+	if status.FailedGeneration != 0 {
+		if upgrade.Generation == status.FailedGeneration {
+			return status, nil
+		}
+		// the spec has been changed after the failure, retry the failed batch
+		for i := range status.Batches {
+			if status.Batches[i].State == batchStateFailed {
+				status.Batches[i] = DataplaneUpgradeBatch{State: batchStatePending, Namespaces: status.Batches[i].Namespaces}
+			}
+		}
+		status.FailedGeneration = 0
+	}
+
+	current := -1
+	for i := range status.Batches {
+		if status.Batches[i].State != batchStateSucceeded {
+			current = i
+			break
+		}
+	}
+	if current == -1 {
+		status.Phase = dataplaneUpgradePhaseSucceeded
+		status.Message = fmt.Sprintf("All namespaces use revision %s", target.Revision)
+		status.FinishedAt = u.now.Format(time.RFC3339)
+		return status, nil
+	}
+
+	status.Phase = dataplaneUpgradePhaseProgressing
+	status.CurrentBatch = current + 1
+	batch := &status.Batches[current]
+
+	switch batch.State {
+	case batchStatePending:
+		for _, ns := range batch.Namespaces {
+			if !u.namespaceExists(ns.Name) {
+				continue
+			}
+			u.relabelNamespace(ns.Name, target.Revision)
+			u.restartControllers(ns.Name, target.FullVersion)
+		}
+		batch.State = batchStateInProgress
+		batch.StartedAt = u.now.Format(time.RFC3339)
+		batch.Message = fmt.Sprintf("Restarting workloads with revision %s", target.Revision)
+
+	case batchStateInProgress:
+		startedAt, err := time.Parse(time.RFC3339, batch.StartedAt)
+		if err != nil {
+			return status, fmt.Errorf("parse batch start time: %v", err)
+		}
+		timedOut := u.now.After(startedAt.Add(readinessTimeout))
+
+		if problem := u.batchReadiness(batch.Namespaces, target.Revision); problem != "" {
+			if timedOut {
+				u.failBatch(&status, batch, upgrade, fmt.Sprintf("Not ready after %s: %s", readinessTimeout, problem))
+				return status, nil
+			}
+			batch.Message = problem
+			break
+		}
+
+		if batch.ReadyAt == "" {
+			batch.ReadyAt = u.now.Format(time.RFC3339)
+		}
+		readyAt, err := time.Parse(time.RFC3339, batch.ReadyAt)
+		if err != nil {
+			return status, fmt.Errorf("parse batch ready time: %v", err)
+		}
+
+		if spec.HealthChecks.MaxErrorRate != nil {
+			errorRate, err := u.batchErrorRate(batch.Namespaces, observationPeriod)
+			if err != nil {
+				u.input.LogEntry.Warnf("Cannot get error rate of IstioDataplaneUpgrade %s batch %d: %v", upgrade.Name, current+1, err)
+				if timedOut {
+					u.failBatch(&status, batch, upgrade, fmt.Sprintf("Cannot get the error rate from Prometheus: %v", err))
+					return status, nil
+				}
+				batch.Message = "Waiting for the error rate from Prometheus"
+				break
+			}
+			if errorRate > *spec.HealthChecks.MaxErrorRate {
+				u.failBatch(&status, batch, upgrade, fmt.Sprintf("Error rate %.2f%% exceeds %.2f%%", errorRate, *spec.HealthChecks.MaxErrorRate))
+				return status, nil
+			}
+		}
+
+		if u.now.Before(readyAt.Add(observationPeriod)) {
+			batch.Message = fmt.Sprintf("Workloads are ready, observing until %s", readyAt.Add(observationPeriod).Format(time.RFC3339))
+			break
+		}
+
+		batch.State = batchStateSucceeded
+		batch.Message = ""
+		batch.FinishedAt = u.now.Format(time.RFC3339)
+	}
+
+	status.Message = fmt.Sprintf("Batch %d of %d: %s", current+1, len(status.Batches), batch.State)
+	return status, nil
+}
+
+// planBatches splits the namespaces that do not use the target revision into batches ordered by name.
+func (u *dataplaneUpgrader) planBatches(spec DataplaneUpgradeSpec, targetRevision string) ([]DataplaneUpgradeBatch, error) {
+	selector := labels.Everything()
+	if spec.NamespaceSelector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(spec.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("parse namespaceSelector: %v", err)
+		}
+	}
+
+	names := make([]string, 0)
+	for name, ns := range u.namespaces {
+		if ns.DeletionTimestampExists || ns.Revision == targetRevision || !selector.Matches(labels.Set(ns.Labels)) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	batchSize := spec.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	batches := make([]DataplaneUpgradeBatch, 0, (len(names)+batchSize-1)/batchSize)
+	for i := 0; i < len(names); i += batchSize {
+		batch := DataplaneUpgradeBatch{State: batchStatePending}
+		for _, name := range names[i:int(math.Min(float64(i+batchSize), float64(len(names))))] {
+			batch.Namespaces = append(batch.Namespaces, DataplaneUpgradeNamespace{
+				Name:             name,
+				PreviousRevision: u.namespaces[name].RevisionRaw,
+			})
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
+
+// namespaceExists checks that the planned namespace has not been deleted since the batches were planned.
+func (u *dataplaneUpgrader) namespaceExists(namespace string) bool {
+	if _, ok := u.namespaces[namespace]; ok {
+		return true
+	}
+
+	u.input.LogEntry.Infof("Namespace '%s' is not found, skip it", namespace)
+	return false
+}
+
+// relabelNamespace switches the namespace to the revision, the global revision is set with the istio-injection label.
+func (u *dataplaneUpgrader) relabelNamespace(namespace, revision string) {
+	nsLabels := map[string]interface{}{
+		"istio.io/rev":    revision,
+		"istio-injection": nil,
+	}
+	if revision == dataplaneUpgradeRevisionGlobal {
+		nsLabels = map[string]interface{}{
+			"istio.io/rev":    nil,
+			"istio-injection": "enabled",
+		}
+	}
+
+	u.input.LogEntry.Infof("Switch namespace '%s' to revision '%s'", namespace, revision)
+	u.input.PatchCollector.MergePatch(map[string]interface{}{"metadata": map[string]interface{}{"labels": nsLabels}}, "v1", "Namespace", "", namespace, object_patch.IgnoreMissingObject())
+}
+
+func (u *dataplaneUpgrader) restartControllers(namespace, fullVersion string) {
+	for _, kind := range []string{"deployment", "statefulset", "daemonset"} {
+		for _, obj := range u.input.Snapshots[kind] {
+			controller := obj.(K8SControllerFilterResult)
+			if controller.Namespace != namespace {
+				continue
+			}
+
+			u.input.LogEntry.Infof("Restart %s '%s' in namespace '%s' with full version '%s'", controller.Kind, controller.Name, namespace, fullVersion)
+			u.input.PatchCollector.MergePatch(fmt.Sprintf(restartPatchTemplate, fullVersion, u.now.Format(time.RFC3339)),
+				"apps/v1", controller.Kind, namespace, controller.Name, object_patch.IgnoreMissingObject())
+		}
+	}
+}
+
+// batchReadiness returns the description of what the batch is waiting for or an empty string if it is ready.
+func (u *dataplaneUpgrader) batchReadiness(namespaces []DataplaneUpgradeNamespace, revision string) string {
+	inBatch := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		inBatch[ns.Name] = true
+	}
+
+	var oldPods int
+	for _, obj := range u.input.Snapshots["istio_pod"] {
+		pod := obj.(IstioDrivenPodFilterResult)
+		if inBatch[pod.Namespace] && pod.Revision != istioRevsionAbsent && pod.Revision != revision && pod.SpecificRevision == "" {
+			oldPods++
+		}
+	}
+
+	var notReady []string
+	for _, kind := range []string{"deployment", "statefulset", "daemonset"} {
+		for _, obj := range u.input.Snapshots[kind] {
+			controller := obj.(K8SControllerFilterResult)
+			if inBatch[controller.Namespace] && !controller.IsReady {
+				notReady = append(notReady, fmt.Sprintf("%s/%s/%s", controller.Namespace, controller.Kind, controller.Name))
+			}
+		}
+	}
+	sort.Strings(notReady)
+
+	var problems []string
+	if oldPods > 0 {
+		problems = append(problems, fmt.Sprintf("%d Pods with the previous revision", oldPods))
+	}
+	if len(notReady) > 0 {
+		problems = append(problems, "not ready: "+strings.Join(notReady, ", "))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// batchErrorRate returns the percentage of 5xx responses received by the workloads of the batch.
+func (u *dataplaneUpgrader) batchErrorRate(namespaces []DataplaneUpgradeNamespace, window time.Duration) (float64, error) {
+	names := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+	}
+	query := fmt.Sprintf(errorRateQueryTemplate, strings.Join(names, "|"), fmt.Sprintf("%ds", int(window.Seconds())))
+
+	req, err := http.NewRequest(http.MethodGet, "https://prometheus.d8-monitoring:9090/api/v1/query?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return 0, err
+	}
+	err = d8http.SetKubeAuthToken(req)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := u.dc.GetHTTPClient(d8http.WithInsecureSkipVerify()).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("prometheus responded with status %d", res.StatusCode)
+	}
+
+	var response struct {
+		Data struct {
+			Result []struct {
+				Value []interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return 0, err
+	}
+
+	// no requests to the workloads of the batch
+	if len(response.Data.Result) == 0 || len(response.Data.Result[0].Value) < 2 {
+		return 0, nil
+	}
+
+	value, ok := response.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected value %v", response.Data.Result[0].Value[1])
+	}
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(ratio) {
+		return 0, nil
+	}
+
+	return ratio * 100, nil
+}
+
+func (u *dataplaneUpgrader) failBatch(status *DataplaneUpgradeStatus, batch *DataplaneUpgradeBatch, upgrade IstioDataplaneUpgrade, reason string) {
+	batch.FinishedAt = u.now.Format(time.RFC3339)
+	batch.Message = reason
+
+	if upgrade.Spec.FailurePolicy != failurePolicyRollback {
+		batch.State = batchStateFailed
+		status.Phase = dataplaneUpgradePhasePaused
+		status.FailedGeneration = upgrade.Generation
+		status.Message = fmt.Sprintf("Batch %d failed: %s. Change the spec to retry", status.CurrentBatch, reason)
+		return
+	}
+
+	for _, ns := range batch.Namespaces {
+		if !u.namespaceExists(ns.Name) {
+			continue
+		}
+
+		revision := ns.PreviousRevision
+		if revision == dataplaneUpgradeRevisionGlobal {
+			revision = u.globalRevision
+		}
+		fullVersion := u.versionMap.GetFullVersionByRevision(revision)
+		if fullVersion == "" {
+			u.input.LogEntry.Warnf("Cannot roll back namespace '%s': revision '%s' is not installed", ns.Name, revision)
+			continue
+		}
+
+		u.relabelNamespace(ns.Name, ns.PreviousRevision)
+		u.restartControllers(ns.Name, fullVersion)
+	}
+
+	batch.State = batchStateRolledBack
+	status.Phase = dataplaneUpgradePhaseRolledBack
+	status.Message = fmt.Sprintf("Batch %d rolled back: %s", status.CurrentBatch, reason)
+	status.FinishedAt = u.now.Format(time.RFC3339)
+}
+
+func parseDurationWithDefault(value string, defaultDuration time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultDuration, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -11,3 +11,15 @@ type AlliancePublicMetadata struct {
 	AuthnKeyPub string `json:"authnKeyPub"`
 	RootCA      string `json:"rootCA"`
 }
+
+type AllianceDiagnostics struct {
+	Healthy            bool                       `json:"healthy"`
+	LastCheckTimestamp string                     `json:"lastCheckTimestamp"`
+	Checks             []AllianceDiagnosticsCheck `json:"checks"`
+}
+
+type AllianceDiagnosticsCheck struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"user-authz-webhook/web"
+	"user-authz-webhook/web/hook"
+)
+
+const usage = `Usage:
+  audit access --user <name> [--group <name>]...
+      Show effective access granted to the user by ClusterAuthorizationRules.
+  audit who-can <verb> <resource>[/<subresource>] [--api-group <group>] [--namespace <namespace>]
+      Show subjects of ClusterAuthorizationRules allowed to perform the action.
+  audit simulate [--delete] [-f <file>]
+      Show how the ClusterAuthorizationRule manifest (YAML or JSON, stdin by default) changes access of its subjects.
+`
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	var (
+		path   string
+		review interface{}
+		err    error
+	)
+
+	switch os.Args[1] {
+	case "access":
+		path = "audit/access"
+		review, err = accessReview(os.Args[2:])
+	case "who-can":
+		path = "audit/who-can"
+		review, err = whoCanReview(os.Args[2:])
+	case "simulate":
+		path = "audit/simulate"
+		review, err = simulationReview(os.Args[2:])
+	default:
+		log.Fatal(usage)
+	}
+	check(err)
+
+	body, err := json.Marshal(review)
+	check(err)
+
+	client, err := web.NewClient()
+	check(err)
+
+	addr := url.URL{
+		Scheme: "https",
+		Host:   web.ListenAddr,
+		Path:   path,
+	}
+	response, err := client.Post(addr.String(), "application/json", bytes.NewReader(body))
+	check(err)
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		io.Copy(log.Writer(), response.Body)
+		log.Fatalln()
+	}
+
+	var result interface{}
+	check(json.NewDecoder(response.Body).Decode(&result))
+
+	out, err := json.MarshalIndent(result, "", "  ")
+	check(err)
+	fmt.Println(string(out))
+}
+
+func accessReview(args []string) (*hook.AccessReview, error) {
+	var groups stringList
+
+	fs := flag.NewFlagSet("access", flag.ExitOnError)
+	user := fs.String("user", "", "user name")
+	fs.Var(&groups, "group", "group of the user, can be set multiple times")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *user == "" && len(groups) == 0 {
+		return nil, fmt.Errorf("--user or --group is required")
+	}
+
+	return &hook.AccessReview{User: *user, Groups: groups}, nil
+}
+
+func whoCanReview(args []string) (*hook.WhoCanReview, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("verb and resource are required\n%s", usage)
+	}
+
+	fs := flag.NewFlagSet("who-can", flag.ExitOnError)
+	group := fs.String("api-group", "", "API group of the resource, empty for the core group")
+	namespace := fs.String("namespace", "", "namespace, empty for cluster-scoped requests")
+	if err := fs.Parse(args[2:]); err != nil {
+		return nil, err
+	}
+
+	return &hook.WhoCanReview{
+		Verb:      args[0],
+		Group:     *group,
+		Resource:  args[1],
+		Namespace: *namespace,
+	}, nil
+}
+
+func simulationReview(args []string) (*hook.SimulationReview, error) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	file := fs.String("f", "-", "ClusterAuthorizationRule manifest, - for stdin")
+	deleteRule := fs.Bool("delete", false, "simulate deletion of the rule")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	var manifest struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec hook.ClusterAuthorizationRuleSpec `json:"spec"`
+	}
+	if err := yaml.NewYAMLOrJSONDecoder(input, 4096).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("decode ClusterAuthorizationRule: %w", err)
+	}
+
+	return &hook.SimulationReview{
+		Rule:   hook.ClusterAuthorizationRule{Name: manifest.Metadata.Name, Spec: manifest.Spec},
+		Delete: *deleteRule,
+	}, nil
+}
+
+func check(err error) {
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
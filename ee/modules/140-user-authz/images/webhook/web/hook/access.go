@@ -0,0 +1,458 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccessReview asks what a user can do according to ClusterAuthorizationRules
+type AccessReview struct {
+	User   string   `json:"user"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// Access is the effective access granted by ClusterAuthorizationRules
+type Access struct {
+	Rules                         []string             `json:"rules"`
+	AccessLevels                  []string             `json:"accessLevels,omitempty"`
+	PortForwarding                bool                 `json:"portForwarding"`
+	AllowScale                    bool                 `json:"allowScale"`
+	AdditionalRoles               []string             `json:"additionalRoles,omitempty"`
+	AllowAccessToSystemNamespaces bool                 `json:"allowAccessToSystemNamespaces"`
+	LimitNamespaces               []string             `json:"limitNamespaces,omitempty"`
+	NamespaceSelectors            []*NamespaceSelector `json:"namespaceSelectors,omitempty"`
+	// AllNamespaces is true if there are no namespace restrictions, cluster-scoped requests are allowed in this case
+	AllNamespaces bool `json:"allNamespaces"`
+	// Namespaces are existing namespaces available to the user, listed only if there are namespace restrictions
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// WhoCanReview asks who can perform the action according to ClusterAuthorizationRules
+type WhoCanReview struct {
+	Verb string `json:"verb"`
+	// Group is an API group of the resource, empty for the core group
+	Group string `json:"group,omitempty"`
+	// Resource may contain a subresource, e.g., pods/log
+	Resource string `json:"resource"`
+	// Namespace is empty for cluster-scoped requests
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type WhoCanSubject struct {
+	Subject `json:",inline"`
+	Rules   []string `json:"rules"`
+}
+
+// SimulationReview asks how a proposed ClusterAuthorizationRule changes access of its subjects
+type SimulationReview struct {
+	Rule ClusterAuthorizationRule `json:"rule"`
+	// Delete simulates deletion of the rule instead of applying it
+	Delete bool `json:"delete,omitempty"`
+}
+
+type SimulatedSubject struct {
+	Subject `json:",inline"`
+	Before  *Access `json:"before"`
+	After   *Access `json:"after"`
+}
+
+// ServeAccess lists effective access of a user and its groups.
+func (h *Handler) ServeAccess(w http.ResponseWriter, r *http.Request) {
+	var review AccessReview
+	if !h.decodeReview(w, r, &review) {
+		return
+	}
+
+	config, directory := h.snapshot()
+	access, err := h.access(r.Context(), config, directory, review.User, review.Groups)
+	h.respond(w, access, err)
+}
+
+// ServeWhoCan lists subjects of ClusterAuthorizationRules allowed to perform the action.
+func (h *Handler) ServeWhoCan(w http.ResponseWriter, r *http.Request) {
+	var review WhoCanReview
+	if !h.decodeReview(w, r, &review) {
+		return
+	}
+	if review.Verb == "" || review.Resource == "" {
+		http.Error(w, "verb and resource are required", http.StatusBadRequest)
+		return
+	}
+
+	config, directory := h.snapshot()
+	subjects, err := h.whoCan(r.Context(), config, directory, &review)
+	h.respond(w, subjects, err)
+}
+
+// ServeSimulation compares access of subjects before and after applying the proposed ClusterAuthorizationRule.
+func (h *Handler) ServeSimulation(w http.ResponseWriter, r *http.Request) {
+	var review SimulationReview
+	if !h.decodeReview(w, r, &review) {
+		return
+	}
+	if review.Rule.Name == "" {
+		http.Error(w, "rule name is required", http.StatusBadRequest)
+		return
+	}
+
+	config, directory := h.snapshot()
+	subjects, err := h.simulate(r.Context(), config, directory, &review)
+	h.respond(w, subjects, err)
+}
+
+func (h *Handler) decodeReview(w http.ResponseWriter, r *http.Request, review interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is supported.", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		http.Error(w, "Invalid json request", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) respond(w http.ResponseWriter, result interface{}, err error) {
+	if err != nil {
+		h.logger.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Printf("cannot marshal json response: %v", err)
+	}
+}
+
+func (h *Handler) snapshot() (*UserAuthzConfig, map[string]map[string]DirectoryEntry) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.config == nil {
+		return &UserAuthzConfig{}, h.directory
+	}
+	return h.config, h.directory
+}
+
+func (h *Handler) access(ctx context.Context, config *UserAuthzConfig, directory map[string]map[string]DirectoryEntry, user string, groups []string) (*Access, error) {
+	access := &Access{Rules: make([]string, 0)}
+
+	for _, rule := range config.CRDs {
+		if !ruleAppliesTo(&rule, user, groups) {
+			continue
+		}
+
+		access.Rules = append(access.Rules, rule.Name)
+		if rule.Spec.AccessLevel != "" {
+			access.AccessLevels = appendUnique(access.AccessLevels, rule.Spec.AccessLevel)
+		}
+		access.PortForwarding = access.PortForwarding || rule.Spec.PortForwarding
+		access.AllowScale = access.AllowScale || rule.Spec.AllowScale
+		for _, role := range rule.Spec.AdditionalRoles {
+			access.AdditionalRoles = appendUnique(access.AdditionalRoles, role.Name)
+		}
+	}
+
+	if len(access.Rules) == 0 {
+		return access, nil
+	}
+
+	request := &WebhookRequest{Spec: WebhookResourceSpec{User: user, Group: groups}}
+	entry := combineDirs(affectedDirs(directory, request))
+
+	access.AllowAccessToSystemNamespaces = entry.AllowAccessToSystemNamespaces
+	access.NamespaceSelectors = entry.NamespaceSelectors
+	for _, pattern := range entry.LimitNamespaces {
+		access.LimitNamespaces = append(access.LimitNamespaces, pattern.String())
+	}
+
+	access.AllNamespaces = !hasAnyFilters(&entry)
+	if access.AllNamespaces {
+		return access, nil
+	}
+
+	namespaces, err := h.kubeclient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ns := range namespaces.Items {
+		request := &WebhookRequest{Spec: WebhookResourceSpec{
+			User:               user,
+			Group:              groups,
+			ResourceAttributes: WebhookResourceAttributes{Namespace: ns.Name},
+		}}
+		if !h.authorizeRequestWithDirectory(directory, request).Status.Denied {
+			access.Namespaces = append(access.Namespaces, ns.Name)
+		}
+	}
+
+	return access, nil
+}
+
+func (h *Handler) whoCan(ctx context.Context, config *UserAuthzConfig, directory map[string]map[string]DirectoryEntry, review *WhoCanReview) ([]WhoCanSubject, error) {
+	roles := make(map[string][]rbacv1.PolicyRule)
+	subjects := make(map[Subject]*WhoCanSubject)
+
+	for _, rule := range config.CRDs {
+		granted := false
+		for _, roleName := range ruleClusterRoles(config, &rule) {
+			policyRules, err := h.clusterRoleRules(ctx, roles, roleName)
+			if err != nil {
+				return nil, err
+			}
+			if policyRulesAllow(policyRules, review) {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			continue
+		}
+
+		for _, subject := range rule.Spec.Subjects {
+			if !h.subjectHasNamespaceAccess(directory, subject, review) {
+				continue
+			}
+
+			s, ok := subjects[subject]
+			if !ok {
+				s = &WhoCanSubject{Subject: subject}
+				subjects[subject] = s
+			}
+			s.Rules = append(s.Rules, rule.Name)
+		}
+	}
+
+	res := make([]WhoCanSubject, 0, len(subjects))
+	for _, s := range subjects {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Kind != res[j].Kind {
+			return res[i].Kind < res[j].Kind
+		}
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+// subjectHasNamespaceAccess checks namespace restrictions of the subject as the webhook does on the SubjectAccessReview
+func (h *Handler) subjectHasNamespaceAccess(directory map[string]map[string]DirectoryEntry, subject Subject, review *WhoCanReview) bool {
+	user, groups := subjectIdentity(subject)
+
+	request := &WebhookRequest{Spec: WebhookResourceSpec{
+		User:  user,
+		Group: groups,
+		ResourceAttributes: WebhookResourceAttributes{
+			Group:     review.Group,
+			Resource:  review.Resource,
+			Namespace: review.Namespace,
+			Verb:      review.Verb,
+		},
+	}}
+
+	return !h.authorizeRequestWithDirectory(directory, request).Status.Denied
+}
+
+func (h *Handler) simulate(ctx context.Context, config *UserAuthzConfig, directory map[string]map[string]DirectoryEntry, review *SimulationReview) ([]SimulatedSubject, error) {
+	proposed := &UserAuthzConfig{
+		CRDs:               make([]ClusterAuthorizationRule, 0, len(config.CRDs)+1),
+		CustomClusterRoles: config.CustomClusterRoles,
+	}
+
+	affected := make([]Subject, 0)
+	for _, rule := range config.CRDs {
+		if rule.Name == review.Rule.Name {
+			affected = append(affected, rule.Spec.Subjects...)
+			continue
+		}
+		proposed.CRDs = append(proposed.CRDs, rule)
+	}
+	if !review.Delete {
+		proposed.CRDs = append(proposed.CRDs, review.Rule)
+		affected = append(affected, review.Rule.Spec.Subjects...)
+	}
+
+	proposedDirectory := buildDirectory(proposed)
+
+	res := make([]SimulatedSubject, 0, len(affected))
+	seen := make(map[Subject]struct{}, len(affected))
+	for _, subject := range affected {
+		if _, ok := seen[subject]; ok {
+			continue
+		}
+		seen[subject] = struct{}{}
+
+		user, groups := subjectIdentity(subject)
+
+		before, err := h.access(ctx, config, directory, user, groups)
+		if err != nil {
+			return nil, err
+		}
+		after, err := h.access(ctx, proposed, proposedDirectory, user, groups)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, SimulatedSubject{Subject: subject, Before: before, After: after})
+	}
+
+	return res, nil
+}
+
+func (h *Handler) clusterRoleRules(ctx context.Context, cache map[string][]rbacv1.PolicyRule, name string) ([]rbacv1.PolicyRule, error) {
+	if rules, ok := cache[name]; ok {
+		return rules, nil
+	}
+
+	role, err := h.kubeclient.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// A missing role grants nothing, the same as RBAC does
+		cache[name] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get ClusterRole %s: %w", name, err)
+	}
+
+	cache[name] = role.Rules
+	return role.Rules, nil
+}
+
+// ruleClusterRoles returns ClusterRoles bound by the user-authz module for the rule
+func ruleClusterRoles(config *UserAuthzConfig, rule *ClusterAuthorizationRule) []string {
+	var roles []string
+
+	if level := rule.Spec.AccessLevel; level != "" {
+		roles = append(roles, "user-authz:"+kebabCase(level))
+		roles = append(roles, config.CustomClusterRoles[lowerFirst(level)]...)
+	}
+	if rule.Spec.PortForwarding {
+		roles = append(roles, "user-authz:port-forward")
+	}
+	if rule.Spec.AllowScale {
+		roles = append(roles, "user-authz:scale")
+	}
+	for _, role := range rule.Spec.AdditionalRoles {
+		roles = append(roles, role.Name)
+	}
+
+	return roles
+}
+
+// policyRulesAllow checks resource rules the same way as RBAC does, rules limited to resource names are skipped
+func policyRulesAllow(rules []rbacv1.PolicyRule, review *WhoCanReview) bool {
+	_, subresource, _ := strings.Cut(review.Resource, "/")
+
+	for _, rule := range rules {
+		if len(rule.ResourceNames) > 0 {
+			continue
+		}
+		if !hasItem(rule.Verbs, review.Verb) || !hasItem(rule.APIGroups, review.Group) {
+			continue
+		}
+
+		for _, r := range rule.Resources {
+			if r == rbacv1.ResourceAll || r == review.Resource || (subresource != "" && r == "*/"+subresource) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func hasItem(items []string, item string) bool {
+	for _, i := range items {
+		if i == "*" || i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleAppliesTo(rule *ClusterAuthorizationRule, user string, groups []string) bool {
+	for _, subject := range rule.Spec.Subjects {
+		switch subject.Kind {
+		case "User":
+			if subject.Name == user {
+				return true
+			}
+		case "ServiceAccount":
+			if "system:serviceaccount:"+subject.Namespace+":"+subject.Name == user {
+				return true
+			}
+		case "Group":
+			for _, group := range groups {
+				if subject.Name == group {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// subjectIdentity returns the user and groups of SubjectAccessReviews made by the subject
+func subjectIdentity(subject Subject) (string, []string) {
+	switch subject.Kind {
+	case "Group":
+		return "", []string{subject.Name}
+	case "ServiceAccount":
+		return "system:serviceaccount:" + subject.Namespace + ":" + subject.Name, nil
+	}
+	return subject.Name, nil
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+// kebabCase converts access levels to ClusterRole names, e.g., PrivilegedUser -> privileged-user
+func kebabCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// lowerFirst converts access levels to keys of custom cluster roles, e.g., PrivilegedUser -> privilegedUser
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package hook
+
+import (
+	"context"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPolicyRulesAllow(t *testing.T) {
+	rules := []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "pods/log"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"*/scale"}, Verbs: []string{"patch"}},
+		{APIGroups: []string{"*"}, Resources: []string{"secrets"}, Verbs: []string{"*"}, ResourceNames: []string{"one"}},
+	}
+
+	tc := []struct {
+		Name   string
+		Review WhoCanReview
+		Result bool
+	}{
+		{Name: "Exact", Review: WhoCanReview{Verb: "get", Resource: "pods"}, Result: true},
+		{Name: "Subresource", Review: WhoCanReview{Verb: "list", Resource: "pods/log"}, Result: true},
+		{Name: "Missing subresource", Review: WhoCanReview{Verb: "get", Resource: "pods/exec"}},
+		{Name: "Wrong verb", Review: WhoCanReview{Verb: "delete", Resource: "pods"}},
+		{Name: "Wrong group", Review: WhoCanReview{Verb: "get", Group: "apps", Resource: "pods"}},
+		{Name: "Wildcard subresource", Review: WhoCanReview{Verb: "patch", Group: "apps", Resource: "deployments/scale"}, Result: true},
+		{Name: "Resource names are skipped", Review: WhoCanReview{Verb: "get", Resource: "secrets"}},
+	}
+
+	for _, testCase := range tc {
+		t.Run(testCase.Name, func(t *testing.T) {
+			if res := policyRulesAllow(rules, &testCase.Review); res != testCase.Result {
+				t.Errorf("got %v | expected %v", res, testCase.Result)
+			}
+		})
+	}
+}
+
+func TestAccessReviews(t *testing.T) {
+	config := &UserAuthzConfig{
+		CRDs: []ClusterAuthorizationRule{
+			{
+				Name: "admins",
+				Spec: ClusterAuthorizationRuleSpec{
+					AccessLevel:                   "SuperAdmin",
+					AllowAccessToSystemNamespaces: true,
+					Subjects:                      []Subject{{Kind: "Group", Name: "admins"}},
+				},
+			},
+			{
+				Name: "developers",
+				Spec: ClusterAuthorizationRuleSpec{
+					AccessLevel:     "User",
+					PortForwarding:  true,
+					LimitNamespaces: []string{"dev-.*"},
+					Subjects:        []Subject{{Kind: "User", Name: "alice"}},
+				},
+			},
+		},
+		CustomClusterRoles: map[string][]string{"user": {"custom:logs"}},
+	}
+
+	handler := &Handler{
+		logger: log.New(io.Discard, "", 0),
+		kubeclient: fake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev-app"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod-app"}},
+			&rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-authz:super-admin"},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			},
+			&rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-authz:user"},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+			},
+			&rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom:logs"},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}}},
+			},
+		),
+		cache: &dummyCache{
+			data: map[string]map[string]bool{
+				"v1": {"pods": true, "pods/log": true, "nodes": false},
+			},
+		},
+		config:    config,
+		directory: buildDirectory(config),
+	}
+
+	t.Run("Access", func(t *testing.T) {
+		access, err := handler.access(context.Background(), config, handler.directory, "alice", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := &Access{
+			Rules:           []string{"developers"},
+			AccessLevels:    []string{"User"},
+			PortForwarding:  true,
+			LimitNamespaces: []string{"^dev-.*$"},
+			Namespaces:      []string{"dev-app"},
+		}
+		if !reflect.DeepEqual(access, expected) {
+			t.Errorf("got %+v | expected %+v", access, expected)
+		}
+	})
+
+	t.Run("Who can", func(t *testing.T) {
+		tc := []struct {
+			Name     string
+			Review   WhoCanReview
+			Subjects []Subject
+		}{
+			{
+				Name:     "Custom role in the allowed namespace",
+				Review:   WhoCanReview{Verb: "get", Resource: "pods/log", Namespace: "dev-app"},
+				Subjects: []Subject{{Kind: "Group", Name: "admins"}, {Kind: "User", Name: "alice"}},
+			},
+			{
+				Name:     "Forbidden namespace",
+				Review:   WhoCanReview{Verb: "get", Resource: "pods", Namespace: "prod-app"},
+				Subjects: []Subject{{Kind: "Group", Name: "admins"}},
+			},
+			{
+				Name:     "Cluster-scoped resource",
+				Review:   WhoCanReview{Verb: "get", Resource: "nodes"},
+				Subjects: []Subject{{Kind: "Group", Name: "admins"}},
+			},
+		}
+
+		for _, testCase := range tc {
+			t.Run(testCase.Name, func(t *testing.T) {
+				res, err := handler.whoCan(context.Background(), config, handler.directory, &testCase.Review)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				subjects := make([]Subject, 0, len(res))
+				for _, s := range res {
+					subjects = append(subjects, s.Subject)
+				}
+				if !reflect.DeepEqual(subjects, testCase.Subjects) {
+					t.Errorf("got %+v | expected %+v", subjects, testCase.Subjects)
+				}
+			})
+		}
+	})
+
+	t.Run("Simulate", func(t *testing.T) {
+		review := &SimulationReview{Rule: ClusterAuthorizationRule{
+			Name: "developers",
+			Spec: ClusterAuthorizationRuleSpec{
+				AccessLevel:     "User",
+				LimitNamespaces: []string{".*-app"},
+				Subjects:        []Subject{{Kind: "User", Name: "alice"}, {Kind: "User", Name: "bob"}},
+			},
+		}}
+
+		res, err := handler.simulate(context.Background(), config, handler.directory, review)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != 2 {
+			t.Fatalf("expected 2 subjects, got %d", len(res))
+		}
+		if !reflect.DeepEqual(res[0].After.Namespaces, []string{"dev-app", "prod-app"}) || res[0].After.PortForwarding {
+			t.Errorf("unexpected access of alice after the change: %+v", res[0].After)
+		}
+		if len(res[1].Before.Rules) != 0 || !reflect.DeepEqual(res[1].After.Rules, []string{"developers"}) {
+			t.Errorf("unexpected access of bob: %+v -> %+v", res[1].Before, res[1].After)
+		}
+	})
+}
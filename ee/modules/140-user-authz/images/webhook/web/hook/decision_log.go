@@ -0,0 +1,252 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	decisionLogSinkStdout = "Stdout"
+	decisionLogSinkFile   = "File"
+	decisionLogSinkHTTP   = "HTTP"
+
+	decisionLogPath        = "/var/log/user-authz-webhook/decisions.log"
+	decisionLogMaxFileSize = 100 << 20
+
+	decisionLogQueueSize     = 1024
+	decisionLogBatchSize     = 100
+	decisionLogFlushInterval = time.Second
+)
+
+// Decision is a structured record of the authorization decision
+type Decision struct {
+	Time               time.Time                 `json:"time"`
+	User               string                    `json:"user"`
+	Groups             []string                  `json:"groups,omitempty"`
+	ResourceAttributes WebhookResourceAttributes `json:"resourceAttributes"`
+	// Rules are ClusterAuthorizationRules applied to the user and its groups
+	Rules    []string `json:"rules"`
+	Decision string   `json:"decision"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+// DecisionLog writes decisions to the sink. Denials are always written, allowed requests are sampled.
+type DecisionLog struct {
+	sampleRate float64
+	writer     io.Writer
+
+	mu sync.Mutex
+}
+
+// NewDecisionLogFromEnv configures the decision log with DECISION_LOG_SINK, DECISION_LOG_SAMPLE_RATE
+// and DECISION_LOG_URL environment variables.
+func NewDecisionLogFromEnv(logger *log.Logger) (*DecisionLog, error) {
+	sampleRate := 1.0
+	if rate := os.Getenv("DECISION_LOG_SAMPLE_RATE"); rate != "" {
+		var err error
+		sampleRate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || sampleRate < 0 || sampleRate > 1 {
+			return nil, fmt.Errorf("DECISION_LOG_SAMPLE_RATE must be a number from 0 to 1, got %q", rate)
+		}
+	}
+
+	var writer io.Writer
+	switch sink := os.Getenv("DECISION_LOG_SINK"); sink {
+	case "", decisionLogSinkStdout:
+		writer = os.Stdout
+	case decisionLogSinkFile:
+		w, err := newRotatingFile(decisionLogPath, decisionLogMaxFileSize)
+		if err != nil {
+			return nil, err
+		}
+		writer = w
+	case decisionLogSinkHTTP:
+		url := os.Getenv("DECISION_LOG_URL")
+		if url == "" {
+			return nil, fmt.Errorf("DECISION_LOG_URL is required for the %s sink", decisionLogSinkHTTP)
+		}
+		writer = newHTTPSink(logger, url)
+	default:
+		return nil, fmt.Errorf("unknown DECISION_LOG_SINK %q", sink)
+	}
+
+	return &DecisionLog{sampleRate: sampleRate, writer: writer}, nil
+}
+
+// Record writes the decision if it passes sampling.
+func (d *DecisionLog) Record(request *WebhookRequest, rules []string) {
+	decision := decisionOf(request)
+	if decision == decisionAllow && rand.Float64() >= d.sampleRate {
+		return
+	}
+
+	if rules == nil {
+		rules = []string{}
+	}
+
+	data, err := json.Marshal(&Decision{
+		Time:               time.Now().UTC(),
+		User:               request.Spec.User,
+		Groups:             request.Spec.Group,
+		ResourceAttributes: request.Spec.ResourceAttributes,
+		Rules:              rules,
+		Decision:           decision,
+		Reason:             request.Status.Reason,
+	})
+	if err != nil {
+		decisionLogDroppedTotal.Inc()
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.writer.Write(append(data, '\n')); err != nil {
+		decisionLogDroppedTotal.Inc()
+	}
+}
+
+// rotatingFile keeps the single previous file with the .1 suffix when the size limit is reached
+type rotatingFile struct {
+	path    string
+	maxSize int64
+
+	file *os.File
+	size int64
+}
+
+func newRotatingFile(path string, maxSize int64) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open decision log %s: %w", r.path, err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat decision log %s: %w", r.path, err)
+	}
+
+	r.file = file
+	r.size = stat.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.size+int64(len(p)) > r.maxSize {
+		r.file.Close()
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return 0, err
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// httpSink sends newline-delimited JSON records in batches. Writes never block the authorization,
+// records are dropped if the queue is full.
+type httpSink struct {
+	logger *log.Logger
+	url    string
+	client *http.Client
+	queue  chan []byte
+}
+
+func newHTTPSink(logger *log.Logger, url string) *httpSink {
+	s := &httpSink{
+		logger: logger,
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		queue:  make(chan []byte, decisionLogQueueSize),
+	}
+	go s.run()
+	return s
+}
+
+func (s *httpSink) Write(p []byte) (int, error) {
+	record := make([]byte, len(p))
+	copy(record, p)
+
+	select {
+	case s.queue <- record:
+		return len(p), nil
+	default:
+		return 0, fmt.Errorf("decision log queue is full")
+	}
+}
+
+func (s *httpSink) run() {
+	ticker := time.NewTicker(decisionLogFlushInterval)
+	defer ticker.Stop()
+
+	var (
+		batch bytes.Buffer
+		count int
+	)
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		if err := s.send(batch.Bytes()); err != nil {
+			s.logger.Printf("cannot send %d decision log records: %v", count, err)
+			decisionLogDroppedTotal.Add(float64(count))
+		}
+		batch.Reset()
+		count = 0
+	}
+
+	for {
+		select {
+		case record := <-s.queue:
+			batch.Write(record)
+			count++
+			if count >= decisionLogBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *httpSink) send(body []byte) error {
+	response, err := s.client.Post(s.url, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecisionLogRecord(t *testing.T) {
+	var buf bytes.Buffer
+	decisionLog := &DecisionLog{sampleRate: 0, writer: &buf}
+
+	allowed := &WebhookRequest{Spec: WebhookResourceSpec{User: "alice"}}
+	denied := &WebhookRequest{
+		Spec: WebhookResourceSpec{
+			User:               "alice",
+			Group:              []string{"developers"},
+			ResourceAttributes: WebhookResourceAttributes{Namespace: "kube-system", Resource: "pods", Verb: "get"},
+		},
+		Status: WebhookRequestStatus{Denied: true, Reason: noNamespaceAccessReason},
+	}
+
+	decisionLog.Record(allowed, []string{"developers"})
+	if buf.Len() != 0 {
+		t.Fatalf("allowed decision must be sampled out, got %q", buf.String())
+	}
+
+	decisionLog.Record(denied, []string{"developers"})
+
+	var decision Decision
+	if err := json.Unmarshal(buf.Bytes(), &decision); err != nil {
+		t.Fatal(err)
+	}
+	if decision.Decision != decisionDeny || decision.Reason != noNamespaceAccessReason ||
+		decision.ResourceAttributes.Namespace != "kube-system" || len(decision.Rules) != 1 || decision.Rules[0] != "developers" {
+		t.Errorf("unexpected decision: %+v", decision)
+	}
+
+	buf.Reset()
+	decisionLog.sampleRate = 1
+	decisionLog.Record(allowed, nil)
+	if !strings.Contains(buf.String(), `"decision":"allow"`) || !strings.Contains(buf.String(), `"rules":[]`) {
+		t.Errorf("unexpected allowed decision: %q", buf.String())
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+
+	file, err := newRotatingFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, record := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := file.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(current) != "third\n" || string(previous) != "second\n" {
+		t.Errorf("unexpected rotation result: current %q, previous %q", current, previous)
+	}
+}
@@ -47,9 +47,12 @@ type Handler struct {
 	//        [user type] [user name]
 	mu        sync.RWMutex
 	directory map[string]map[string]DirectoryEntry
+	config    *UserAuthzConfig
+
+	decisionLog *DecisionLog
 }
 
-func NewHandler(logger *log.Logger, discoveryCache cache.Cache) (*Handler, error) {
+func NewHandler(logger *log.Logger, discoveryCache cache.Cache, decisionLog *DecisionLog) (*Handler, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
@@ -61,9 +64,10 @@ func NewHandler(logger *log.Logger, discoveryCache cache.Cache) (*Handler, error
 	}
 
 	return &Handler{
-		logger:     logger,
-		cache:      discoveryCache,
-		kubeclient: clientSet,
+		logger:      logger,
+		cache:       discoveryCache,
+		kubeclient:  clientSet,
+		decisionLog: decisionLog,
 	}, nil
 }
 
@@ -80,7 +84,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.authorizeRequest(&request)
+	_, directory := h.snapshot()
+
+	start := time.Now()
+	h.authorizeRequestWithDirectory(directory, &request)
+	duration := time.Since(start)
 
 	respData, err := json.Marshal(request)
 	if err != nil {
@@ -91,7 +99,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(respData)
 
-	h.logger.Printf("response body: %s", respData)
+	rules := combineDirs(affectedDirs(directory, &request)).Rules
+	observeDecision(&request, rules, duration)
+	if h.decisionLog != nil {
+		h.decisionLog.Record(&request, rules)
+	}
 }
 
 func (h *Handler) authorizeNamespacedRequest(request *WebhookRequest, entry *DirectoryEntry) *WebhookRequest {
@@ -191,14 +203,38 @@ func (h *Handler) authorizeClusterScopedRequest(request *WebhookRequest, entry *
 }
 
 func (h *Handler) authorizeRequest(request *WebhookRequest) *WebhookRequest {
-	dirEntriesAffected := h.affectedDirs(request)
+	h.mu.RLock()
+	directory := h.directory
+	h.mu.RUnlock()
+
+	return h.authorizeRequestWithDirectory(directory, request)
+}
+
+// authorizeRequestWithDirectory authorizes the request using the passed directory instead of the applied one.
+// It is used to simulate rules that are not applied yet.
+func (h *Handler) authorizeRequestWithDirectory(directory map[string]map[string]DirectoryEntry, request *WebhookRequest) *WebhookRequest {
+	dirEntriesAffected := affectedDirs(directory, request)
 	if len(dirEntriesAffected) == 0 {
 		return request
 	}
 
+	combinedDir := combineDirs(dirEntriesAffected)
+
+	if request.Spec.ResourceAttributes.Namespace != "" {
+		return h.authorizeNamespacedRequest(request, &combinedDir)
+	}
+
+	if request.Spec.ResourceAttributes.Resource != "" {
+		return h.authorizeClusterScopedRequest(request, &combinedDir)
+	}
+
+	return request
+}
+
+// combineDirs combines dirs for the current request. Users may have more than one rule attached to their groups or usernames.
+func combineDirs(dirEntriesAffected []DirectoryEntry) DirectoryEntry {
 	var combinedDir DirectoryEntry
 
-	// Combine dirs for the current request. Users may have more than one rule attached to their groups or usernames.
 	for _, dirEntry := range dirEntriesAffected {
 		if !combinedDir.AllowAccessToSystemNamespaces {
 			combinedDir.AllowAccessToSystemNamespaces = dirEntry.AllowAccessToSystemNamespaces
@@ -212,17 +248,13 @@ func (h *Handler) authorizeRequest(request *WebhookRequest) *WebhookRequest {
 			combinedDir.LimitNamespaces = append(combinedDir.LimitNamespaces, dirEntry.LimitNamespaces...)
 		}
 		combinedDir.NamespaceFiltersAbsent = combinedDir.NamespaceFiltersAbsent || dirEntry.NamespaceFiltersAbsent
-	}
-
-	if request.Spec.ResourceAttributes.Namespace != "" {
-		return h.authorizeNamespacedRequest(request, &combinedDir)
-	}
 
-	if request.Spec.ResourceAttributes.Resource != "" {
-		return h.authorizeClusterScopedRequest(request, &combinedDir)
+		for _, rule := range dirEntry.Rules {
+			combinedDir.Rules = appendUnique(combinedDir.Rules, rule)
+		}
 	}
 
-	return request
+	return combinedDir
 }
 
 // renewDirectories reads the configuration file (actually it is a json file with all CRs from the cluster) and composes
@@ -253,6 +285,18 @@ func (h *Handler) renewDirectories() {
 		return
 	}
 
+	directory := buildDirectory(&config)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.directory = directory
+	h.config = &config
+	h.logger.Println("configuration was reloaded successfully")
+}
+
+// buildDirectory composes rules for users, groups, and service accounts from ClusterAuthorizationRules.
+func buildDirectory(config *UserAuthzConfig) map[string]map[string]DirectoryEntry {
 	directory := map[string]map[string]DirectoryEntry{
 		"User":           make(map[string]DirectoryEntry),
 		"Group":          make(map[string]DirectoryEntry),
@@ -295,15 +339,13 @@ func (h *Handler) renewDirectories() {
 				dirEntry.NamespaceSelectors = append(dirEntry.NamespaceSelectors, crd.Spec.NamespaceSelector)
 			}
 
+			dirEntry.Rules = appendUnique(dirEntry.Rules, crd.Name)
+
 			directory[kind][name] = dirEntry
 		}
 	}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	h.directory = directory
-	h.logger.Println("configuration was reloaded successfully")
+	return directory
 }
 
 func isLabelSelectorApplied(namespaceSelector *NamespaceSelector) bool {
@@ -333,22 +375,19 @@ func (h *Handler) StartRenewConfigLoop(stopCh <-chan struct{}) {
 }
 
 // affectedDirs checks that User/Group/ServiceAccount from the review request has corresponding ClusterAuthorizationRules
-func (h *Handler) affectedDirs(r *WebhookRequest) []DirectoryEntry {
+func affectedDirs(directory map[string]map[string]DirectoryEntry, r *WebhookRequest) []DirectoryEntry {
 	var dirEntriesAffected []DirectoryEntry
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	if dirEntry, ok := h.directory["User"][r.Spec.User]; ok {
+	if dirEntry, ok := directory["User"][r.Spec.User]; ok {
 		dirEntriesAffected = append(dirEntriesAffected, dirEntry)
 	}
 
-	if dirEntry, ok := h.directory["ServiceAccount"][r.Spec.User]; ok {
+	if dirEntry, ok := directory["ServiceAccount"][r.Spec.User]; ok {
 		dirEntriesAffected = append(dirEntriesAffected, dirEntry)
 	}
 
 	for _, group := range r.Spec.Group {
-		if dirEntry, ok := h.directory["Group"][group]; ok {
+		if dirEntry, ok := directory["Group"][group]; ok {
 			dirEntriesAffected = append(dirEntriesAffected, dirEntry)
 		}
 	}
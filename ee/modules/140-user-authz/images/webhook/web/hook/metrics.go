@@ -0,0 +1,65 @@
+/*
+Copyright 2023 Flant JSC
+Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https://github.com/deckhouse/deckhouse/blob/main/ee/LICENSE
+*/
+
+package hook
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	decisionAllow = "allow"
+	decisionDeny  = "deny"
+)
+
+var (
+	decisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_authz_webhook_decisions_total",
+			Help: "Authorization decisions by the ClusterAuthorizationRule applied to the requester.",
+		},
+		[]string{"rule", "decision"},
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "user_authz_webhook_request_duration_seconds",
+			Help:    "Duration of SubjectAccessReview processing.",
+			Buckets: []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1},
+		},
+		[]string{"decision"},
+	)
+
+	decisionLogDroppedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "user_authz_webhook_decision_log_dropped_total",
+			Help: "Decision log records dropped because the sink could not keep up or failed.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(decisionsTotal, requestDuration, decisionLogDroppedTotal)
+}
+
+// observeDecision updates metrics. The webhook never allows requests by itself, "allow" means that the request
+// was not denied and the decision is left to RBAC.
+func observeDecision(request *WebhookRequest, rules []string, duration time.Duration) {
+	decision := decisionOf(request)
+
+	requestDuration.WithLabelValues(decision).Observe(duration.Seconds())
+	for _, rule := range rules {
+		decisionsTotal.WithLabelValues(rule, decision).Inc()
+	}
+}
+
+func decisionOf(request *WebhookRequest) string {
+	if request.Status.Denied {
+		return decisionDeny
+	}
+	return decisionAllow
+}
@@ -20,6 +20,8 @@ type DirectoryEntry struct {
 	// If LimitNamespaces is present, we do not need to mind about allowed access to system namespaces.
 	// Thus presence of LimitNamespaces matters when we summarise rules from all CRs to get the allowed namespaces.
 	NamespaceFiltersAbsent bool
+	// Rules are names of ClusterAuthorizationRules the entry is composed of
+	Rules []string
 }
 
 type NamespaceSelector struct {
@@ -29,27 +31,35 @@ type NamespaceSelector struct {
 
 // UserAuthzConfig is a config composed from ClusterAuthorizationRules collected from Kubernetes cluster
 type UserAuthzConfig struct {
-	CRDs []struct {
-		Name string `json:"name"`
-		Spec struct {
-			AccessLevel                   string             `json:"accessLevel"`
-			PortForwarding                bool               `json:"portForwarding"`
-			AllowScale                    bool               `json:"allowScale"`
-			AllowAccessToSystemNamespaces bool               `json:"allowAccessToSystemNamespaces"`
-			LimitNamespaces               []string           `json:"limitNamespaces"`
-			NamespaceSelector             *NamespaceSelector `json:"namespaceSelector"`
-			AdditionalRoles               []struct {
-				APIGroup string `json:"apiGroup"`
-				Kind     string `json:"kind"`
-				Name     string `json:"name"`
-			} `json:"additionalRoles"`
-			Subjects []struct {
-				Kind      string `json:"kind"`
-				Name      string `json:"name"`
-				Namespace string `json:"namespace"`
-			} `json:"subjects"`
-		} `json:"spec,omitempty"`
-	} `json:"crds"`
+	CRDs []ClusterAuthorizationRule `json:"crds"`
+	// CustomClusterRoles are ClusterRoles bound in addition to the access level ones, by access level in lower camel case
+	CustomClusterRoles map[string][]string `json:"customClusterRoles,omitempty"`
+}
+
+type ClusterAuthorizationRule struct {
+	Name string                       `json:"name"`
+	Spec ClusterAuthorizationRuleSpec `json:"spec,omitempty"`
+}
+
+type ClusterAuthorizationRuleSpec struct {
+	AccessLevel                   string             `json:"accessLevel"`
+	PortForwarding                bool               `json:"portForwarding"`
+	AllowScale                    bool               `json:"allowScale"`
+	AllowAccessToSystemNamespaces bool               `json:"allowAccessToSystemNamespaces"`
+	LimitNamespaces               []string           `json:"limitNamespaces"`
+	NamespaceSelector             *NamespaceSelector `json:"namespaceSelector"`
+	AdditionalRoles               []struct {
+		APIGroup string `json:"apiGroup"`
+		Kind     string `json:"kind"`
+		Name     string `json:"name"`
+	} `json:"additionalRoles"`
+	Subjects []Subject `json:"subjects"`
+}
+
+type Subject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // WebhookRequest is a replica of the SubjectAccessReview Kubernetes kind with only important fields
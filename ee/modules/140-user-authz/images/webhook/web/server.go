@@ -14,6 +14,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"user-authz-webhook/cache"
 	"user-authz-webhook/web/hook"
 )
@@ -29,6 +31,9 @@ const (
 	authClientCA = "/etc/ssl/apiserver-authentication-requestheader-client-ca/ca.crt"
 
 	ListenAddr = "127.0.0.1:40443"
+
+	// Plain HTTP metrics endpoint, exposed by kube-rbac-proxy
+	metricsListenAddr = "127.0.0.1:40444"
 )
 
 func buildTLSConfig() (*tls.Config, error) {
@@ -63,7 +68,11 @@ type Server struct {
 
 func NewServer(l *log.Logger) (*Server, error) {
 	c := cache.NewNamespacedDiscoveryCache(l)
-	h, err := hook.NewHandler(l, c)
+	d, err := hook.NewDecisionLogFromEnv(l)
+	if err != nil {
+		return nil, err
+	}
+	h, err := hook.NewHandler(l, c, d)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +83,9 @@ func (s *Server) prepareHTTPServer() (*http.Server, error) {
 	router := http.NewServeMux()
 
 	router.Handle("/", s.handler)
+	router.HandleFunc("/audit/access", s.handler.ServeAccess)
+	router.HandleFunc("/audit/who-can", s.handler.ServeWhoCan)
+	router.HandleFunc("/audit/simulate", s.handler.ServeSimulation)
 	router.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		err := s.cache.Check()
 		if err == nil {
@@ -121,6 +133,15 @@ func (s *Server) Run() error {
 		stopCh <- struct{}{}
 	})
 
+	go func() {
+		metricsRouter := http.NewServeMux()
+		metricsRouter.Handle("/metrics", promhttp.Handler())
+
+		if err := http.ListenAndServe(metricsListenAddr, metricsRouter); err != nil {
+			s.logger.Printf("metrics server on %s stopped: %v", metricsListenAddr, err)
+		}
+	}()
+
 	if err := httpServer.ListenAndServeTLS(sslListenCert, sslListenKey); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("could not listen on %s: %v", ListenAddr, err)
 	}
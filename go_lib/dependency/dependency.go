@@ -128,6 +128,12 @@ func (dc *dependencyContainer) GetHTTPClient(options ...http.Option) http.Client
 		return TestDC.GetHTTPClient(options...)
 	}
 
+	// The shared client is created without options, otherwise options of the first caller
+	// would apply to everyone.
+	if len(options) > 0 {
+		return newHTTPClient(options...)
+	}
+
 	dc.m.RLock()
 	if dc.httpClient != nil {
 		defer dc.m.RUnlock()
@@ -138,6 +144,12 @@ func (dc *dependencyContainer) GetHTTPClient(options ...http.Option) http.Client
 	dc.m.Lock()
 	defer dc.m.Unlock()
 
+	dc.httpClient = newHTTPClient()
+
+	return dc.httpClient
+}
+
+func newHTTPClient(options ...http.Option) http.Client {
 	var opts []http.Option
 	opts = append(opts, options...)
 
@@ -146,9 +158,7 @@ func (dc *dependencyContainer) GetHTTPClient(options ...http.Option) http.Client
 		opts = append(opts, http.WithAdditionalCACerts([][]byte{contentCA}))
 	}
 
-	dc.httpClient = http.NewClient(opts...)
-
-	return dc.httpClient
+	return http.NewClient(opts...)
 }
 
 func (dc *dependencyContainer) GetEtcdClient(endpoints []string, options ...etcd.Option) (etcd.Client, error) {
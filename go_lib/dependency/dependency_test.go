@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -31,6 +32,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	d8http "github.com/deckhouse/deckhouse/go_lib/dependency/http"
 	"github.com/deckhouse/deckhouse/go_lib/dependency/k8s"
 )
 
@@ -115,6 +117,38 @@ func TestRace(_ *testing.T) {
 	os.Setenv("D8_IS_TESTS_ENVIRONMENT", prev)
 }
 
+func TestGetHTTPClientOptionsAreNotShared(t *testing.T) {
+	t.Setenv("D8_IS_TESTS_ENVIRONMENT", "false")
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	get := func(client d8http.Client) error {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	dc := dependency.NewDependencyContainer()
+
+	if err := get(dc.GetHTTPClient(d8http.WithInsecureSkipVerify())); err != nil {
+		t.Fatalf("client with InsecureSkipVerify: %v", err)
+	}
+
+	shared := dc.GetHTTPClient()
+	if err := get(shared); err == nil {
+		t.Fatal("shared client must verify the server certificate")
+	}
+	if shared != dc.GetHTTPClient() {
+		t.Fatal("client without options must be shared")
+	}
+}
+
 func someHandlerWithHTTP(_ *go_hook.HookInput, dc dependency.Container) error {
 	ht := dc.GetHTTPClient()
 
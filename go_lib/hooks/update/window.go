@@ -71,6 +71,15 @@ func (uw Window) IsAllowed(now time.Time) bool {
 	fromTime := time.Date(now.Year(), now.Month(), now.Day(), fromInput.Hour(), fromInput.Minute(), 0, 0, time.UTC)
 	toTime := time.Date(now.Year(), now.Month(), now.Day(), toInput.Hour(), toInput.Minute(), 0, 0, time.UTC)
 
+	// the window crosses midnight, e.g. 22:00-02:00: it starts on the allowed day and ends on the next one
+	if toTime.Before(fromTime) {
+		if now.After(fromTime) {
+			return uw.isTodayAllowed(now, uw.Days)
+		}
+
+		return now.Before(toTime) && uw.isTodayAllowed(now.AddDate(0, 0, -1), uw.Days)
+	}
+
 	updateToday := uw.isTodayAllowed(now, uw.Days)
 
 	if !updateToday {
@@ -182,3 +182,103 @@ func TestNextAllowedWindow(t *testing.T) {
 		assert.Equal(t, time.Sunday, res.Weekday())
 	})
 }
+
+func TestWindowsIsAllowed(t *testing.T) {
+	// 2021-10-13 is wednesday
+	tests := []struct {
+		name    string
+		windows Windows
+		now     time.Time
+		allowed bool
+	}{
+		{
+			name:    "no windows",
+			now:     time.Date(2021, 10, 13, 3, 0, 0, 0, time.UTC),
+			allowed: true,
+		},
+		{
+			name:    "inside the window",
+			windows: Windows{{From: "16:00", To: "18:00"}},
+			now:     time.Date(2021, 10, 13, 16, 35, 0, 0, time.UTC),
+			allowed: true,
+		},
+		{
+			name:    "after the window",
+			windows: Windows{{From: "16:00", To: "18:00"}},
+			now:     time.Date(2021, 10, 13, 18, 35, 0, 0, time.UTC),
+			allowed: false,
+		},
+		{
+			name:    "inside the window on another day",
+			windows: Windows{{From: "16:00", To: "18:00", Days: []string{"Mon", "Tue"}}},
+			now:     time.Date(2021, 10, 13, 16, 35, 0, 0, time.UTC),
+			allowed: false,
+		},
+		{
+			name:    "inside the window on the allowed day",
+			windows: Windows{{From: "16:00", To: "18:00", Days: []string{"Mon", "Wed"}}},
+			now:     time.Date(2021, 10, 13, 16, 35, 0, 0, time.UTC),
+			allowed: true,
+		},
+		{
+			name:    "inside the second window",
+			windows: Windows{{From: "08:00", To: "10:00"}, {From: "16:00", To: "18:00"}},
+			now:     time.Date(2021, 10, 13, 17, 0, 0, 0, time.UTC),
+			allowed: true,
+		},
+		{
+			name:    "time in other timezone is converted to UTC",
+			windows: Windows{{From: "16:00", To: "18:00"}},
+			now:     time.Date(2021, 10, 13, 19, 35, 0, 0, time.FixedZone("UTC+3", 3*60*60)),
+			allowed: true,
+		},
+		{
+			name:    "empty window",
+			windows: Windows{{From: "00:00", To: "00:00"}},
+			now:     time.Date(2021, 10, 13, 12, 0, 0, 0, time.UTC),
+			allowed: false,
+		},
+		{
+			name:    "crossing midnight: before midnight",
+			windows: Windows{{From: "22:00", To: "02:00"}},
+			now:     time.Date(2021, 10, 13, 23, 0, 0, 0, time.UTC),
+			allowed: true,
+		},
+		{
+			name:    "crossing midnight: after midnight",
+			windows: Windows{{From: "22:00", To: "02:00"}},
+			now:     time.Date(2021, 10, 13, 1, 0, 0, 0, time.UTC),
+			allowed: true,
+		},
+		{
+			name:    "crossing midnight: outside the window",
+			windows: Windows{{From: "22:00", To: "02:00"}},
+			now:     time.Date(2021, 10, 13, 12, 0, 0, 0, time.UTC),
+			allowed: false,
+		},
+		{
+			name:    "crossing midnight: after midnight of the allowed day",
+			windows: Windows{{From: "22:00", To: "02:00", Days: []string{"Tue"}}},
+			now:     time.Date(2021, 10, 13, 1, 0, 0, 0, time.UTC),
+			allowed: true,
+		},
+		{
+			name:    "crossing midnight: after midnight of not allowed day",
+			windows: Windows{{From: "22:00", To: "02:00", Days: []string{"Wed"}}},
+			now:     time.Date(2021, 10, 13, 1, 0, 0, 0, time.UTC),
+			allowed: false,
+		},
+		{
+			name:    "crossing midnight: before midnight of not allowed day",
+			windows: Windows{{From: "22:00", To: "02:00", Days: []string{"Tue"}}},
+			now:     time.Date(2021, 10, 13, 23, 0, 0, 0, time.UTC),
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, tt.windows.IsAllowed(tt.now))
+		})
+	}
+}
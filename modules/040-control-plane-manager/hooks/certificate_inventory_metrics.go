@@ -0,0 +1,118 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The control-plane-manager publishes the list of certificates and kubeconfigs it manages, with their
+// expiration dates, in the node annotation. The hook exposes the expiration dates as metrics.
+
+const (
+	certificatesAnnotation           = "control-plane-manager.deckhouse.io/certificates"
+	certificateInventoryMetricsGroup = "control_plane_manager_certificate_inventory"
+)
+
+type inventoryCertificate struct {
+	Node     string    `json:"-"`
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	Queue: moduleQueue + "/certificate_inventory_metrics",
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "master_nodes",
+			ApiVersion: "v1",
+			Kind:       "Node",
+			LabelSelector: &v1.LabelSelector{
+				MatchExpressions: []v1.LabelSelectorRequirement{
+					{
+						Key:      "node-role.kubernetes.io/control-plane",
+						Operator: v1.LabelSelectorOpExists,
+					},
+				},
+			},
+			FilterFunc: certificateInventoryFilterNode,
+		},
+	},
+}, handleCertificateInventoryMetrics)
+
+func certificateInventoryFilterNode(unstructured *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var node corev1.Node
+
+	err := sdk.FromUnstructured(unstructured, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := make([]inventoryCertificate, 0)
+
+	data, ok := node.Annotations[certificatesAnnotation]
+	if !ok {
+		return certificates, nil
+	}
+
+	// A malformed annotation must not block the queue, the controller rewrites it on the next start.
+	err = json.Unmarshal([]byte(data), &certificates)
+	if err != nil {
+		return make([]inventoryCertificate, 0), nil
+	}
+
+	for i := range certificates {
+		certificates[i].Node = node.Name
+	}
+	sort.Slice(certificates, func(i, j int) bool { return certificates[i].NotAfter.Before(certificates[j].NotAfter) })
+
+	return certificates, nil
+}
+
+func handleCertificateInventoryMetrics(input *go_hook.HookInput) error {
+	input.MetricsCollector.Expire(certificateInventoryMetricsGroup)
+
+	for _, snap := range input.Snapshots["master_nodes"] {
+		for _, certificate := range snap.([]inventoryCertificate) {
+			input.MetricsCollector.Set(
+				"d8_control_plane_manager_certificate_expiration_timestamp_seconds",
+				float64(certificate.NotAfter.Unix()),
+				map[string]string{
+					"node":    certificate.Node,
+					"name":    certificate.Name,
+					"kind":    certificate.Kind,
+					"subject": certificate.Subject,
+					"issuer":  certificate.Issuer,
+				},
+				metrics.WithGroup(certificateInventoryMetricsGroup))
+		}
+	}
+
+	return nil
+}
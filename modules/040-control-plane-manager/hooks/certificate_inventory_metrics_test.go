@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+var _ = Describe("Modules :: control-plane-manager :: hooks :: certificate-inventory-metrics ::", func() {
+	const (
+		initValuesString       = `{"controlPlaneManager":{"internal": {}, "apiserver": {"authn": {}, "authz": {}}}}`
+		initConfigValuesString = ``
+	)
+
+	f := HookExecutionConfigInit(initValuesString, initConfigValuesString)
+
+	Context("Masters publish certificate inventory", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: main-master-0
+  labels:
+    node-role.kubernetes.io/control-plane: ""
+  annotations:
+    control-plane-manager.deckhouse.io/certificates: '[{"name":"apiserver","kind":"certificate","subject":"CN=kube-apiserver","sans":["kubernetes"],"issuer":"CN=kubernetes","notAfter":"2030-01-01T00:00:00Z"},{"name":"admin","kind":"kubeconfig","subject":"O=system:masters,CN=kubernetes-admin","issuer":"CN=kubernetes","notAfter":"2029-01-01T00:00:00Z"}]'
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: main-master-1
+  labels:
+    node-role.kubernetes.io/control-plane: ""
+`))
+			f.RunHook()
+		})
+
+		It("Exposes expiration timestamps, the earliest first", func() {
+			Expect(f).Should(ExecuteSuccessfully())
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(3))
+			Expect(m[0].Action).To(Equal("expire"))
+			Expect(m[1].Name).To(Equal("d8_control_plane_manager_certificate_expiration_timestamp_seconds"))
+			Expect(*m[1].Value).To(Equal(float64(1861920000)))
+			Expect(m[1].Labels).To(Equal(map[string]string{
+				"node":    "main-master-0",
+				"name":    "admin",
+				"kind":    "kubeconfig",
+				"subject": "O=system:masters,CN=kubernetes-admin",
+				"issuer":  "CN=kubernetes",
+			}))
+			Expect(m[2].Labels["name"]).To(Equal("apiserver"))
+			Expect(*m[2].Value).To(Equal(float64(1893456000)))
+		})
+	})
+
+	Context("Annotation is malformed", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: main-master-0
+  labels:
+    node-role.kubernetes.io/control-plane: ""
+  annotations:
+    control-plane-manager.deckhouse.io/certificates: 'not json'
+`))
+			f.RunHook()
+		})
+
+		It("Skips the node", func() {
+			Expect(f).Should(ExecuteSuccessfully())
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(1))
+			Expect(m[0].Action).To(Equal("expire"))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The control-plane-manager rolls back a component manifest if the new pod does not become ready
+// and records the failed checksum in the node annotation. The hook exposes these records as metrics.
+
+const (
+	failedChecksumAnnotationPrefix = "control-plane-manager.deckhouse.io/failed-checksum-"
+	componentRollbackMetricsGroup  = "control_plane_manager_component_rollback"
+)
+
+type rolledBackComponent struct {
+	Node      string
+	Component string
+	Checksum  string
+}
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	Queue: moduleQueue + "/component_rollback_metrics",
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "master_nodes",
+			ApiVersion: "v1",
+			Kind:       "Node",
+			LabelSelector: &v1.LabelSelector{
+				MatchExpressions: []v1.LabelSelectorRequirement{
+					{
+						Key:      "node-role.kubernetes.io/control-plane",
+						Operator: v1.LabelSelectorOpExists,
+					},
+				},
+			},
+			FilterFunc: componentRollbackFilterNode,
+		},
+	},
+}, handleComponentRollbackMetrics)
+
+func componentRollbackFilterNode(unstructured *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var node corev1.Node
+
+	err := sdk.FromUnstructured(unstructured, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	components := make([]rolledBackComponent, 0)
+	for key, value := range node.Annotations {
+		if !strings.HasPrefix(key, failedChecksumAnnotationPrefix) {
+			continue
+		}
+		components = append(components, rolledBackComponent{
+			Node:      node.Name,
+			Component: strings.TrimPrefix(key, failedChecksumAnnotationPrefix),
+			Checksum:  value,
+		})
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Component < components[j].Component })
+
+	return components, nil
+}
+
+func handleComponentRollbackMetrics(input *go_hook.HookInput) error {
+	input.MetricsCollector.Expire(componentRollbackMetricsGroup)
+
+	for _, snap := range input.Snapshots["master_nodes"] {
+		for _, component := range snap.([]rolledBackComponent) {
+			input.MetricsCollector.Set(
+				"d8_control_plane_manager_component_rolled_back",
+				1.0,
+				map[string]string{
+					"node":      component.Node,
+					"component": component.Component,
+					"checksum":  component.Checksum,
+				},
+				metrics.WithGroup(componentRollbackMetricsGroup))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+var _ = Describe("Modules :: control-plane-manager :: hooks :: component-rollback-metrics ::", func() {
+	const (
+		initValuesString       = `{"controlPlaneManager":{"internal": {}, "apiserver": {"authn": {}, "authz": {}}}}`
+		initConfigValuesString = ``
+	)
+
+	f := HookExecutionConfigInit(initValuesString, initConfigValuesString)
+
+	Context("Component is rolled back on one of the masters", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: main-master-0
+  labels:
+    node-role.kubernetes.io/control-plane: ""
+  annotations:
+    control-plane-manager.deckhouse.io/approved: ""
+    control-plane-manager.deckhouse.io/failed-checksum-kube-apiserver: "abc123"
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: main-master-1
+  labels:
+    node-role.kubernetes.io/control-plane: ""
+`))
+			f.RunHook()
+		})
+
+		It("Exposes the failed component and checksum", func() {
+			Expect(f).Should(ExecuteSuccessfully())
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(2))
+			Expect(m[0].Action).To(Equal("expire"))
+			Expect(m[1].Name).To(Equal("d8_control_plane_manager_component_rolled_back"))
+			Expect(*m[1].Value).To(Equal(1.0))
+			Expect(m[1].Labels).To(Equal(map[string]string{
+				"node":      "main-master-0",
+				"component": "kube-apiserver",
+				"checksum":  "abc123",
+			}))
+		})
+	})
+
+	Context("No components are rolled back", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: main-master-0
+  labels:
+    node-role.kubernetes.io/control-plane: ""
+`))
+			f.RunHook()
+		})
+
+		It("Only expires metrics", func() {
+			Expect(f).Should(ExecuteSuccessfully())
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(1))
+			Expect(m[0].Action).To(Equal("expire"))
+		})
+	})
+})
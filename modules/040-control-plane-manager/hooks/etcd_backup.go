@@ -0,0 +1,380 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	"github.com/flant/shell-operator/pkg/kube_events_manager/types"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	"github.com/deckhouse/deckhouse/go_lib/dependency/http"
+	"github.com/deckhouse/deckhouse/modules/040-control-plane-manager/hooks/internal/etcdbackup"
+)
+
+// The hook takes etcd snapshots on schedule and uploads them to an S3-compatible storage.
+// Every snapshot is stored along with a file with its sha256 sum in the `sha256sum` format,
+// so a downloaded backup can be checked with `sha256sum -c` before restoring.
+
+const (
+	etcdBackupMetricsGroup = "etcd_backup"
+	etcdBackupSecretName   = "d8-etcd-backup"
+	etcdBackupSecretNS     = "d8-system"
+	etcdBackupTimeout      = 10 * time.Minute
+)
+
+type etcdBackupCredentials struct {
+	AccessKeyID          string
+	SecretAccessKey      string
+	EncryptionPassphrase string
+}
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	Queue: moduleQueue + "/etcd_backup",
+	Schedule: []go_hook.ScheduleConfig{
+		{
+			Crontab: "*/15 * * * *",
+			Name:    "etcd-backup",
+		},
+	},
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "etcd_endpoints",
+			ApiVersion: "v1",
+			Kind:       "Pod",
+			NamespaceSelector: &types.NamespaceSelector{
+				NameSelector: &types.NameSelector{
+					MatchNames: []string{"kube-system"},
+				},
+			},
+			LabelSelector: &v1.LabelSelector{
+				MatchLabels: map[string]string{
+					"component": "etcd",
+					"tier":      "control-plane",
+				},
+			},
+			FieldSelector: &types.FieldSelector{
+				MatchExpressions: []types.FieldSelectorRequirement{
+					{
+						Field:    "status.phase",
+						Operator: "Equals",
+						Value:    "Running",
+					},
+				},
+			},
+			ExecuteHookOnSynchronization: pointer.Bool(false),
+			ExecuteHookOnEvents:          pointer.Bool(false),
+			FilterFunc:                   maintenanceEtcdFilter,
+		},
+		{
+			Name:       "backup_credentials",
+			ApiVersion: "v1",
+			Kind:       "Secret",
+			NamespaceSelector: &types.NamespaceSelector{
+				NameSelector: &types.NameSelector{
+					MatchNames: []string{etcdBackupSecretNS},
+				},
+			},
+			NameSelector:                 &types.NameSelector{MatchNames: []string{etcdBackupSecretName}},
+			ExecuteHookOnSynchronization: pointer.Bool(false),
+			ExecuteHookOnEvents:          pointer.Bool(false),
+			FilterFunc:                   etcdBackupCredentialsFilter,
+		},
+		// common etcd certificate snapshot
+		etcdSecretK8sConfig,
+	},
+}, dependency.WithExternalDependencies(handleEtcdBackup))
+
+func etcdBackupCredentialsFilter(unstructured *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var sec corev1.Secret
+
+	err := sdk.FromUnstructured(unstructured, &sec)
+	if err != nil {
+		return nil, err
+	}
+
+	return etcdBackupCredentials{
+		AccessKeyID:          string(sec.Data["accessKeyID"]),
+		SecretAccessKey:      string(sec.Data["secretAccessKey"]),
+		EncryptionPassphrase: string(sec.Data["encryptionPassphrase"]),
+	}, nil
+}
+
+func handleEtcdBackup(input *go_hook.HookInput, dc dependency.Container) error {
+	input.MetricsCollector.Expire(etcdBackupMetricsGroup)
+
+	if !input.Values.Get("controlPlaneManager.etcd.backup.enabled").Bool() {
+		return nil
+	}
+
+	// Backups are retried by the schedule, the hook does not fail to not retry a heavy operation in a loop
+	err := runEtcdBackup(input, dc)
+	if err != nil {
+		input.LogEntry.Errorf("etcd backup failed: %v", err)
+	}
+
+	failed := 0.0
+	if err != nil {
+		failed = 1.0
+	}
+	input.MetricsCollector.Set("d8_etcd_backup_failed", failed, map[string]string{}, metrics.WithGroup(etcdBackupMetricsGroup))
+
+	return nil
+}
+
+func runEtcdBackup(input *go_hook.HookInput, dc dependency.Container) error {
+	snap := input.Snapshots["backup_credentials"]
+	if len(snap) == 0 {
+		return fmt.Errorf("secret %s/%s with storage credentials not found", etcdBackupSecretNS, etcdBackupSecretName)
+	}
+	creds := snap[0].(etcdBackupCredentials)
+
+	interval, err := time.ParseDuration(input.Values.Get("controlPlaneManager.etcd.backup.interval").String())
+	if err != nil {
+		return fmt.Errorf("parse interval: %v", err)
+	}
+	retention := int(input.Values.Get("controlPlaneManager.etcd.backup.retentionCount").Int())
+	prefix := strings.Trim(input.Values.Get("controlPlaneManager.etcd.backup.s3.prefix").String(), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	httpOptions := []http.Option{http.WithTimeout(etcdBackupTimeout)}
+	if ca := input.Values.Get("controlPlaneManager.etcd.backup.s3.ca").String(); ca != "" {
+		httpOptions = append(httpOptions, http.WithAdditionalCACerts([][]byte{[]byte(ca)}))
+	}
+
+	s3, err := etcdbackup.NewS3Client(etcdbackup.S3Config{
+		Endpoint:        input.Values.Get("controlPlaneManager.etcd.backup.s3.endpoint").String(),
+		Region:          input.Values.Get("controlPlaneManager.etcd.backup.s3.region").String(),
+		Bucket:          input.Values.Get("controlPlaneManager.etcd.backup.s3.bucket").String(),
+		PathStyle:       input.Values.Get("controlPlaneManager.etcd.backup.s3.pathStyle").Bool(),
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+	}, dc.GetHTTPClient(httpOptions...))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdBackupTimeout)
+	defer cancel()
+
+	backups, err := listEtcdBackups(ctx, s3, prefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if len(backups) == 0 || now.Sub(backups[len(backups)-1].Time) >= interval {
+		endpoint, err := etcdBackupEndpoint(input)
+		if err != nil {
+			return err
+		}
+
+		backup, err := uploadEtcdSnapshot(ctx, input, dc, s3, endpoint, prefix, creds.EncryptionPassphrase, now)
+		if err != nil {
+			return err
+		}
+		backups = append(backups, backup)
+		input.LogEntry.Infof("etcd snapshot from %s uploaded to %s", endpoint, backup.Key)
+	}
+
+	for len(backups) > retention {
+		if err := deleteEtcdBackup(ctx, s3, backups[0].Key); err != nil {
+			return err
+		}
+		input.LogEntry.Infof("etcd snapshot %s deleted by retention", backups[0].Key)
+		backups = backups[1:]
+	}
+
+	latest := backups[len(backups)-1]
+	input.MetricsCollector.Set("d8_etcd_backup_last_success_timestamp_seconds", float64(latest.Time.Unix()), map[string]string{}, metrics.WithGroup(etcdBackupMetricsGroup))
+	input.MetricsCollector.Set("d8_etcd_backup_last_size_bytes", float64(latest.Size), map[string]string{}, metrics.WithGroup(etcdBackupMetricsGroup))
+	input.MetricsCollector.Set("d8_etcd_backup_count", float64(len(backups)), map[string]string{}, metrics.WithGroup(etcdBackupMetricsGroup))
+
+	return nil
+}
+
+type etcdBackup struct {
+	Key  string
+	Time time.Time
+	Size int64
+}
+
+// listEtcdBackups returns snapshots from the oldest to the newest, checksum files and foreign objects are skipped
+func listEtcdBackups(ctx context.Context, s3 *etcdbackup.S3Client, prefix string) ([]etcdBackup, error) {
+	objects, err := s3.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]etcdBackup, 0, len(objects))
+	for _, obj := range objects {
+		t, ok := etcdbackup.ParseSnapshotName(path.Base(obj.Key))
+		if !ok {
+			continue
+		}
+		backups = append(backups, etcdBackup{Key: obj.Key, Time: t, Size: obj.Size})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// etcdBackupEndpoint picks the same member every run to make snapshots comparable
+func etcdBackupEndpoint(input *go_hook.HookInput) (string, error) {
+	snap := input.Snapshots["etcd_endpoints"]
+	if len(snap) == 0 {
+		return "", fmt.Errorf("no running etcd pods found")
+	}
+
+	endpoints := make([]string, 0, len(snap))
+	for _, s := range snap {
+		endpoints = append(endpoints, s.(*etcdInstance).Endpoint)
+	}
+	sort.Strings(endpoints)
+
+	return endpoints[0], nil
+}
+
+func uploadEtcdSnapshot(
+	ctx context.Context,
+	input *go_hook.HookInput,
+	dc dependency.Container,
+	s3 *etcdbackup.S3Client,
+	endpoint, prefix, passphrase string,
+	now time.Time,
+) (etcdBackup, error) {
+	tmpDir, err := os.MkdirTemp("", "etcd-backup-")
+	if err != nil {
+		return etcdBackup{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshotPath := filepath.Join(tmpDir, "snapshot.db")
+	if err := saveEtcdSnapshot(ctx, input, dc, endpoint, snapshotPath); err != nil {
+		return etcdBackup{}, err
+	}
+	if err := etcdbackup.VerifySnapshot(snapshotPath); err != nil {
+		return etcdBackup{}, fmt.Errorf("verify snapshot: %v", err)
+	}
+
+	uploadPath := snapshotPath
+	if passphrase != "" {
+		uploadPath = snapshotPath + etcdbackup.EncryptedExt
+		if err := encryptFile(snapshotPath, uploadPath, passphrase); err != nil {
+			return etcdBackup{}, fmt.Errorf("encrypt snapshot: %v", err)
+		}
+	}
+
+	f, err := os.Open(uploadPath)
+	if err != nil {
+		return etcdBackup{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return etcdBackup{}, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return etcdBackup{}, err
+	}
+
+	name := etcdbackup.SnapshotName(now, passphrase != "")
+	key := prefix + name
+	if err := s3.PutObject(ctx, key, f, size, sum); err != nil {
+		return etcdBackup{}, err
+	}
+
+	checksum := []byte(sum + "  " + name + "\n")
+	checksumSum := sha256.Sum256(checksum)
+	err = s3.PutObject(ctx, key+etcdbackup.ChecksumExt, strings.NewReader(string(checksum)), int64(len(checksum)), hex.EncodeToString(checksumSum[:]))
+	if err != nil {
+		return etcdBackup{}, err
+	}
+
+	return etcdBackup{Key: key, Time: now, Size: size}, nil
+}
+
+func saveEtcdSnapshot(ctx context.Context, input *go_hook.HookInput, dc dependency.Container, endpoint, dst string) error {
+	etcdcli, err := getETCDClient(input, dc, []string{endpoint})
+	if err != nil {
+		return err
+	}
+	defer etcdcli.Close()
+
+	rc, err := etcdcli.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %v", endpoint, err)
+	}
+	defer rc.Close()
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return fmt.Errorf("snapshot %s: %v", endpoint, err)
+	}
+	return f.Close()
+}
+
+func encryptFile(src, dst, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := etcdbackup.Encrypt(out, in, passphrase); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func deleteEtcdBackup(ctx context.Context, s3 *etcdbackup.S3Client, key string) error {
+	if err := s3.DeleteObject(ctx, key+etcdbackup.ChecksumExt); err != nil {
+		return err
+	}
+	return s3.DeleteObject(ctx, key)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	"github.com/deckhouse/deckhouse/modules/040-control-plane-manager/hooks/internal/etcdbackup"
+	"github.com/deckhouse/deckhouse/modules/040-control-plane-manager/hooks/internal/etcdbackup/s3test"
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+var _ = Describe("Modules :: control-plane-manager :: hooks :: etcd-backup ::", func() {
+	const (
+		initConfigValuesString = ``
+	)
+
+	initValues := func() string {
+		return `{"controlPlaneManager":{"internal": {}, "apiserver": {"authn": {}, "authz": {}}, "etcd": {"backup": {
+"enabled": true, "interval": "6h", "retentionCount": 2,
+"s3": {"endpoint": "http://minio.local:9000", "bucket": "backups", "prefix": "etcd/main", "region": "us-east-1", "pathStyle": true}}}}}`
+	}
+
+	credentialsSecret := func(passphrase string) string {
+		s := `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: d8-etcd-backup
+  namespace: d8-system
+data:
+  accessKeyID: a2V5 # key
+  secretAccessKey: c2VjcmV0 # secret
+`
+		if passphrase != "" {
+			s += "  encryptionPassphrase: " + base64.StdEncoding.EncodeToString([]byte(passphrase)) + "\n"
+		}
+		return s
+	}
+
+	const etcdPods = `
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd-main-master-1
+  namespace: kube-system
+  labels:
+    component: etcd
+    tier: control-plane
+spec:
+  nodeName: main-master-1
+  hostNetwork: true
+  containers:
+  - name: etcd
+    command:
+    - etcd
+status:
+  hostIP: 192.168.1.2
+  phase: Running
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd-main-master-0
+  namespace: kube-system
+  labels:
+    component: etcd
+    tier: control-plane
+spec:
+  nodeName: main-master-0
+  hostNetwork: true
+  containers:
+  - name: etcd
+    command:
+    - etcd
+status:
+  hostIP: 192.168.1.1
+  phase: Running
+`
+
+	// etcd appends sha256 of the database to the snapshot
+	snapshot := func() []byte {
+		data := bytes.Repeat([]byte("etcd"), 256)
+		sum := sha256.Sum256(data)
+		return append(data, sum[:]...)
+	}
+
+	var fakeS3 *s3test.Bucket
+	setStorage := func(data []byte) {
+		fakeS3 = s3test.NewBucket("backups", "key", "secret")
+		dependency.TestDC.HTTPClient.DoMock.Set(fakeS3.Do)
+		dependency.TestDC.EtcdClient.SnapshotMock.Set(func(ctx context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		})
+	}
+
+	metricValue := func(f *HookExecutionConfig, name string) *float64 {
+		for _, m := range f.MetricsCollector.CollectedMetrics() {
+			if m.Name == name {
+				return m.Value
+			}
+		}
+		return nil
+	}
+
+	Context("Backups are disabled", func() {
+		f := HookExecutionConfigInit(`{"controlPlaneManager":{"internal": {}, "apiserver": {"authn": {}, "authz": {}}}}`, initConfigValuesString)
+
+		BeforeEach(func() {
+			setStorage(snapshot())
+			f.KubeStateSet(testETCDSecret + etcdPods + credentialsSecret(""))
+			f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * *"))
+			f.RunHook()
+		})
+
+		It("Does not upload snapshots", func() {
+			Expect(f).Should(ExecuteSuccessfully())
+			Expect(fakeS3.Keys()).To(BeEmpty())
+			Expect(metricValue(f, "d8_etcd_backup_failed")).To(BeNil())
+		})
+	})
+
+	Context("Backups are enabled", func() {
+		f := HookExecutionConfigInit(initValues(), initConfigValuesString)
+
+		Context("Storage is empty", func() {
+			BeforeEach(func() {
+				setStorage(snapshot())
+				f.KubeStateSet(testETCDSecret + etcdPods + credentialsSecret(""))
+				f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * *"))
+				f.RunHook()
+			})
+
+			It("Uploads the snapshot with its checksum", func() {
+				Expect(f).Should(ExecuteSuccessfully())
+				Expect(*metricValue(f, "d8_etcd_backup_failed")).To(Equal(0.0))
+				Expect(*metricValue(f, "d8_etcd_backup_count")).To(Equal(1.0))
+
+				keys := fakeS3.Keys()
+				Expect(keys).To(HaveLen(2))
+				Expect(keys[0]).To(MatchRegexp(`^etcd/main/etcd-snapshot-\d{8}T\d{6}Z\.db$`))
+				Expect(keys[1]).To(Equal(keys[0] + ".sha256"))
+
+				data, _ := fakeS3.Get(keys[0])
+				Expect(data).To(Equal(snapshot()))
+
+				checksum, _ := fakeS3.Get(keys[1])
+				sum := sha256.Sum256(data)
+				Expect(string(checksum)).To(Equal(fmt.Sprintf("%x  %s\n", sum, strings.TrimPrefix(keys[0], "etcd/main/"))))
+
+				calls := dependency.TestDC.EtcdClient.SnapshotMock.Calls()
+				Expect(calls).ToNot(BeEmpty())
+			})
+		})
+
+		Context("Recent snapshot exists", func() {
+			var recent string
+
+			BeforeEach(func() {
+				setStorage(snapshot())
+				recent = "etcd/main/" + etcdbackup.SnapshotName(time.Now().Add(-time.Hour), false)
+				fakeS3.Put(recent, snapshot(), time.Now())
+				f.KubeStateSet(testETCDSecret + etcdPods + credentialsSecret(""))
+				f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * *"))
+				f.RunHook()
+			})
+
+			It("Does not take a new snapshot", func() {
+				Expect(f).Should(ExecuteSuccessfully())
+				Expect(fakeS3.Keys()).To(Equal([]string{recent}))
+				Expect(*metricValue(f, "d8_etcd_backup_failed")).To(Equal(0.0))
+			})
+		})
+
+		Context("Old snapshots exist", func() {
+			var oldest, older string
+
+			BeforeEach(func() {
+				setStorage(snapshot())
+				oldest = "etcd/main/" + etcdbackup.SnapshotName(time.Now().Add(-48*time.Hour), false)
+				older = "etcd/main/" + etcdbackup.SnapshotName(time.Now().Add(-24*time.Hour), false)
+				for _, key := range []string{oldest, older} {
+					fakeS3.Put(key, snapshot(), time.Now())
+					fakeS3.Put(key+".sha256", []byte("sum"), time.Now())
+				}
+				fakeS3.Put("etcd/main/unrelated", []byte("data"), time.Now())
+				f.KubeStateSet(testETCDSecret + etcdPods + credentialsSecret(""))
+				f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * *"))
+				f.RunHook()
+			})
+
+			It("Takes a new snapshot and deletes the oldest one", func() {
+				Expect(f).Should(ExecuteSuccessfully())
+
+				keys := fakeS3.Keys()
+				Expect(keys).To(HaveLen(5))
+				Expect(keys).ToNot(ContainElement(oldest))
+				Expect(keys).ToNot(ContainElement(oldest + ".sha256"))
+				Expect(keys).To(ContainElement(older))
+				Expect(keys).To(ContainElement("etcd/main/unrelated"))
+				Expect(*metricValue(f, "d8_etcd_backup_count")).To(Equal(2.0))
+			})
+		})
+
+		Context("Snapshot is corrupted", func() {
+			BeforeEach(func() {
+				data := snapshot()
+				data[0] = 'x'
+				setStorage(data)
+				f.KubeStateSet(testETCDSecret + etcdPods + credentialsSecret(""))
+				f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * *"))
+				f.RunHook()
+			})
+
+			It("Does not upload it and reports the failure", func() {
+				Expect(f).Should(ExecuteSuccessfully())
+				Expect(fakeS3.Keys()).To(BeEmpty())
+				Expect(*metricValue(f, "d8_etcd_backup_failed")).To(Equal(1.0))
+			})
+		})
+
+		Context("Credentials secret is missing", func() {
+			BeforeEach(func() {
+				setStorage(snapshot())
+				f.KubeStateSet(testETCDSecret + etcdPods)
+				f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * *"))
+				f.RunHook()
+			})
+
+			It("Reports the failure", func() {
+				Expect(f).Should(ExecuteSuccessfully())
+				Expect(*metricValue(f, "d8_etcd_backup_failed")).To(Equal(1.0))
+			})
+		})
+
+		Context("Encryption passphrase is set", func() {
+			BeforeEach(func() {
+				setStorage(snapshot())
+				f.KubeStateSet(testETCDSecret + etcdPods + credentialsSecret("passphrase"))
+				f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * *"))
+				f.RunHook()
+			})
+
+			It("Uploads the encrypted snapshot", func() {
+				Expect(f).Should(ExecuteSuccessfully())
+
+				keys := fakeS3.Keys()
+				Expect(keys).To(HaveLen(2))
+				Expect(keys[0]).To(HaveSuffix(".db.enc"))
+
+				encrypted, _ := fakeS3.Get(keys[0])
+				var decrypted bytes.Buffer
+				Expect(etcdbackup.Decrypt(&decrypted, bytes.NewReader(encrypted), "passphrase")).To(Succeed())
+				Expect(decrypted.Bytes()).To(Equal(snapshot()))
+			})
+		})
+	})
+})
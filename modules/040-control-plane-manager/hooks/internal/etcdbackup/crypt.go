@@ -0,0 +1,167 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// The encrypted snapshot format is the one produced by
+//
+//	openssl enc -aes-256-cbc -pbkdf2 -iter 100000 -md sha256 -salt
+//
+// so a backup can be decrypted without deckhouse:
+//
+//	openssl enc -d -aes-256-cbc -pbkdf2 -iter 100000 -md sha256 -in snapshot.db.enc -out snapshot.db
+const (
+	PBKDF2Iterations = 100000
+
+	saltMagic = "Salted__"
+	saltSize  = 8
+	keySize   = 32
+)
+
+// Encrypt streams src into dst encrypted with the passphrase
+func Encrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("generate salt: %v", err)
+	}
+
+	block, iv, err := deriveCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(append([]byte(saltMagic), salt...)); err != nil {
+		return err
+	}
+
+	enc := cipher.NewCBCEncrypter(block, iv)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// PKCS#7 padding always adds at least one byte, so the last chunk is never empty
+			last := pad(buf[:n], aes.BlockSize)
+			enc.CryptBlocks(last, last)
+			_, err = dst.Write(last)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		enc.CryptBlocks(buf[:n], buf[:n])
+		if _, err := dst.Write(buf[:n]); err != nil {
+			return err
+		}
+	}
+}
+
+// Decrypt reverses Encrypt. Backups are restored with openssl, so it is only used in tests
+// to check the round trip.
+func Decrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	header := make([]byte, len(saltMagic)+saltSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return fmt.Errorf("read header: %v", err)
+	}
+	if string(header[:len(saltMagic)]) != saltMagic {
+		return errors.New("bad magic, the data is not encrypted or corrupted")
+	}
+
+	block, iv, err := deriveCipher(passphrase, header[len(saltMagic):])
+	if err != nil {
+		return err
+	}
+	dec := cipher.NewCBCDecrypter(block, iv)
+
+	// Hold back the last block to strip the padding once the input ends
+	var pending []byte
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n%aes.BlockSize != 0 {
+			return errors.New("ciphertext is not a multiple of the block size")
+		}
+		if n > 0 {
+			if _, err := dst.Write(pending); err != nil {
+				return err
+			}
+			chunk := buf[:n]
+			dec.CryptBlocks(chunk, chunk)
+			pending = append(pending[:0], chunk...)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	plain, err := unpad(pending, aes.BlockSize)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(plain)
+	return err
+}
+
+func deriveCipher(passphrase string, salt []byte) (cipher.Block, []byte, error) {
+	if passphrase == "" {
+		return nil, nil, errors.New("empty passphrase")
+	}
+	derived := pbkdf2.Key([]byte(passphrase), salt, PBKDF2Iterations, keySize+aes.BlockSize, sha256.New)
+	block, err := aes.NewCipher(derived[:keySize])
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, derived[keySize:], nil
+}
+
+func pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	padded := make([]byte, len(data), len(data)+n)
+	copy(padded, data)
+	for i := 0; i < n; i++ {
+		padded = append(padded, byte(n))
+	}
+	return padded
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("bad padding, wrong passphrase or corrupted data")
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errors.New("bad padding, wrong passphrase or corrupted data")
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errors.New("bad padding, wrong passphrase or corrupted data")
+		}
+	}
+	return data[:len(data)-n], nil
+}
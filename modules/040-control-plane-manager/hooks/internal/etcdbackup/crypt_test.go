@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	for _, size := range []int{0, 1, 15, 16, 17, 32*1024 - 1, 32 * 1024, 100000} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			plain := make([]byte, size)
+			_, _ = rand.Read(plain)
+
+			var encrypted bytes.Buffer
+			require.NoError(t, Encrypt(&encrypted, bytes.NewReader(plain), "passphrase"))
+			assert.Equal(t, 16+size+16-size%16, encrypted.Len())
+
+			var decrypted bytes.Buffer
+			require.NoError(t, Decrypt(&decrypted, bytes.NewReader(encrypted.Bytes()), "passphrase"))
+			assert.True(t, bytes.Equal(plain, decrypted.Bytes()))
+		})
+	}
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		var encrypted bytes.Buffer
+		require.NoError(t, Encrypt(&encrypted, bytes.NewReader([]byte("secret data")), "passphrase"))
+		err := Decrypt(&bytes.Buffer{}, &encrypted, "wrong")
+		assert.Error(t, err)
+	})
+}
+
+// The documented restore procedure relies on the openssl CLI being able to decrypt backups
+func TestEncryptOpenSSLCompatibility(t *testing.T) {
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl is not installed")
+	}
+
+	dir := t.TempDir()
+	plain := make([]byte, 5000)
+	_, _ = rand.Read(plain)
+
+	var encrypted bytes.Buffer
+	require.NoError(t, Encrypt(&encrypted, bytes.NewReader(plain), "pass phrase"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "snapshot.db.enc"), encrypted.Bytes(), 0o600))
+
+	cmd := exec.Command(openssl, "enc", "-d", "-aes-256-cbc", "-pbkdf2", "-iter", strconv.Itoa(PBKDF2Iterations), "-md", "sha256",
+		"-in", filepath.Join(dir, "snapshot.db.enc"), "-out", filepath.Join(dir, "snapshot.db"), "-pass", "pass:pass phrase")
+	out, err := cmd.CombinedOutput()
+	if err != nil && bytes.Contains(out, []byte("pbkdf2")) {
+		t.Skipf("openssl does not support pbkdf2: %s", out)
+	}
+	require.NoError(t, err, string(out))
+
+	decrypted, err := os.ReadFile(filepath.Join(dir, "snapshot.db"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, decrypted)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/deckhouse/deckhouse/modules/040-control-plane-manager/hooks/internal/etcdbackup"
+	"github.com/deckhouse/deckhouse/modules/040-control-plane-manager/hooks/internal/etcdbackup/s3test"
+)
+
+func TestS3Client(t *testing.T) {
+	bucket := s3test.NewBucket("backups", "key", "secret")
+	ctx := context.Background()
+
+	newClient := func(secret string) *etcdbackup.S3Client {
+		c, err := etcdbackup.NewS3Client(etcdbackup.S3Config{
+			Endpoint:        "http://minio.local:9000",
+			Bucket:          "backups",
+			PathStyle:       true,
+			AccessKeyID:     "key",
+			SecretAccessKey: secret,
+		}, bucket)
+		require.NoError(t, err)
+		return c
+	}
+	client := newClient("secret")
+
+	put := func(key, data string) {
+		sum := sha256.Sum256([]byte(data))
+		err := client.PutObject(ctx, key, bytes.NewReader([]byte(data)), int64(len(data)), hex.EncodeToString(sum[:]))
+		require.NoError(t, err)
+	}
+	put("etcd/b", "bbb")
+	put("etcd/a", "a")
+	put("other/c", "c")
+
+	objects, err := client.ListObjects(ctx, "etcd/")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "etcd/a", objects[0].Key)
+	assert.Equal(t, int64(3), objects[1].Size)
+
+	body, err := client.GetObject(ctx, "etcd/b")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "bbb", string(data))
+
+	require.NoError(t, client.DeleteObject(ctx, "etcd/a"))
+	assert.Equal(t, []string{"etcd/b", "other/c"}, bucket.Keys())
+
+	_, err = newClient("wrong").ListObjects(ctx, "")
+	assert.ErrorContains(t, err, "SignatureDoesNotMatch")
+
+	emptySum := sha256.Sum256(nil)
+	err = client.PutObject(ctx, "etcd/x", bytes.NewReader([]byte("x")), 1, hex.EncodeToString(emptySum[:]))
+	assert.ErrorContains(t, err, "XAmzContentSHA256Mismatch")
+}
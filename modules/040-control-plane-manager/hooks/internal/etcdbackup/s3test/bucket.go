@@ -0,0 +1,209 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package s3test provides an in-memory S3 bucket for testing the etcd backup hooks.
+package s3test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deckhouse/deckhouse/modules/040-control-plane-manager/hooks/internal/etcdbackup"
+)
+
+const amzDateFormat = "20060102T150405Z"
+
+// Bucket is an in-memory S3 bucket for tests. It checks request signatures the same way a real
+// storage does and can be plugged into hooks instead of the HTTP client.
+type Bucket struct {
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+type object struct {
+	data     []byte
+	modified time.Time
+}
+
+// NewBucket returns an empty bucket that accepts requests signed with the given credentials
+func NewBucket(bucket, accessKeyID, secretAccessKey string) *Bucket {
+	return &Bucket{
+		Bucket:          bucket,
+		Region:          "us-east-1",
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		objects:         make(map[string]object),
+	}
+}
+
+// Put stores the object bypassing the API
+func (s *Bucket) Put(key string, data []byte, modified time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = object{data: data, modified: modified}
+}
+
+// Get returns the object bypassing the API
+func (s *Bucket) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[key]
+	return obj.data, ok
+}
+
+// Keys returns sorted object keys
+func (s *Bucket) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Do serves the request like an HTTP client would do, it expects path-style requests
+func (s *Bucket) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+func (s *Bucket) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			s.fail(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+	}
+
+	if code, msg := s.verify(req, body); code != "" {
+		s.fail(w, http.StatusForbidden, code, msg)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	if bucket != s.Bucket {
+		s.fail(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case req.Method == http.MethodPut && key != "":
+		s.objects[key] = object{data: body, modified: time.Now()}
+	case req.Method == http.MethodDelete && key != "":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodGet && key != "":
+		obj, ok := s.objects[key]
+		if !ok {
+			s.fail(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+		_, _ = w.Write(obj.data)
+	case req.Method == http.MethodGet && req.URL.Query().Get("list-type") == "2":
+		s.list(w, req.URL.Query().Get("prefix"))
+	default:
+		s.fail(w, http.StatusNotImplemented, "NotImplemented", req.Method+" "+req.URL.String())
+	}
+}
+
+func (s *Bucket) list(w http.ResponseWriter, prefix string) {
+	var result struct {
+		XMLName  xml.Name `xml:"ListBucketResult"`
+		Contents []struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			LastModified time.Time `xml:"LastModified"`
+		} `xml:"Contents"`
+	}
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		obj := s.objects[key]
+		result.Contents = append(result.Contents, struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			LastModified time.Time `xml:"LastModified"`
+		}{Key: key, Size: int64(len(obj.data)), LastModified: obj.modified.UTC()})
+	}
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (s *Bucket) verify(req *http.Request, body []byte) (string, string) {
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, "Credential="+s.AccessKeyID+"/") {
+		return "InvalidAccessKeyId", "The access key does not exist"
+	}
+
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	sum := sha256.Sum256(body)
+	if payloadHash != hex.EncodeToString(sum[:]) {
+		return "XAmzContentSHA256Mismatch", "The provided x-amz-content-sha256 header does not match"
+	}
+
+	date, err := time.Parse(amzDateFormat, req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return "AccessDenied", "bad X-Amz-Date"
+	}
+
+	signed := req.Clone(req.Context())
+	if signed.URL.Host == "" {
+		signed.URL.Host = req.Host
+	}
+	signed.Header.Del("Authorization")
+	etcdbackup.SignV4(signed, payloadHash, s.AccessKeyID, s.SecretAccessKey, s.Region, date)
+	if signed.Header.Get("Authorization") != auth {
+		return "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided"
+	}
+	return "", ""
+}
+
+func (s *Bucket) fail(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	snapshotPrefix    = "etcd-snapshot-"
+	snapshotExt       = ".db"
+	EncryptedExt      = ".enc"
+	ChecksumExt       = ".sha256"
+	snapshotTimestamp = "20060102T150405Z"
+)
+
+// SnapshotName returns the object name of a snapshot taken at the time
+func SnapshotName(t time.Time, encrypted bool) string {
+	name := snapshotPrefix + t.UTC().Format(snapshotTimestamp) + snapshotExt
+	if encrypted {
+		name += EncryptedExt
+	}
+	return name
+}
+
+// ParseSnapshotName returns the time the snapshot was taken, checksum files and foreign objects
+// are not snapshots
+func ParseSnapshotName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, snapshotPrefix) {
+		return time.Time{}, false
+	}
+	ts := strings.TrimPrefix(name, snapshotPrefix)
+	ts = strings.TrimSuffix(ts, EncryptedExt)
+	if !strings.HasSuffix(ts, snapshotExt) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(snapshotTimestamp, strings.TrimSuffix(ts, snapshotExt))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// VerifySnapshot checks the integrity hash etcd appends to the snapshot. It is the same check
+// `etcdutl snapshot restore` does unless --skip-hash-check is passed.
+func VerifySnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size < 512+sha256.Size || size%512 != sha256.Size {
+		return fmt.Errorf("snapshot size %d does not include the integrity hash", size)
+	}
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, size-sha256.Size); err != nil {
+		return err
+	}
+	expected := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(f, expected); err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return errors.New("snapshot integrity hash mismatch")
+	}
+	return nil
+}
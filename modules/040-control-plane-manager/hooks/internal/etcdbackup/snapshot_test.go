@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotName(t *testing.T) {
+	ts := time.Date(2023, 10, 1, 12, 30, 5, 0, time.UTC)
+
+	name := SnapshotName(ts, true)
+	assert.Equal(t, "etcd-snapshot-20231001T123005Z.db.enc", name)
+
+	parsed, ok := ParseSnapshotName(name)
+	require.True(t, ok)
+	assert.True(t, ts.Equal(parsed))
+
+	_, ok = ParseSnapshotName(SnapshotName(ts, false) + ChecksumExt)
+	assert.False(t, ok)
+	_, ok = ParseSnapshotName("etcd-snapshot-latest.db")
+	assert.False(t, ok)
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	data := make([]byte, 1024)
+	sum := sha256.Sum256(data)
+	valid := append(append([]byte{}, data...), sum[:]...)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "valid.db"), valid, 0o600))
+	assert.NoError(t, VerifySnapshot(filepath.Join(dir, "valid.db")))
+
+	corrupted := append([]byte{}, valid...)
+	corrupted[10] = 1
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "corrupted.db"), corrupted, 0o600))
+	assert.ErrorContains(t, VerifySnapshot(filepath.Join(dir, "corrupted.db")), "mismatch")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "truncated.db"), valid[:700], 0o600))
+	assert.ErrorContains(t, VerifySnapshot(filepath.Join(dir, "truncated.db")), "integrity hash")
+}
@@ -44,6 +44,7 @@ const (
 	pkiPath                   = `/pki`
 	kubernetesPkiPath         = kubernetesConfigPath + `/pki`
 	kubeadmPath               = "/kubeadm"
+	podReadyTimeout           = 5 * time.Minute
 )
 
 type Config struct {
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"os/signal"
@@ -72,14 +73,32 @@ func main() {
 	runPhase(renewKubeconfigs())
 	runPhase(updateRootKubeconfig())
 	runPhase(installExtraFiles())
-	runPhase(convergeComponents())
+	err = convergeComponents()
+	if errors.Is(err, errComponentRolledBack) {
+		log.Error(err)
+		if err := reportFailedChecksums(); err != nil {
+			log.Warn(err)
+		}
+		cleanup()
+		// pause loop without becoming ready, the daemonset rollout stops on this node
+		<-config.ExitChannel
+		return
+	}
+	runPhase(err)
+	if err := reportFailedChecksums(); err != nil {
+		log.Warn(err)
+	}
 	runPhase(config.writeLastAppliedConfigurationChecksum())
 
 	cleanup()
 
+	if err := publishCertificateInventory(); err != nil {
+		log.Warn(err)
+	}
+
 	controlPlaneManagerIsReady = true
-	// pause loop
-	<-config.ExitChannel
+	// pause loop, certificates can be renewed on demand
+	watchRenewalRequests()
 }
 
 func httpServerClose() {
@@ -19,6 +19,7 @@ package main
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -103,7 +104,16 @@ func convergeComponent(componentName string) error {
 		recreateConfig = true
 	}
 
-	if recreateConfig {
+	if !recreateConfig {
+		log.Infof("skip manifest generation for component %s because checksum in manifest is up to date", componentName)
+		if err := waitPodIsReady(componentName, checksum); err != nil {
+			return err
+		}
+		// the configuration may be reverted to the working one after a rollback
+		return removeFailedChecksum(failedChecksumsDir, componentName)
+	}
+
+	err = convergeComponentWithRollback(componentName, checksum, func() error {
 		log.Infof("generate new manifest for %s", componentName)
 		if err := backupFile(filepath.Join(manifestsPath, componentName+".yaml")); err != nil {
 			log.Warnf("Backup failed, %s", err)
@@ -125,12 +135,12 @@ func convergeComponent(componentName string) error {
 		}
 
 		_ = os.Remove(filepath.Join(deckhousePath, "kubeadm", "patches", componentName+"999checksum.yaml"))
-
-	} else {
-		log.Infof("skip manifest generation for component %s because checksum in manifest is up to date", componentName)
+		return nil
+	})
+	if errors.Is(err, errComponentRolledBack) {
+		return fmt.Errorf("%s: %w", componentName, err)
 	}
-
-	return waitPodIsReady(componentName, checksum)
+	return err
 }
 
 func prepareConverge(componentName string, isTemp bool) error {
@@ -229,16 +239,21 @@ func etcdJoinConverge() error {
 }
 
 func waitPodIsReady(componentName string, checksum string) error {
-	tries := 0
+	deadline := time.Now().Add(podReadyTimeout)
 	log.Infof("waiting for the %s pod component to be ready with the new manifest in apiserver", componentName)
 	for {
-		tries++
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for pod %s-%s to become ready with expected checksum %s", componentName, config.NodeName, checksum)
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		podName := fmt.Sprintf("%s-%s", componentName, config.NodeName)
 		pod, err := config.K8sClient.CoreV1().Pods("kube-system").Get(ctx, podName, metav1.GetOptions{})
 		cancel()
 		if err != nil {
 			log.Warn(err)
+			time.Sleep(1 * time.Second)
+			continue
 		}
 		if podChecksum := pod.Annotations["control-plane-manager.deckhouse.io/checksum"]; podChecksum != checksum {
 			log.Warnf("kubernetes pod %s checksum %s does not match expected checksum %s", podName, podChecksum, checksum)
@@ -258,10 +273,6 @@ func waitPodIsReady(componentName string, checksum string) error {
 			continue
 		}
 
-		if tries > 240 {
-			return fmt.Errorf("timeout waiting for pod %s to become ready with expected checksum %s", podName, checksum)
-		}
-
 		log.Infof("kubernetes pod %s has matching checksum %s and is ready", podName, checksum)
 		return nil
 	}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	certificatesAnnotation = `control-plane-manager.deckhouse.io/certificates`
+
+	certificateKind = "certificate"
+	kubeconfigKind  = "kubeconfig"
+)
+
+// caFiles are not renewed by the controller, they come from the d8-pki secret, but they are part of the inventory
+var caFiles = map[string]string{
+	"ca":             "ca",
+	"front-proxy-ca": "front-proxy-ca",
+	"etcd-ca":        "etcd/ca",
+}
+
+type certificateInfo struct {
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Subject  string    `json:"subject"`
+	SANs     []string  `json:"sans,omitempty"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+func newCertificateInfo(name, kind string, cert *x509.Certificate) certificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return certificateInfo{
+		Name:     name,
+		Kind:     kind,
+		Subject:  cert.Subject.String(),
+		SANs:     sans,
+		Issuer:   cert.Issuer.String(),
+		NotAfter: cert.NotAfter.UTC(),
+	}
+}
+
+// collectCertificateInventory reads certificates and kubeconfigs from disk, missing or broken files are skipped
+func collectCertificateInventory(pkiDir, kubeconfigDir string) []certificateInfo {
+	inventory := make([]certificateInfo, 0, len(caFiles)+len(certificateFiles)+len(kubeconfigNames))
+
+	for _, files := range []map[string]string{caFiles, certificateFiles} {
+		for name, file := range files {
+			cert, err := loadCert(filepath.Join(pkiDir, file+".crt"))
+			if err != nil {
+				log.Warnf("cannot load certificate %s: %v", name, err)
+				continue
+			}
+			inventory = append(inventory, newCertificateInfo(name, certificateKind, cert))
+		}
+	}
+
+	for _, name := range kubeconfigNames {
+		path := filepath.Join(kubeconfigDir, name+".conf")
+		kubeconfig, err := loadKubeconfig(path)
+		if err != nil {
+			log.Warnf("cannot load kubeconfig %s: %v", path, err)
+			continue
+		}
+		cert, err := kubeconfigClientCertificate(kubeconfig, path)
+		if err != nil {
+			log.Warnf("cannot load kubeconfig %s client certificate: %v", path, err)
+			continue
+		}
+		inventory = append(inventory, newCertificateInfo(name, kubeconfigKind, cert))
+	}
+
+	sort.Slice(inventory, func(i, j int) bool {
+		if inventory[i].Kind != inventory[j].Kind {
+			return inventory[i].Kind < inventory[j].Kind
+		}
+		return inventory[i].Name < inventory[j].Name
+	})
+	return inventory
+}
+
+// publishCertificateInventory stores the inventory in the node annotation, deckhouse turns it into metrics
+func publishCertificateInventory() error {
+	log.Infof("phase: publish certificate inventory in node %s annotations", config.NodeName)
+
+	inventory, err := json.Marshal(collectCertificateInventory(kubernetesPkiPath, kubernetesConfigPath))
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{certificatesAnnotation: string(inventory)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = config.K8sClient.CoreV1().Nodes().Patch(context.TODO(), config.NodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, path, commonName string, notAfter time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"kubernetes", "kubernetes.default"},
+		IPAddresses:  []net.IP{net.ParseIP("10.222.0.1")},
+		NotBefore:    notAfter.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectCertificateInventory(t *testing.T) {
+	pkiDir := t.TempDir()
+	kubeconfigDir := t.TempDir()
+
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeTestCertificate(t, filepath.Join(pkiDir, "apiserver.crt"), "kube-apiserver", notAfter)
+	writeTestCertificate(t, filepath.Join(pkiDir, "etcd", "server.crt"), "dev-master-0", notAfter)
+
+	kubeconfig, err := os.ReadFile("testdata/kubeconfig.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(kubeconfigDir, "admin.conf"), kubeconfig, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	inventory := collectCertificateInventory(pkiDir, kubeconfigDir)
+	if len(inventory) != 3 {
+		t.Fatalf("expected 3 items in inventory, got %d: %+v", len(inventory), inventory)
+	}
+
+	apiserver := inventory[0]
+	if apiserver.Name != "apiserver" || apiserver.Kind != certificateKind {
+		t.Fatalf("unexpected first item %+v", apiserver)
+	}
+	if apiserver.Subject != "CN=kube-apiserver" || apiserver.Issuer != "CN=kube-apiserver" {
+		t.Fatalf("unexpected subject or issuer %+v", apiserver)
+	}
+	if !apiserver.NotAfter.Equal(notAfter) {
+		t.Fatalf("unexpected notAfter %s", apiserver.NotAfter)
+	}
+	if len(apiserver.SANs) != 3 || apiserver.SANs[2] != "10.222.0.1" {
+		t.Fatalf("unexpected SANs %v", apiserver.SANs)
+	}
+
+	if inventory[1].Name != "etcd-server" {
+		t.Fatalf("unexpected second item %+v", inventory[1])
+	}
+	if inventory[2].Name != "admin" || inventory[2].Kind != kubeconfigKind {
+		t.Fatalf("unexpected kubeconfig item %+v", inventory[2])
+	}
+}
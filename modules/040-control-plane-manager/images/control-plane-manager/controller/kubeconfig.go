@@ -34,9 +34,11 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
+var kubeconfigNames = []string{"admin", "controller-manager", "scheduler"}
+
 func renewKubeconfigs() error {
 	log.Info("phase: renew kubeconfigs")
-	for _, v := range []string{"admin", "controller-manager", "scheduler"} {
+	for _, v := range kubeconfigNames {
 		if err := renewKubeconfig(v); err != nil {
 			return err
 		}
@@ -77,21 +79,7 @@ func renewKubeconfig(componentName string) error {
 			remove = true
 		}
 
-		if len(currentKubeconfig.AuthInfos) == 0 {
-			return fmt.Errorf("users field of kubeconfig %s is empty", path)
-		}
-
-		certData := currentKubeconfig.AuthInfos[0].AuthInfo.ClientCertificateData
-		if len(certData) == 0 {
-			return fmt.Errorf("client-certificate-data field of kubeconfig %s is empty", path)
-		}
-
-		block, _ := pem.Decode(certData)
-		if len(block.Bytes) == 0 {
-			return fmt.Errorf("cannot pem decode client-rtificate-data field of kubeconfig %s", path)
-		}
-
-		cert, err := x509.ParseCertificate(block.Bytes)
+		cert, err := kubeconfigClientCertificate(currentKubeconfig, path)
 		if err != nil {
 			return err
 		}
@@ -117,6 +105,24 @@ func renewKubeconfig(componentName string) error {
 	return prepareKubeconfig(componentName, false)
 }
 
+func kubeconfigClientCertificate(kubeconfig *configv1.Config, path string) (*x509.Certificate, error) {
+	if len(kubeconfig.AuthInfos) == 0 {
+		return nil, fmt.Errorf("users field of kubeconfig %s is empty", path)
+	}
+
+	certData := kubeconfig.AuthInfos[0].AuthInfo.ClientCertificateData
+	if len(certData) == 0 {
+		return nil, fmt.Errorf("client-certificate-data field of kubeconfig %s is empty", path)
+	}
+
+	block, _ := pem.Decode(certData)
+	if block == nil || len(block.Bytes) == 0 {
+		return nil, fmt.Errorf("cannot pem decode client-certificate-data field of kubeconfig %s", path)
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
 func prepareKubeconfig(componentName string, isTemp bool) error {
 	// kubeadm init phase kubeconfig apiserver --config /etc/kubernetes/deckhouse/kubeadm/config.yaml
 	args := []string{"init", "phase", "kubeconfig", componentName, "--config", deckhousePath + "/kubeadm/config.yaml"}
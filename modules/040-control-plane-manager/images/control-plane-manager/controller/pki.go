@@ -65,17 +65,20 @@ func installBasePKIfiles() error {
 	return nil
 }
 
+// certificateFiles maps kubeadm certificate names to file paths relative to the pki directory
+var certificateFiles = map[string]string{
+	"apiserver":                "apiserver",
+	"apiserver-kubelet-client": "apiserver-kubelet-client",
+	"apiserver-etcd-client":    "apiserver-etcd-client",
+	"front-proxy-client":       "front-proxy-client",
+	"etcd-server":              "etcd/server",
+	"etcd-peer":                "etcd/peer",
+	"etcd-healthcheck-client":  "etcd/healthcheck-client",
+}
+
 func renewCertificates() error {
 	log.Info("phase: renew certificates")
-	components := make(map[string]string, 7)
-	components["apiserver"] = "apiserver"
-	components["apiserver-kubelet-client"] = "apiserver-kubelet-client"
-	components["apiserver-etcd-client"] = "apiserver-etcd-client"
-	components["front-proxy-client"] = "front-proxy-client"
-	components["etcd-server"] = "etcd/server"
-	components["etcd-peer"] = "etcd/peer"
-	components["etcd-healthcheck-client"] = "etcd/healthcheck-client"
-	for k, v := range components {
+	for k, v := range certificateFiles {
 		if err := renewCertificate(k, v); err != nil {
 			return err
 		}
@@ -0,0 +1,302 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+// Certificates are renewed on demand by annotating the master node:
+//
+//	kubectl annotate node master-0 control-plane-manager.deckhouse.io/renew-certificates=apiserver,admin
+//
+// The value is a comma separated list of certificate and kubeconfig names from the inventory, or "all".
+// Masters renew certificates one at a time holding the lease, components are restarted by the regular
+// converge with the readiness check and the rollback.
+const (
+	renewCertificatesAnnotation       = `control-plane-manager.deckhouse.io/renew-certificates`
+	renewCertificatesResultAnnotation = `control-plane-manager.deckhouse.io/renew-certificates-result`
+	renewalLeaseName                  = `d8-control-plane-manager-certificates-renewal`
+	renewalLeaseDuration              = 30 * time.Minute
+	renewalPollInterval               = 30 * time.Second
+)
+
+type renewRequest struct {
+	Certificates []string
+	Kubeconfigs  []string
+}
+
+func parseRenewRequest(value string) (renewRequest, error) {
+	var req renewRequest
+
+	value = strings.TrimSpace(value)
+	if value == "all" {
+		for name := range certificateFiles {
+			req.Certificates = append(req.Certificates, name)
+		}
+		sort.Strings(req.Certificates)
+		req.Kubeconfigs = append(req.Kubeconfigs, kubeconfigNames...)
+		return req, nil
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := certificateFiles[name]; ok {
+			req.Certificates = append(req.Certificates, name)
+			continue
+		}
+		if stringSliceContains(kubeconfigNames, name) {
+			req.Kubeconfigs = append(req.Kubeconfigs, name)
+			continue
+		}
+		return req, fmt.Errorf("unknown certificate or kubeconfig %q", name)
+	}
+
+	if len(req.Certificates) == 0 && len(req.Kubeconfigs) == 0 {
+		return req, errors.New("no certificates to renew")
+	}
+	return req, nil
+}
+
+func stringSliceContains(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// watchRenewalRequests replaces the pause loop after a successful converge
+func watchRenewalRequests() {
+	ticker := time.NewTicker(renewalPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-config.ExitChannel:
+			return
+		case <-ticker.C:
+		}
+
+		node, err := config.K8sClient.CoreV1().Nodes().Get(context.TODO(), config.NodeName, metav1.GetOptions{})
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		value, ok := node.Annotations[renewCertificatesAnnotation]
+		if !ok {
+			continue
+		}
+
+		err = handleRenewRequest(value)
+		if errors.Is(err, errComponentRolledBack) {
+			log.Error(err)
+			// stay unready like after a failed converge, the request is not retried
+			_ = finishRenewRequest(err)
+			<-config.ExitChannel
+			return
+		}
+		if err := finishRenewRequest(err); err != nil {
+			log.Warn(err)
+		}
+	}
+}
+
+func handleRenewRequest(value string) (err error) {
+	req, err := parseRenewRequest(value)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("renew certificates %v and kubeconfigs %v on demand", req.Certificates, req.Kubeconfigs)
+
+	controlPlaneManagerIsReady = false
+	defer func() {
+		// a rolled back component keeps the manager unready like after a failed converge
+		if !errors.Is(err, errComponentRolledBack) {
+			controlPlaneManagerIsReady = true
+		}
+	}()
+
+	if err := acquireRenewalLease(); err != nil {
+		return err
+	}
+	defer releaseRenewalLease()
+	defer cleanup()
+
+	if err := fillTmpDirWithPKIData(); err != nil {
+		return err
+	}
+
+	for _, name := range req.Certificates {
+		path := filepath.Join(kubernetesPkiPath, certificateFiles[name])
+		for _, file := range []string{path + ".crt", path + ".key"} {
+			if err := removeFile(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := renewCertificate(name, certificateFiles[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range req.Kubeconfigs {
+		if err := removeFile(filepath.Join(kubernetesConfigPath, name+".conf")); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := renewKubeconfig(name); err != nil {
+			return err
+		}
+	}
+
+	// components referencing renewed files get a new checksum and are restarted one by one
+	return convergeComponents()
+}
+
+// finishRenewRequest removes the request and stores the result, failed requests are not retried
+func finishRenewRequest(renewErr error) error {
+	result := "renewed at " + time.Now().UTC().Format(time.RFC3339)
+	if renewErr != nil {
+		log.Errorf("on-demand certificates renewal failed: %v", renewErr)
+		result = "failed at " + time.Now().UTC().Format(time.RFC3339) + ": " + renewErr.Error()
+	}
+
+	if err := publishCertificateInventory(); err != nil {
+		log.Warn(err)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				renewCertificatesAnnotation:       nil,
+				renewCertificatesResultAnnotation: result,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = config.K8sClient.CoreV1().Nodes().Patch(context.TODO(), config.NodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// acquireRenewalLease waits until no other master renews certificates
+func acquireRenewalLease() error {
+	for {
+		acquired, err := tryAcquireRenewalLease(time.Now())
+		if err != nil {
+			log.Warnf("cannot acquire lease %s: %v", renewalLeaseName, err)
+		}
+		if acquired {
+			return nil
+		}
+
+		log.Infof("waiting for lease %s held by another master", renewalLeaseName)
+		select {
+		case <-config.ExitChannel:
+			return errors.New("interrupted while waiting for the renewal lease")
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+func tryAcquireRenewalLease(now time.Time) (bool, error) {
+	leases := config.K8sClient.CoordinationV1().Leases(namespace)
+
+	lease, err := leases.Get(context.TODO(), renewalLeaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: renewalLeaseName, Namespace: namespace}}
+		setRenewalLeaseHolder(lease, now)
+		_, err = leases.Create(context.TODO(), lease, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !renewalLeaseIsFree(lease, config.NodeName, now) {
+		return false, nil
+	}
+
+	// the update fails with a conflict if another master takes the lease in the meantime
+	setRenewalLeaseHolder(lease, now)
+	_, err = leases.Update(context.TODO(), lease, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func renewalLeaseIsFree(lease *coordinationv1.Lease, holder string, now time.Time) bool {
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" || *lease.Spec.HolderIdentity == holder {
+		return true
+	}
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expires := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return now.After(expires)
+}
+
+func setRenewalLeaseHolder(lease *coordinationv1.Lease, now time.Time) {
+	renewTime := metav1.NewMicroTime(now)
+	lease.Spec.HolderIdentity = pointer.String(config.NodeName)
+	lease.Spec.LeaseDurationSeconds = pointer.Int32(int32(renewalLeaseDuration.Seconds()))
+	lease.Spec.AcquireTime = &renewTime
+	lease.Spec.RenewTime = &renewTime
+}
+
+func releaseRenewalLease() {
+	leases := config.K8sClient.CoordinationV1().Leases(namespace)
+
+	lease, err := leases.Get(context.TODO(), renewalLeaseName, metav1.GetOptions{})
+	if err != nil {
+		log.Warnf("cannot release lease %s: %v", renewalLeaseName, err)
+		return
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != config.NodeName {
+		return
+	}
+
+	lease.Spec.HolderIdentity = nil
+	if _, err := leases.Update(context.TODO(), lease, metav1.UpdateOptions{}); err != nil {
+		log.Warnf("cannot release lease %s: %v", renewalLeaseName, err)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestParseRenewRequest(t *testing.T) {
+	req, err := parseRenewRequest("apiserver, admin,etcd-peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(req.Certificates, []string{"apiserver", "etcd-peer"}) || !reflect.DeepEqual(req.Kubeconfigs, []string{"admin"}) {
+		t.Fatalf("unexpected request %+v", req)
+	}
+
+	req, err = parseRenewRequest("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Certificates) != len(certificateFiles) || len(req.Kubeconfigs) != len(kubeconfigNames) {
+		t.Fatalf("unexpected request %+v", req)
+	}
+
+	for _, value := range []string{"", " , ", "ca", "apiserver,unknown"} {
+		if _, err := parseRenewRequest(value); err == nil {
+			t.Fatalf("request %q should be rejected", value)
+		}
+	}
+}
+
+func TestRenewalLeaseIsFree(t *testing.T) {
+	now := time.Now()
+	lease := func(holder string, renewed time.Time) *coordinationv1.Lease {
+		renewTime := metav1.NewMicroTime(renewed)
+		return &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       pointer.String(holder),
+			LeaseDurationSeconds: pointer.Int32(int32(renewalLeaseDuration.Seconds())),
+			RenewTime:            &renewTime,
+		}}
+	}
+
+	if !renewalLeaseIsFree(&coordinationv1.Lease{}, "master-0", now) {
+		t.Fatal("lease without holder should be free")
+	}
+	if !renewalLeaseIsFree(lease("master-0", now), "master-0", now) {
+		t.Fatal("lease held by us should be free")
+	}
+	if renewalLeaseIsFree(lease("master-1", now.Add(-time.Minute)), "master-0", now) {
+		t.Fatal("lease held by another master should not be free")
+	}
+	if !renewalLeaseIsFree(lease("master-1", now.Add(-renewalLeaseDuration-time.Minute)), "master-0", now) {
+		t.Fatal("expired lease should be free")
+	}
+}
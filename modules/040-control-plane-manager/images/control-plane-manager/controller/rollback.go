@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// failedChecksumAnnotationPrefix is followed by the component name, the value is the checksum of the rolled back manifest
+	failedChecksumAnnotationPrefix = `control-plane-manager.deckhouse.io/failed-checksum-`
+	failedChecksumsDir             = deckhousePath + `/failed-checksums`
+)
+
+var (
+	// errComponentRolledBack stops the converge without exiting, so the pod stays unready and the
+	// daemonset rollout does not proceed to other masters
+	errComponentRolledBack = errors.New("component is rolled back to the previous manifest")
+
+	manifestChecksumRegexp = regexp.MustCompile(`control-plane-manager\.deckhouse\.io/checksum: "?([0-9a-f]+)"?`)
+)
+
+// convergeComponentWithRollback applies the new manifest and restores the previous one if the pod
+// does not become ready in time. The checksum of a rolled back manifest is recorded, so the same
+// configuration is not applied again until it changes.
+func convergeComponentWithRollback(componentName string, checksum string, apply func() error) error {
+	if failed := readFailedChecksum(failedChecksumsDir, componentName); failed == checksum {
+		log.Warnf("skip manifest generation for component %s because checksum %s was rolled back before", componentName, checksum)
+		return errComponentRolledBack
+	}
+
+	manifestPath := filepath.Join(manifestsPath, componentName+".yaml")
+	previous, err := os.ReadFile(manifestPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+
+	waitErr := waitPodIsReady(componentName, checksum)
+	if waitErr == nil {
+		return removeFailedChecksum(failedChecksumsDir, componentName)
+	}
+
+	if previous == nil {
+		// nothing to roll back to, e.g. the first installation of the component
+		return waitErr
+	}
+
+	log.Errorf("%v, rolling back %s to the previous manifest", waitErr, componentName)
+	if err := os.WriteFile(manifestPath, previous, 0600); err != nil {
+		return fmt.Errorf("restore previous manifest %s: %v", manifestPath, err)
+	}
+	if err := writeFailedChecksum(failedChecksumsDir, componentName, checksum); err != nil {
+		return err
+	}
+
+	if previousChecksum := manifestChecksum(previous); previousChecksum != "" {
+		if err := waitPodIsReady(componentName, previousChecksum); err != nil {
+			return fmt.Errorf("%s is not ready after the rollback: %v", componentName, err)
+		}
+	}
+
+	return errComponentRolledBack
+}
+
+func manifestChecksum(manifest []byte) string {
+	m := manifestChecksumRegexp.FindSubmatch(manifest)
+	if m == nil {
+		return ""
+	}
+	return string(m[1])
+}
+
+func readFailedChecksum(dir, componentName string) string {
+	content, err := os.ReadFile(filepath.Join(dir, componentName))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func writeFailedChecksum(dir, componentName, checksum string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, componentName), []byte(checksum+"\n"), 0600)
+}
+
+func removeFailedChecksum(dir, componentName string) error {
+	err := os.Remove(filepath.Join(dir, componentName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// failedChecksumsPatch returns a node merge patch to set annotations for recorded failed checksums
+// and to remove annotations of components converged since then
+func failedChecksumsPatch(dir string, annotations map[string]string) ([]byte, bool, error) {
+	patch := make(map[string]interface{})
+
+	for _, componentName := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "etcd"} {
+		key := failedChecksumAnnotationPrefix + componentName
+		checksum := readFailedChecksum(dir, componentName)
+		current, exists := annotations[key]
+
+		switch {
+		case checksum != "" && current != checksum:
+			patch[key] = checksum
+		case checksum == "" && exists:
+			patch[key] = nil
+		}
+	}
+
+	if len(patch) == 0 {
+		return nil, false, nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": patch},
+	})
+	return data, true, err
+}
+
+// reportFailedChecksums exposes rolled back components in node annotations, deckhouse turns them into metrics
+func reportFailedChecksums() error {
+	log.Infof("phase: report rolled back components in node %s annotations", config.NodeName)
+
+	node, err := config.K8sClient.CoreV1().Nodes().Get(context.TODO(), config.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	patch, changed, err := failedChecksumsPatch(failedChecksumsDir, node.Annotations)
+	if err != nil || !changed {
+		return err
+	}
+
+	_, err = config.K8sClient.CoreV1().Nodes().Patch(context.TODO(), config.NodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestManifestChecksum(t *testing.T) {
+	manifest := []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  annotations:
+    control-plane-manager.deckhouse.io/checksum: "0a1b2c"
+  name: kube-apiserver
+`)
+	if checksum := manifestChecksum(manifest); checksum != "0a1b2c" {
+		t.Fatalf("unexpected checksum %q", checksum)
+	}
+	if checksum := manifestChecksum([]byte("kind: Pod")); checksum != "" {
+		t.Fatalf("unexpected checksum %q for manifest without annotation", checksum)
+	}
+}
+
+func TestFailedChecksums(t *testing.T) {
+	dir := t.TempDir()
+
+	if checksum := readFailedChecksum(dir, "kube-apiserver"); checksum != "" {
+		t.Fatalf("unexpected checksum %q before write", checksum)
+	}
+
+	if err := writeFailedChecksum(dir, "kube-apiserver", "aaa"); err != nil {
+		t.Fatal(err)
+	}
+	if checksum := readFailedChecksum(dir, "kube-apiserver"); checksum != "aaa" {
+		t.Fatalf("unexpected checksum %q after write", checksum)
+	}
+
+	annotations := map[string]string{
+		failedChecksumAnnotationPrefix + "kube-scheduler": "bbb",
+	}
+	patch, changed, err := failedChecksumsPatch(dir, annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"metadata":{"annotations":{"control-plane-manager.deckhouse.io/failed-checksum-kube-apiserver":"aaa","control-plane-manager.deckhouse.io/failed-checksum-kube-scheduler":null}}}`
+	if !changed || string(patch) != expected {
+		t.Fatalf("unexpected patch %s", patch)
+	}
+
+	annotations = map[string]string{
+		failedChecksumAnnotationPrefix + "kube-apiserver": "aaa",
+	}
+	if _, changed, _ := failedChecksumsPatch(dir, annotations); changed {
+		t.Fatal("annotations are up to date, patch is not expected")
+	}
+
+	if err := removeFailedChecksum(dir, "kube-apiserver"); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeFailedChecksum(dir, "kube-apiserver"); err != nil {
+		t.Fatalf("removing absent checksum should not fail: %v", err)
+	}
+	if checksum := readFailedChecksum(dir, "kube-apiserver"); checksum != "" {
+		t.Fatalf("unexpected checksum %q after remove", checksum)
+	}
+}
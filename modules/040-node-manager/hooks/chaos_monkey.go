@@ -21,16 +21,20 @@ import (
 	"math/rand"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
 	"github.com/flant/addon-operator/sdk"
 	"github.com/flant/shell-operator/pkg/kube/object_patch"
 	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update"
 	"github.com/deckhouse/deckhouse/modules/040-node-manager/hooks/internal/mcm/v1alpha1"
 	ngv1 "github.com/deckhouse/deckhouse/modules/040-node-manager/hooks/internal/v1"
 )
@@ -86,6 +90,27 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 	},
 }, handleChaosMonkey)
 
+const (
+	chaosModeDisabled         = "Disabled"
+	chaosModeDrainAndDelete   = "DrainAndDelete"
+	chaosModeDrain            = "Drain"
+	chaosModeRestartKubelet   = "RestartKubelet"
+	chaosModeRestartCRI       = "RestartCRI"
+	chaosModeNetworkPartition = "NetworkPartition"
+
+	// chaosFaultAnnotation marks a node with an active fault. Bashible removes it after executing
+	// RestartKubelet, RestartCRI and NetworkPartition faults, the hook removes it after reverting a Drain fault.
+	chaosFaultAnnotation = "node.deckhouse.io/chaos-monkey-fault"
+	// chaosFaultTimeAnnotation keeps the time of the last injected fault, it is used to limit the blast radius.
+	chaosFaultTimeAnnotation     = "node.deckhouse.io/chaos-monkey-fault-time"
+	chaosFaultDurationAnnotation = "node.deckhouse.io/chaos-monkey-fault-duration"
+	// chaosDeletionTimesAnnotation keeps times of nodes deleted by the chaos monkey in the NodeGroup, since
+	// deleted nodes can't keep the time of the fault themselves.
+	chaosDeletionTimesAnnotation = "node.deckhouse.io/chaos-monkey-deletion-times"
+
+	chaosDrainingSource = "chaos-monkey"
+)
+
 func handleChaosMonkey(input *go_hook.HookInput) error {
 	random := time.Now().UnixNano()
 	testRandomSeed := os.Getenv("D8_TEST_RANDOM_SEED")
@@ -94,6 +119,9 @@ func handleChaosMonkey(input *go_hook.HookInput) error {
 		random = res
 	}
 	randomizer := rand.New(rand.NewSource(random))
+	now := time.Now()
+
+	revertExpiredChaosFaults(input, now)
 
 	nodeGroups, machines, nodes, err := prepareChaosData(input)
 	if err != nil {
@@ -103,7 +131,7 @@ func handleChaosMonkey(input *go_hook.HookInput) error {
 
 	// preparation complete, main hook logic goes here
 	for _, ng := range nodeGroups {
-		if ng.ChaosMode != "DrainAndDelete" {
+		if !ng.ChaosWindows.IsAllowed(now) {
 			continue
 		}
 
@@ -113,6 +141,12 @@ func handleChaosMonkey(input *go_hook.HookInput) error {
 			continue
 		}
 
+		chaosDuration, err := time.ParseDuration(ng.ChaosDuration)
+		if err != nil {
+			input.LogEntry.Warnf("chaos duration (%s) for NodeGroup:%s is invalid", ng.ChaosDuration, ng.Name)
+			continue
+		}
+
 		run := randomizer.Uint32() % uint32(chaosPeriod.Milliseconds()/1000/60)
 
 		if run != 0 {
@@ -124,21 +158,119 @@ func handleChaosMonkey(input *go_hook.HookInput) error {
 			continue
 		}
 
-		victimNode := nodeGroupNodes[randomizer.Intn(len(nodeGroupNodes))]
+		windowStart := now.Add(-chaosPeriod)
+
+		candidates, affected, hasActiveFault := chaosCandidates(nodeGroupNodes, windowStart, now)
+		if hasActiveFault {
+			continue
+		}
+
+		deletionTimes := chaosTimesSince(ng.DeletionTimes, windowStart)
+		affected += int32(len(deletionTimes))
+
+		if affected >= ng.MaxNodesPerWindow {
+			input.LogEntry.Infof("chaos monkey already affected %d nodes of NodeGroup:%s during the last %s, skipping", affected, ng.Name, ng.ChaosPeriod)
+			continue
+		}
 
-		victimMachine, ok := machines[victimNode.Name]
-		if !ok {
+		if len(candidates) == 0 {
 			continue
 		}
 
-		input.PatchCollector.MergePatch(victimAnnotationPatch, "machine.sapcloud.io/v1alpha1", "Machine", "d8-cloud-instance-manager", victimMachine.Name)
+		victimNode := candidates[randomizer.Intn(len(candidates))]
+
+		switch ng.ChaosMode {
+		case chaosModeDrainAndDelete:
+			victimMachine, ok := machines[victimNode.Name]
+			if !ok {
+				continue
+			}
+
+			input.PatchCollector.MergePatch(newChaosDeletionTimesPatch(append(deletionTimes, now)), "deckhouse.io/v1", "NodeGroup", "", ng.Name)
+			input.PatchCollector.MergePatch(victimAnnotationPatch, "machine.sapcloud.io/v1alpha1", "Machine", "d8-cloud-instance-manager", victimMachine.Name)
+
+			input.PatchCollector.Delete("machine.sapcloud.io/v1alpha1", "Machine", "d8-cloud-instance-manager", victimMachine.Name, object_patch.InBackground())
+
+		case chaosModeDrain, chaosModeRestartKubelet, chaosModeRestartCRI, chaosModeNetworkPartition:
+			input.PatchCollector.MergePatch(newChaosFaultPatch(ng.ChaosMode, now, chaosDuration), "v1", "Node", "", victimNode.Name)
 
-		input.PatchCollector.Delete("machine.sapcloud.io/v1alpha1", "Machine", "d8-cloud-instance-manager", victimMachine.Name, object_patch.InBackground())
+		default:
+			continue
+		}
+
+		input.LogEntry.Infof("chaos monkey injected %s fault into Node:%s of NodeGroup:%s", ng.ChaosMode, victimNode.Name, ng.Name)
+		input.PatchCollector.Create(buildChaosEvent(victimNode.Name, "ChaosMonkeyFaultInjected",
+			fmt.Sprintf("Chaos monkey injected %s fault into the node of NodeGroup %s", ng.ChaosMode, ng.Name), now), object_patch.UpdateIfExists())
+		input.MetricsCollector.Inc("d8_chaos_monkey_injected_faults_total", map[string]string{
+			"node_group": ng.Name,
+			"node":       victimNode.Name,
+			"mode":       ng.ChaosMode,
+		})
 	}
 
 	return nil
 }
 
+// revertExpiredChaosFaults uncordons nodes drained by the chaos monkey when the fault duration is over.
+// Faults executed by bashible are removed if bashible hasn't picked them up during the fault duration,
+// e.g., the node is down, otherwise the stale annotation would block the chaos monkey for the NodeGroup.
+func revertExpiredChaosFaults(input *go_hook.HookInput, now time.Time) {
+	for _, sn := range input.Snapshots["nodes"] {
+		node := sn.(chaosNode)
+		if node.Fault == "" || !node.isFaultExpired(now) {
+			continue
+		}
+
+		if node.Fault == chaosModeDrain {
+			input.PatchCollector.MergePatch(revertChaosDrainPatch, "v1", "Node", "", node.Name)
+			input.PatchCollector.Create(buildChaosEvent(node.Name, "ChaosMonkeyFaultReverted",
+				fmt.Sprintf("Chaos monkey reverted %s fault after %s", node.Fault, node.FaultDuration), now), object_patch.UpdateIfExists())
+			continue
+		}
+
+		input.LogEntry.Warnf("chaos monkey %s fault was not executed on Node:%s during %s, removing it", node.Fault, node.Name, node.FaultDuration)
+		input.PatchCollector.MergePatch(expiredChaosFaultPatch, "v1", "Node", "", node.Name)
+		input.PatchCollector.Create(buildChaosEvent(node.Name, "ChaosMonkeyFaultExpired",
+			fmt.Sprintf("Chaos monkey removed %s fault which was not executed during %s", node.Fault, node.FaultDuration), now), object_patch.UpdateIfExists())
+	}
+}
+
+// chaosCandidates returns nodes which were not affected by the chaos monkey since the windowStart,
+// the amount of affected nodes and whether some node has an active fault right now.
+func chaosCandidates(nodes []chaosNode, windowStart, now time.Time) ([]chaosNode, int32, bool) {
+	var (
+		candidates     = make([]chaosNode, 0, len(nodes))
+		affected       int32
+		hasActiveFault bool
+	)
+
+	for _, node := range nodes {
+		if node.Fault != "" && !node.isFaultExpired(now) {
+			hasActiveFault = true
+		}
+
+		if node.FaultTime.After(windowStart) {
+			affected++
+			continue
+		}
+
+		candidates = append(candidates, node)
+	}
+
+	return candidates, affected, hasActiveFault
+}
+
+// chaosTimesSince returns times after the windowStart.
+func chaosTimesSince(times []time.Time, windowStart time.Time) []time.Time {
+	res := make([]time.Time, 0, len(times))
+	for _, t := range times {
+		if t.After(windowStart) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
 func prepareChaosData(input *go_hook.HookInput) ([]chaosNodeGroup, map[string]chaosMachine, map[string][]chaosNode, error) {
 	snap := input.Snapshots["machines"]
 	machines := make(map[string]chaosMachine, len(snap)) // map by node name
@@ -156,7 +288,7 @@ func prepareChaosData(input *go_hook.HookInput) ([]chaosNodeGroup, map[string]ch
 	for _, sn := range snap {
 		ng := sn.(chaosNodeGroup)
 		// if chaos mode is empty - it's disabled
-		if ng.ChaosMode == "" || !ng.IsReadyForChaos {
+		if ng.ChaosMode == "" || ng.ChaosMode == chaosModeDisabled || !ng.IsReadyForChaos {
 			continue
 		}
 		nodeGroups = append(nodeGroups, ng)
@@ -206,9 +338,25 @@ func chaosFilterNode(obj *unstructured.Unstructured) (go_hook.FilterResult, erro
 		return nil, err
 	}
 
+	var (
+		faultTime     time.Time
+		faultDuration time.Duration
+	)
+	if v, ok := node.Annotations[chaosFaultTimeAnnotation]; ok {
+		// broken timestamp is treated as a fault injected long time ago
+		faultTime, _ = time.Parse(time.RFC3339, v)
+	}
+	if v, ok := node.Annotations[chaosFaultDurationAnnotation]; ok {
+		seconds, _ := strconv.ParseInt(v, 10, 64)
+		faultDuration = time.Duration(seconds) * time.Second
+	}
+
 	return chaosNode{
-		Name:      node.Name,
-		NodeGroup: node.Labels["node.deckhouse.io/group"],
+		Name:          node.Name,
+		NodeGroup:     node.Labels["node.deckhouse.io/group"],
+		Fault:         node.Annotations[chaosFaultAnnotation],
+		FaultTime:     faultTime,
+		FaultDuration: faultDuration,
 	}, nil
 }
 
@@ -236,19 +384,107 @@ func chaosFilterNodeGroup(obj *unstructured.Unstructured) (go_hook.FilterResult,
 		period = "6h"
 	}
 
+	duration := ng.Spec.Chaos.Duration
+	if duration == "" {
+		duration = "5m"
+	}
+
+	maxNodesPerWindow := int32(1)
+	if ng.Spec.Chaos.MaxNodesPerWindow != nil {
+		maxNodesPerWindow = *ng.Spec.Chaos.MaxNodesPerWindow
+	}
+
+	var deletionTimes []time.Time
+	if v := ng.Annotations[chaosDeletionTimesAnnotation]; v != "" {
+		for _, s := range strings.Split(v, ",") {
+			// broken timestamps are skipped
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				deletionTimes = append(deletionTimes, t)
+			}
+		}
+	}
+
 	return chaosNodeGroup{
-		Name:            ng.Name,
-		ChaosMode:       ng.Spec.Chaos.Mode,
-		ChaosPeriod:     period,
-		IsReadyForChaos: isReadyForChaos,
+		Name:              ng.Name,
+		ChaosMode:         ng.Spec.Chaos.Mode,
+		ChaosPeriod:       period,
+		ChaosDuration:     duration,
+		ChaosWindows:      ng.Spec.Chaos.Windows,
+		MaxNodesPerWindow: maxNodesPerWindow,
+		DeletionTimes:     deletionTimes,
+		IsReadyForChaos:   isReadyForChaos,
 	}, nil
 }
 
+func newChaosFaultPatch(mode string, now time.Time, duration time.Duration) map[string]interface{} {
+	annotations := map[string]interface{}{
+		chaosFaultAnnotation:         mode,
+		chaosFaultTimeAnnotation:     now.UTC().Format(time.RFC3339),
+		chaosFaultDurationAnnotation: strconv.FormatInt(int64(duration.Seconds()), 10),
+	}
+
+	if mode == chaosModeDrain {
+		annotations[drainingAnnotationKey] = chaosDrainingSource
+	}
+
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	}
+}
+
+func newChaosDeletionTimesPatch(times []time.Time) map[string]interface{} {
+	values := make([]string, 0, len(times))
+	for _, t := range times {
+		values = append(values, t.UTC().Format(time.RFC3339))
+	}
+
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				chaosDeletionTimesAnnotation: strings.Join(values, ","),
+			},
+		},
+	}
+}
+
+func buildChaosEvent(nodeName, reason, note string, now time.Time) *eventsv1.Event {
+	return &eventsv1.Event{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Event",
+			APIVersion: "events.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			// Events for cluster scoped Nodes are stored in the 'default' namespace
+			Namespace: "default",
+			Name:      fmt.Sprintf("chaos-monkey-%s-%d", nodeName, now.Unix()),
+		},
+		Regarding: corev1.ObjectReference{
+			Kind:       "Node",
+			Name:       nodeName,
+			UID:        types.UID(nodeName),
+			APIVersion: "v1",
+		},
+		Reason:              reason,
+		Note:                note,
+		Type:                "Warning",
+		EventTime:           metav1.MicroTime{Time: now},
+		Action:              "ChaosMonkey",
+		ReportingInstance:   "deckhouse",
+		ReportingController: "deckhouse",
+	}
+}
+
 type chaosNodeGroup struct {
-	Name            string
-	ChaosMode       string
-	ChaosPeriod     string // default 6h
-	IsReadyForChaos bool
+	Name              string
+	ChaosMode         string
+	ChaosPeriod       string // default 6h
+	ChaosDuration     string // default 5m
+	ChaosWindows      update.Windows
+	MaxNodesPerWindow int32 // default 1
+	DeletionTimes     []time.Time
+	IsReadyForChaos   bool
 }
 
 type chaosMachine struct {
@@ -258,8 +494,15 @@ type chaosMachine struct {
 }
 
 type chaosNode struct {
-	Name      string
-	NodeGroup string
+	Name          string
+	NodeGroup     string
+	Fault         string
+	FaultTime     time.Time
+	FaultDuration time.Duration
+}
+
+func (n chaosNode) isFaultExpired(now time.Time) bool {
+	return !n.FaultTime.Add(n.FaultDuration).After(now)
 }
 
 var (
@@ -270,4 +513,27 @@ var (
 			},
 		},
 	}
+
+	expiredChaosFaultPatch = map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				chaosFaultAnnotation:         nil,
+				chaosFaultDurationAnnotation: nil,
+			},
+		},
+	}
+
+	revertChaosDrainPatch = map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				chaosFaultAnnotation:         nil,
+				chaosFaultDurationAnnotation: nil,
+				drainingAnnotationKey:        nil,
+				drainedAnnotationKey:         nil,
+			},
+		},
+		"spec": map[string]interface{}{
+			"unschedulable": nil,
+		},
+	}
 )
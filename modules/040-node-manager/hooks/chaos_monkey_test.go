@@ -18,6 +18,8 @@ package hooks
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -188,6 +190,85 @@ metadata:
     node.deckhouse.io/group: someng
     node.deckhouse.io/chaos-monkey-victim: ""
     node: victimnode
+`
+		stateNGDrain = `
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: largeng
+spec:
+  nodeType: Static
+  chaos:
+    mode: Drain
+    period: 5m
+    duration: 10m
+status:
+  nodes: 3
+  ready: 3
+`
+		stateNGDrainOutOfWindows = `
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: largeng
+spec:
+  nodeType: Static
+  chaos:
+    mode: Drain
+    period: 5m
+    windows:
+    - from: "00:00"
+      to: "00:00"
+status:
+  nodes: 3
+  ready: 3
+`
+		stateNodeDrainedByChaos = `
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node1
+  labels:
+    node.deckhouse.io/group: largeng
+  annotations:
+    node.deckhouse.io/chaos-monkey-fault: Drain
+    node.deckhouse.io/chaos-monkey-fault-time: "2021-01-01T10:00:00Z"
+    node.deckhouse.io/chaos-monkey-fault-duration: "300"
+    update.node.deckhouse.io/drained: chaos-monkey
+spec:
+  unschedulable: true
+`
+		stateNodeStaleFault = `
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node1
+  labels:
+    node.deckhouse.io/group: largeng
+  annotations:
+    node.deckhouse.io/chaos-monkey-fault: RestartKubelet
+    node.deckhouse.io/chaos-monkey-fault-time: "2021-01-01T10:00:00Z"
+    node.deckhouse.io/chaos-monkey-fault-duration: "300"
+`
+		stateNodesTail = `
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node2
+  labels:
+    node.deckhouse.io/group: largeng
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node3
+  labels:
+    node.deckhouse.io/group: largeng
 `
 	)
 
@@ -206,6 +287,118 @@ metadata:
 		})
 	})
 
+	Context("Static NodeGroup with Drain mode", func() {
+		BeforeEach(func() {
+			f.KubeStateSet(stateNGDrain + stateNodes)
+			f.BindingContexts.Set(f.GenerateScheduleContext("* * * * *"))
+			f.AddHookEnv("D8_TEST_RANDOM_SEED=11")
+			f.RunHook()
+		})
+
+		It("Hook must mark one node for draining", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.KubernetesGlobalResource("Node", "node1").Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).Exists()).To(BeFalse())
+			Expect(f.KubernetesGlobalResource("Node", "node2").Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).Exists()).To(BeFalse())
+
+			node := f.KubernetesGlobalResource("Node", "node3")
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).String()).To(Equal("Drain"))
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault-duration`).String()).To(Equal("600"))
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault-time`).Exists()).To(BeTrue())
+			Expect(node.Field(`metadata.annotations.update\.node\.deckhouse\.io/draining`).String()).To(Equal("chaos-monkey"))
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(1))
+			Expect(m[0].Name).To(Equal("d8_chaos_monkey_injected_faults_total"))
+			Expect(m[0].Labels).To(Equal(map[string]string{"node_group": "largeng", "node": "node3", "mode": "Drain"}))
+		})
+	})
+
+	Context("Static NodeGroup with Drain mode outside of chaos windows", func() {
+		BeforeEach(func() {
+			f.KubeStateSet(stateNGDrainOutOfWindows + stateNodes)
+			f.BindingContexts.Set(f.GenerateScheduleContext("* * * * *"))
+			f.AddHookEnv("D8_TEST_RANDOM_SEED=11")
+			f.RunHook()
+		})
+
+		It("All nodes must stay intact", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			for _, name := range []string{"node1", "node2", "node3"} {
+				Expect(f.KubernetesGlobalResource("Node", name).Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).Exists()).To(BeFalse())
+			}
+			Expect(f.MetricsCollector.CollectedMetrics()).To(HaveLen(0))
+		})
+	})
+
+	Context("Static NodeGroup with a node affected during the current period", func() {
+		BeforeEach(func() {
+			stateNodeAffected := fmt.Sprintf(`
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node1
+  labels:
+    node.deckhouse.io/group: largeng
+  annotations:
+    node.deckhouse.io/chaos-monkey-fault-time: %q
+`, time.Now().UTC().Format(time.RFC3339))
+			f.KubeStateSet(stateNGDrain + stateNodeAffected + stateNodesTail)
+			f.BindingContexts.Set(f.GenerateScheduleContext("* * * * *"))
+			f.AddHookEnv("D8_TEST_RANDOM_SEED=11")
+			f.RunHook()
+		})
+
+		It("Blast radius limit must be respected", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			for _, name := range []string{"node1", "node2", "node3"} {
+				Expect(f.KubernetesGlobalResource("Node", name).Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).Exists()).To(BeFalse())
+			}
+		})
+	})
+
+	Context("Node drained by chaos monkey and fault duration is over", func() {
+		BeforeEach(func() {
+			f.KubeStateSet(stateNGDrain + stateNodeDrainedByChaos + stateNodesTail)
+			f.BindingContexts.Set(f.GenerateScheduleContext("* * * * *"))
+			f.AddHookEnv("D8_TEST_RANDOM_SEED=0")
+			f.RunHook()
+		})
+
+		It("Node must be uncordoned", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			node := f.KubernetesGlobalResource("Node", "node1")
+			Expect(node.Field("spec.unschedulable").Exists()).To(BeFalse())
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).Exists()).To(BeFalse())
+			Expect(node.Field(`metadata.annotations.update\.node\.deckhouse\.io/drained`).Exists()).To(BeFalse())
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault-time`).String()).To(Equal("2021-01-01T10:00:00Z"))
+		})
+	})
+
+	Context("Node with a fault which bashible has not executed during the fault duration", func() {
+		BeforeEach(func() {
+			f.KubeStateSet(stateNGDrain + stateNodeStaleFault + stateNodesTail)
+			f.BindingContexts.Set(f.GenerateScheduleContext("* * * * *"))
+			f.AddHookEnv("D8_TEST_RANDOM_SEED=11")
+			f.RunHook()
+		})
+
+		It("Stale fault must be removed and must not block the chaos monkey", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			node := f.KubernetesGlobalResource("Node", "node1")
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).Exists()).To(BeFalse())
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault-duration`).Exists()).To(BeFalse())
+			Expect(node.Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault-time`).String()).To(Equal("2021-01-01T10:00:00Z"))
+
+			Expect(f.KubernetesGlobalResource("Node", "node3").Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-fault`).String()).To(Equal("Drain"))
+		})
+	})
+
 	for _, gIsNgCloud := range []bool{true, false} {
 		Context(fmt.Sprintf("Cloud: %t :: ", gIsNgCloud), func() {
 			isNgCloud := gIsNgCloud
@@ -238,6 +431,31 @@ metadata:
 					Expect(f.KubernetesResource("Machine", "d8-cloud-instance-manager", "node2").Exists()).To(BeTrue())
 					Expect(f.KubernetesResource("Machine", "d8-cloud-instance-manager", "node3").Exists()).To(BeFalse())
 					Expect(f.KubernetesResource("Machine", "d8-cloud-instance-manager", "smallnode1").Exists()).To(BeTrue())
+
+					deletionTimes := f.KubernetesGlobalResource("NodeGroup", "largeng").Field(`metadata.annotations.node\.deckhouse\.io/chaos-monkey-deletion-times`).String()
+					_, err := time.Parse(time.RFC3339, deletionTimes)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+			})
+
+			Context("Cluster with ngs ready for chaos and a node deleted during the current period", func() {
+				BeforeEach(func() {
+					stateNGLargeDeleted := strings.Replace(stateNGLarge, "  name: largeng\n", fmt.Sprintf(
+						"  name: largeng\n  annotations:\n    node.deckhouse.io/chaos-monkey-deletion-times: 2021-01-01T10:00:00Z,%s\n",
+						time.Now().UTC().Format(time.RFC3339)), 1)
+					f.KubeStateSet(stateNGSmall + stateNGLargeDeleted + stateNodes + stateMachines)
+					f.BindingContexts.Set(f.GenerateScheduleContext("* * * * *"))
+					f.AddHookEnv("D8_TEST_RANDOM_SEED=11")
+					f.RunHook()
+				})
+
+				It("Blast radius limit must be respected. All machines must survive.", func() {
+					Expect(f).To(ExecuteSuccessfully())
+
+					Expect(f.KubernetesResource("Machine", "d8-cloud-instance-manager", "node1").Exists()).To(BeTrue())
+					Expect(f.KubernetesResource("Machine", "d8-cloud-instance-manager", "node2").Exists()).To(BeTrue())
+					Expect(f.KubernetesResource("Machine", "d8-cloud-instance-manager", "node3").Exists()).To(BeTrue())
+					Expect(f.KubernetesResource("Machine", "d8-cloud-instance-manager", "smallnode1").Exists()).To(BeTrue())
 				})
 			})
 
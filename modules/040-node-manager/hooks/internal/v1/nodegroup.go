@@ -234,15 +234,24 @@ func (c ClassReference) IsEmpty() bool {
 
 // Chaos is a chaos-monkey settings.
 type Chaos struct {
-	// Chaos monkey mode: DrainAndDelete or Disabled (default).
+	// Chaos monkey mode: DrainAndDelete, Drain, RestartKubelet, RestartCRI, NetworkPartition or Disabled (default).
 	Mode string `json:"mode,omitempty"`
 
 	// Chaos monkey wake up period. Default is 6h.
 	Period string `json:"period,omitempty"`
+
+	// Maximum amount of nodes affected by the chaos monkey during one period. Default is 1.
+	MaxNodesPerWindow *int32 `json:"maxNodesPerWindow,omitempty"`
+
+	// How long the node stays drained or partitioned from the network. Default is 5m.
+	Duration string `json:"duration,omitempty"`
+
+	// Time windows when the chaos monkey is allowed to inject faults.
+	Windows update.Windows `json:"windows,omitempty"`
 }
 
 func (c Chaos) IsEmpty() bool {
-	return c.Mode == "" && c.Period == ""
+	return c.Mode == "" && c.Period == "" && c.MaxNodesPerWindow == nil && c.Duration == "" && len(c.Windows) == 0
 }
 
 type OperatingSystem struct {
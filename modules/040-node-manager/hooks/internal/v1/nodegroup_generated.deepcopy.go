@@ -81,6 +81,12 @@ func (in *CRI) DeepCopy() *CRI {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Chaos) DeepCopyInto(out *Chaos) {
 	*out = *in
+	if in.MaxNodesPerWindow != nil {
+		in, out := &in.MaxNodesPerWindow, &out.MaxNodesPerWindow
+		*out = new(int32)
+		**out = **in
+	}
+	out.Windows = in.Windows.DeepCopy()
 	return
 }
 
@@ -387,7 +393,7 @@ func (in *NodeGroupSpec) DeepCopyInto(out *NodeGroupSpec) {
 	in.CRI.DeepCopyInto(&out.CRI)
 	in.CloudInstances.DeepCopyInto(&out.CloudInstances)
 	in.NodeTemplate.DeepCopyInto(&out.NodeTemplate)
-	out.Chaos = in.Chaos
+	in.Chaos.DeepCopyInto(&out.Chaos)
 	in.OperatingSystem.DeepCopyInto(&out.OperatingSystem)
 	in.Disruptions.DeepCopyInto(&out.Disruptions)
 	in.Update.DeepCopyInto(&out.Update)
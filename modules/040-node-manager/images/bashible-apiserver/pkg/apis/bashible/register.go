@@ -51,6 +51,7 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&BashibleList{},
 		&NodeGroupBundle{},
 		&NodeGroupBundleList{},
+		&NodeGroupBundlePreview{},
 		&Bootstrap{},
 		&BootstrapList{},
 	)
@@ -100,3 +100,60 @@ type BootstrapList struct {
 	// Items is a List of Bootstraps
 	Items []Bootstrap
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeGroupBundlePreview renders the node group bundle with a proposed NodeGroupConfiguration applied
+// and compares it with the currently served bundle. It is served as the nodegroupbundles/preview subresource.
+type NodeGroupBundlePreview struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Spec   NodeGroupBundlePreviewSpec
+	Status NodeGroupBundlePreviewStatus
+}
+
+// NodeGroupBundlePreviewSpec contains the proposed changes
+type NodeGroupBundlePreviewSpec struct {
+	// NodeGroupConfiguration is the proposed NodeGroupConfiguration, it replaces the existing one with the same name
+	NodeGroupConfiguration PreviewNodeGroupConfiguration
+}
+
+// PreviewNodeGroupConfiguration is the NodeGroupConfiguration to preview
+type PreviewNodeGroupConfiguration struct {
+	Name       string
+	Content    string
+	Weight     int
+	NodeGroups []string
+	Bundles    []string
+
+	// Delete previews the removal of the NodeGroupConfiguration
+	Delete bool
+}
+
+// NodeGroupBundlePreviewStatus contains the result of the preview
+type NodeGroupBundlePreviewStatus struct {
+	// Steps contains changed steps only
+	Steps []StepDiff
+
+	// Errors contains template rendering errors
+	Errors []string
+}
+
+// StepChange describes how the step is changed
+type StepChange string
+
+const (
+	StepAdded    = StepChange("Added")
+	StepRemoved  = StepChange("Removed")
+	StepModified = StepChange("Modified")
+)
+
+// StepDiff is a difference of a single step between the served and the proposed bundles
+type StepDiff struct {
+	Name   string
+	Change StepChange
+
+	// Diff is the unified diff of the step content
+	Diff string
+}
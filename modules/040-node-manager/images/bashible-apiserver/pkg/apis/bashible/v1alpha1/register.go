@@ -52,6 +52,7 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&BashibleList{},
 		&NodeGroupBundle{},
 		&NodeGroupBundleList{},
+		&NodeGroupBundlePreview{},
 		&Bootstrap{},
 		&BootstrapList{},
 	)
@@ -99,3 +99,60 @@ type BootstrapList struct {
 	// Items is a List of Bootstraps
 	Items []Bootstrap `json:"items" protobuf:"bytes,2,rep,name=items"`
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeGroupBundlePreview renders the node group bundle with a proposed NodeGroupConfiguration applied
+// and compares it with the currently served bundle. It is served as the nodegroupbundles/preview subresource.
+type NodeGroupBundlePreview struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	Spec   NodeGroupBundlePreviewSpec   `json:"spec" protobuf:"bytes,2,opt,name=spec"`
+	Status NodeGroupBundlePreviewStatus `json:"status,omitempty" protobuf:"bytes,3,opt,name=status"`
+}
+
+// NodeGroupBundlePreviewSpec contains the proposed changes
+type NodeGroupBundlePreviewSpec struct {
+	// NodeGroupConfiguration is the proposed NodeGroupConfiguration, it replaces the existing one with the same name
+	NodeGroupConfiguration PreviewNodeGroupConfiguration `json:"nodeGroupConfiguration" protobuf:"bytes,1,opt,name=nodeGroupConfiguration"`
+}
+
+// PreviewNodeGroupConfiguration is the NodeGroupConfiguration to preview
+type PreviewNodeGroupConfiguration struct {
+	Name       string   `json:"name" protobuf:"bytes,1,opt,name=name"`
+	Content    string   `json:"content,omitempty" protobuf:"bytes,2,opt,name=content"`
+	Weight     int      `json:"weight,omitempty" protobuf:"varint,3,opt,name=weight"`
+	NodeGroups []string `json:"nodeGroups,omitempty" protobuf:"bytes,4,rep,name=nodeGroups"`
+	Bundles    []string `json:"bundles,omitempty" protobuf:"bytes,5,rep,name=bundles"`
+
+	// Delete previews the removal of the NodeGroupConfiguration
+	Delete bool `json:"delete,omitempty" protobuf:"varint,6,opt,name=delete"`
+}
+
+// NodeGroupBundlePreviewStatus contains the result of the preview
+type NodeGroupBundlePreviewStatus struct {
+	// Steps contains changed steps only
+	Steps []StepDiff `json:"steps,omitempty" protobuf:"bytes,1,rep,name=steps"`
+
+	// Errors contains template rendering errors
+	Errors []string `json:"errors,omitempty" protobuf:"bytes,2,rep,name=errors"`
+}
+
+// StepChange describes how the step is changed
+type StepChange string
+
+const (
+	StepAdded    = StepChange("Added")
+	StepRemoved  = StepChange("Removed")
+	StepModified = StepChange("Modified")
+)
+
+// StepDiff is a difference of a single step between the served and the proposed bundles
+type StepDiff struct {
+	Name   string     `json:"name" protobuf:"bytes,1,opt,name=name"`
+	Change StepChange `json:"change" protobuf:"bytes,2,opt,name=change,casttype=StepChange"`
+
+	// Diff is the unified diff of the step content
+	Diff string `json:"diff,omitempty" protobuf:"bytes,3,opt,name=diff"`
+}
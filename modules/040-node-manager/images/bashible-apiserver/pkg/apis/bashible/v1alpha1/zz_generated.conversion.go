@@ -96,6 +96,56 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*NodeGroupBundlePreview)(nil), (*bashible.NodeGroupBundlePreview)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_NodeGroupBundlePreview_To_bashible_NodeGroupBundlePreview(a.(*NodeGroupBundlePreview), b.(*bashible.NodeGroupBundlePreview), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*bashible.NodeGroupBundlePreview)(nil), (*NodeGroupBundlePreview)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_bashible_NodeGroupBundlePreview_To_v1alpha1_NodeGroupBundlePreview(a.(*bashible.NodeGroupBundlePreview), b.(*NodeGroupBundlePreview), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*NodeGroupBundlePreviewSpec)(nil), (*bashible.NodeGroupBundlePreviewSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_NodeGroupBundlePreviewSpec_To_bashible_NodeGroupBundlePreviewSpec(a.(*NodeGroupBundlePreviewSpec), b.(*bashible.NodeGroupBundlePreviewSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*bashible.NodeGroupBundlePreviewSpec)(nil), (*NodeGroupBundlePreviewSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_bashible_NodeGroupBundlePreviewSpec_To_v1alpha1_NodeGroupBundlePreviewSpec(a.(*bashible.NodeGroupBundlePreviewSpec), b.(*NodeGroupBundlePreviewSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*NodeGroupBundlePreviewStatus)(nil), (*bashible.NodeGroupBundlePreviewStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_NodeGroupBundlePreviewStatus_To_bashible_NodeGroupBundlePreviewStatus(a.(*NodeGroupBundlePreviewStatus), b.(*bashible.NodeGroupBundlePreviewStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*bashible.NodeGroupBundlePreviewStatus)(nil), (*NodeGroupBundlePreviewStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_bashible_NodeGroupBundlePreviewStatus_To_v1alpha1_NodeGroupBundlePreviewStatus(a.(*bashible.NodeGroupBundlePreviewStatus), b.(*NodeGroupBundlePreviewStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*PreviewNodeGroupConfiguration)(nil), (*bashible.PreviewNodeGroupConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_PreviewNodeGroupConfiguration_To_bashible_PreviewNodeGroupConfiguration(a.(*PreviewNodeGroupConfiguration), b.(*bashible.PreviewNodeGroupConfiguration), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*bashible.PreviewNodeGroupConfiguration)(nil), (*PreviewNodeGroupConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_bashible_PreviewNodeGroupConfiguration_To_v1alpha1_PreviewNodeGroupConfiguration(a.(*bashible.PreviewNodeGroupConfiguration), b.(*PreviewNodeGroupConfiguration), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*StepDiff)(nil), (*bashible.StepDiff)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_StepDiff_To_bashible_StepDiff(a.(*StepDiff), b.(*bashible.StepDiff), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*bashible.StepDiff)(nil), (*StepDiff)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_bashible_StepDiff_To_v1alpha1_StepDiff(a.(*bashible.StepDiff), b.(*StepDiff), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -230,3 +280,135 @@ func autoConvert_bashible_NodeGroupBundleList_To_v1alpha1_NodeGroupBundleList(in
 func Convert_bashible_NodeGroupBundleList_To_v1alpha1_NodeGroupBundleList(in *bashible.NodeGroupBundleList, out *NodeGroupBundleList, s conversion.Scope) error {
 	return autoConvert_bashible_NodeGroupBundleList_To_v1alpha1_NodeGroupBundleList(in, out, s)
 }
+
+func autoConvert_v1alpha1_NodeGroupBundlePreview_To_bashible_NodeGroupBundlePreview(in *NodeGroupBundlePreview, out *bashible.NodeGroupBundlePreview, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if err := Convert_v1alpha1_NodeGroupBundlePreviewSpec_To_bashible_NodeGroupBundlePreviewSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
+	if err := Convert_v1alpha1_NodeGroupBundlePreviewStatus_To_bashible_NodeGroupBundlePreviewStatus(&in.Status, &out.Status, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1alpha1_NodeGroupBundlePreview_To_bashible_NodeGroupBundlePreview is an autogenerated conversion function.
+func Convert_v1alpha1_NodeGroupBundlePreview_To_bashible_NodeGroupBundlePreview(in *NodeGroupBundlePreview, out *bashible.NodeGroupBundlePreview, s conversion.Scope) error {
+	return autoConvert_v1alpha1_NodeGroupBundlePreview_To_bashible_NodeGroupBundlePreview(in, out, s)
+}
+
+func autoConvert_bashible_NodeGroupBundlePreview_To_v1alpha1_NodeGroupBundlePreview(in *bashible.NodeGroupBundlePreview, out *NodeGroupBundlePreview, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if err := Convert_bashible_NodeGroupBundlePreviewSpec_To_v1alpha1_NodeGroupBundlePreviewSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
+	if err := Convert_bashible_NodeGroupBundlePreviewStatus_To_v1alpha1_NodeGroupBundlePreviewStatus(&in.Status, &out.Status, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_bashible_NodeGroupBundlePreview_To_v1alpha1_NodeGroupBundlePreview is an autogenerated conversion function.
+func Convert_bashible_NodeGroupBundlePreview_To_v1alpha1_NodeGroupBundlePreview(in *bashible.NodeGroupBundlePreview, out *NodeGroupBundlePreview, s conversion.Scope) error {
+	return autoConvert_bashible_NodeGroupBundlePreview_To_v1alpha1_NodeGroupBundlePreview(in, out, s)
+}
+
+func autoConvert_v1alpha1_NodeGroupBundlePreviewSpec_To_bashible_NodeGroupBundlePreviewSpec(in *NodeGroupBundlePreviewSpec, out *bashible.NodeGroupBundlePreviewSpec, s conversion.Scope) error {
+	if err := Convert_v1alpha1_PreviewNodeGroupConfiguration_To_bashible_PreviewNodeGroupConfiguration(&in.NodeGroupConfiguration, &out.NodeGroupConfiguration, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1alpha1_NodeGroupBundlePreviewSpec_To_bashible_NodeGroupBundlePreviewSpec is an autogenerated conversion function.
+func Convert_v1alpha1_NodeGroupBundlePreviewSpec_To_bashible_NodeGroupBundlePreviewSpec(in *NodeGroupBundlePreviewSpec, out *bashible.NodeGroupBundlePreviewSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_NodeGroupBundlePreviewSpec_To_bashible_NodeGroupBundlePreviewSpec(in, out, s)
+}
+
+func autoConvert_bashible_NodeGroupBundlePreviewSpec_To_v1alpha1_NodeGroupBundlePreviewSpec(in *bashible.NodeGroupBundlePreviewSpec, out *NodeGroupBundlePreviewSpec, s conversion.Scope) error {
+	if err := Convert_bashible_PreviewNodeGroupConfiguration_To_v1alpha1_PreviewNodeGroupConfiguration(&in.NodeGroupConfiguration, &out.NodeGroupConfiguration, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_bashible_NodeGroupBundlePreviewSpec_To_v1alpha1_NodeGroupBundlePreviewSpec is an autogenerated conversion function.
+func Convert_bashible_NodeGroupBundlePreviewSpec_To_v1alpha1_NodeGroupBundlePreviewSpec(in *bashible.NodeGroupBundlePreviewSpec, out *NodeGroupBundlePreviewSpec, s conversion.Scope) error {
+	return autoConvert_bashible_NodeGroupBundlePreviewSpec_To_v1alpha1_NodeGroupBundlePreviewSpec(in, out, s)
+}
+
+func autoConvert_v1alpha1_NodeGroupBundlePreviewStatus_To_bashible_NodeGroupBundlePreviewStatus(in *NodeGroupBundlePreviewStatus, out *bashible.NodeGroupBundlePreviewStatus, s conversion.Scope) error {
+	out.Steps = *(*[]bashible.StepDiff)(unsafe.Pointer(&in.Steps))
+	out.Errors = *(*[]string)(unsafe.Pointer(&in.Errors))
+	return nil
+}
+
+// Convert_v1alpha1_NodeGroupBundlePreviewStatus_To_bashible_NodeGroupBundlePreviewStatus is an autogenerated conversion function.
+func Convert_v1alpha1_NodeGroupBundlePreviewStatus_To_bashible_NodeGroupBundlePreviewStatus(in *NodeGroupBundlePreviewStatus, out *bashible.NodeGroupBundlePreviewStatus, s conversion.Scope) error {
+	return autoConvert_v1alpha1_NodeGroupBundlePreviewStatus_To_bashible_NodeGroupBundlePreviewStatus(in, out, s)
+}
+
+func autoConvert_bashible_NodeGroupBundlePreviewStatus_To_v1alpha1_NodeGroupBundlePreviewStatus(in *bashible.NodeGroupBundlePreviewStatus, out *NodeGroupBundlePreviewStatus, s conversion.Scope) error {
+	out.Steps = *(*[]StepDiff)(unsafe.Pointer(&in.Steps))
+	out.Errors = *(*[]string)(unsafe.Pointer(&in.Errors))
+	return nil
+}
+
+// Convert_bashible_NodeGroupBundlePreviewStatus_To_v1alpha1_NodeGroupBundlePreviewStatus is an autogenerated conversion function.
+func Convert_bashible_NodeGroupBundlePreviewStatus_To_v1alpha1_NodeGroupBundlePreviewStatus(in *bashible.NodeGroupBundlePreviewStatus, out *NodeGroupBundlePreviewStatus, s conversion.Scope) error {
+	return autoConvert_bashible_NodeGroupBundlePreviewStatus_To_v1alpha1_NodeGroupBundlePreviewStatus(in, out, s)
+}
+
+func autoConvert_v1alpha1_PreviewNodeGroupConfiguration_To_bashible_PreviewNodeGroupConfiguration(in *PreviewNodeGroupConfiguration, out *bashible.PreviewNodeGroupConfiguration, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Content = in.Content
+	out.Weight = in.Weight
+	out.NodeGroups = *(*[]string)(unsafe.Pointer(&in.NodeGroups))
+	out.Bundles = *(*[]string)(unsafe.Pointer(&in.Bundles))
+	out.Delete = in.Delete
+	return nil
+}
+
+// Convert_v1alpha1_PreviewNodeGroupConfiguration_To_bashible_PreviewNodeGroupConfiguration is an autogenerated conversion function.
+func Convert_v1alpha1_PreviewNodeGroupConfiguration_To_bashible_PreviewNodeGroupConfiguration(in *PreviewNodeGroupConfiguration, out *bashible.PreviewNodeGroupConfiguration, s conversion.Scope) error {
+	return autoConvert_v1alpha1_PreviewNodeGroupConfiguration_To_bashible_PreviewNodeGroupConfiguration(in, out, s)
+}
+
+func autoConvert_bashible_PreviewNodeGroupConfiguration_To_v1alpha1_PreviewNodeGroupConfiguration(in *bashible.PreviewNodeGroupConfiguration, out *PreviewNodeGroupConfiguration, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Content = in.Content
+	out.Weight = in.Weight
+	out.NodeGroups = *(*[]string)(unsafe.Pointer(&in.NodeGroups))
+	out.Bundles = *(*[]string)(unsafe.Pointer(&in.Bundles))
+	out.Delete = in.Delete
+	return nil
+}
+
+// Convert_bashible_PreviewNodeGroupConfiguration_To_v1alpha1_PreviewNodeGroupConfiguration is an autogenerated conversion function.
+func Convert_bashible_PreviewNodeGroupConfiguration_To_v1alpha1_PreviewNodeGroupConfiguration(in *bashible.PreviewNodeGroupConfiguration, out *PreviewNodeGroupConfiguration, s conversion.Scope) error {
+	return autoConvert_bashible_PreviewNodeGroupConfiguration_To_v1alpha1_PreviewNodeGroupConfiguration(in, out, s)
+}
+
+func autoConvert_v1alpha1_StepDiff_To_bashible_StepDiff(in *StepDiff, out *bashible.StepDiff, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Change = bashible.StepChange(in.Change)
+	out.Diff = in.Diff
+	return nil
+}
+
+// Convert_v1alpha1_StepDiff_To_bashible_StepDiff is an autogenerated conversion function.
+func Convert_v1alpha1_StepDiff_To_bashible_StepDiff(in *StepDiff, out *bashible.StepDiff, s conversion.Scope) error {
+	return autoConvert_v1alpha1_StepDiff_To_bashible_StepDiff(in, out, s)
+}
+
+func autoConvert_bashible_StepDiff_To_v1alpha1_StepDiff(in *bashible.StepDiff, out *StepDiff, s conversion.Scope) error {
+	out.Name = in.Name
+	out.Change = StepChange(in.Change)
+	out.Diff = in.Diff
+	return nil
+}
+
+// Convert_bashible_StepDiff_To_v1alpha1_StepDiff is an autogenerated conversion function.
+func Convert_bashible_StepDiff_To_v1alpha1_StepDiff(in *bashible.StepDiff, out *StepDiff, s conversion.Scope) error {
+	return autoConvert_bashible_StepDiff_To_v1alpha1_StepDiff(in, out, s)
+}
@@ -215,3 +215,116 @@ func (in *NodeGroupBundleList) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroupBundlePreview) DeepCopyInto(out *NodeGroupBundlePreview) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupBundlePreview.
+func (in *NodeGroupBundlePreview) DeepCopy() *NodeGroupBundlePreview {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroupBundlePreview)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeGroupBundlePreview) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroupBundlePreviewSpec) DeepCopyInto(out *NodeGroupBundlePreviewSpec) {
+	*out = *in
+	in.NodeGroupConfiguration.DeepCopyInto(&out.NodeGroupConfiguration)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupBundlePreviewSpec.
+func (in *NodeGroupBundlePreviewSpec) DeepCopy() *NodeGroupBundlePreviewSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroupBundlePreviewSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGroupBundlePreviewStatus) DeepCopyInto(out *NodeGroupBundlePreviewStatus) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]StepDiff, len(*in))
+		copy(*out, *in)
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGroupBundlePreviewStatus.
+func (in *NodeGroupBundlePreviewStatus) DeepCopy() *NodeGroupBundlePreviewStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGroupBundlePreviewStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreviewNodeGroupConfiguration) DeepCopyInto(out *PreviewNodeGroupConfiguration) {
+	*out = *in
+	if in.NodeGroups != nil {
+		in, out := &in.NodeGroups, &out.NodeGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Bundles != nil {
+		in, out := &in.Bundles, &out.Bundles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewNodeGroupConfiguration.
+func (in *PreviewNodeGroupConfiguration) DeepCopy() *PreviewNodeGroupConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(PreviewNodeGroupConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepDiff) DeepCopyInto(out *StepDiff) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepDiff.
+func (in *StepDiff) DeepCopy() *StepDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(StepDiff)
+	in.DeepCopyInto(out)
+	return out
+}
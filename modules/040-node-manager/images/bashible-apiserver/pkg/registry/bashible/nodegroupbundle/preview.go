@@ -0,0 +1,125 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupbundle
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"bashible-apiserver/pkg/apis/bashible"
+	"bashible-apiserver/pkg/template"
+)
+
+// Preview renders the bundle by name with the proposed NodeGroupConfiguration applied and returns the diff
+// against the currently served bundle. Nothing is cached or changed, so nodes are not affected.
+func (s StorageWithK8sBundles) Preview(name string, preview *bashible.NodeGroupBundlePreview) (runtime.Object, error) {
+	_, ng, err := template.ParseName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.Render(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render current bundle: %v", err)
+	}
+	currentData := current.(*bashible.NodeGroupBundle).Data
+
+	proposedConfiguration := preview.Spec.NodeGroupConfiguration
+	if proposedConfiguration.Name == "" {
+		return nil, fmt.Errorf("spec.nodeGroupConfiguration.name is required")
+	}
+
+	ngc := &template.NodeGroupConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: proposedConfiguration.Name},
+		Spec: template.NodeGroupConfigurationSpec{
+			Content:    proposedConfiguration.Content,
+			Weight:     proposedConfiguration.Weight,
+			NodeGroups: proposedConfiguration.NodeGroups,
+			Bundles:    proposedConfiguration.Bundles,
+		},
+	}
+
+	result := &bashible.NodeGroupBundlePreview{}
+	result.ObjectMeta.Name = name
+	result.ObjectMeta.CreationTimestamp = metav1.NewTime(time.Now())
+	result.Spec = preview.Spec
+
+	proposedData, errs := s.ngRenderer.RenderPreview(name, ng, ngc, proposedConfiguration.Delete)
+	for _, err := range errs {
+		result.Status.Errors = append(result.Status.Errors, err.Error())
+	}
+
+	if proposedData == nil {
+		return result, nil
+	}
+
+	k8sBundleName, err := s.getK8sBundleName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	k8sBundleData, err := s.k8sRenderer.Render(k8sBundleName)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range k8sBundleData {
+		if _, keyPresent := proposedData[k]; keyPresent {
+			result.Status.Errors = append(result.Status.Errors, fmt.Sprintf("%s already present in node-group bundle", k))
+			continue
+		}
+		proposedData[k] = v
+	}
+
+	result.Status.Steps = diffSteps(currentData, proposedData)
+
+	return result, nil
+}
+
+func diffSteps(current, proposed map[string]string) []bashible.StepDiff {
+	steps := make([]bashible.StepDiff, 0)
+
+	for name, content := range proposed {
+		currentContent, ok := current[name]
+		switch {
+		case !ok:
+			steps = append(steps, bashible.StepDiff{Name: name, Change: bashible.StepAdded, Diff: unifiedDiff("", content)})
+		case currentContent != content:
+			steps = append(steps, bashible.StepDiff{Name: name, Change: bashible.StepModified, Diff: unifiedDiff(currentContent, content)})
+		}
+	}
+
+	for name, content := range current {
+		if _, ok := proposed[name]; !ok {
+			steps = append(steps, bashible.StepDiff{Name: name, Change: bashible.StepRemoved, Diff: unifiedDiff(content, "")})
+		}
+	}
+
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].Name < steps[j].Name
+	})
+
+	return steps
+}
+
+func (s StorageWithK8sBundles) NewPreview() runtime.Object {
+	return &bashible.NodeGroupBundlePreview{}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupbundle
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bashible-apiserver/pkg/apis/bashible"
+)
+
+var _ = Describe("Module :: node-manager :: bashible-apiserver :: ng bundles preview", func() {
+	Context("unifiedDiff", func() {
+		It("returns empty diff for equal texts", func() {
+			Expect(unifiedDiff("a\nb\n", "a\nb\n")).To(BeEmpty())
+		})
+
+		It("returns diff for added text", func() {
+			Expect(unifiedDiff("", "a\nb\n")).To(Equal("@@ -0,0 +1,2 @@\n+a\n+b\n"))
+		})
+
+		It("returns diff with context for modified line", func() {
+			a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+			b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"
+
+			Expect(unifiedDiff(a, b)).To(Equal("@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"))
+		})
+
+		It("splits distant changes into separate hunks", func() {
+			a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+			b := "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n"
+
+			Expect(unifiedDiff(a, b)).To(Equal("@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n"))
+		})
+	})
+
+	Context("diffLines", func() {
+		It("returns the shortest edit script", func() {
+			r := rand.New(rand.NewSource(1))
+			randomLines := func() []string {
+				lines := make([]string, r.Intn(40))
+				for i := range lines {
+					lines[i] = strconv.Itoa(r.Intn(5))
+				}
+				return lines
+			}
+
+			for i := 0; i < 500; i++ {
+				a, b := randomLines(), randomLines()
+
+				gotA, gotB := []string{}, []string{}
+				common := 0
+				for _, op := range diffLines(a, b) {
+					if op.kind != '+' {
+						gotA = append(gotA, op.text)
+					}
+					if op.kind != '-' {
+						gotB = append(gotB, op.text)
+					}
+					if op.kind == ' ' {
+						common++
+					}
+				}
+
+				Expect(gotA).To(Equal(a))
+				Expect(gotB).To(Equal(b))
+				Expect(common).To(Equal(lcsLength(a, b)), "a: %v, b: %v", a, b)
+			}
+		})
+
+		It("diffs large texts", func() {
+			a := make([]string, 100000)
+			for i := range a {
+				a[i] = strconv.Itoa(i)
+			}
+			b := append([]string{"first"}, a[:50000]...)
+			b = append(b, a[50001:]...)
+
+			Expect(unifiedDiff(strings.Join(a, "\n"), strings.Join(b, "\n"))).To(Equal(
+				"@@ -1,3 +1,4 @@\n+first\n 0\n 1\n 2\n@@ -49998,7 +49999,6 @@\n 49997\n 49998\n 49999\n-50000\n 50001\n 50002\n 50003\n"))
+		})
+	})
+
+	Context("diffSteps", func() {
+		It("returns changed steps only", func() {
+			current := map[string]string{
+				"001_unchanged.sh": "echo unchanged\n",
+				"002_modified.sh":  "echo old\n",
+				"003_removed.sh":   "echo removed\n",
+			}
+			proposed := map[string]string{
+				"001_unchanged.sh": "echo unchanged\n",
+				"002_modified.sh":  "echo new\n",
+				"004_added.sh":     "echo added\n",
+			}
+
+			steps := diffSteps(current, proposed)
+
+			Expect(steps).To(Equal([]bashible.StepDiff{
+				{Name: "002_modified.sh", Change: bashible.StepModified, Diff: "@@ -1,1 +1,1 @@\n-echo old\n+echo new\n"},
+				{Name: "003_removed.sh", Change: bashible.StepRemoved, Diff: "@@ -1,1 +0,0 @@\n-echo removed\n"},
+				{Name: "004_added.sh", Change: bashible.StepAdded, Diff: "@@ -0,0 +1,1 @@\n+echo added\n"},
+			}))
+		})
+	})
+})
+
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -74,3 +74,7 @@ type testTemplateContext struct {
 func (c *testTemplateContext) Get(_ string) (map[string]interface{}, error) {
 	return c.returnedVal, c.errVal
 }
+
+func (c *testTemplateContext) GetBootstrapContext(_ string) (map[string]interface{}, error) {
+	return c.returnedVal, c.errVal
+}
@@ -34,6 +34,7 @@ func GetStorage(rootDir string, bashibleContext *template.BashibleContext, steps
 
 	ngStorage, err := nodegroupbundle.NewStorage(rootDir, stepsStorage, bashibleContext)
 	v1alpha1storage["nodegroupbundles"] = RESTInPeace(ngStorage, err, manager.GetCache())
+	v1alpha1storage["nodegroupbundles/preview"] = PreviewRESTInPeace(ngStorage, err)
 
 	bootstrapStorage, err := bootstrap.NewStorage(rootDir, bashibleContext)
 	v1alpha1storage["bootstrap"] = RESTBootstrapInPeace(bootstrapStorage, err, manager.GetCache())
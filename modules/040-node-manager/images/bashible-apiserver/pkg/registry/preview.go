@@ -0,0 +1,76 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"bashible-apiserver/pkg/apis/bashible"
+)
+
+type PreviewStorage interface {
+	// Preview renders object by name with the proposed changes and compares it with the served one
+	Preview(name string, preview *bashible.NodeGroupBundlePreview) (runtime.Object, error)
+
+	NewPreview() runtime.Object
+}
+
+// PreviewREST implements the preview subresource. It accepts the proposed changes with POST
+// and returns the rendering result without storing anything.
+type PreviewREST struct {
+	storage PreviewStorage
+}
+
+var _ rest.NamedCreater = &PreviewREST{}
+
+func PreviewRESTInPeace(storage PreviewStorage, err error) *PreviewREST {
+	if err != nil {
+		err = fmt.Errorf("unable to create preview REST storage for a resource due to %v, will die", err)
+		panic(err)
+	}
+	return &PreviewREST{storage: storage}
+}
+
+func (r *PreviewREST) Create(_ context.Context, name string, obj runtime.Object, _ rest.ValidateObjectFunc, _ *metav1.CreateOptions) (runtime.Object, error) {
+	preview, ok := obj.(*bashible.NodeGroupBundlePreview)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("not a NodeGroupBundlePreview: %T", obj))
+	}
+
+	result, err := r.storage.Preview(name, preview)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	return result, nil
+}
+
+func (r *PreviewREST) New() runtime.Object {
+	return r.storage.NewPreview()
+}
+
+func (r *PreviewREST) Destroy() {}
+
+func (r *PreviewREST) NamespaceScoped() bool {
+	return false
+}
@@ -18,6 +18,7 @@ package template
 
 import (
 	"fmt"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -44,6 +45,19 @@ func (ng NodeGroupConfiguration) GenerateScriptName() string {
 	return fmt.Sprintf("%03d_%s", ng.Spec.Weight, ng.Name)
 }
 
+// matches checks if the NodeGroupConfiguration is applied to the bundle of the node group.
+func (ng NodeGroupConfiguration) matches(bundle, nodeGroup string) bool {
+	for _, pair := range generateNgBundlePairs(ng.Spec.NodeGroups, ng.Spec.Bundles) {
+		pairBundle, pairNodeGroup, _ := strings.Cut(pair, ":")
+
+		if (pairBundle == "*" || pairBundle == bundle) && (pairNodeGroup == "*" || pairNodeGroup == nodeGroup) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type NodeGroupConfigurationSpec struct {
 	Content    string   `json:"content"`
 	Weight     int      `json:"weight"`
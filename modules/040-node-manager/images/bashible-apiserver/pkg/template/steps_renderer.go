@@ -60,6 +60,24 @@ func (s StepsRenderer) Render(name string, ng ...string) (map[string]string, err
 	return s.stepsStorage.Render(s.target, bundle, providerType, templateContext, ng...)
 }
 
+// RenderPreview renders single script content by name with the proposed NodeGroupConfiguration applied, see StepsStorage.RenderPreview
+func (s StepsRenderer) RenderPreview(name, ng string, proposed *NodeGroupConfiguration, remove bool) (map[string]string, []error) {
+	templateContext, err := s.getContext(name)
+	if err != nil {
+		return nil, []error{err}
+	}
+	providerType, err := s.getProviderType(templateContext)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	bundle, ok := templateContext["bundle"].(string)
+	if !ok {
+		return nil, []error{errors.New("expected string in templateContext[\"bundle\"]")}
+	}
+	return s.stepsStorage.RenderPreview(s.target, bundle, providerType, templateContext, ng, proposed, remove)
+}
+
 func (s StepsRenderer) getContext(name string) (map[string]interface{}, error) {
 	fullContext := make(map[string]interface{})
 	contextKey, err := s.contextName(name)
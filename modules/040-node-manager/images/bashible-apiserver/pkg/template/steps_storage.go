@@ -58,6 +58,9 @@ type nodeConfigurationQueueAction struct {
 type nodeConfigurationScript struct {
 	Name    string
 	Content string
+
+	// ConfigurationName is the name of the NodeGroupConfiguration object
+	ConfigurationName string
 }
 
 // NewStepsStorage creates StepsStorage for target and cloud provider.
@@ -258,8 +261,9 @@ func (s *StepsStorage) AddNodeGroupConfiguration(nc *NodeGroupConfiguration) {
 	ngBundlePairs := generateNgBundlePairs(nc.Spec.NodeGroups, nc.Spec.Bundles)
 
 	sc := nodeConfigurationScript{
-		Name:    name,
-		Content: nc.Spec.Content,
+		Name:              name,
+		Content:           nc.Spec.Content,
+		ConfigurationName: nc.Name,
 	}
 
 	s.m.Lock()
@@ -294,7 +298,51 @@ func (s *StepsStorage) RemoveNodeGroupConfiguration(nc *NodeGroupConfiguration)
 	}
 }
 
-func (s *StepsStorage) renderNodeGroupConfigurations(bundle, ng string, templateContext map[string]interface{}) (map[string]string, error) {
+// RenderPreview renders steps the same way as Render does, but with the proposed NodeGroupConfiguration applied
+// instead of the existing one with the same name, or with the existing one removed if remove is true.
+// NodeGroupConfigurations rendering errors do not stop the rendering and are returned as a list.
+func (s *StepsStorage) RenderPreview(target, bundle, provider string, templateContext map[string]interface{}, ng string, proposed *NodeGroupConfiguration, remove bool) (map[string]string, []error) {
+	steps, err := s.renderSystemScripts(target, bundle, provider, templateContext)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	configurations := make([]*nodeConfigurationScript, 0)
+	for _, sc := range s.nodeGroupConfigurationsFor(bundle, ng) {
+		if sc.ConfigurationName == proposed.Name {
+			continue
+		}
+		configurations = append(configurations, sc)
+	}
+
+	if !remove && proposed.matches(bundle, ng) {
+		configurations = append(configurations, &nodeConfigurationScript{
+			Name:              proposed.GenerateScriptName(),
+			Content:           proposed.Spec.Content,
+			ConfigurationName: proposed.Name,
+		})
+	}
+
+	var errs []error
+
+	for _, sc := range configurations {
+		step, err := RenderTemplate(sc.Name, []byte(sc.Content), templateContext)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot render node configuration %q for bundle %q: %v", sc.Name, bundle, err))
+			continue
+		}
+
+		if _, ok := steps[step.FileName]; ok {
+			errs = append(errs, fmt.Errorf("NodeGroupConfigurations conflicts with system script: %s", step.FileName))
+			continue
+		}
+		steps[step.FileName] = step.Content.String()
+	}
+
+	return steps, errs
+}
+
+func (s *StepsStorage) nodeGroupConfigurationsFor(bundle, ng string) []*nodeConfigurationScript {
 	configurations := make([]*nodeConfigurationScript, 0)
 
 	key := fmt.Sprintf("%s:%s", bundle, ng)
@@ -309,6 +357,12 @@ func (s *StepsStorage) renderNodeGroupConfigurations(bundle, ng string, template
 	configurations = append(configurations, s.nodeGroupConfigurations[totalWildcard]...)
 	s.m.RUnlock()
 
+	return configurations
+}
+
+func (s *StepsStorage) renderNodeGroupConfigurations(bundle, ng string, templateContext map[string]interface{}) (map[string]string, error) {
+	configurations := s.nodeGroupConfigurationsFor(bundle, ng)
+
 	steps := make(map[string]string, len(configurations))
 	for _, sc := range configurations {
 		step, err := RenderTemplate(sc.Name, []byte(sc.Content), templateContext)
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStepsStorageRenderPreview(t *testing.T) {
+	rootDir := t.TempDir()
+
+	stepsDir := filepath.Join(rootDir, "bashible", "common-steps", "node-group")
+	if err := os.MkdirAll(stepsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stepsDir, "001_system.sh.tpl"), []byte("echo system"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := NewStepsStorage(context.Background(), rootDir, nil)
+	storage.AddNodeGroupConfiguration(&NodeGroupConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "user.sh"},
+		Spec: NodeGroupConfigurationSpec{
+			Content:    "echo old",
+			Weight:     100,
+			NodeGroups: []string{"*"},
+			Bundles:    []string{"*"},
+		},
+	})
+
+	proposed := &NodeGroupConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "user.sh"},
+		Spec: NodeGroupConfigurationSpec{
+			Content:    "echo {{ .nodeGroup.name }}",
+			Weight:     200,
+			NodeGroups: []string{"worker"},
+			Bundles:    []string{"*"},
+		},
+	}
+	templateContext := map[string]interface{}{"nodeGroup": map[string]interface{}{"name": "worker"}}
+
+	tests := []struct {
+		name      string
+		nodeGroup string
+		proposed  *NodeGroupConfiguration
+		remove    bool
+		want      map[string]string
+		wantErrs  int
+	}{
+		{
+			name:      "replaced",
+			nodeGroup: "worker",
+			proposed:  proposed,
+			want:      map[string]string{"001_system.sh": "echo system", "200_user.sh": "echo worker"},
+		},
+		{
+			name:      "not matching node group",
+			nodeGroup: "master",
+			proposed:  proposed,
+			want:      map[string]string{"001_system.sh": "echo system"},
+		},
+		{
+			name:      "removed",
+			nodeGroup: "worker",
+			proposed:  proposed,
+			remove:    true,
+			want:      map[string]string{"001_system.sh": "echo system"},
+		},
+		{
+			name:      "template error",
+			nodeGroup: "worker",
+			proposed: &NodeGroupConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "user.sh"},
+				Spec:       NodeGroupConfigurationSpec{Content: "{{ .broken ", Weight: 100},
+			},
+			want:     map[string]string{"001_system.sh": "echo system"},
+			wantErrs: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := storage.RenderPreview("node-group", "ubuntu-lts", "", templateContext, tt.nodeGroup, tt.proposed, tt.remove)
+			if len(errs) != tt.wantErrs {
+				t.Fatalf("RenderPreview() errors = %v, want %d errors", errs, tt.wantErrs)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("RenderPreview() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("RenderPreview()[%q] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	// the served configuration must stay untouched
+	steps, err := storage.Render("node-group", "ubuntu-lts", "", templateContext, "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps["100_user.sh"] != "echo old" {
+		t.Errorf("Render() = %v, served configuration is changed", steps)
+	}
+}
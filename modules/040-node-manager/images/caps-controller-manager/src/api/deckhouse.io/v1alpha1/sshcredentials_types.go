@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,9 +30,15 @@ type SSHCredentialsSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	User          string `json:"user"`
-	PrivateSSHKey string `json:"privateSSHKey"`
-	SudoPassword  string `json:"sudoPassword,omitempty"`
+	User string `json:"user"`
+
+	// Only one of PrivateSSHKey, PrivateSSHKeySecretRef and CertificateProvider can be set.
+	PrivateSSHKey          string                      `json:"privateSSHKey,omitempty"`
+	PrivateSSHKeySecretRef *SSHCredentialsSecretKeyRef `json:"privateSSHKeySecretRef,omitempty"`
+	CertificateProvider    *SSHCertificateProvider     `json:"certificateProvider,omitempty"`
+
+	SudoPassword          string                      `json:"sudoPassword,omitempty"`
+	SudoPasswordSecretRef *SSHCredentialsSecretKeyRef `json:"sudoPasswordSecretRef,omitempty"`
 
 	//+kubebuilder:default:=22
 	//+kubebuilder:validation:Minimum=1
@@ -40,6 +48,42 @@ type SSHCredentialsSpec struct {
 	SSHExtraArgs string `json:"sshExtraArgs,omitempty"`
 }
 
+// SSHCredentialsSecretsNamespace is the only namespace of Secrets referenced by SSHCredentials.
+// SSHCredentials are cluster-scoped, so without the restriction they could expose any Secret of the cluster.
+const SSHCredentialsSecretsNamespace = "d8-cloud-instance-manager"
+
+// SSHCredentialsSecretKeyRef is a reference to a key of a Kubernetes Secret.
+type SSHCredentialsSecretKeyRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+// SSHCertificateProvider is an external service signing OpenSSH user certificates,
+// e.g. the HashiCorp Vault SSH secrets engine.
+type SSHCertificateProvider struct {
+	// URL of the signing endpoint, e.g. https://vault.example.com/v1/ssh-client-signer/sign/caps.
+	URL string `json:"url"`
+
+	// TokenSecretRef is a reference to the token used to authenticate to the provider.
+	TokenSecretRef *SSHCredentialsSecretKeyRef `json:"tokenSecretRef,omitempty"`
+
+	// CABundle is a base64 encoded PEM bundle used to verify the provider TLS certificate.
+	CABundle string `json:"caBundle,omitempty"`
+
+	//+kubebuilder:default:="10m"
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// GetTTL returns the requested certificate lifetime.
+func (p *SSHCertificateProvider) GetTTL() time.Duration {
+	if p == nil || p.TTL == nil {
+		return 10 * time.Minute
+	}
+
+	return p.TTL.Duration
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:resource:scope=Cluster
 
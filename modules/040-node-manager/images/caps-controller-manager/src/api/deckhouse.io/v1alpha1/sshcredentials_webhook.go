@@ -57,34 +57,86 @@ var _ webhook.Validator = &SSHCredentials{}
 func (r *SSHCredentials) ValidateCreate() (admission.Warnings, error) {
 	sshcredentialslog.Info("validate create", "name", r.Name)
 
-	privateSSHKey, err := base64.StdEncoding.DecodeString(r.Spec.PrivateSSHKey)
-	if err != nil {
-		return nil, field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid base64 encoded string")
-	}
-
-	_, err = ssh.ParseRawPrivateKey(privateSSHKey)
-	if err != nil {
-		return nil, field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid private key encoded as base64 string")
-	}
-
-	return nil, nil
+	return nil, r.validate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *SSHCredentials) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
 	sshcredentialslog.Info("validate update", "name", r.Name)
 
+	return nil, r.validate()
+}
+
+// validate checks that exactly one SSH key source is set and the private SSH key is valid.
+func (r *SSHCredentials) validate() error {
+	var sources int
+
+	if r.Spec.PrivateSSHKey != "" {
+		sources++
+	}
+	if r.Spec.PrivateSSHKeySecretRef != nil {
+		sources++
+	}
+	if r.Spec.CertificateProvider != nil {
+		sources++
+	}
+
+	if sources != 1 {
+		return field.Invalid(field.NewPath("spec"), "******", "exactly one of privateSSHKey, privateSSHKeySecretRef and certificateProvider must be set")
+	}
+
+	if err := validateSecretRef(field.NewPath("spec", "privateSSHKeySecretRef"), r.Spec.PrivateSSHKeySecretRef); err != nil {
+		return err
+	}
+	if err := validateSecretRef(field.NewPath("spec", "sudoPasswordSecretRef"), r.Spec.SudoPasswordSecretRef); err != nil {
+		return err
+	}
+	if r.Spec.CertificateProvider != nil {
+		err := validateSecretRef(field.NewPath("spec", "certificateProvider", "tokenSecretRef"), r.Spec.CertificateProvider.TokenSecretRef)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Spec.SudoPassword != "" && r.Spec.SudoPasswordSecretRef != nil {
+		return field.Invalid(field.NewPath("spec", "sudoPasswordSecretRef"), "******", "only one of sudoPassword and sudoPasswordSecretRef can be set")
+	}
+
+	if r.Spec.CertificateProvider != nil {
+		if r.Spec.CertificateProvider.CABundle != "" {
+			_, err := base64.StdEncoding.DecodeString(r.Spec.CertificateProvider.CABundle)
+			if err != nil {
+				return field.Invalid(field.NewPath("spec", "certificateProvider", "caBundle"), "******", "caBundle must be a valid base64 encoded string")
+			}
+		}
+
+		return nil
+	}
+
+	if r.Spec.PrivateSSHKey == "" {
+		return nil
+	}
+
 	privateSSHKey, err := base64.StdEncoding.DecodeString(r.Spec.PrivateSSHKey)
 	if err != nil {
-		return nil, field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid base64 encoded string")
+		return field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid base64 encoded string")
 	}
 
 	_, err = ssh.ParseRawPrivateKey(privateSSHKey)
 	if err != nil {
-		return nil, field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid private key encoded as base64 string")
+		return field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid private key encoded as base64 string")
 	}
 
-	return nil, nil
+	return nil
+}
+
+// validateSecretRef checks that the referenced Secret is in the namespace allowed for SSHCredentials.
+func validateSecretRef(path *field.Path, ref *SSHCredentialsSecretKeyRef) error {
+	if ref == nil || ref.Namespace == SSHCredentialsSecretsNamespace {
+		return nil
+	}
+
+	return field.NotSupported(path.Child("namespace"), ref.Namespace, []string{SSHCredentialsSecretsNamespace})
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
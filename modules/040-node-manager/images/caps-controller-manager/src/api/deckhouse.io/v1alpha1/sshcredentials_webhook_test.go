@@ -0,0 +1,93 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSSHCredentialsValidateSecretNamespace(t *testing.T) {
+	ref := func(namespace string) *SSHCredentialsSecretKeyRef {
+		return &SSHCredentialsSecretKeyRef{Namespace: namespace, Name: "caps", Key: "key"}
+	}
+
+	tests := []struct {
+		name    string
+		spec    SSHCredentialsSpec
+		wantErr string
+	}{
+		{
+			name: "private key in the controller namespace",
+			spec: SSHCredentialsSpec{User: "caps", PrivateSSHKeySecretRef: ref(SSHCredentialsSecretsNamespace)},
+		},
+		{
+			name:    "private key in another namespace",
+			spec:    SSHCredentialsSpec{User: "caps", PrivateSSHKeySecretRef: ref("kube-system")},
+			wantErr: "spec.privateSSHKeySecretRef.namespace",
+		},
+		{
+			name: "sudo password in another namespace",
+			spec: SSHCredentialsSpec{
+				User:                   "caps",
+				PrivateSSHKeySecretRef: ref(SSHCredentialsSecretsNamespace),
+				SudoPasswordSecretRef:  ref("default"),
+			},
+			wantErr: "spec.sudoPasswordSecretRef.namespace",
+		},
+		{
+			name: "provider token in another namespace",
+			spec: SSHCredentialsSpec{
+				User: "caps",
+				CertificateProvider: &SSHCertificateProvider{
+					URL:            "https://vault.example.com/v1/ssh-client-signer/sign/caps",
+					TokenSecretRef: ref("vault"),
+				},
+			},
+			wantErr: "spec.certificateProvider.tokenSecretRef.namespace",
+		},
+		{
+			name: "provider token in the controller namespace",
+			spec: SSHCredentialsSpec{
+				User: "caps",
+				CertificateProvider: &SSHCertificateProvider{
+					URL:            "https://vault.example.com/v1/ssh-client-signer/sign/caps",
+					TokenSecretRef: ref(SSHCredentialsSecretsNamespace),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			credentials := &SSHCredentials{Spec: tt.spec}
+
+			_, err := credentials.ValidateCreate()
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+
+			_, err = credentials.ValidateUpdate(credentials)
+			g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -32,6 +34,30 @@ type StaticInstanceSpec struct {
 
 	Address        string                  `json:"address"`
 	CredentialsRef *corev1.ObjectReference `json:"credentialsRef"`
+
+	// +optional
+	HealthCheck *StaticInstanceHealthCheck `json:"healthCheck,omitempty"`
+}
+
+// StaticInstanceHealthCheck defines the health probing and the reprovisioning policy of the StaticInstance.
+type StaticInstanceHealthCheck struct {
+	// AutoReprovision enables cleanup and bootstrap of the StaticInstance whose Node stays NotReady longer than NotReadyTimeout.
+	// +optional
+	AutoReprovision bool `json:"autoReprovision,omitempty"`
+
+	// +optional
+	//+kubebuilder:default:="10m"
+	NotReadyTimeout *metav1.Duration `json:"notReadyTimeout,omitempty"`
+
+	// +optional
+	//+kubebuilder:default:=90
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=100
+	MaxDiskUsagePercent int `json:"maxDiskUsagePercent,omitempty"`
+
+	// +optional
+	//+kubebuilder:default:="30s"
+	MaxTimeSkew *metav1.Duration `json:"maxTimeSkew,omitempty"`
 }
 
 // StaticInstanceStatus defines the observed state of StaticInstance
@@ -58,7 +84,7 @@ type StaticInstanceStatusCurrentStatus struct {
 	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
 
 	// +optional
-	// +kubebuilder:validation:Enum=Pending;Bootstrapping;Running;Cleaning
+	// +kubebuilder:validation:Enum=Pending;Bootstrapping;Running;Degraded;Cleaning
 	Phase StaticInstanceStatusCurrentStatusPhase `json:"phase"`
 }
 
@@ -68,6 +94,7 @@ const (
 	StaticInstanceStatusCurrentStatusPhasePending       StaticInstanceStatusCurrentStatusPhase = "Pending"
 	StaticInstanceStatusCurrentStatusPhaseBootstrapping StaticInstanceStatusCurrentStatusPhase = "Bootstrapping"
 	StaticInstanceStatusCurrentStatusPhaseRunning       StaticInstanceStatusCurrentStatusPhase = "Running"
+	StaticInstanceStatusCurrentStatusPhaseDegraded      StaticInstanceStatusCurrentStatusPhase = "Degraded"
 	StaticInstanceStatusCurrentStatusPhaseCleaning      StaticInstanceStatusCurrentStatusPhase = "Cleaning"
 )
 
@@ -110,3 +137,35 @@ func (r *StaticInstance) GetConditions() clusterv1.Conditions {
 func (r *StaticInstance) SetConditions(conditions clusterv1.Conditions) {
 	r.Status.Conditions = conditions
 }
+
+// GetNotReadyTimeout returns the time after which the StaticInstance with NotReady Node is reprovisioned.
+func (h *StaticInstanceHealthCheck) GetNotReadyTimeout() time.Duration {
+	if h == nil || h.NotReadyTimeout == nil {
+		return 10 * time.Minute
+	}
+
+	return h.NotReadyTimeout.Duration
+}
+
+// GetMaxDiskUsagePercent returns the maximum allowed disk usage of the StaticInstance host.
+func (h *StaticInstanceHealthCheck) GetMaxDiskUsagePercent() int {
+	if h == nil || h.MaxDiskUsagePercent == 0 {
+		return 90
+	}
+
+	return h.MaxDiskUsagePercent
+}
+
+// GetMaxTimeSkew returns the maximum allowed time skew between the StaticInstance host and the controller.
+func (h *StaticInstanceHealthCheck) GetMaxTimeSkew() time.Duration {
+	if h == nil || h.MaxTimeSkew == nil {
+		return 30 * time.Second
+	}
+
+	return h.MaxTimeSkew.Duration
+}
+
+// IsAutoReprovisionEnabled returns true if the StaticInstance should be reprovisioned when its Node stays NotReady.
+func (h *StaticInstanceHealthCheck) IsAutoReprovisionEnabled() bool {
+	return h != nil && h.AutoReprovision
+}
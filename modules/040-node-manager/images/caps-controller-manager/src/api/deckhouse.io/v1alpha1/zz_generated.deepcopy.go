@@ -23,6 +23,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -32,7 +33,7 @@ func (in *SSHCredentials) DeepCopyInto(out *SSHCredentials) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHCredentials.
@@ -88,6 +89,21 @@ func (in *SSHCredentialsList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSHCredentialsSpec) DeepCopyInto(out *SSHCredentialsSpec) {
 	*out = *in
+	if in.PrivateSSHKeySecretRef != nil {
+		in, out := &in.PrivateSSHKeySecretRef, &out.PrivateSSHKeySecretRef
+		*out = new(SSHCredentialsSecretKeyRef)
+		**out = **in
+	}
+	if in.CertificateProvider != nil {
+		in, out := &in.CertificateProvider, &out.CertificateProvider
+		*out = new(SSHCertificateProvider)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SudoPasswordSecretRef != nil {
+		in, out := &in.SudoPasswordSecretRef, &out.SudoPasswordSecretRef
+		*out = new(SSHCredentialsSecretKeyRef)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHCredentialsSpec.
@@ -127,6 +143,31 @@ func (in *StaticInstance) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticInstanceHealthCheck) DeepCopyInto(out *StaticInstanceHealthCheck) {
+	*out = *in
+	if in.NotReadyTimeout != nil {
+		in, out := &in.NotReadyTimeout, &out.NotReadyTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxTimeSkew != nil {
+		in, out := &in.MaxTimeSkew, &out.MaxTimeSkew
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstanceHealthCheck.
+func (in *StaticInstanceHealthCheck) DeepCopy() *StaticInstanceHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticInstanceHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StaticInstanceList) DeepCopyInto(out *StaticInstanceList) {
 	*out = *in
@@ -167,6 +208,11 @@ func (in *StaticInstanceSpec) DeepCopyInto(out *StaticInstanceSpec) {
 		*out = new(v1.ObjectReference)
 		**out = **in
 	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(StaticInstanceHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstanceSpec.
@@ -231,3 +277,43 @@ func (in *StaticInstanceStatusCurrentStatus) DeepCopy() *StaticInstanceStatusCur
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHCertificateProvider) DeepCopyInto(out *SSHCertificateProvider) {
+	*out = *in
+	if in.TokenSecretRef != nil {
+		in, out := &in.TokenSecretRef, &out.TokenSecretRef
+		*out = new(SSHCredentialsSecretKeyRef)
+		**out = **in
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHCertificateProvider.
+func (in *SSHCertificateProvider) DeepCopy() *SSHCertificateProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHCertificateProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHCredentialsSecretKeyRef) DeepCopyInto(out *SSHCredentialsSecretKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHCredentialsSecretKeyRef.
+func (in *SSHCredentialsSecretKeyRef) DeepCopy() *SSHCredentialsSecretKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHCredentialsSecretKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -36,6 +36,15 @@ const (
 	// StaticInstanceWaitingForNodeRefReason indicates when a StaticInstance is registered into a capacity pool and
 	// waiting for a StaticInstance.Status.NodeRef to be assigned.
 	StaticInstanceWaitingForNodeRefReason = "WaitingForNodeRefToBeAssigned"
+
+	// StaticInstanceHealthyCondition documents the result of the last StaticInstance health check.
+	StaticInstanceHealthyCondition clusterv1.ConditionType = "Healthy"
+
+	// StaticInstanceHostProbeFailedReason indicates that the host is unreachable over SSH or one of the host checks failed.
+	StaticInstanceHostProbeFailedReason = "HostProbeFailed"
+
+	// StaticInstanceNodeNotReadyReason indicates that the Node of the StaticInstance is NotReady.
+	StaticInstanceNodeNotReadyReason = "NodeNotReady"
 )
 
 // Conditions and Reasons defined on StaticMachine.
@@ -31,7 +31,8 @@ func (c *Client) Cleanup(ctx context.Context, instanceScope *scope.InstanceScope
 	switch instanceScope.GetPhase() {
 	case
 		deckhousev1.StaticInstanceStatusCurrentStatusPhaseBootstrapping,
-		deckhousev1.StaticInstanceStatusCurrentStatusPhaseRunning:
+		deckhousev1.StaticInstanceStatusCurrentStatusPhaseRunning,
+		deckhousev1.StaticInstanceStatusCurrentStatusPhaseDegraded:
 		err := c.cleanupFromBootstrappingOrRunningPhase(ctx, instanceScope)
 		if err != nil {
 			return errors.Wrap(err, "failed to clean up StaticInstance from running phase")
@@ -42,7 +43,7 @@ func (c *Client) Cleanup(ctx context.Context, instanceScope *scope.InstanceScope
 			return errors.Wrap(err, "failed to clean up StaticInstance from cleaning phase")
 		}
 	default:
-		return errors.New("StaticInstance is not running or cleaning")
+		return errors.New("StaticInstance is not running, degraded or cleaning")
 	}
 
 	return nil
@@ -17,14 +17,20 @@ limitations under the License.
 package client
 
 import (
+	"sync"
+
 	"caps-controller-manager/internal/event"
 )
 
 // Client is a client that executes commands on hosts using the OpenSSH client.
 // It spawns tasks and stores their results by providerID.
 type Client struct {
-	bootstrapTaskManager *taskManager
-	cleanupTaskManager   *taskManager
+	bootstrapTaskManager   *taskManager
+	cleanupTaskManager     *taskManager
+	healthCheckTaskManager *taskManager
+
+	// healthCheckResults stores the results of finished health checks by providerID.
+	healthCheckResults sync.Map
 
 	recorder *event.Recorder
 }
@@ -32,8 +38,9 @@ type Client struct {
 // NewClient creates a new Client.
 func NewClient(recorder *event.Recorder) *Client {
 	return &Client{
-		bootstrapTaskManager: newTaskManager(),
-		cleanupTaskManager:   newTaskManager(),
-		recorder:             recorder,
+		bootstrapTaskManager:   newTaskManager(),
+		cleanupTaskManager:     newTaskManager(),
+		healthCheckTaskManager: newTaskManager(),
+		recorder:               recorder,
 	}
 }
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"caps-controller-manager/internal/scope"
+	"caps-controller-manager/internal/ssh"
+)
+
+// healthCheckScript prints the state of the host in the key=value format.
+const healthCheckScript = `echo "kubelet=$(systemctl is-active kubelet.service || true)"
+df -P / /var/lib/kubelet 2>/dev/null | awk 'NR > 1 { sub("%", "", $5); print "disk=" $5 " " $6 }'
+echo "time=$(date +%s)"
+`
+
+// HealthCheck checks that the StaticInstance is reachable over SSH, the kubelet service is active,
+// the disk usage and the time skew are within the limits.
+// It returns true when the check spawned on the previous call is finished, and the error describing why the host is unhealthy.
+func (c *Client) HealthCheck(instanceScope *scope.InstanceScope) (bool, error) {
+	providerID := instanceScope.MachineScope.StaticMachine.Spec.ProviderID
+
+	done := c.healthCheckTaskManager.spawn(providerID, func() bool {
+		c.healthCheckResults.Store(providerID, checkHost(instanceScope))
+
+		return true
+	})
+	if !done {
+		return false, nil
+	}
+
+	result, ok := c.healthCheckResults.LoadAndDelete(providerID)
+	if !ok || result == nil {
+		return true, nil
+	}
+
+	return true, result.(error)
+}
+
+func checkHost(instanceScope *scope.InstanceScope) error {
+	start := time.Now()
+
+	output, err := ssh.ExecSSHCommandToString(instanceScope, fmt.Sprintf("echo '%s' | base64 -d | sh", base64.StdEncoding.EncodeToString([]byte(healthCheckScript))))
+	if err != nil {
+		return errors.Wrap(err, "host is unreachable over ssh")
+	}
+
+	// The time on the host is compared with the middle of the ssh session.
+	now := start.Add(time.Since(start) / 2)
+
+	return checkHostState(output, now, instanceScope.Instance.Spec.HealthCheck.GetMaxDiskUsagePercent(), instanceScope.Instance.Spec.HealthCheck.GetMaxTimeSkew())
+}
+
+func checkHostState(output string, now time.Time, maxDiskUsagePercent int, maxTimeSkew time.Duration) error {
+	var problems []string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "kubelet":
+			if value != "active" {
+				problems = append(problems, fmt.Sprintf("kubelet service is %s", value))
+			}
+		case "disk":
+			usage, mountPoint, _ := strings.Cut(value, " ")
+
+			percent, err := strconv.Atoi(usage)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse disk usage '%s'", value)
+			}
+
+			if percent > maxDiskUsagePercent {
+				problems = append(problems, fmt.Sprintf("disk usage of %s is %d%% (max %d%%)", mountPoint, percent, maxDiskUsagePercent))
+			}
+		case "time":
+			timestamp, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse host time '%s'", value)
+			}
+
+			skew := time.Unix(timestamp, 0).Sub(now).Round(time.Second)
+			if skew < -maxTimeSkew || skew > maxTimeSkew {
+				problems = append(problems, fmt.Sprintf("time skew is %s (max %s)", skew, maxTimeSkew))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCheckHostState(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	output := func(kubelet string, rootUsage, kubeletUsage int, hostTime time.Time) string {
+		return fmt.Sprintf("kubelet=%s\ndisk=%d /\ndisk=%d /var/lib/kubelet\ntime=%d\n", kubelet, rootUsage, kubeletUsage, hostTime.Unix())
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		wantErr string
+	}{
+		{
+			name:   "healthy host",
+			output: output("active", 50, 60, now),
+		},
+		{
+			name:    "kubelet is not active",
+			output:  output("inactive", 50, 60, now),
+			wantErr: "kubelet service is inactive",
+		},
+		{
+			name:    "disk usage is too high",
+			output:  output("active", 50, 95, now),
+			wantErr: "disk usage of /var/lib/kubelet is 95% (max 90%)",
+		},
+		{
+			name:   "disk usage is at the limit",
+			output: output("active", 90, 90, now),
+		},
+		{
+			name:    "host time is ahead",
+			output:  output("active", 50, 60, now.Add(time.Minute)),
+			wantErr: "time skew is 1m0s (max 30s)",
+		},
+		{
+			name:    "host time is behind",
+			output:  output("active", 50, 60, now.Add(-time.Minute)),
+			wantErr: "time skew is -1m0s (max 30s)",
+		},
+		{
+			name:   "time skew is within the limit",
+			output: output("active", 50, 60, now.Add(-20*time.Second)),
+		},
+		{
+			name:    "several problems",
+			output:  output("failed", 95, 60, now.Add(time.Hour)),
+			wantErr: "kubelet service is failed, disk usage of / is 95% (max 90%), time skew is 1h0m0s (max 30s)",
+		},
+		{
+			name:   "unknown lines are ignored",
+			output: "Welcome to the host!\n" + output("active", 50, 60, now) + "unknown=value\n",
+		},
+		{
+			name:    "invalid disk usage",
+			output:  "disk=- /\n",
+			wantErr: "failed to parse disk usage '- /'",
+		},
+		{
+			name:    "invalid host time",
+			output:  "time=now\n",
+			wantErr: "failed to parse host time 'now'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := checkHostState(tt.output, now, 90, 30*time.Second)
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			}
+		})
+	}
+}
@@ -367,10 +367,14 @@ func (r *StaticMachineReconciler) reconcileStaticInstancePhase(
 		}
 
 		return ctrl.Result{RequeueAfter: RequeueForStaticInstanceBootstrapping}, nil
-	case deckhousev1.StaticInstanceStatusCurrentStatusPhaseRunning:
+	case
+		deckhousev1.StaticInstanceStatusCurrentStatusPhaseRunning,
+		deckhousev1.StaticInstanceStatusCurrentStatusPhaseDegraded:
 		instanceScope.MachineScope.SetReady()
 
-		instanceScope.Logger.Info("StaticInstance is running")
+		instanceScope.Logger.Info("StaticInstance is running", "phase", instanceScope.GetPhase())
+
+		return r.reconcileHealthCheck(ctx, instanceScope)
 	}
 
 	return ctrl.Result{}, nil
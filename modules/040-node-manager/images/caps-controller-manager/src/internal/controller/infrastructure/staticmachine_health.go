@@ -0,0 +1,230 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
+	"caps-controller-manager/internal/scope"
+)
+
+const (
+	RequeueForStaticInstanceHealthCheck = 10 * time.Second
+	StaticInstanceHealthCheckInterval   = 2 * time.Minute
+)
+
+// reconcileHealthCheck probes the running StaticInstance, moves it between Running and Degraded phases
+// and reprovisions it if its Node stays NotReady for too long and the reprovisioning is enabled.
+func (r *StaticMachineReconciler) reconcileHealthCheck(
+	ctx context.Context,
+	instanceScope *scope.InstanceScope,
+) (ctrl.Result, error) {
+	done, probeErr := r.HostClient.HealthCheck(instanceScope)
+	if !done {
+		return ctrl.Result{RequeueAfter: RequeueForStaticInstanceHealthCheck}, nil
+	}
+
+	notReadySince, err := r.nodeNotReadySince(ctx, instanceScope)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get StaticInstance Node readiness")
+	}
+
+	nodeGroup := instanceScope.MachineScope.StaticMachine.Labels["node-group"]
+
+	switch {
+	case probeErr != nil:
+		conditions.MarkFalse(instanceScope.Instance, infrav1.StaticInstanceHealthyCondition, infrav1.StaticInstanceHostProbeFailedReason, clusterv1.ConditionSeverityWarning, probeErr.Error())
+	case !notReadySince.IsZero():
+		conditions.MarkFalse(instanceScope.Instance, infrav1.StaticInstanceHealthyCondition, infrav1.StaticInstanceNodeNotReadyReason, clusterv1.ConditionSeverityWarning, "Node is NotReady since %s", notReadySince.UTC().Format(time.RFC3339))
+	default:
+		conditions.MarkTrue(instanceScope.Instance, infrav1.StaticInstanceHealthyCondition)
+	}
+
+	healthy := conditions.IsTrue(instanceScope.Instance, infrav1.StaticInstanceHealthyCondition)
+
+	switch {
+	case !healthy && instanceScope.GetPhase() == deckhousev1.StaticInstanceStatusCurrentStatusPhaseRunning:
+		instanceScope.SetPhase(deckhousev1.StaticInstanceStatusCurrentStatusPhaseDegraded)
+
+		r.Recorder.SendWarningEvent(instanceScope.Instance, nodeGroup, "StaticInstanceDegraded", conditions.GetMessage(instanceScope.Instance, infrav1.StaticInstanceHealthyCondition))
+	case healthy && instanceScope.GetPhase() == deckhousev1.StaticInstanceStatusCurrentStatusPhaseDegraded:
+		instanceScope.SetPhase(deckhousev1.StaticInstanceStatusCurrentStatusPhaseRunning)
+
+		r.Recorder.SendNormalEvent(instanceScope.Instance, nodeGroup, "StaticInstanceRecovered", "StaticInstance is healthy again")
+	}
+
+	err = instanceScope.Patch(ctx)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to patch StaticInstance health status")
+	}
+
+	if !isReprovisionRequired(instanceScope.Instance.Spec.HealthCheck, notReadySince, time.Now()) {
+		return ctrl.Result{RequeueAfter: StaticInstanceHealthCheckInterval}, nil
+	}
+
+	err = r.reprovision(ctx, instanceScope, nodeGroup)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to reprovision StaticInstance")
+	}
+
+	return ctrl.Result{RequeueAfter: StaticInstanceHealthCheckInterval}, nil
+}
+
+// reprovision deletes the Machine of the StaticInstance, so the StaticInstance is cleaned up and bootstrapped again.
+// The NodeGroup disruption settings are honored: nothing is done in the Manual approval mode or outside of the disruption windows,
+// and only one StaticInstance of the NodeGroup is reprovisioned at a time.
+func (r *StaticMachineReconciler) reprovision(
+	ctx context.Context,
+	instanceScope *scope.InstanceScope,
+	nodeGroup string,
+) error {
+	allowed, reason, err := r.isDisruptionAllowed(ctx, nodeGroup, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to check NodeGroup disruption settings")
+	}
+
+	if !allowed {
+		instanceScope.Logger.Info("StaticInstance Node is NotReady for too long, but reprovisioning is postponed", "reason", reason)
+
+		r.Recorder.SendWarningEvent(instanceScope.Instance, nodeGroup, "StaticInstanceReprovisionPostponed", reason)
+
+		return nil
+	}
+
+	err = r.Client.Delete(ctx, instanceScope.MachineScope.Machine)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete Machine")
+	}
+
+	instanceScope.Logger.Info("StaticInstance Node is NotReady for too long, reprovisioning StaticInstance")
+
+	r.Recorder.SendWarningEvent(instanceScope.Instance, nodeGroup, "StaticInstanceReprovisionStarted", fmt.Sprintf("Node is NotReady longer than %s, Machine '%s' is deleted to clean up and bootstrap StaticInstance again", instanceScope.Instance.Spec.HealthCheck.GetNotReadyTimeout(), instanceScope.MachineScope.Machine.Name))
+
+	return nil
+}
+
+// isReprovisionRequired returns true if the reprovisioning is enabled and the Node is NotReady longer than the timeout.
+func isReprovisionRequired(healthCheck *deckhousev1.StaticInstanceHealthCheck, notReadySince, now time.Time) bool {
+	if notReadySince.IsZero() || !healthCheck.IsAutoReprovisionEnabled() {
+		return false
+	}
+
+	return now.Sub(notReadySince) >= healthCheck.GetNotReadyTimeout()
+}
+
+// nodeNotReadySince returns the time when the StaticInstance Node became NotReady or zero time if the Node is Ready.
+func (r *StaticMachineReconciler) nodeNotReadySince(ctx context.Context, instanceScope *scope.InstanceScope) (time.Time, error) {
+	if instanceScope.Instance.Status.NodeRef == nil {
+		return time.Time{}, nil
+	}
+
+	node := &corev1.Node{}
+
+	err := r.Get(ctx, k8sClient.ObjectKey{Name: instanceScope.Instance.Status.NodeRef.Name}, node)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to get Node '%s'", instanceScope.Instance.Status.NodeRef.Name)
+	}
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+
+		if condition.Status == corev1.ConditionTrue {
+			return time.Time{}, nil
+		}
+
+		return condition.LastTransitionTime.Time, nil
+	}
+
+	return time.Time{}, nil
+}
+
+// isDisruptionAllowed checks the NodeGroup disruption settings and returns the reason if the disruption is not allowed.
+func (r *StaticMachineReconciler) isDisruptionAllowed(ctx context.Context, nodeGroupName string, now time.Time) (bool, string, error) {
+	nodeGroup := new(unstructured.Unstructured)
+	nodeGroup.SetAPIVersion("deckhouse.io/v1")
+	nodeGroup.SetKind("NodeGroup")
+
+	err := r.Get(ctx, k8sClient.ObjectKey{Name: nodeGroupName}, nodeGroup)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, fmt.Sprintf("NodeGroup '%s' not found", nodeGroupName), nil
+		}
+
+		return false, "", errors.Wrapf(err, "failed to get NodeGroup '%s'", nodeGroupName)
+	}
+
+	approvalMode, _, _ := unstructured.NestedString(nodeGroup.Object, "spec", "disruptions", "approvalMode")
+	if approvalMode == "Manual" {
+		return false, fmt.Sprintf("NodeGroup '%s' disruptions approval mode is Manual", nodeGroupName), nil
+	}
+
+	windows, err := disruptionWindows(nodeGroup)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "failed to get NodeGroup '%s' disruption windows", nodeGroupName)
+	}
+
+	if !windows.IsAllowed(now) {
+		return false, fmt.Sprintf("NodeGroup '%s' disruption windows don't allow disruption now", nodeGroupName), nil
+	}
+
+	staticMachines := &infrav1.StaticMachineList{}
+
+	err = r.List(ctx, staticMachines, k8sClient.MatchingLabels{"node-group": nodeGroupName})
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to list StaticMachines")
+	}
+
+	for _, staticMachine := range staticMachines.Items {
+		if !staticMachine.DeletionTimestamp.IsZero() {
+			return false, fmt.Sprintf("StaticMachine '%s' of NodeGroup '%s' is being deleted", staticMachine.Name, nodeGroupName), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// disruptionWindows returns the NodeGroup disruption windows.
+func disruptionWindows(nodeGroup *unstructured.Unstructured) (update.Windows, error) {
+	windows, found, err := unstructured.NestedSlice(nodeGroup.Object, "spec", "disruptions", "automatic", "windows")
+	if err != nil || !found {
+		return nil, err
+	}
+
+	data, err := json.Marshal(windows)
+	if err != nil {
+		return nil, err
+	}
+
+	return update.FromJSON(data)
+}
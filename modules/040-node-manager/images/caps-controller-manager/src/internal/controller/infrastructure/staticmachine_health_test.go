@@ -0,0 +1,467 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
+	"caps-controller-manager/internal/event"
+	"caps-controller-manager/internal/scope"
+)
+
+// 2023-10-18 is wednesday.
+var wednesdayNoon = time.Date(2023, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func newNodeGroup(name string, disruptions map[string]interface{}) *unstructured.Unstructured {
+	nodeGroup := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"nodeType": "Static",
+		},
+	}}
+	nodeGroup.SetAPIVersion("deckhouse.io/v1")
+	nodeGroup.SetKind("NodeGroup")
+	nodeGroup.SetName(name)
+
+	if disruptions != nil {
+		_ = unstructured.SetNestedMap(nodeGroup.Object, disruptions, "spec", "disruptions")
+	}
+
+	return nodeGroup
+}
+
+func newStaticMachine(name, nodeGroup string, deleting bool) *infrav1.StaticMachine {
+	staticMachine := &infrav1.StaticMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "d8-cloud-instance-manager",
+			Labels:    map[string]string{"node-group": nodeGroup},
+		},
+	}
+
+	if deleting {
+		now := metav1.Now()
+		staticMachine.DeletionTimestamp = &now
+		staticMachine.Finalizers = []string{infrav1.MachineFinalizer}
+	}
+
+	return staticMachine
+}
+
+func newReconciler(t *testing.T, objects ...k8sClient.Object) *StaticMachineReconciler {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		clusterv1.AddToScheme,
+		infrav1.AddToScheme,
+		deckhousev1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	return &StaticMachineReconciler{
+		Client:   client,
+		Scheme:   scheme,
+		Recorder: event.NewRecorder(client, logr.Discard()),
+	}
+}
+
+func TestIsDisruptionAllowedWindows(t *testing.T) {
+	windows := func(windows ...map[string]interface{}) map[string]interface{} {
+		items := make([]interface{}, 0, len(windows))
+		for _, w := range windows {
+			items = append(items, w)
+		}
+
+		return map[string]interface{}{
+			"approvalMode": "Automatic",
+			"automatic": map[string]interface{}{
+				"windows": items,
+			},
+		}
+	}
+	window := func(from, to string, days ...interface{}) map[string]interface{} {
+		w := map[string]interface{}{"from": from, "to": to}
+		if len(days) > 0 {
+			w["days"] = days
+		}
+
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		disruptions map[string]interface{}
+		now         time.Time
+		allowed     bool
+	}{
+		{
+			name:    "no disruption settings",
+			now:     wednesdayNoon,
+			allowed: true,
+		},
+		{
+			name:        "no windows",
+			disruptions: map[string]interface{}{"approvalMode": "Automatic"},
+			now:         wednesdayNoon,
+			allowed:     true,
+		},
+		{
+			name:        "inside the window",
+			disruptions: windows(window("10:00", "14:00")),
+			now:         wednesdayNoon,
+			allowed:     true,
+		},
+		{
+			name:        "outside the window",
+			disruptions: windows(window("13:00", "14:00")),
+			now:         wednesdayNoon,
+			allowed:     false,
+		},
+		{
+			name:        "inside the window on another day",
+			disruptions: windows(window("10:00", "14:00", "Mon", "Tue")),
+			now:         wednesdayNoon,
+			allowed:     false,
+		},
+		{
+			name:        "inside the window on the allowed day",
+			disruptions: windows(window("10:00", "14:00", "Mon", "Wed")),
+			now:         wednesdayNoon,
+			allowed:     true,
+		},
+		{
+			name:        "inside one of the windows",
+			disruptions: windows(window("01:00", "02:00"), window("10:00", "14:00")),
+			now:         wednesdayNoon,
+			allowed:     true,
+		},
+		{
+			name:        "window crossing midnight: before midnight",
+			disruptions: windows(window("22:00", "02:00", "Wed")),
+			now:         time.Date(2023, 10, 18, 23, 30, 0, 0, time.UTC),
+			allowed:     true,
+		},
+		{
+			name:        "window crossing midnight: after midnight",
+			disruptions: windows(window("22:00", "02:00", "Wed")),
+			now:         time.Date(2023, 10, 19, 1, 30, 0, 0, time.UTC),
+			allowed:     true,
+		},
+		{
+			name:        "window crossing midnight: after midnight of not allowed day",
+			disruptions: windows(window("22:00", "02:00", "Wed")),
+			now:         time.Date(2023, 10, 18, 1, 30, 0, 0, time.UTC),
+			allowed:     false,
+		},
+		{
+			name:        "window crossing midnight: outside the window",
+			disruptions: windows(window("22:00", "02:00")),
+			now:         wednesdayNoon,
+			allowed:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := newReconciler(t, newNodeGroup("worker", tt.disruptions))
+
+			allowed, reason, err := r.isDisruptionAllowed(context.Background(), "worker", tt.now)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(allowed).To(Equal(tt.allowed), reason)
+		})
+	}
+}
+
+func TestIsDisruptionAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []k8sClient.Object
+		allowed bool
+		reason  string
+	}{
+		{
+			name:    "NodeGroup not found",
+			allowed: false,
+			reason:  "NodeGroup 'worker' not found",
+		},
+		{
+			name: "manual approval mode",
+			objects: []k8sClient.Object{
+				newNodeGroup("worker", map[string]interface{}{"approvalMode": "Manual"}),
+			},
+			allowed: false,
+			reason:  "NodeGroup 'worker' disruptions approval mode is Manual",
+		},
+		{
+			name: "another StaticMachine of the NodeGroup is being deleted",
+			objects: []k8sClient.Object{
+				newNodeGroup("worker", nil),
+				newStaticMachine("worker-a", "worker", false),
+				newStaticMachine("worker-b", "worker", true),
+			},
+			allowed: false,
+			reason:  "StaticMachine 'worker-b' of NodeGroup 'worker' is being deleted",
+		},
+		{
+			name: "StaticMachine of another NodeGroup is being deleted",
+			objects: []k8sClient.Object{
+				newNodeGroup("worker", nil),
+				newStaticMachine("worker-a", "worker", false),
+				newStaticMachine("system-a", "system", true),
+			},
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := newReconciler(t, tt.objects...)
+
+			allowed, reason, err := r.isDisruptionAllowed(context.Background(), "worker", wednesdayNoon)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(allowed).To(Equal(tt.allowed))
+			g.Expect(reason).To(Equal(tt.reason))
+		})
+	}
+}
+
+func TestIsReprovisionRequired(t *testing.T) {
+	enabled := &deckhousev1.StaticInstanceHealthCheck{
+		AutoReprovision: true,
+		NotReadyTimeout: &metav1.Duration{Duration: 5 * time.Minute},
+	}
+
+	tests := []struct {
+		name          string
+		healthCheck   *deckhousev1.StaticInstanceHealthCheck
+		notReadySince time.Time
+		required      bool
+	}{
+		{
+			name:          "Node is Ready",
+			healthCheck:   enabled,
+			notReadySince: time.Time{},
+			required:      false,
+		},
+		{
+			name:          "health check is not configured",
+			healthCheck:   nil,
+			notReadySince: wednesdayNoon.Add(-time.Hour),
+			required:      false,
+		},
+		{
+			name:          "reprovisioning is disabled",
+			healthCheck:   &deckhousev1.StaticInstanceHealthCheck{NotReadyTimeout: &metav1.Duration{Duration: 5 * time.Minute}},
+			notReadySince: wednesdayNoon.Add(-time.Hour),
+			required:      false,
+		},
+		{
+			name:          "Node is NotReady shorter than the timeout",
+			healthCheck:   enabled,
+			notReadySince: wednesdayNoon.Add(-4 * time.Minute),
+			required:      false,
+		},
+		{
+			name:          "Node is NotReady longer than the timeout",
+			healthCheck:   enabled,
+			notReadySince: wednesdayNoon.Add(-5 * time.Minute),
+			required:      true,
+		},
+		{
+			name:          "default timeout",
+			healthCheck:   &deckhousev1.StaticInstanceHealthCheck{AutoReprovision: true},
+			notReadySince: wednesdayNoon.Add(-9 * time.Minute),
+			required:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			g.Expect(isReprovisionRequired(tt.healthCheck, tt.notReadySince, wednesdayNoon)).To(Equal(tt.required))
+		})
+	}
+}
+
+func TestNodeNotReadySince(t *testing.T) {
+	transition := metav1.NewTime(wednesdayNoon.Add(-time.Hour).Truncate(time.Second))
+
+	node := func(name string, status corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{
+					{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+					{Type: corev1.NodeReady, Status: status, LastTransitionTime: transition},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		nodeRef *corev1.ObjectReference
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "StaticInstance without Node",
+		},
+		{
+			name:    "Node is Ready",
+			nodeRef: &corev1.ObjectReference{Name: "ready"},
+		},
+		{
+			name:    "Node is NotReady",
+			nodeRef: &corev1.ObjectReference{Name: "not-ready"},
+			want:    transition.Time,
+		},
+		{
+			name:    "Node status is Unknown",
+			nodeRef: &corev1.ObjectReference{Name: "unknown"},
+			want:    transition.Time,
+		},
+		{
+			name:    "Node not found",
+			nodeRef: &corev1.ObjectReference{Name: "missing"},
+			wantErr: true,
+		},
+	}
+
+	r := newReconciler(t, node("ready", corev1.ConditionTrue), node("not-ready", corev1.ConditionFalse), node("unknown", corev1.ConditionUnknown))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			instanceScope := &scope.InstanceScope{
+				Instance: &deckhousev1.StaticInstance{
+					Status: deckhousev1.StaticInstanceStatus{NodeRef: tt.nodeRef},
+				},
+			}
+
+			notReadySince, err := r.nodeNotReadySince(context.Background(), instanceScope)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(notReadySince.Equal(tt.want)).To(BeTrue(), "got %s, want %s", notReadySince, tt.want)
+		})
+	}
+}
+
+func TestReprovision(t *testing.T) {
+	tests := []struct {
+		name          string
+		disruptions   map[string]interface{}
+		deleteMachine bool
+	}{
+		{
+			name:          "disruption is allowed",
+			disruptions:   map[string]interface{}{"approvalMode": "Automatic"},
+			deleteMachine: true,
+		},
+		{
+			name:          "disruption is not approved",
+			disruptions:   map[string]interface{}{"approvalMode": "Manual"},
+			deleteMachine: false,
+		},
+		{
+			name: "outside the disruption windows",
+			disruptions: map[string]interface{}{
+				"approvalMode": "Automatic",
+				"automatic": map[string]interface{}{
+					"windows": []interface{}{
+						// reprovision uses the current time, so the window is on another day
+						map[string]interface{}{
+							"from": "00:00",
+							"to":   "23:59",
+							"days": []interface{}{time.Now().UTC().AddDate(0, 0, 2).Weekday().String()[:3]},
+						},
+					},
+				},
+			},
+			deleteMachine: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Namespace: "d8-cloud-instance-manager"},
+			}
+			staticMachine := newStaticMachine("worker-a", "worker", false)
+
+			r := newReconciler(t, newNodeGroup("worker", tt.disruptions), machine, staticMachine)
+
+			instanceScope := &scope.InstanceScope{
+				Scope: &scope.Scope{Client: r.Client, Logger: logr.Discard()},
+				MachineScope: &scope.MachineScope{
+					Machine:       machine,
+					StaticMachine: staticMachine,
+				},
+				Instance: &deckhousev1.StaticInstance{
+					ObjectMeta: metav1.ObjectMeta{Name: "worker-a"},
+					Spec: deckhousev1.StaticInstanceSpec{
+						HealthCheck: &deckhousev1.StaticInstanceHealthCheck{AutoReprovision: true},
+					},
+				},
+			}
+
+			err := r.reprovision(context.Background(), instanceScope, "worker")
+			g.Expect(err).NotTo(HaveOccurred())
+
+			err = r.Get(context.Background(), k8sClient.ObjectKeyFromObject(machine), &clusterv1.Machine{})
+			if tt.deleteMachine {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "Machine is expected to be deleted")
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
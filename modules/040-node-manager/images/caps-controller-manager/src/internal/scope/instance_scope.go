@@ -18,9 +18,12 @@ package scope
 
 import (
 	"context"
+	"encoding/base64"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
@@ -39,6 +42,9 @@ type InstanceScope struct {
 
 	Instance    *deckhousev1.StaticInstance
 	Credentials *deckhousev1.SSHCredentials
+
+	// CertificateProviderToken is the token to authenticate to the SSHCredentials certificate provider.
+	CertificateProviderToken string
 }
 
 // NewInstanceScope creates a new instance scope.
@@ -73,24 +79,85 @@ func (i *InstanceScope) LoadSSHCredentials(ctx context.Context, recorder *event.
 		Name: i.Instance.Spec.CredentialsRef.Name,
 	}
 
-	err := i.Client.Get(ctx, credentialsKey, credentials)
-	if err != nil {
-		var nodeGroup string
+	var nodeGroup string
 
-		if i.MachineScope != nil {
-			nodeGroup = i.MachineScope.StaticMachine.Labels["node-group"]
-		}
+	if i.MachineScope != nil {
+		nodeGroup = i.MachineScope.StaticMachine.Labels["node-group"]
+	}
 
+	err := i.Client.Get(ctx, credentialsKey, credentials)
+	if err != nil {
 		recorder.SendWarningEvent(i.Instance, nodeGroup, "StaticInstanceCredentialsUnavailable", "Credentials are unavailable")
 
 		return errors.Wrap(err, "failed to get StaticInstance credentials")
 	}
 
+	err = i.resolveSSHCredentialsSecrets(ctx, credentials)
+	if err != nil {
+		recorder.SendWarningEvent(i.Instance, nodeGroup, "StaticInstanceCredentialsUnavailable", "Credentials Secrets are unavailable")
+
+		return errors.Wrap(err, "failed to resolve StaticInstance credentials Secrets")
+	}
+
 	i.Credentials = credentials
 
 	return nil
 }
 
+// resolveSSHCredentialsSecrets fills the SSHCredentials spec with the values of the referenced Secrets.
+// The SSHCredentials object is never patched, so the values stay in memory only.
+func (i *InstanceScope) resolveSSHCredentialsSecrets(ctx context.Context, credentials *deckhousev1.SSHCredentials) error {
+	if credentials.Spec.PrivateSSHKeySecretRef != nil {
+		privateSSHKey, err := i.getSecretKey(ctx, credentials.Spec.PrivateSSHKeySecretRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to get private SSH key")
+		}
+
+		credentials.Spec.PrivateSSHKey = base64.StdEncoding.EncodeToString(privateSSHKey)
+	}
+
+	if credentials.Spec.SudoPasswordSecretRef != nil {
+		sudoPassword, err := i.getSecretKey(ctx, credentials.Spec.SudoPasswordSecretRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to get sudo password")
+		}
+
+		credentials.Spec.SudoPassword = string(sudoPassword)
+	}
+
+	if credentials.Spec.CertificateProvider != nil && credentials.Spec.CertificateProvider.TokenSecretRef != nil {
+		token, err := i.getSecretKey(ctx, credentials.Spec.CertificateProvider.TokenSecretRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to get certificate provider token")
+		}
+
+		i.CertificateProviderToken = strings.TrimSpace(string(token))
+	}
+
+	return nil
+}
+
+func (i *InstanceScope) getSecretKey(ctx context.Context, ref *deckhousev1.SSHCredentialsSecretKeyRef) ([]byte, error) {
+	// The webhook does not check SSHCredentials created before the restriction
+	if ref.Namespace != deckhousev1.SSHCredentialsSecretsNamespace {
+		return nil, errors.Errorf("Secret '%s/%s' is not in the '%s' namespace", ref.Namespace, ref.Name, deckhousev1.SSHCredentialsSecretsNamespace)
+	}
+
+	secret := &corev1.Secret{}
+
+	err := i.Client.Get(ctx, k8sClient.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get Secret '%s/%s'", ref.Namespace, ref.Name)
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, errors.Errorf("Secret '%s/%s' has no key '%s'", ref.Namespace, ref.Name, ref.Key)
+	}
+
+	return value, nil
+}
+
 // GetPhase returns the current phase of the static instance.
 func (i *InstanceScope) GetPhase() deckhousev1.StaticInstanceStatusCurrentStatusPhase {
 	if i.Instance.Status.CurrentStatus == nil {
@@ -116,6 +183,7 @@ func (i *InstanceScope) Patch(ctx context.Context) error {
 		conditions.WithConditions(
 			infrav1.StaticInstanceAddedToNodeGroupCondition,
 			infrav1.StaticInstanceBootstrapSucceededCondition,
+			infrav1.StaticInstanceHealthyCondition,
 		),
 		conditions.WithStepCounterIf(i.Instance.ObjectMeta.DeletionTimestamp.IsZero()),
 		conditions.WithStepCounter(),
@@ -128,6 +196,7 @@ func (i *InstanceScope) Patch(ctx context.Context) error {
 			clusterv1.ReadyCondition,
 			infrav1.StaticInstanceAddedToNodeGroupCondition,
 			infrav1.StaticInstanceBootstrapSucceededCondition,
+			infrav1.StaticInstanceHealthyCondition,
 		}})
 	if err != nil {
 		return errors.Wrap(err, "failed to patch StaticInstance")
@@ -142,6 +211,7 @@ func (i *InstanceScope) ToPending(ctx context.Context) error {
 	i.Instance.Status.CurrentStatus = nil
 
 	conditions.MarkFalse(i.Instance, infrav1.StaticInstanceBootstrapSucceededCondition, infrav1.StaticInstanceWaitingForNodeRefReason, clusterv1.ConditionSeverityInfo, "")
+	conditions.Delete(i.Instance, infrav1.StaticInstanceHealthyCondition)
 
 	i.SetPhase(deckhousev1.StaticInstanceStatusCurrentStatusPhasePending)
 
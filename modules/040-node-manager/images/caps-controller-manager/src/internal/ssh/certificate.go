@@ -0,0 +1,168 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	"caps-controller-manager/internal/scope"
+)
+
+const certificateProviderTimeout = 30 * time.Second
+
+type signRequest struct {
+	PublicKey       string `json:"public_key"`
+	ValidPrincipals string `json:"valid_principals"`
+	CertType        string `json:"cert_type"`
+	TTL             string `json:"ttl"`
+}
+
+type signResponse struct {
+	Data struct {
+		SignedKey string `json:"signed_key"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// generateSignedKey generates a new ephemeral key pair and signs its public key with the SSHCredentials certificate provider.
+// It returns the private key and the OpenSSH user certificate, both in the OpenSSH file formats.
+func generateSignedKey(instanceScope *scope.InstanceScope) ([]byte, []byte, error) {
+	provider := instanceScope.Credentials.Spec.CertificateProvider
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate key pair")
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to convert public key")
+	}
+
+	privateKeyBlock, err := ssh.MarshalPrivateKey(privateKey, "caps-controller-manager")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal private key")
+	}
+
+	certificate, err := signPublicKey(provider, instanceScope.CertificateProviderToken, instanceScope.Credentials.Spec.User, ssh.MarshalAuthorizedKey(sshPublicKey))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to sign public key")
+	}
+
+	instanceScope.Logger.Info("Signed short-lived SSH certificate", "ttl", provider.GetTTL().String())
+
+	return pem.EncodeToMemory(privateKeyBlock), certificate, nil
+}
+
+// signPublicKey requests the provider to sign the public key using the HashiCorp Vault SSH secrets engine API.
+func signPublicKey(provider *deckhousev1.SSHCertificateProvider, token, user string, publicKey []byte) ([]byte, error) {
+	body, err := json.Marshal(signRequest{
+		PublicKey:       string(publicKey),
+		ValidPrincipals: user,
+		CertType:        "user",
+		TTL:             fmt.Sprintf("%ds", int(provider.GetTTL().Seconds())),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal sign request")
+	}
+
+	httpClient, err := newProviderHTTPClient(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, provider.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create sign request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send sign request")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read sign response")
+	}
+
+	var signResp signResponse
+
+	err = json.Unmarshal(respBody, &signResp)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return nil, errors.Wrap(err, "failed to unmarshal sign response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("certificate provider returned status %d: %v", resp.StatusCode, signResp.Errors)
+	}
+
+	certificate := []byte(signResp.Data.SignedKey)
+
+	parsedKey, _, _, _, err := ssh.ParseAuthorizedKey(certificate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse signed certificate")
+	}
+
+	if _, ok := parsedKey.(*ssh.Certificate); !ok {
+		return nil, errors.New("certificate provider returned a public key instead of a certificate")
+	}
+
+	return certificate, nil
+}
+
+func newProviderHTTPClient(provider *deckhousev1.SSHCertificateProvider) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if provider.CABundle != "" {
+		caBundle, err := base64.StdEncoding.DecodeString(provider.CABundle)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode certificate provider CA bundle")
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("failed to parse certificate provider CA bundle")
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Client{Transport: transport, Timeout: certificateProviderTimeout}, nil
+}
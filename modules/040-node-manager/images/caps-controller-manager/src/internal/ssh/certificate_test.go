@@ -0,0 +1,263 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	"caps-controller-manager/internal/scope"
+)
+
+const fakeVaultToken = "s.fake-vault-token"
+
+// fakeVault is a local stand-in for the HashiCorp Vault SSH secrets engine signing endpoint.
+type fakeVault struct {
+	*httptest.Server
+
+	ca       ssh.Signer
+	requests []signRequest
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &fakeVault{ca: ca}
+	v.Server = httptest.NewTLSServer(http.HandlerFunc(v.sign))
+	t.Cleanup(v.Close)
+
+	return v
+}
+
+func (v *fakeVault) sign(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != fakeVaultToken {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	var req signRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	v.requests = append(v.requests, req)
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ttl, err := time.ParseDuration(req.TTL)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	certificate := &ssh.Certificate{
+		Key:             publicKey,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{req.ValidPrincipals},
+		ValidAfter:      uint64(now.Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+	}
+	if err := certificate.SignCert(rand.Reader, v.ca); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := signResponse{}
+	resp.Data.SignedKey = string(ssh.MarshalAuthorizedKey(certificate))
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (v *fakeVault) provider(ttl *metav1.Duration) *deckhousev1.SSHCertificateProvider {
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: v.Certificate().Raw})
+
+	return &deckhousev1.SSHCertificateProvider{
+		URL:      v.URL + "/v1/ssh-client-signer/sign/caps",
+		CABundle: base64.StdEncoding.EncodeToString(caBundle),
+		TTL:      ttl,
+	}
+}
+
+func parseCertificate(g *WithT, raw []byte) *ssh.Certificate {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(raw)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	certificate, ok := key.(*ssh.Certificate)
+	g.Expect(ok).To(BeTrue())
+
+	return certificate
+}
+
+func TestSignPublicKey(t *testing.T) {
+	g := NewWithT(t)
+	vault := newFakeVault(t)
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	raw, err := signPublicKey(vault.provider(nil), fakeVaultToken, "caps", ssh.MarshalAuthorizedKey(sshPublicKey))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	certificate := parseCertificate(g, raw)
+	g.Expect(certificate.CertType).To(Equal(uint32(ssh.UserCert)))
+	g.Expect(certificate.ValidPrincipals).To(Equal([]string{"caps"}))
+	g.Expect(certificate.Key.Marshal()).To(Equal(sshPublicKey.Marshal()))
+	g.Expect(certificate.SignatureKey.Marshal()).To(Equal(vault.ca.PublicKey().Marshal()))
+
+	g.Expect(vault.requests).To(HaveLen(1))
+	g.Expect(vault.requests[0].CertType).To(Equal("user"))
+}
+
+func TestSignPublicKeyExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  *metav1.Duration
+		want time.Duration
+	}{
+		{name: "default", ttl: nil, want: 10 * time.Minute},
+		{name: "custom", ttl: &metav1.Duration{Duration: 90 * time.Second}, want: 90 * time.Second},
+		{name: "long", ttl: &metav1.Duration{Duration: time.Hour}, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vault := newFakeVault(t)
+
+			publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+			g.Expect(err).NotTo(HaveOccurred())
+			sshPublicKey, err := ssh.NewPublicKey(publicKey)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			raw, err := signPublicKey(vault.provider(tt.ttl), fakeVaultToken, "caps", ssh.MarshalAuthorizedKey(sshPublicKey))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			certificate := parseCertificate(g, raw)
+			validFor := time.Until(time.Unix(int64(certificate.ValidBefore), 0))
+			g.Expect(validFor).To(BeNumerically("~", tt.want, 5*time.Second))
+		})
+	}
+}
+
+func TestSignPublicKeyErrors(t *testing.T) {
+	vault := newFakeVault(t)
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizedKey := ssh.MarshalAuthorizedKey(sshPublicKey)
+
+	t.Run("invalid token", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := signPublicKey(vault.provider(nil), "invalid", "caps", authorizedKey)
+		g.Expect(err).To(MatchError(ContainSubstring("status 403")))
+	})
+
+	t.Run("untrusted provider certificate", func(t *testing.T) {
+		g := NewWithT(t)
+
+		provider := vault.provider(nil)
+		provider.CABundle = ""
+
+		_, err := signPublicKey(provider, fakeVaultToken, "caps", authorizedKey)
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("public key instead of certificate", func(t *testing.T) {
+		g := NewWithT(t)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp := signResponse{}
+			resp.Data.SignedKey = string(authorizedKey)
+			_ = json.NewEncoder(w).Encode(resp)
+		}))
+		defer server.Close()
+
+		_, err := signPublicKey(&deckhousev1.SSHCertificateProvider{URL: server.URL}, fakeVaultToken, "caps", authorizedKey)
+		g.Expect(err).To(MatchError(ContainSubstring("instead of a certificate")))
+	})
+}
+
+func TestGenerateSignedKeyRefresh(t *testing.T) {
+	g := NewWithT(t)
+	vault := newFakeVault(t)
+
+	instanceScope := &scope.InstanceScope{
+		Scope: &scope.Scope{Logger: logr.Discard()},
+		Credentials: &deckhousev1.SSHCredentials{
+			Spec: deckhousev1.SSHCredentialsSpec{
+				User:                "caps",
+				CertificateProvider: vault.provider(nil),
+			},
+		},
+		CertificateProviderToken: fakeVaultToken,
+	}
+
+	var previous *ssh.Certificate
+	for i := 0; i < 2; i++ {
+		privateKey, raw, err := generateSignedKey(instanceScope)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		signer, err := ssh.ParsePrivateKey(privateKey)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		certificate := parseCertificate(g, raw)
+		g.Expect(certificate.Key.Marshal()).To(Equal(signer.PublicKey().Marshal()))
+
+		// Every command gets a new key pair and a new certificate
+		if previous != nil {
+			g.Expect(bytes.Equal(certificate.Key.Marshal(), previous.Key.Marshal())).To(BeFalse())
+			g.Expect(certificate.Nonce).NotTo(Equal(previous.Nonce))
+		}
+		previous = certificate
+	}
+
+	g.Expect(vault.requests).To(HaveLen(2))
+}
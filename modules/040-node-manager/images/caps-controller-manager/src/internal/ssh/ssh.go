@@ -33,12 +33,32 @@ import (
 
 // ExecSSHCommand executes a command on the StaticInstance.
 func ExecSSHCommand(instanceScope *scope.InstanceScope, command string, stdout io.Writer) error {
-	privateSSHKey, err := base64.StdEncoding.DecodeString(instanceScope.Credentials.Spec.PrivateSSHKey)
+	var (
+		privateSSHKey []byte
+		certificate   []byte
+		err           error
+	)
+
+	// With the certificate provider a new key pair is generated and signed for every command,
+	// so no long-lived key is stored anywhere.
+	if instanceScope.Credentials.Spec.CertificateProvider != nil {
+		privateSSHKey, certificate, err = generateSignedKey(instanceScope)
+		if err != nil {
+			return errors.Wrap(err, "failed to get signed ssh certificate")
+		}
+	} else {
+		privateSSHKey, err = base64.StdEncoding.DecodeString(instanceScope.Credentials.Spec.PrivateSSHKey)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode private ssh key")
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "ssh-key-")
 	if err != nil {
-		return errors.Wrap(err, "failed to decode private ssh key")
+		return errors.Wrap(err, "failed to create temporary directory for private ssh key")
 	}
+	defer os.RemoveAll(dir)
 
-	dir := os.TempDir()
 	sshKey := filepath.Join(dir, "ssh-key")
 
 	err = os.WriteFile(sshKey, privateSSHKey, 0600)
@@ -55,6 +75,17 @@ func ExecSSHCommand(instanceScope *scope.InstanceScope, command string, stdout i
 		fmt.Sprintf("-p %d", instanceScope.Credentials.Spec.SSHPort),
 	}
 
+	if certificate != nil {
+		sshCertificate := filepath.Join(dir, "ssh-key-cert.pub")
+
+		err = os.WriteFile(sshCertificate, certificate, 0600)
+		if err != nil {
+			return errors.Wrap(err, "failed to write ssh certificate to temporary file")
+		}
+
+		args = append(args, "-o", fmt.Sprintf("CertificateFile=%s", sshCertificate))
+	}
+
 	for _, arg := range strings.Split(instanceScope.Credentials.Spec.SSHExtraArgs, " ") {
 		if arg == "" {
 			continue
@@ -0,0 +1,205 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	qosGuaranteed = "Guaranteed"
+	qosBurstable  = "Burstable"
+	qosBestEffort = "BestEffort"
+)
+
+// podCgroupRegexp matches pod cgroups of both cgroupfs (pod<uid>) and systemd (kubepods-...-pod<uid>.slice) drivers.
+var podCgroupRegexp = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})(\.slice)?$`)
+
+type podCgroup struct {
+	UID      string
+	Path     string
+	QOSClass string
+
+	FullAvg10     float64
+	MemoryCurrent uint64
+}
+
+// kubepodsRoot returns the root cgroup of Kubernetes pods for cgroupfs or systemd cgroup driver.
+func kubepodsRoot(cgroupRoot string) (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroup v2 is not mounted to %s: %w", cgroupRoot, err)
+	}
+
+	for _, dir := range []string{"kubepods.slice", "kubepods"} {
+		path := filepath.Join(cgroupRoot, dir)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("kubepods cgroup is not found in %s", cgroupRoot)
+}
+
+// qosCgroups returns the cgroups of QoS classes. The Guaranteed pods are placed directly into the kubepods cgroup,
+// so its pressure includes all pods.
+func qosCgroups(kubepods string) map[string]string {
+	result := map[string]string{qosGuaranteed: kubepods}
+
+	if strings.HasSuffix(kubepods, ".slice") {
+		result[qosBurstable] = filepath.Join(kubepods, "kubepods-burstable.slice")
+		result[qosBestEffort] = filepath.Join(kubepods, "kubepods-besteffort.slice")
+	} else {
+		result[qosBurstable] = filepath.Join(kubepods, "burstable")
+		result[qosBestEffort] = filepath.Join(kubepods, "besteffort")
+	}
+
+	return result
+}
+
+// listPodCgroups lists the pod cgroups with their memory pressure and usage.
+func listPodCgroups(kubepods string) ([]podCgroup, error) {
+	qos := qosCgroups(kubepods)
+
+	var pods []podCgroup
+
+	for _, class := range []string{qosGuaranteed, qosBurstable, qosBestEffort} {
+		entries, err := os.ReadDir(qos[class])
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			match := podCgroupRegexp.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+
+			pod := podCgroup{
+				UID:      strings.ReplaceAll(match[1], "_", "-"),
+				Path:     filepath.Join(qos[class], entry.Name()),
+				QOSClass: class,
+			}
+
+			pod.FullAvg10, err = readFullAvg10(filepath.Join(pod.Path, "memory.pressure"))
+			if err != nil {
+				return nil, err
+			}
+
+			pod.MemoryCurrent, err = readUint(filepath.Join(pod.Path, "memory.current"))
+			if err != nil {
+				return nil, err
+			}
+
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods, nil
+}
+
+// readFullAvg10 reads the "full avg10" value from a PSI file.
+func readFullAvg10(path string) (float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseFullAvg10(content)
+}
+
+func parseFullAvg10(content []byte) (float64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "full" {
+			continue
+		}
+
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "avg10=") {
+				continue
+			}
+
+			return strconv.ParseFloat(strings.TrimPrefix(field, "avg10="), 64)
+		}
+	}
+
+	return 0, fmt.Errorf("full avg10 value is not found")
+}
+
+func readUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// killCgroup kills all processes of the cgroup with cgroup.kill (Linux >= 5.14).
+// On older kernels, every process listed in cgroup.procs of the cgroup and its children is killed with SIGKILL.
+func killCgroup(path string) error {
+	err := os.WriteFile(filepath.Join(path, "cgroup.kill"), []byte("1"), 0o644)
+	if err == nil {
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return fmt.Errorf("write cgroup.kill: %w", err)
+	}
+
+	return filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		procs, err := os.ReadFile(filepath.Join(p, "cgroup.procs"))
+		if err != nil {
+			return err
+		}
+
+		for _, line := range strings.Fields(string(procs)) {
+			pid, err := strconv.Atoi(line)
+			if err != nil {
+				continue
+			}
+
+			if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+				return fmt.Errorf("kill process %d: %w", pid, err)
+			}
+		}
+
+		return nil
+	})
+}
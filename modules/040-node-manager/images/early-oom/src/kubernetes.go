@@ -0,0 +1,146 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	apiRequestTimeout = 5 * time.Second
+)
+
+type podInfo struct {
+	Namespace         string
+	Name              string
+	UID               string
+	PriorityClassName string
+	Priority          int32
+	QOSClass          string
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+			UID       string `json:"uid"`
+		} `json:"metadata"`
+		Spec struct {
+			PriorityClassName string `json:"priorityClassName"`
+			Priority          *int32 `json:"priority"`
+		} `json:"spec"`
+		Status struct {
+			QOSClass string `json:"qosClass"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// kubeClient is a minimal in-cluster client listing the pods of the node.
+type kubeClient struct {
+	httpClient *http.Client
+	host       string
+	tokenFile  string
+}
+
+func newKubeClient() (*kubeClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be defined")
+	}
+
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("failed to parse service account CA certificate")
+	}
+
+	return &kubeClient{
+		httpClient: &http.Client{
+			Timeout: apiRequestTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12},
+			},
+		},
+		host:      "https://" + net.JoinHostPort(host, port),
+		tokenFile: serviceAccountDir + "/token",
+	}, nil
+}
+
+// listNodePods returns the pods of the node by UID.
+func (c *kubeClient) listNodePods(nodeName string) (map[string]podInfo, error) {
+	// the token is rotated by kubelet, so it is read on every request
+	token, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"fieldSelector": []string{"spec.nodeName=" + nodeName}}
+
+	req, err := http.NewRequest(http.MethodGet, c.host+"/api/v1/pods?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+string(token))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list pods: unexpected status %s", resp.Status)
+	}
+
+	var list podList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	pods := make(map[string]podInfo, len(list.Items))
+	for _, item := range list.Items {
+		pod := podInfo{
+			Namespace:         item.Metadata.Namespace,
+			Name:              item.Metadata.Name,
+			UID:               item.Metadata.UID,
+			PriorityClassName: item.Spec.PriorityClassName,
+			QOSClass:          item.Status.QOSClass,
+		}
+		if item.Spec.Priority != nil {
+			pod.Priority = *item.Spec.Priority
+		}
+
+		pods[pod.UID] = pod
+	}
+
+	return pods, nil
+}
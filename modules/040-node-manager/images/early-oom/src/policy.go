@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	policySysrq  = "sysrq"
+	policyCgroup = "cgroup"
+
+	// pods with this priority class are never killed
+	systemNodeCriticalPriorityClass = "system-node-critical"
+)
+
+var (
+	qosMemoryPressure = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "early_oom_qos_memory_pressure_full_avg10",
+		Help: "PSI memory full avg10 value of the QoS class cgroup",
+	}, []string{"qos_class"})
+
+	oomDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "early_oom_decisions_total",
+		Help: "Number of the early OOM killer decisions",
+	}, []string{"policy", "qos_class", "priority_class", "result"})
+
+	errNoVictim = errors.New("no pod to kill is found")
+)
+
+func init() {
+	prometheus.MustRegister(qosMemoryPressure, oomDecisions)
+}
+
+var qosRank = map[string]int{
+	qosBestEffort: 0,
+	qosBurstable:  1,
+	qosGuaranteed: 2,
+}
+
+// cgroupPolicy kills a single pod selected by QoS class and priority instead of triggering the system OOM killer.
+type cgroupPolicy struct {
+	kubepods string
+	nodeName string
+	client   *kubeClient
+}
+
+func newCgroupPolicy(cgroupRoot, nodeName string) (*cgroupPolicy, error) {
+	if nodeName == "" {
+		return nil, errors.New("node name is required for the cgroup policy")
+	}
+
+	kubepods, err := kubepodsRoot(cgroupRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newKubeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cgroupPolicy{kubepods: kubepods, nodeName: nodeName, client: client}, nil
+}
+
+// updateMetrics exports the memory pressure of the QoS classes.
+func (p *cgroupPolicy) updateMetrics() {
+	for class, path := range qosCgroups(p.kubepods) {
+		avg10, err := readFullAvg10(path + "/memory.pressure")
+		if err != nil {
+			continue
+		}
+
+		qosMemoryPressure.WithLabelValues(class).Set(avg10)
+	}
+}
+
+// killVictim selects a pod and kills all its processes.
+func (p *cgroupPolicy) killVictim() error {
+	cgroups, err := listPodCgroups(p.kubepods)
+	if err != nil {
+		return fmt.Errorf("list pod cgroups: %w", err)
+	}
+
+	pods, err := p.client.listNodePods(p.nodeName)
+	if err != nil {
+		return fmt.Errorf("list node pods: %w", err)
+	}
+
+	victim, pod := selectVictim(cgroups, pods)
+	if victim == nil {
+		oomDecisions.WithLabelValues(policyCgroup, "", "", "no_victim").Inc()
+		return errNoVictim
+	}
+
+	log.Printf("Killing pod %s/%s (QoS class %s, priority class %q, priority %d, memory full avg10 %f, memory usage %d bytes)",
+		pod.Namespace, pod.Name, victim.QOSClass, pod.PriorityClassName, pod.Priority, victim.FullAvg10, victim.MemoryCurrent)
+
+	if err := killCgroup(victim.Path); err != nil {
+		oomDecisions.WithLabelValues(policyCgroup, victim.QOSClass, pod.PriorityClassName, "failed").Inc()
+		return fmt.Errorf("kill pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	oomDecisions.WithLabelValues(policyCgroup, victim.QOSClass, pod.PriorityClassName, "killed").Inc()
+
+	return nil
+}
+
+// selectVictim selects the pod to kill: BestEffort pods first, then Burstable and Guaranteed ones.
+// Within a QoS class, the pod with the lowest priority, then the highest memory pressure and usage is selected.
+// Unknown pods and pods with the system-node-critical priority class are never selected.
+func selectVictim(cgroups []podCgroup, pods map[string]podInfo) (*podCgroup, *podInfo) {
+	candidates := make([]podCgroup, 0, len(cgroups))
+	for _, cgroup := range cgroups {
+		pod, ok := pods[cgroup.UID]
+		if !ok || pod.PriorityClassName == systemNodeCriticalPriorityClass {
+			continue
+		}
+
+		candidates = append(candidates, cgroup)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		podA, podB := pods[a.UID], pods[b.UID]
+
+		switch {
+		case qosRank[a.QOSClass] != qosRank[b.QOSClass]:
+			return qosRank[a.QOSClass] < qosRank[b.QOSClass]
+		case podA.Priority != podB.Priority:
+			return podA.Priority < podB.Priority
+		case a.FullAvg10 != b.FullAvg10:
+			return a.FullAvg10 > b.FullAvg10
+		default:
+			return a.MemoryCurrent > b.MemoryCurrent
+		}
+	})
+
+	victim := candidates[0]
+	pod := pods[victim.UID]
+
+	return &victim, &pod
+}
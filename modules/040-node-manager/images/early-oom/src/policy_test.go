@@ -0,0 +1,150 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFullAvg10(t *testing.T) {
+	content := []byte("some avg10=1.50 avg60=0.00 avg300=0.00 total=100\nfull avg10=42.10 avg60=0.00 avg300=0.00 total=50\n")
+
+	got, err := parseFullAvg10(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 42.10 {
+		t.Errorf("parseFullAvg10() = %f, want 42.10", got)
+	}
+
+	if _, err := parseFullAvg10([]byte("some avg10=1.50\n")); err == nil {
+		t.Error("parseFullAvg10() expected error for missing full line")
+	}
+}
+
+func TestListPodCgroups(t *testing.T) {
+	root := t.TempDir()
+	kubepods := filepath.Join(root, "kubepods.slice")
+
+	pods := map[string]string{
+		"kubepods-pod11111111_1111_1111_1111_111111111111.slice":                                      "1000",
+		"kubepods-besteffort.slice/kubepods-besteffort-pod22222222_2222_2222_2222_222222222222.slice": "2000",
+		"kubepods-burstable.slice/kubepods-burstable-pod33333333_3333_3333_3333_333333333333.slice":   "3000",
+	}
+	for dir, memory := range pods {
+		path := filepath.Join(kubepods, dir)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(path, "memory.current"), memory)
+		writeFile(t, filepath.Join(path, "memory.pressure"), "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=5.00 avg60=0.00 avg300=0.00 total=0\n")
+	}
+	writeFile(t, filepath.Join(root, "cgroup.controllers"), "memory")
+
+	got, err := kubepodsRoot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != kubepods {
+		t.Errorf("kubepodsRoot() = %s, want %s", got, kubepods)
+	}
+
+	cgroups, err := listPodCgroups(kubepods)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"11111111-1111-1111-1111-111111111111": qosGuaranteed,
+		"22222222-2222-2222-2222-222222222222": qosBestEffort,
+		"33333333-3333-3333-3333-333333333333": qosBurstable,
+	}
+	if len(cgroups) != len(want) {
+		t.Fatalf("listPodCgroups() = %v, want %d pods", cgroups, len(want))
+	}
+	for _, cgroup := range cgroups {
+		if want[cgroup.UID] != cgroup.QOSClass {
+			t.Errorf("pod %s QoS class = %s, want %s", cgroup.UID, cgroup.QOSClass, want[cgroup.UID])
+		}
+		if cgroup.FullAvg10 != 5 || cgroup.MemoryCurrent == 0 {
+			t.Errorf("pod %s pressure = %f, memory = %d", cgroup.UID, cgroup.FullAvg10, cgroup.MemoryCurrent)
+		}
+	}
+}
+
+func TestSelectVictim(t *testing.T) {
+	pods := map[string]podInfo{
+		"critical":   {Name: "critical", PriorityClassName: systemNodeCriticalPriorityClass, Priority: 2000001000},
+		"besteffort": {Name: "besteffort", Priority: 1000},
+		"low":        {Name: "low", Priority: 0},
+		"pressured":  {Name: "pressured", Priority: 0},
+	}
+
+	tests := []struct {
+		name    string
+		cgroups []podCgroup
+		want    string
+	}{
+		{
+			name: "BestEffort first",
+			cgroups: []podCgroup{
+				{UID: "low", QOSClass: qosBurstable},
+				{UID: "besteffort", QOSClass: qosBestEffort},
+			},
+			want: "besteffort",
+		},
+		{
+			name: "higher pressure within the same priority",
+			cgroups: []podCgroup{
+				{UID: "low", QOSClass: qosBurstable, FullAvg10: 1},
+				{UID: "pressured", QOSClass: qosBurstable, FullAvg10: 10},
+			},
+			want: "pressured",
+		},
+		{
+			name: "never system-node-critical or unknown pods",
+			cgroups: []podCgroup{
+				{UID: "critical", QOSClass: qosBestEffort},
+				{UID: "unknown", QOSClass: qosBestEffort},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, pod := selectVictim(tt.cgroups, pods)
+
+			var got string
+			if pod != nil {
+				got = pod.Name
+			}
+			if got != tt.want {
+				t.Errorf("selectVictim() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -57,6 +57,8 @@ func main() {
 		log.Fatal("failed to get memory threshold argument")
 	}
 	memoryThreshold := flag.Float64("memory-threshold", 0, "Memory threshold for PSI memory avg10")
+	policy := flag.String("policy", policySysrq, "OOM policy: 'sysrq' triggers the system OOM killer, 'cgroup' kills a pod selected by QoS class and priority")
+	cgroupRoot := flag.String("cgroup-root", "/sys/fs/cgroup", "Host cgroup v2 mount point, used by the cgroup policy")
 
 	flag.Parse()
 
@@ -64,6 +66,22 @@ func main() {
 		log.Fatalf("Please, provide positive value in memory-threshold flag: %v", *memoryThreshold)
 	}
 
+	var cgroupOOMPolicy *cgroupPolicy
+
+	switch *policy {
+	case policySysrq:
+	case policyCgroup:
+		var err error
+
+		// the system OOM killer is used as a fallback if the cgroup policy is unavailable
+		cgroupOOMPolicy, err = newCgroupPolicy(*cgroupRoot, os.Getenv("NODE_NAME"))
+		if err != nil {
+			log.Printf("Cgroup policy is unavailable, falling back to the system OOM killer: %s", err)
+		}
+	default:
+		log.Fatalf("Unknown policy %q, must be %q or %q", *policy, policySysrq, policyCgroup)
+	}
+
 	server := &http.Server{Addr: "127.0.0.1:8080"}
 	http.Handle("/metrics", promhttp.Handler())
 
@@ -88,7 +106,7 @@ func main() {
 		case sig := <-c:
 			shutdown(sig, server)
 		case <-t.C:
-			if iteration(*memoryThreshold) {
+			if iteration(*memoryThreshold, cgroupOOMPolicy) {
 				t.Reset(recoveryInterval)
 			} else {
 				t.Reset(pollInterval)
@@ -116,7 +134,7 @@ func probePSISupport() error {
 	return nil
 }
 
-func iteration(memoryThreshold float64) bool {
+func iteration(memoryThreshold float64, cgroupOOMPolicy *cgroupPolicy) bool {
 	fs, err := procfs.NewDefaultFS()
 	if err != nil {
 		log.Fatal(err)
@@ -132,12 +150,30 @@ func iteration(memoryThreshold float64) bool {
 		}
 	}
 
+	if cgroupOOMPolicy != nil {
+		cgroupOOMPolicy.updateMetrics()
+	}
+
 	if stats.Full.Avg10 > memoryThreshold {
+		if cgroupOOMPolicy != nil {
+			log.Printf("full avg10 value %f, threshold %f, selecting a pod to kill...", stats.Full.Avg10, memoryThreshold)
+
+			err := cgroupOOMPolicy.killVictim()
+			if err == nil {
+				log.Printf("Waiting for recovery for %s", recoveryInterval.String())
+
+				return true
+			}
+
+			log.Printf("Cgroup policy failed: %s, falling back to the system OOM killer", err)
+		}
+
 		log.Printf("full avg10 value %f, threshold %f, triggering system OOM killer...", stats.Full.Avg10, memoryThreshold)
 		err := triggerSystemOOM(sysrqTriggerFile)
 		if err != nil {
 			log.Fatal(err)
 		}
+		oomDecisions.WithLabelValues(policySysrq, "", "", "triggered").Inc()
 
 		log.Printf("Waiting for recovery for %s", recoveryInterval.String())
 
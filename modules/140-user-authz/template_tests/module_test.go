@@ -87,6 +87,11 @@ crds:
       - apiGroup: rbac.authorization.k8s.io
         kind: ClusterRole
         name: cluster-write-all
+customClusterRoles:
+  admin:
+  - cert-manager:user-authz:user
+  editor:
+  - cert-manager:user-authz:editor
 `
 
 	testRoleCRDs = `---
@@ -129,6 +134,7 @@ var _ = Describe("Module :: user-authz :: helm template ::", func() {
 
 			f.ValuesSet("userAuthz.enableMultiTenancy", true)
 			f.ValuesSet("userAuthz.controlPlaneConfigurator.enabled", true)
+			f.ValuesSetFromYaml("userAuthz.decisionLog", `{"sink": "Stdout", "sampleRate": 1}`)
 			f.ValuesSet("global.discovery.extensionAPIServerAuthenticationRequestheaderClientCA", "test")
 			f.ValuesSet("userAuthz.internal.webhookCertificate.ca", "test")
 			f.ValuesSet("userAuthz.internal.webhookCertificate.crt", "test")
@@ -281,6 +287,43 @@ var _ = Describe("Module :: user-authz :: helm template ::", func() {
 			Expect(f.KubernetesResource("ConfigMap", "d8-user-authz", "user-authz-webhook").Exists()).To(BeTrue())
 			Expect(f.KubernetesResource("ConfigMap", "d8-user-authz", "user-authz-webhook").Field("data.config\\.json").String()).To(MatchJSON(testCRDsWithCRDsKeyJSON))
 		})
+
+		It("Should configure the decision log and expose metrics", func() {
+			ds := f.KubernetesResource("DaemonSet", "d8-user-authz", "user-authz-webhook")
+			Expect(ds.Field(`spec.template.spec.containers.0.env.#(name=="DECISION_LOG_SINK").value`).String()).To(Equal("Stdout"))
+			Expect(ds.Field(`spec.template.spec.containers.0.env.#(name=="DECISION_LOG_SAMPLE_RATE").value`).String()).To(Equal("1"))
+			Expect(ds.Field("spec.template.spec.initContainers").Exists()).To(BeFalse())
+			Expect(ds.Field("spec.template.spec.containers.1.name").String()).To(Equal("kube-rbac-proxy"))
+
+			Expect(f.KubernetesResource("Role", "d8-user-authz", "access-to-user-authz-webhook").Exists()).To(BeTrue())
+		})
+
+		Context("File decision log sink", func() {
+			BeforeEach(func() {
+				f.ValuesSet("userAuthz.decisionLog.sink", "File")
+				f.HelmRender()
+			})
+
+			It("Should mount the host directory writable by the webhook", func() {
+				Expect(f.RenderError).ShouldNot(HaveOccurred())
+
+				ds := f.KubernetesResource("DaemonSet", "d8-user-authz", "user-authz-webhook")
+				Expect(ds.Field(`spec.template.spec.volumes.#(name=="decision-log").hostPath.path`).String()).To(Equal("/var/log/user-authz-webhook"))
+				Expect(ds.Field("spec.template.spec.initContainers.0.name").String()).To(Equal("chown-volume-decision-log"))
+			})
+		})
+
+		Context("HTTP decision log sink without url", func() {
+			BeforeEach(func() {
+				f.ValuesSet("userAuthz.decisionLog.sink", "HTTP")
+				f.HelmRender()
+			})
+
+			It("Helm should fail", func() {
+				Expect(f.RenderError).Should(HaveOccurred())
+				Expect(f.RenderError.Error()).Should(ContainSubstring("userAuthz.decisionLog.url is required"))
+			})
+		})
 	})
 
 	Context("With CAR (incl. limitNamespaces) and not enabledMultiTenancy", func() {
@@ -17,13 +17,19 @@ limitations under the License.
 package hooks
 
 import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
 	"github.com/flant/addon-operator/sdk"
 	"github.com/flant/shell-operator/pkg/kube/object_patch"
+	"github.com/flant/shell-operator/pkg/kube_events_manager/types"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
@@ -31,9 +37,21 @@ import (
 	"github.com/deckhouse/deckhouse/go_lib/set"
 )
 
-type expirePatch struct {
-	ExpireAt string   `json:"expireAt,omitempty"`
-	Groups   []string `json:"groups"`
+const (
+	userLockReasonTooManyFailedAttempts   = "TooManyFailedAttempts"
+	userLockReasonPasswordExpired         = "PasswordExpired"
+	userLockReasonPasswordPolicyViolation = "PasswordPolicyViolation"
+
+	totpSecretLabel = "user-authn.deckhouse.io/totp-user"
+)
+
+type userStatusPatch struct {
+	ExpireAt          string                 `json:"expireAt,omitempty"`
+	Groups            []string               `json:"groups"`
+	LastLogin         string                 `json:"lastLogin,omitempty"`
+	PasswordChangedAt string                 `json:"passwordChangedAt"`
+	PasswordHistory   []string               `json:"passwordHistory"`
+	Lock              map[string]interface{} `json:"lock"`
 }
 
 type DexUserInternalValues struct {
@@ -43,6 +61,10 @@ type DexUserInternalValues struct {
 	Spec   DexUserSpec   `json:"spec"`
 	Status DexUserStatus `json:"status,omitempty"`
 
+	PreviousPasswords []string `json:"previousPasswords,omitempty"`
+	TOTPSecret        string   `json:"totpSecret,omitempty"`
+	PasswordExpired   bool     `json:"passwordExpired,omitempty"`
+
 	ExpireAt string `json:"-"`
 }
 
@@ -59,10 +81,42 @@ type DexUserSpec struct {
 	UserID   string   `json:"userID,omitempty"`
 	Groups   []string `json:"groups,omitempty"`
 	TTL      string   `json:"ttl,omitempty"`
+
+	TOTP *DexUserTOTP `json:"totp,omitempty"`
+}
+
+type DexUserTOTP struct {
+	Enabled bool `json:"enabled"`
 }
 
 type DexUserStatus struct {
 	ExpireAt string `json:"expireAt,omitempty"`
+
+	LastLogin         string       `json:"lastLogin,omitempty"`
+	PasswordChangedAt string       `json:"passwordChangedAt,omitempty"`
+	PasswordHistory   []string     `json:"passwordHistory,omitempty"`
+	Lock              *DexUserLock `json:"lock,omitempty"`
+}
+
+type DexUserLock struct {
+	State   bool   `json:"state"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Until   string `json:"until,omitempty"`
+}
+
+// DexPasswordLoginState is the state of password logins maintained by Dex in the Password object.
+type DexPasswordLoginState struct {
+	Name string `json:"-"`
+
+	LockedUntil     string `json:"lockedUntil,omitempty"`
+	PolicyViolation bool   `json:"policyViolation,omitempty"`
+	LastLogin       string `json:"lastLogin,omitempty"`
+}
+
+type DexUserTOTPSecret struct {
+	User   string
+	Secret string
 }
 
 type DexGroup struct {
@@ -111,6 +165,33 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			Kind:       "Group",
 			FilterFunc: applyDexGroupFilter,
 		},
+		{
+			Name:       "passwords",
+			ApiVersion: "dex.coreos.com/v1",
+			Kind:       "Password",
+			NamespaceSelector: &types.NamespaceSelector{
+				NameSelector: &types.NameSelector{
+					MatchNames: []string{"d8-user-authn"},
+				},
+			},
+			FilterFunc: applyDexPasswordLoginStateFilter,
+		},
+		{
+			Name:       "totp_secrets",
+			ApiVersion: "v1",
+			Kind:       "Secret",
+			NamespaceSelector: &types.NamespaceSelector{
+				NameSelector: &types.NameSelector{
+					MatchNames: []string{"d8-user-authn"},
+				},
+			},
+			LabelSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: totpSecretLabel, Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			FilterFunc: applyDexUserTOTPSecretFilter,
+		},
 	},
 }, getDexUsers)
 
@@ -124,6 +205,31 @@ func getDexUsers(input *go_hook.HookInput) error {
 		makeUserGroupsMap(groupsSnap, group.Spec.Name, []string{}, mapOfUsersToGroups)
 	}
 
+	loginStates := make(map[string]DexPasswordLoginState, len(input.Snapshots["passwords"]))
+	for _, obj := range input.Snapshots["passwords"] {
+		loginState := obj.(DexPasswordLoginState)
+		loginStates[loginState.Name] = loginState
+	}
+
+	totpSecrets := make(map[string]string, len(input.Snapshots["totp_secrets"]))
+	for _, obj := range input.Snapshots["totp_secrets"] {
+		totpSecret := obj.(DexUserTOTPSecret)
+		totpSecrets[totpSecret.User] = totpSecret.Secret
+	}
+
+	var renew time.Duration
+	if renewValue := input.Values.Get("userAuthn.passwordPolicy.renew").String(); renewValue != "" {
+		var err error
+		renew, err = time.ParseDuration(renewValue)
+		if err != nil {
+			return fmt.Errorf("cannot parse password renew duration: %v", err)
+		}
+	}
+	passwordHistoryLimit := int(input.Values.Get("userAuthn.passwordPolicy.passwordHistoryLimit").Int())
+
+	now := time.Now()
+	usersWithTOTP := make(map[string]bool)
+
 	for _, user := range input.Snapshots["users"] {
 		dexUser, ok := user.(*DexUser)
 		if !ok {
@@ -154,38 +260,200 @@ func getDexUsers(input *go_hook.HookInput) error {
 			expireAt = dexUser.Status.ExpireAt
 		}
 
-		users = append(users, DexUserInternalValues{
-			Name:        dexUser.Name,
-			EncodedName: encoding.ToFnvLikeDex(strings.ToLower(dexUser.Spec.Email)),
-			Spec:        dexUser.Spec,
-			Status:      dexUser.Status,
-			ExpireAt:    expireAt,
-		})
+		encodedName := encoding.ToFnvLikeDex(strings.ToLower(dexUser.Spec.Email))
+
+		// The current password hash is the last one in the history
+		history := append([]string{}, dexUser.Status.PasswordHistory...)
+		passwordChangedAt := dexUser.Status.PasswordChangedAt
+		passwordChanged := len(history) == 0 || history[len(history)-1] != dexUser.Spec.Password
+		if passwordChanged {
+			history = append(history, dexUser.Spec.Password)
+			passwordChangedAt = now.Format(time.RFC3339)
+
+			if len(dexUser.Status.PasswordHistory) > 0 {
+				// A new password unlocks the user
+				input.PatchCollector.MergePatch(
+					map[string]interface{}{"loginState": nil},
+					"dex.coreos.com/v1", "Password", "d8-user-authn", encodedName,
+					object_patch.IgnoreMissingObject(),
+				)
+			}
+		}
+		if len(history) > passwordHistoryLimit+1 {
+			history = history[len(history)-passwordHistoryLimit-1:]
+		}
 
-		var patch map[string]interface{}
-		if expireAt == "" {
-			patch = map[string]interface{}{
-				"status": expirePatch{
-					Groups: groups,
-				},
+		passwordExpired := false
+		if renew > 0 {
+			changedAt, err := time.Parse(time.RFC3339, passwordChangedAt)
+			if err != nil {
+				return fmt.Errorf("cannot parse password change time of user %s: %v", dexUser.Name, err)
 			}
-		} else {
-			patch = map[string]interface{}{
-				"status": expirePatch{
-					ExpireAt: expireAt,
-					Groups:   groups,
-				},
+			passwordExpired = changedAt.Add(renew).Before(now)
+		}
+
+		loginState := loginStates[encodedName]
+		if passwordChanged {
+			loginState = DexPasswordLoginState{LastLogin: loginState.LastLogin}
+		}
+		lock := userLockPatch(loginState, passwordExpired, renew, now)
+
+		var totpSecret string
+		if dexUser.Spec.TOTP != nil && dexUser.Spec.TOTP.Enabled {
+			usersWithTOTP[dexUser.Name] = true
+
+			totpSecret = totpSecrets[dexUser.Name]
+			if totpSecret == "" {
+				var err error
+				totpSecret, err = generateTOTPSecret()
+				if err != nil {
+					return fmt.Errorf("cannot generate TOTP secret for user %s: %v", dexUser.Name, err)
+				}
+
+				input.LogEntry.Infof("Generate TOTP secret for user %s", dexUser.Name)
+				input.PatchCollector.Create(totpSecretObject(dexUser, totpSecret), object_patch.IgnoreIfExists())
 			}
 		}
 
-		input.LogEntry.Infof("Update groups in user status %s. Groups: %v", dexUser.Name, patch["status"].(expirePatch).Groups)
-		input.PatchCollector.MergePatch(patch, "deckhouse.io/v1", "User", "", dexUser.Name, object_patch.WithSubresource("/status"))
+		users = append(users, DexUserInternalValues{
+			Name:              dexUser.Name,
+			EncodedName:       encodedName,
+			Spec:              dexUser.Spec,
+			Status:            dexUser.Status,
+			PreviousPasswords: history[:len(history)-1],
+			TOTPSecret:        totpSecret,
+			PasswordExpired:   passwordExpired,
+			ExpireAt:          expireAt,
+		})
+
+		status := userStatusPatch{
+			ExpireAt:          expireAt,
+			Groups:            groups,
+			PasswordChangedAt: passwordChangedAt,
+			PasswordHistory:   history,
+			Lock:              lock,
+		}
+		if loginState.LastLogin != "" {
+			status.LastLogin = loginState.LastLogin
+		}
+
+		input.LogEntry.Infof("Update groups in user status %s. Groups: %v", dexUser.Name, status.Groups)
+		input.PatchCollector.MergePatch(map[string]interface{}{"status": status}, "deckhouse.io/v1", "User", "", dexUser.Name, object_patch.WithSubresource("/status"))
+	}
+
+	// Secrets of deleted users and users with disabled TOTP are not needed anymore
+	for user := range totpSecrets {
+		if !usersWithTOTP[user] {
+			input.PatchCollector.Delete("v1", "Secret", "d8-user-authn", totpSecretName(user))
+		}
 	}
 
 	input.Values.Set("userAuthn.internal.dexUsersCRDs", users)
 	return nil
 }
 
+// userLockPatch returns the status lock of the user. Fields of the previous lock are removed by null values.
+func userLockPatch(loginState DexPasswordLoginState, passwordExpired bool, renew time.Duration, now time.Time) map[string]interface{} {
+	lock := map[string]interface{}{
+		"state":   true,
+		"reason":  nil,
+		"message": nil,
+		"until":   nil,
+	}
+
+	switch {
+	case passwordExpired:
+		lock["reason"] = userLockReasonPasswordExpired
+		lock["message"] = fmt.Sprintf("The password has not been changed for %s", renew)
+	case loginState.LockedUntil != "" && lockedUntilAfter(loginState.LockedUntil, now):
+		lock["reason"] = userLockReasonTooManyFailedAttempts
+		lock["message"] = "Too many failed login attempts"
+		lock["until"] = loginState.LockedUntil
+	case loginState.PolicyViolation:
+		lock["reason"] = userLockReasonPasswordPolicyViolation
+		lock["message"] = "The password does not satisfy the password policy, set a new one"
+	default:
+		lock["state"] = false
+	}
+
+	return lock
+}
+
+func lockedUntilAfter(lockedUntil string, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339, lockedUntil)
+	if err != nil {
+		return false
+	}
+	return t.After(now)
+}
+
+func generateTOTPSecret() (string, error) {
+	// 160 bits as recommended by RFC 4226
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key), nil
+}
+
+func totpSecretName(user string) string {
+	return "user-totp-" + user
+}
+
+func totpSecretObject(user *DexUser, secret string) *v1.Secret {
+	label := url.PathEscape("Deckhouse:" + user.Spec.Email)
+	uri := fmt.Sprintf("otpauth://totp/%s?secret=%s&issuer=Deckhouse", label, secret)
+
+	return &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      totpSecretName(user.Name),
+			Namespace: "d8-user-authn",
+			Labels: map[string]string{
+				"heritage":      "deckhouse",
+				"module":        "user-authn",
+				"app":           "dex",
+				totpSecretLabel: user.Name,
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"secret": []byte(secret),
+			"uri":    []byte(uri),
+		},
+	}
+}
+
+func applyDexPasswordLoginStateFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var password struct {
+		LoginState DexPasswordLoginState `json:"loginState"`
+	}
+	err := sdk.FromUnstructured(obj, &password)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert kubernetes object: %v", err)
+	}
+
+	password.LoginState.Name = obj.GetName()
+	return password.LoginState, nil
+}
+
+func applyDexUserTOTPSecretFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	secret, _, err := unstructured.NestedString(obj.Object, "data", "secret")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get TOTP secret: %v", err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode TOTP secret: %v", err)
+	}
+
+	return DexUserTOTPSecret{User: obj.GetLabels()[totpSecretLabel], Secret: string(decoded)}, nil
+}
+
 func applyDexGroupFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
 	var group = &DexGroup{}
 	err := sdk.FromUnstructured(obj, group)
@@ -17,6 +17,7 @@ limitations under the License.
 package hooks
 
 import (
+	"encoding/base64"
 	"time"
 
 	. "github.com/benjamintf1/unmarshalledmatchers"
@@ -30,6 +31,7 @@ var _ = Describe("User Authn hooks :: get dex user crds ::", func() {
 	f := HookExecutionConfigInit(`{"userAuthn":{"internal": {}}}`, "")
 	f.RegisterCRD("deckhouse.io", "v1", "User", false)
 	f.RegisterCRD("deckhouse.io", "v1alpha1", "Group", false)
+	f.RegisterCRD("dex.coreos.com", "v1", "Password", true)
 
 	Context("Fresh cluster", func() {
 		BeforeEach(func() {
@@ -511,3 +513,256 @@ spec:
 	})
 
 })
+
+var _ = Describe("User Authn hooks :: get dex user crds :: password policy and TOTP ::", func() {
+	f := HookExecutionConfigInit(`{"userAuthn":{"passwordPolicy":{"renew":"720h","passwordHistoryLimit":1},"internal": {}}}`, "")
+	f.RegisterCRD("deckhouse.io", "v1", "User", false)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "Group", false)
+	f.RegisterCRD("dex.coreos.com", "v1", "Password", true)
+
+	Context("Cluster with a new User", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: User
+metadata:
+  name: admin
+spec:
+  email: admin@example.com
+  password: password
+`))
+			f.RunHook()
+		})
+		It("Should start the password history", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			user := f.KubernetesGlobalResource("User", "admin")
+			Expect(user.Field("status.passwordHistory").String()).To(MatchJSON(`["password"]`))
+			Expect(user.Field("status.passwordChangedAt").Time()).Should(BeTemporally("~", time.Now(), time.Minute))
+			Expect(user.Field("status.lock").String()).To(MatchJSON(`{"state": false}`))
+
+			Expect(f.ValuesGet("userAuthn.internal.dexUsersCRDs.0.previousPasswords").Exists()).To(BeFalse())
+		})
+	})
+
+	Context("Cluster with a User with the changed password", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: User
+metadata:
+  name: admin
+spec:
+  email: admin@example.com
+  password: password3
+status:
+  passwordChangedAt: "2020-02-02T22:22:22Z"
+  passwordHistory:
+  - password1
+  - password2
+  lock:
+    state: true
+    reason: PasswordPolicyViolation
+    message: The password does not satisfy the password policy, set a new one
+---
+apiVersion: dex.coreos.com/v1
+kind: Password
+metadata:
+  name: mfsg22loibsxqylnobwgkltdn5w4x4u44scceizf
+  namespace: d8-user-authn
+email: admin@example.com
+loginState:
+  failedAttempts: 0
+  policyViolation: true
+  lastLogin: "2020-02-02T20:00:00Z"
+`))
+			f.RunHook()
+		})
+		It("Should rotate the password history and unlock the user", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			user := f.KubernetesGlobalResource("User", "admin")
+			Expect(user.Field("status.passwordHistory").String()).To(MatchJSON(`["password2", "password3"]`))
+			Expect(user.Field("status.passwordChangedAt").Time()).Should(BeTemporally("~", time.Now(), time.Minute))
+			Expect(user.Field("status.lock").String()).To(MatchJSON(`{"state": false}`))
+			Expect(user.Field("status.lastLogin").String()).To(Equal("2020-02-02T20:00:00Z"))
+
+			Expect(f.ValuesGet("userAuthn.internal.dexUsersCRDs.0.previousPasswords").String()).To(MatchJSON(`["password2"]`))
+			Expect(f.ValuesGet("userAuthn.internal.dexUsersCRDs.0.passwordExpired").Exists()).To(BeFalse())
+
+			password := f.KubernetesResource("Password", "d8-user-authn", "mfsg22loibsxqylnobwgkltdn5w4x4u44scceizf")
+			Expect(password.Field("loginState").Exists()).To(BeFalse())
+		})
+	})
+
+	Context("Cluster with a User with the expired password", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: User
+metadata:
+  name: admin
+spec:
+  email: admin@example.com
+  password: password
+status:
+  passwordChangedAt: "2020-02-02T22:22:22Z"
+  passwordHistory:
+  - password
+`))
+			f.RunHook()
+		})
+		It("Should lock the user", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.KubernetesGlobalResource("User", "admin").Field("status.lock").String()).To(MatchJSON(`
+{
+  "state": true,
+  "reason": "PasswordExpired",
+  "message": "The password has not been changed for 720h0m0s"
+}`))
+			Expect(f.ValuesGet("userAuthn.internal.dexUsersCRDs.0.passwordExpired").Bool()).To(BeTrue())
+		})
+	})
+
+	Context("Cluster with a User locked by Dex", func() {
+		lockedUntil := time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)
+
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: User
+metadata:
+  name: admin
+spec:
+  email: admin@example.com
+  password: password
+status:
+  passwordChangedAt: "` + time.Now().UTC().Format(time.RFC3339) + `"
+  passwordHistory:
+  - password
+---
+apiVersion: dex.coreos.com/v1
+kind: Password
+metadata:
+  name: mfsg22loibsxqylnobwgkltdn5w4x4u44scceizf
+  namespace: d8-user-authn
+email: admin@example.com
+loginState:
+  failedAttempts: 0
+  lockedUntil: "` + lockedUntil + `"
+`))
+			f.RunHook()
+		})
+		It("Should show the lock in the user status", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.KubernetesGlobalResource("User", "admin").Field("status.lock").String()).To(MatchJSON(`
+{
+  "state": true,
+  "reason": "TooManyFailedAttempts",
+  "message": "Too many failed login attempts",
+  "until": "` + lockedUntil + `"
+}`))
+			Expect(f.KubernetesGlobalResource("User", "admin").Field("status.lastLogin").Exists()).To(BeFalse())
+		})
+	})
+
+	Context("Cluster with a User with TOTP enabled", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: User
+metadata:
+  name: admin
+spec:
+  email: admin@example.com
+  password: password
+  totp:
+    enabled: true
+`))
+			f.RunHook()
+		})
+		It("Should generate the TOTP secret", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			secret := f.KubernetesResource("Secret", "d8-user-authn", "user-totp-admin")
+			Expect(secret.Exists()).To(BeTrue())
+			Expect(secret.Field(`metadata.labels.user-authn\.deckhouse\.io/totp-user`).String()).To(Equal("admin"))
+
+			totpSecret := f.ValuesGet("userAuthn.internal.dexUsersCRDs.0.totpSecret").String()
+			Expect(totpSecret).To(HaveLen(32))
+			Expect(secret.Field("data.secret").String()).To(Equal(base64.StdEncoding.EncodeToString([]byte(totpSecret))))
+			Expect(secret.Field("data.uri").String()).To(Equal(base64.StdEncoding.EncodeToString(
+				[]byte("otpauth://totp/Deckhouse:admin@example.com?secret=" + totpSecret + "&issuer=Deckhouse"))))
+		})
+	})
+
+	Context("Cluster with a User with TOTP enabled and the existing TOTP secret", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: User
+metadata:
+  name: admin
+spec:
+  email: admin@example.com
+  password: password
+  totp:
+    enabled: true
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: user-totp-admin
+  namespace: d8-user-authn
+  labels:
+    user-authn.deckhouse.io/totp-user: admin
+data:
+  secret: SkJTV1kzRFBFSFBLM1BYUA== # JBSWY3DPEHPK3PXP
+`))
+			f.RunHook()
+		})
+		It("Should keep the TOTP secret", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("userAuthn.internal.dexUsersCRDs.0.totpSecret").String()).To(Equal("JBSWY3DPEHPK3PXP"))
+		})
+	})
+
+	Context("Cluster with a User with TOTP disabled and the existing TOTP secret", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: User
+metadata:
+  name: admin
+spec:
+  email: admin@example.com
+  password: password
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: user-totp-admin
+  namespace: d8-user-authn
+  labels:
+    user-authn.deckhouse.io/totp-user: admin
+data:
+  secret: SkJTV1kzRFBFSFBLM1BYUA== # JBSWY3DPEHPK3PXP
+`))
+			f.RunHook()
+		})
+		It("Should delete the TOTP secret", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.KubernetesResource("Secret", "d8-user-authn", "user-totp-admin").Exists()).To(BeFalse())
+			Expect(f.ValuesGet("userAuthn.internal.dexUsersCRDs.0.totpSecret").Exists()).To(BeFalse())
+		})
+	})
+})
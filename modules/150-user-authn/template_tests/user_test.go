@@ -93,6 +93,68 @@ var _ = Describe("Module :: user-authn :: helm template :: user", func() {
 			Expect(adminPassword.Field("userID").String()).To(Equal("adminName"))
 			Expect(adminPassword.Field("hash").String()).To(Equal("JDJhJDEwJEUvTWp5ekZpNkdaa3RhOUdIZDh6Q2V1WWlnYkxlblh2MThqa3hPWjZ2aG9Xc0tuYXhOSm91"))
 			Expect(adminPassword.Field("groups").String()).To(MatchJSON(`["Everyone","Admins"]`))
+			Expect(adminPassword.Field("policy").String()).To(MatchJSON(`
+{
+  "minLength": 0,
+  "complexityLevel": "None",
+  "lockoutMaxAttempts": 0,
+  "lockoutDuration": "15m"
+}`))
+			Expect(adminPassword.Field("totpSecret").Exists()).To(BeFalse())
+		})
+	})
+
+	Context("With password policy and TOTP", func() {
+		BeforeEach(func() {
+			hec.ValuesSetFromYaml("userAuthn.passwordPolicy", `
+complexityLevel: Strong
+minLength: 12
+renew: 2160h
+passwordHistoryLimit: 2
+lockout:
+  maxAttempts: 5
+  lockDuration: 30m
+`)
+			hec.ValuesSetFromYaml("userAuthn.internal.dexUsersCRDs", `
+- encodedName: encodedUser
+  name: userName
+  spec:
+    email: user@example.com
+    password: $2a$10$7rxcwh8r2Rcnwc3jDysqhOrbskLBjtx1zvzWaQVPFO78DDAMZHhLC
+  previousPasswords:
+  - $2a$10$E/MjyzFi6GZkta9GHd8zCeuYigbLenXv18jkxOZ6vhoWsKnaxNJou
+  - JDJhJDEwJDdyeGN3aDhyMlJjbndjM2pEeXNxaE9yYnNrTEJqdHgxenZ6V2FRVlBGTzc4RERBTVpIaExD
+  totpSecret: JBSWY3DPEHPK3PXP
+- encodedName: encodedExpired
+  name: expiredName
+  spec:
+    email: expired@example.com
+    password: $2a$10$7rxcwh8r2Rcnwc3jDysqhOrbskLBjtx1zvzWaQVPFO78DDAMZHhLC
+  passwordExpired: true
+`)
+			hec.HelmRender()
+		})
+		It("Should render the policy and the TOTP secret", func() {
+			Expect(hec.RenderError).ShouldNot(HaveOccurred())
+
+			userPassword := hec.KubernetesResource("Password", "d8-user-authn", "encodedUser")
+			Expect(userPassword.Exists()).To(BeTrue())
+			Expect(userPassword.Field("policy").String()).To(MatchJSON(`
+{
+  "minLength": 12,
+  "complexityLevel": "Strong",
+  "lockoutMaxAttempts": 5,
+  "lockoutDuration": "30m",
+  "previousHashes": [
+    "JDJhJDEwJEUvTWp5ekZpNkdaa3RhOUdIZDh6Q2V1WWlnYkxlblh2MThqa3hPWjZ2aG9Xc0tuYXhOSm91",
+    "JDJhJDEwJDdyeGN3aDhyMlJjbndjM2pEeXNxaE9yYnNrTEJqdHgxenZ6V2FRVlBGTzc4RERBTVpIaExD"
+  ]
+}`))
+			Expect(userPassword.Field("totpSecret").String()).To(Equal("JBSWY3DPEHPK3PXP"))
+		})
+
+		It("Should not render Password objects of users with expired passwords", func() {
+			Expect(hec.KubernetesResource("Password", "d8-user-authn", "encodedExpired").Exists()).To(BeFalse())
 		})
 	})
 })
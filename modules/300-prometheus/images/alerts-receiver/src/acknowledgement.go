@@ -0,0 +1,152 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Sync acknowledgement and silence in CR status with annotations set by operators
+func reconcileAcknowledgement(ctx context.Context, s *storeStruct, cr *ClusterAlert, alert *types.Alert) {
+	annotations := cr.GetAnnotations()
+	owner := annotations[acknowledgedByAnnotation]
+	comment := annotations[commentAnnotation]
+	silenceFor := annotations[silenceForAnnotation]
+
+	ack := cr.Status.Acknowledgement
+	sil := cr.Status.Silence
+	changed := false
+
+	switch {
+	case owner == "" && ack != nil:
+		ack = nil
+		changed = true
+	case owner != "" && (ack == nil || ack.Owner != owner || ack.Comment != comment):
+		ack = &ClusterAlertAcknowledgement{Owner: owner, Comment: comment, Time: v1.Now()}
+		changed = true
+	}
+
+	switch {
+	case silenceFor == "" && sil != nil:
+		err := expireSilence(ctx, s, sil)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		sil = nil
+		changed = true
+
+	case silenceFor != "" && (sil == nil || sil.For != silenceFor):
+		// The annotation is changed, the silence is replaced with a new one
+		if sil != nil {
+			err := expireSilence(ctx, s, sil)
+			if err != nil {
+				log.Error(err)
+				return
+			}
+			sil = nil
+			changed = true
+		}
+
+		if newSil := createSilence(ctx, s, cr.GetName(), alert, silenceFor, owner, comment); newSil != nil {
+			sil = newSil
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	err := s.clusterStore.updateCRAcknowledgement(ctx, cr.GetName(), ack, sil)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func createSilence(ctx context.Context, s *storeStruct, fingerprint string, alert *types.Alert, silenceFor, owner, comment string) *ClusterAlertSilence {
+	if len(s.silencer.alertmanagers) == 0 {
+		log.Warnf("cannot silence alert %s, there are no Alertmanagers in the cluster", fingerprint)
+		return nil
+	}
+
+	duration, err := time.ParseDuration(silenceFor)
+	if err != nil || duration <= 0 {
+		log.Errorf("invalid %s annotation of CR with name %s: %q", silenceForAnnotation, fingerprint, silenceFor)
+		return nil
+	}
+
+	// Alertmanager requires both the author and the comment of a silence
+	if owner == "" {
+		owner = "alerts-receiver"
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("Silenced with ClusterAlert %s", fingerprint)
+	}
+
+	endsAt := time.Now().Add(duration)
+	ids, err := s.silencer.createSilences(ctx, alert.Labels, endsAt, owner, comment)
+	if err != nil {
+		log.Error(err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return &ClusterAlertSilence{For: silenceFor, EndsAt: v1.NewTime(endsAt), IDs: ids}
+}
+
+// Expire the silence if it is still active
+func expireSilence(ctx context.Context, s *storeStruct, sil *ClusterAlertSilence) error {
+	if !sil.EndsAt.After(time.Now()) {
+		return nil
+	}
+	return s.silencer.expireSilences(ctx, sil.IDs)
+}
+
+// Expire silence of the resolved alert, remove its CR and keep it in history
+func resolveAlert(ctx context.Context, s *storeStruct, cr *ClusterAlert) {
+	if sil := cr.Status.Silence; sil != nil {
+		err := expireSilence(ctx, s, sil)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	err := s.clusterStore.removeCR(ctx, cr.GetName())
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	s.history.add(historyEntry{
+		Fingerprint:     cr.GetName(),
+		Name:            cr.Alert.Name,
+		SeverityLevel:   cr.Alert.SeverityLevel,
+		Summary:         cr.Alert.Summary,
+		Labels:          cr.Alert.Labels,
+		StartsAt:        cr.Status.StartsAt.Time,
+		ResolvedAt:      time.Now(),
+		Acknowledgement: cr.Status.Acknowledgement,
+	})
+}
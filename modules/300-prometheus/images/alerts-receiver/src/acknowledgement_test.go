@@ -0,0 +1,252 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const testFingerprint = "abcdef"
+
+var clusterAlertGVR = schema.GroupVersionResource{Group: "deckhouse.io", Version: "v1alpha1", Resource: "clusteralerts"}
+
+// newTestStore returns the store with the ClusterAlert in the fake cluster and the fake Alertmanager.
+func newTestStore(t *testing.T, cr *ClusterAlert) (*storeStruct, *fakeAlertmanager) {
+	t.Helper()
+
+	cr.APIVersion = "deckhouse.io/v1alpha1"
+	cr.Kind = "ClusterAlert"
+	cr.Name = testFingerprint
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{clusterAlertGVR: "ClusterAlertList"},
+		&unstructured.Unstructured{Object: obj},
+	)
+
+	am := newFakeAlertmanager(t)
+
+	return &storeStruct{
+		clusterStore: &clusterStore{dc: dc, GVR: clusterAlertGVR},
+		history:      newHistoryStore(10),
+		silencer:     newTestSilencer(t, am.URL),
+	}, am
+}
+
+func getClusterAlert(t *testing.T, s *storeStruct) *ClusterAlert {
+	t.Helper()
+
+	obj, err := s.clusterStore.dc.Resource(clusterAlertGVR).Get(context.Background(), testFingerprint, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &ClusterAlert{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, cr); err != nil {
+		t.Fatal(err)
+	}
+	return cr
+}
+
+func testAlert() *types.Alert {
+	return &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "NodeNotReady", "node": "worker-0"}}}
+}
+
+func TestReconcileAcknowledgement(t *testing.T) {
+	activeSilence := func(silenceFor string) *ClusterAlertSilence {
+		return &ClusterAlertSilence{
+			For:    silenceFor,
+			EndsAt: v1.NewTime(time.Now().Add(time.Hour)),
+			IDs:    map[string]string{},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		status      ClusterAlertStatus
+
+		wantAck      *ClusterAlertAcknowledgement
+		wantSilence  string
+		wantCreated  int
+		wantExpired  int
+		checkSilence func(t *testing.T, sil *ClusterAlertSilence)
+	}{
+		{
+			name:        "nothing to do",
+			annotations: nil,
+		},
+		{
+			name:        "acknowledge",
+			annotations: map[string]string{acknowledgedByAnnotation: "admin", commentAnnotation: "on it"},
+			wantAck:     &ClusterAlertAcknowledgement{Owner: "admin", Comment: "on it"},
+		},
+		{
+			name:        "remove acknowledgement",
+			annotations: nil,
+			status:      ClusterAlertStatus{Acknowledgement: &ClusterAlertAcknowledgement{Owner: "admin"}},
+			wantAck:     nil,
+		},
+		{
+			name:        "silence",
+			annotations: map[string]string{acknowledgedByAnnotation: "admin", silenceForAnnotation: "2h"},
+			wantAck:     &ClusterAlertAcknowledgement{Owner: "admin"},
+			wantSilence: "2h",
+			wantCreated: 1,
+			checkSilence: func(t *testing.T, sil *ClusterAlertSilence) {
+				if d := time.Until(sil.EndsAt.Time); d < time.Hour+59*time.Minute || d > 2*time.Hour {
+					t.Fatalf("silence should end in 2h, ends in %s", d)
+				}
+			},
+		},
+		{
+			name:        "silence is not changed",
+			annotations: map[string]string{silenceForAnnotation: "2h"},
+			status:      ClusterAlertStatus{Silence: activeSilence("2h")},
+			wantSilence: "2h",
+		},
+		{
+			name:        "silence duration is changed",
+			annotations: map[string]string{silenceForAnnotation: "30m"},
+			status:      ClusterAlertStatus{Silence: activeSilence("2h")},
+			wantSilence: "30m",
+			wantCreated: 1,
+			wantExpired: 1,
+			checkSilence: func(t *testing.T, sil *ClusterAlertSilence) {
+				if d := time.Until(sil.EndsAt.Time); d > 30*time.Minute {
+					t.Fatalf("silence should end in 30m, ends in %s", d)
+				}
+			},
+		},
+		{
+			name:        "silence annotation is changed to an invalid value",
+			annotations: map[string]string{silenceForAnnotation: "forever"},
+			status:      ClusterAlertStatus{Silence: activeSilence("2h")},
+			wantExpired: 1,
+		},
+		{
+			name:        "silence annotation is removed",
+			annotations: nil,
+			status:      ClusterAlertStatus{Silence: activeSilence("2h")},
+			wantExpired: 1,
+		},
+		{
+			name:        "invalid silence annotation",
+			annotations: map[string]string{silenceForAnnotation: "-1h"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &ClusterAlert{Status: tt.status}
+			cr.Annotations = tt.annotations
+
+			s, am := newTestStore(t, cr)
+			if cr.Status.Silence != nil {
+				// the previous silence is created in the fake Alertmanager
+				cr.Status.Silence.IDs[am.URL] = "id-0"
+			}
+
+			reconcileAcknowledgement(context.Background(), s, cr, testAlert())
+
+			created, expired := am.requests()
+			if len(created) != tt.wantCreated {
+				t.Fatalf("got %d created silences, want %d", len(created), tt.wantCreated)
+			}
+			if len(expired) != tt.wantExpired {
+				t.Fatalf("got %d expired silences, want %d", len(expired), tt.wantExpired)
+			}
+			if tt.wantExpired > 0 && expired[0] != "id-0" {
+				t.Fatalf("got expired %v, want the previous silence", expired)
+			}
+
+			status := getClusterAlert(t, s).Status
+
+			ack := status.Acknowledgement
+			switch {
+			case tt.wantAck == nil && ack != nil:
+				t.Fatalf("unexpected acknowledgement %+v", ack)
+			case tt.wantAck != nil && (ack == nil || ack.Owner != tt.wantAck.Owner || ack.Comment != tt.wantAck.Comment):
+				t.Fatalf("got acknowledgement %+v, want %+v", ack, tt.wantAck)
+			}
+
+			sil := status.Silence
+			if tt.wantSilence == "" {
+				if sil != nil {
+					t.Fatalf("unexpected silence %+v", sil)
+				}
+				return
+			}
+			if sil == nil || sil.For != tt.wantSilence {
+				t.Fatalf("got silence %+v, want silence for %s", sil, tt.wantSilence)
+			}
+			if tt.wantCreated > 0 && !reflect.DeepEqual(sil.IDs, map[string]string{am.URL: "id-1"}) {
+				t.Fatalf("got silence ids %v", sil.IDs)
+			}
+			if tt.checkSilence != nil {
+				tt.checkSilence(t, sil)
+			}
+		})
+	}
+}
+
+func TestResolveAlert(t *testing.T) {
+	cr := &ClusterAlert{
+		Alert: ClusterAlertSpec{Name: "NodeNotReady", SeverityLevel: "4", Labels: model.LabelSet{"alertname": "NodeNotReady"}},
+		Status: ClusterAlertStatus{
+			StartsAt:        v1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second)),
+			Acknowledgement: &ClusterAlertAcknowledgement{Owner: "admin"},
+			Silence:         &ClusterAlertSilence{For: "2h", EndsAt: v1.NewTime(time.Now().Add(time.Hour)), IDs: map[string]string{}},
+		},
+	}
+	s, am := newTestStore(t, cr)
+	cr.Status.Silence.IDs[am.URL] = "id-0"
+
+	resolveAlert(context.Background(), s, cr)
+
+	if _, expired := am.requests(); !reflect.DeepEqual(expired, []string{"id-0"}) {
+		t.Fatalf("the silence should be expired, got %v", expired)
+	}
+
+	if _, err := s.clusterStore.dc.Resource(clusterAlertGVR).Get(context.Background(), testFingerprint, v1.GetOptions{}); err == nil {
+		t.Fatal("ClusterAlert should be removed")
+	}
+
+	history := s.history.list(historyFilter{})
+	if len(history) != 1 {
+		t.Fatalf("got %d history entries, want 1", len(history))
+	}
+	e := history[0]
+	if e.Fingerprint != testFingerprint || e.Name != "NodeNotReady" || e.Acknowledgement == nil || !e.StartsAt.Equal(cr.Status.StartsAt.Time) {
+		t.Fatalf("unexpected history entry %+v", e)
+	}
+}
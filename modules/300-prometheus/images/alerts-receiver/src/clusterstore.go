@@ -36,7 +36,7 @@ import (
 )
 
 type clusterStore struct {
-	dc  *dynamic.DynamicClient
+	dc  dynamic.Interface
 	GVR schema.GroupVersionResource
 }
 
@@ -61,7 +61,7 @@ func newClusterStore() *clusterStore {
 	}
 }
 
-func (c *clusterStore) listCRs(rootCtx context.Context) (map[string]struct{}, error) {
+func (c *clusterStore) listCRs(rootCtx context.Context) (map[string]*ClusterAlert, error) {
 	log.Info("list CRs")
 	ctx, cancel := context.WithTimeout(rootCtx, contextTimeout)
 	crList, err := c.dc.Resource(c.GVR).List(ctx, v1.ListOptions{
@@ -73,9 +73,14 @@ func (c *clusterStore) listCRs(rootCtx context.Context) (map[string]struct{}, er
 	if err != nil {
 		return nil, err
 	}
-	res := make(map[string]struct{}, len(crList.Items))
+	res := make(map[string]*ClusterAlert, len(crList.Items))
 	for _, item := range crList.Items {
-		res[item.GetName()] = struct{}{}
+		cr := &ClusterAlert{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, cr)
+		if err != nil {
+			return nil, err
+		}
+		res[item.GetName()] = cr
 	}
 	log.Infof("found %d CRs in cluster", len(crList.Items))
 	return res, nil
@@ -176,6 +181,26 @@ func (c *clusterStore) updateCRStatus(rootCtx context.Context, fingerprint strin
 	return err
 }
 
+// Update acknowledgement and silence in CR status, nil values are removed from status
+func (c *clusterStore) updateCRAcknowledgement(rootCtx context.Context, fingerprint string, ack *ClusterAlertAcknowledgement, silence *ClusterAlertSilence) error {
+	log.Infof("update acknowledgement of CR with name %s", fingerprint)
+
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"acknowledgement": ack,
+			"silence":         silence,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(rootCtx, contextTimeout)
+	_, err = c.dc.Resource(c.GVR).Patch(ctx, fingerprint, t.MergePatchType, data, v1.PatchOptions{}, "/status")
+	cancel()
+	return err
+}
+
 // Return label by key as string
 func getLabel(labels model.LabelSet, key string) string {
 	return string(labels[model.LabelName(key)])
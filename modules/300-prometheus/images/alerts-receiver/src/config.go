@@ -19,6 +19,7 @@ package main
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -35,10 +36,12 @@ const (
 )
 
 type config struct {
-	listenHost string
-	listenPort string
-	capacity   int
-	logLevel   log.Level
+	listenHost      string
+	listenPort      string
+	capacity        int
+	historyCapacity int
+	alertmanagers   []string
+	logLevel        log.Level
 }
 
 func newConfig() *config {
@@ -65,6 +68,25 @@ func newConfig() *config {
 		c.capacity = l
 	}
 
+	h := os.Getenv("ALERTS_HISTORY_LENGTH")
+	if h == "" {
+		c.historyCapacity = 1000
+	} else {
+		l, err := strconv.Atoi(h)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		c.historyCapacity = l
+	}
+
+	// Comma-separated addresses of Alertmanagers to create silences in
+	for _, am := range strings.Split(os.Getenv("ALERTMANAGERS"), ",") {
+		if am = strings.TrimSpace(am); am != "" {
+			c.alertmanagers = append(c.alertmanagers, strings.TrimSuffix(am, "/"))
+		}
+	}
+
 	c.logLevel = log.InfoLevel
 	if d := os.Getenv("DEBUG"); d == "YES" {
 		c.logLevel = log.DebugLevel
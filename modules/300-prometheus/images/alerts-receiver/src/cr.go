@@ -26,6 +26,13 @@ const (
 	clusterAlertFiringStaled = "firing (stale)"
 )
 
+// Annotations set by operators on ClusterAlerts
+const (
+	acknowledgedByAnnotation = "alerts.deckhouse.io/acknowledged-by"
+	commentAnnotation        = "alerts.deckhouse.io/comment"
+	silenceForAnnotation     = "alerts.deckhouse.io/silence-for"
+)
+
 type ClusterAlert struct {
 	v1.TypeMeta   `json:",inline"`
 	v1.ObjectMeta `json:"metadata,omitempty"`
@@ -35,9 +42,25 @@ type ClusterAlert struct {
 }
 
 type ClusterAlertStatus struct {
-	AlertStatus    string  `json:"alertStatus,omitempty"`
-	StartsAt       v1.Time `json:"startsAt,omitempty"`
-	LastUpdateTime v1.Time `json:"lastUpdateTime,omitempty"`
+	AlertStatus     string                       `json:"alertStatus,omitempty"`
+	StartsAt        v1.Time                      `json:"startsAt,omitempty"`
+	LastUpdateTime  v1.Time                      `json:"lastUpdateTime,omitempty"`
+	Acknowledgement *ClusterAlertAcknowledgement `json:"acknowledgement,omitempty"`
+	Silence         *ClusterAlertSilence         `json:"silence,omitempty"`
+}
+
+type ClusterAlertAcknowledgement struct {
+	Owner   string  `json:"owner"`
+	Comment string  `json:"comment,omitempty"`
+	Time    v1.Time `json:"time"`
+}
+
+type ClusterAlertSilence struct {
+	// Value of the silence-for annotation the silence is created for
+	For    string  `json:"for,omitempty"`
+	EndsAt v1.Time `json:"endsAt"`
+	// Silence IDs by Alertmanager address
+	IDs map[string]string `json:"ids,omitempty"`
 }
 
 type ClusterAlertSpec struct {
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+type historyEntry struct {
+	Fingerprint     string                       `json:"fingerprint"`
+	Name            string                       `json:"name"`
+	SeverityLevel   string                       `json:"severityLevel,omitempty"`
+	Summary         string                       `json:"summary,omitempty"`
+	Labels          model.LabelSet               `json:"labels"`
+	StartsAt        time.Time                    `json:"startsAt"`
+	ResolvedAt      time.Time                    `json:"resolvedAt"`
+	Acknowledgement *ClusterAlertAcknowledgement `json:"acknowledgement,omitempty"`
+}
+
+type historyFilter struct {
+	name          string
+	severityLevel string
+	since         time.Time
+	limit         int
+}
+
+func (f historyFilter) match(e *historyEntry) bool {
+	if f.name != "" && e.Name != f.name {
+		return false
+	}
+	if f.severityLevel != "" && e.SeverityLevel != f.severityLevel {
+		return false
+	}
+	return e.ResolvedAt.After(f.since)
+}
+
+// historyStore keeps the last resolved alerts, the oldest ones are evicted when the capacity is reached
+type historyStore struct {
+	entries []historyEntry
+	next    int
+	full    bool
+	sync.RWMutex
+}
+
+func newHistoryStore(l int) *historyStore {
+	return &historyStore{entries: make([]historyEntry, l)}
+}
+
+// Add resolved alert to history
+func (h *historyStore) add(e historyEntry) {
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.entries) == 0 {
+		return
+	}
+
+	h.entries[h.next] = e
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// List resolved alerts matching the filter, most recently resolved first
+func (h *historyStore) list(f historyFilter) []historyEntry {
+	h.RLock()
+	defer h.RUnlock()
+
+	size := h.next
+	if h.full {
+		size = len(h.entries)
+	}
+
+	res := make([]historyEntry, 0)
+	for i := 1; i <= size; i++ {
+		e := &h.entries[(h.next-i+len(h.entries))%len(h.entries)]
+		if !f.match(e) {
+			continue
+		}
+		res = append(res, *e)
+		if f.limit > 0 && len(res) == f.limit {
+			break
+		}
+	}
+	return res
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fingerprints(entries []historyEntry) []string {
+	res := make([]string, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e.Fingerprint)
+	}
+	return res
+}
+
+func TestHistoryStoreEviction(t *testing.T) {
+	now := time.Now()
+	h := newHistoryStore(3)
+
+	if got := h.list(historyFilter{}); len(got) != 0 {
+		t.Fatalf("empty history returned %v", fingerprints(got))
+	}
+
+	for i, fp := range []string{"a", "b"} {
+		h.add(historyEntry{Fingerprint: fp, ResolvedAt: now.Add(time.Duration(i) * time.Minute)})
+	}
+	if got := fingerprints(h.list(historyFilter{})); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Fatalf("not full history: got %v", got)
+	}
+
+	for i, fp := range []string{"c", "d", "e"} {
+		h.add(historyEntry{Fingerprint: fp, ResolvedAt: now.Add(time.Duration(i+2) * time.Minute)})
+	}
+	if got := fingerprints(h.list(historyFilter{})); !reflect.DeepEqual(got, []string{"e", "d", "c"}) {
+		t.Fatalf("the oldest entries should be evicted: got %v", got)
+	}
+}
+
+func TestHistoryStoreZeroCapacity(t *testing.T) {
+	h := newHistoryStore(0)
+	h.add(historyEntry{Fingerprint: "a", ResolvedAt: time.Now()})
+
+	if got := h.list(historyFilter{}); len(got) != 0 {
+		t.Fatalf("history with zero capacity returned %v", fingerprints(got))
+	}
+}
+
+func TestHistoryStoreFilter(t *testing.T) {
+	now := time.Now()
+	h := newHistoryStore(10)
+	h.add(historyEntry{Fingerprint: "1", Name: "NodeNotReady", SeverityLevel: "4", ResolvedAt: now.Add(-3 * time.Hour)})
+	h.add(historyEntry{Fingerprint: "2", Name: "DiskFull", SeverityLevel: "3", ResolvedAt: now.Add(-2 * time.Hour)})
+	h.add(historyEntry{Fingerprint: "3", Name: "NodeNotReady", SeverityLevel: "3", ResolvedAt: now.Add(-time.Hour)})
+	h.add(historyEntry{Fingerprint: "4", Name: "NodeNotReady", SeverityLevel: "4", ResolvedAt: now})
+
+	tests := []struct {
+		name   string
+		filter historyFilter
+		want   []string
+	}{
+		{name: "no filter", filter: historyFilter{}, want: []string{"4", "3", "2", "1"}},
+		{name: "by name", filter: historyFilter{name: "NodeNotReady"}, want: []string{"4", "3", "1"}},
+		{name: "by severity level", filter: historyFilter{severityLevel: "3"}, want: []string{"3", "2"}},
+		{name: "by name and severity level", filter: historyFilter{name: "NodeNotReady", severityLevel: "4"}, want: []string{"4", "1"}},
+		{name: "since", filter: historyFilter{since: now.Add(-90 * time.Minute)}, want: []string{"4", "3"}},
+		{name: "limit", filter: historyFilter{limit: 2}, want: []string{"4", "3"}},
+		{name: "limit with filter", filter: historyFilter{name: "NodeNotReady", limit: 2}, want: []string{"4", "3"}},
+		{name: "no matches", filter: historyFilter{name: "Unknown"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fingerprints(h.list(tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -31,7 +31,7 @@ func main() {
 	log.SetFormatter(&log.JSONFormatter{})
 
 	config := newConfig()
-	store := newStore(config.capacity)
+	store := newStore(config)
 
 	log.SetLevel(config.logLevel)
 
@@ -85,7 +85,7 @@ func reconcileLoop(ctx context.Context, s *storeStruct) {
 func reconcile(ctx context.Context, s *storeStruct) {
 	log.Info("starting reconcile")
 
-	crSet, err := s.clusterStore.listCRs(ctx)
+	crs, err := s.clusterStore.listCRs(ctx)
 	if err != nil {
 		log.Error(err)
 		return
@@ -102,7 +102,8 @@ func reconcile(ctx context.Context, s *storeStruct) {
 		alertSet[fingerprint.String()] = struct{}{}
 
 		// is alerts CR does not exist in cluster, insert CR
-		if _, ok := crSet[fingerprint.String()]; !ok {
+		cr, ok := crs[fingerprint.String()]
+		if !ok {
 			err := s.clusterStore.createCR(ctx, fingerprint.String(), alert)
 			if err != nil {
 				log.Error(err)
@@ -114,15 +115,16 @@ func reconcile(ctx context.Context, s *storeStruct) {
 		if err != nil {
 			log.Error(err)
 		}
+
+		if ok {
+			reconcileAcknowledgement(ctx, s, cr, alert)
+		}
 	}
 
 	// Remove CRs which do not have corresponding alerts
-	for k := range crSet {
+	for k, cr := range crs {
 		if _, ok := alertSet[k]; !ok {
-			err := s.clusterStore.removeCR(ctx, k)
-			if err != nil {
-				log.Error(err)
-			}
+			resolveAlert(ctx, s, cr)
 		}
 	}
 }
@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"net"
 	"net/http"
+	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/common/model"
 	log "github.com/sirupsen/logrus"
@@ -56,6 +58,7 @@ func (s *server) setHandlers(config *config, store *storeStruct) {
 	http.HandleFunc("/readyz", s.readinessHandler)
 	http.HandleFunc("/api/v1/alerts", s.alertsHandler(config, store))
 	http.HandleFunc("/api/v2/alerts", s.alertsHandler(config, store))
+	http.HandleFunc("/api/v1/history", s.historyHandler(store))
 }
 
 func (s *server) setReadiness(ready bool) {
@@ -94,3 +97,46 @@ func (s *server) alertsHandler(config *config, store *storeStruct) func(w http.R
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// Resolved alerts, filtered by query parameters:
+//   - alertname — alert name;
+//   - severity_level — alert severity level;
+//   - since — only alerts resolved during this duration, e.g., 24h;
+//   - limit — maximum number of alerts.
+func (s *server) historyHandler(store *storeStruct) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := historyFilter{
+			name:          query.Get("alertname"),
+			severityLevel: query.Get("severity_level"),
+		}
+
+		if since := query.Get("since"); since != "" {
+			d, err := time.ParseDuration(since)
+			if err != nil {
+				http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.since = time.Now().Add(-d)
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			l, err := strconv.Atoi(limit)
+			if err != nil || l < 0 {
+				http.Error(w, "invalid limit: "+limit, http.StatusBadRequest)
+				return
+			}
+			filter.limit = l
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(store.history.list(filter)); err != nil {
+			log.Error(err)
+		}
+	}
+}
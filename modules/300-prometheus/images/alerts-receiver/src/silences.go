@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+type silenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type silence struct {
+	Matchers  []silenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+}
+
+// silencer propagates silences of ClusterAlerts to Alertmanagers deployed in the cluster.
+// Alertmanagers are behind kube-rbac-proxy, requests are authorized with the service account token.
+type silencer struct {
+	alertmanagers []string
+	client        *http.Client
+	tokenPath     string
+}
+
+func newSilencer(alertmanagers []string) *silencer {
+	return &silencer{
+		alertmanagers: alertmanagers,
+		client: &http.Client{
+			Timeout: contextTimeout,
+			Transport: &http.Transport{
+				// kube-rbac-proxy serves a self-signed certificate, Prometheus skips verification the same way
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+		tokenPath: serviceAccountTokenPath,
+	}
+}
+
+// Create the silence matching all labels of the alert in every Alertmanager.
+// Returns silence IDs by Alertmanager address, IDs of successfully created silences are returned even on error.
+func (s *silencer) createSilences(ctx context.Context, labels model.LabelSet, endsAt time.Time, createdBy, comment string) (map[string]string, error) {
+	sil := silence{
+		Matchers:  make([]silenceMatcher, 0, len(labels)),
+		StartsAt:  time.Now(),
+		EndsAt:    endsAt,
+		CreatedBy: createdBy,
+		Comment:   comment,
+	}
+	for k, v := range labels {
+		sil.Matchers = append(sil.Matchers, silenceMatcher{Name: string(k), Value: string(v), IsEqual: true})
+	}
+
+	body, err := json.Marshal(sil)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(s.alertmanagers))
+	var errs []string
+	for _, am := range s.alertmanagers {
+		var res struct {
+			SilenceID string `json:"silenceID"`
+		}
+		err := s.do(ctx, http.MethodPost, am+"/api/v2/silences", body, &res)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		log.Infof("silence %s created in %s", res.SilenceID, am)
+		ids[am] = res.SilenceID
+	}
+
+	if len(errs) > 0 {
+		return ids, fmt.Errorf("create silences: %s", strings.Join(errs, "; "))
+	}
+	return ids, nil
+}
+
+// Expire silences by Alertmanager address
+func (s *silencer) expireSilences(ctx context.Context, ids map[string]string) error {
+	var errs []string
+	for am, id := range ids {
+		err := s.do(ctx, http.MethodDelete, am+"/api/v2/silence/"+id, nil, nil)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		log.Infof("silence %s expired in %s", id, am)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("expire silences: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *silencer) do(rootCtx context.Context, method, url string, body []byte, res interface{}) error {
+	// The token is rotated by kubelet, so it is read on every request
+	token, err := os.ReadFile(s.tokenPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(rootCtx, contextTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Silences are removed from Alertmanager after the retention period
+	if method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: unexpected status %s", method, url, resp.Status)
+	}
+
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
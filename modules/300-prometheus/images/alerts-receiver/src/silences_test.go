@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const fakeToken = "fake-token"
+
+// fakeAlertmanager is a local stand-in for the Alertmanager silences API behind kube-rbac-proxy.
+type fakeAlertmanager struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	created  []silence
+	expired  []string
+	nextID   int
+	fail     bool
+	notFound bool
+}
+
+func newFakeAlertmanager(t *testing.T) *fakeAlertmanager {
+	am := &fakeAlertmanager{}
+	am.Server = httptest.NewServer(http.HandlerFunc(am.serveHTTP))
+	t.Cleanup(am.Close)
+	return am
+}
+
+func (am *fakeAlertmanager) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+fakeToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if am.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/silences":
+		var sil silence
+		if err := json.NewDecoder(r.Body).Decode(&sil); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		am.created = append(am.created, sil)
+		am.nextID++
+		_ = json.NewEncoder(w).Encode(map[string]string{"silenceID": fmt.Sprintf("id-%d", am.nextID)})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+		if am.notFound {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		am.expired = append(am.expired, strings.TrimPrefix(r.URL.Path, "/api/v2/silence/"))
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (am *fakeAlertmanager) setFail(fail bool) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.fail = fail
+}
+
+func (am *fakeAlertmanager) requests() ([]silence, []string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return append([]silence(nil), am.created...), append([]string(nil), am.expired...)
+}
+
+func newTestSilencer(t *testing.T, alertmanagers ...string) *silencer {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte(fakeToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newSilencer(alertmanagers)
+	s.tokenPath = tokenPath
+	return s
+}
+
+func TestSilencerCreateSilences(t *testing.T) {
+	am1, am2 := newFakeAlertmanager(t), newFakeAlertmanager(t)
+	s := newTestSilencer(t, am1.URL, am2.URL)
+
+	endsAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	labels := model.LabelSet{"alertname": "NodeNotReady", "node": "worker-0"}
+
+	ids, err := s.createSilences(context.Background(), labels, endsAt, "admin", "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{am1.URL: "id-1", am2.URL: "id-1"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got ids %v, want %v", ids, want)
+	}
+
+	created, _ := am1.requests()
+	if len(created) != 1 {
+		t.Fatalf("got %d silences, want 1", len(created))
+	}
+	sil := created[0]
+	if sil.CreatedBy != "admin" || sil.Comment != "maintenance" || !sil.EndsAt.Equal(endsAt) {
+		t.Fatalf("unexpected silence %+v", sil)
+	}
+	matchers := make(map[string]string, len(sil.Matchers))
+	for _, m := range sil.Matchers {
+		if !m.IsEqual || m.IsRegex {
+			t.Fatalf("matcher %+v should be an equality matcher", m)
+		}
+		matchers[m.Name] = m.Value
+	}
+	if want := map[string]string{"alertname": "NodeNotReady", "node": "worker-0"}; !reflect.DeepEqual(matchers, want) {
+		t.Fatalf("got matchers %v, want %v", matchers, want)
+	}
+}
+
+func TestSilencerCreateSilencesPartialFailure(t *testing.T) {
+	ok, failing := newFakeAlertmanager(t), newFakeAlertmanager(t)
+	failing.fail = true
+	s := newTestSilencer(t, ok.URL, failing.URL)
+
+	ids, err := s.createSilences(context.Background(), model.LabelSet{"alertname": "A"}, time.Now().Add(time.Hour), "admin", "comment")
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 500") {
+		t.Fatalf("expected an error about the failing Alertmanager, got %v", err)
+	}
+	if want := map[string]string{ok.URL: "id-1"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ids of created silences should be returned: got %v, want %v", ids, want)
+	}
+}
+
+func TestSilencerExpireSilences(t *testing.T) {
+	am, gone := newFakeAlertmanager(t), newFakeAlertmanager(t)
+	gone.notFound = true
+	s := newTestSilencer(t, am.URL, gone.URL)
+
+	err := s.expireSilences(context.Background(), map[string]string{am.URL: "id-1", gone.URL: "id-2"})
+	if err != nil {
+		t.Fatalf("silences removed after the retention period should be ignored: %v", err)
+	}
+	if _, expired := am.requests(); !reflect.DeepEqual(expired, []string{"id-1"}) {
+		t.Fatalf("got expired %v", expired)
+	}
+
+	am.setFail(true)
+	if err := s.expireSilences(context.Background(), map[string]string{am.URL: "id-1"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSilencerReadsToken(t *testing.T) {
+	am := newFakeAlertmanager(t)
+	s := newTestSilencer(t, am.URL)
+	s.tokenPath = filepath.Join(t.TempDir(), "missing")
+
+	if _, err := s.createSilences(context.Background(), model.LabelSet{"alertname": "A"}, time.Now().Add(time.Hour), "admin", "comment"); err == nil {
+		t.Fatal("expected an error without the service account token")
+	}
+}
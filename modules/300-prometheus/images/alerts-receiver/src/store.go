@@ -19,11 +19,15 @@ package main
 type storeStruct struct {
 	memStore     *memStore
 	clusterStore *clusterStore
+	history      *historyStore
+	silencer     *silencer
 }
 
-func newStore(l int) *storeStruct {
+func newStore(c *config) *storeStruct {
 	return &storeStruct{
-		memStore:     newMemStore(l),
+		memStore:     newMemStore(c.capacity),
 		clusterStore: newClusterStore(),
+		history:      newHistoryStore(c.historyCapacity),
+		silencer:     newSilencer(c.alertmanagers),
 	}
 }
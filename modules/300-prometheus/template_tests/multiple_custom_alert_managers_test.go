@@ -194,5 +194,14 @@ spec:
 				Expect(resource.ToYaml()).To(MatchYAML(test.expectedYaml))
 			}
 		})
+
+		It("alerts-receiver must create silences in internal Alertmanagers", func() {
+			Expect(f.RenderError).ShouldNot(HaveOccurred())
+
+			deployment := f.KubernetesResource("Deployment", "d8-monitoring", "alerts-receiver")
+			Expect(deployment.Exists()).To(BeTrue())
+			Expect(deployment.Field(`spec.template.spec.containers.0.env.#(name=="ALERTMANAGERS").value`).String()).To(Equal(
+				"https://test-alert-emailer.d8-monitoring.svc:9093,https://airflow-alert-emailer.d8-monitoring.svc:9093"))
+		})
 	})
 })
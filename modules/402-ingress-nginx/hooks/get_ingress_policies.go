@@ -0,0 +1,334 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// IngressNginxPolicy resources are merged per controller here and rendered to the controller ConfigMap by templates.
+
+const policyMetricsGroup = "ingress_nginx_policies"
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	OnBeforeHelm: &go_hook.OrderedConfig{Order: 10},
+	Queue:        "/modules/ingress-nginx",
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "controllers",
+			ApiVersion: "deckhouse.io/v1",
+			Kind:       "IngressNginxController",
+			FilterFunc: applyPolicyControllerFilter,
+		},
+		{
+			Name:       "policies",
+			ApiVersion: "deckhouse.io/v1alpha1",
+			Kind:       "IngressNginxPolicy",
+			FilterFunc: applyIngressPolicyFilter,
+		},
+	},
+}, setPolicyValues)
+
+type policyController struct {
+	Name         string
+	IngressClass string
+}
+
+type policyRateLimit struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	Header string `json:"header"`
+	Rate   string `json:"rate"`
+	Burst  int    `json:"burst"`
+}
+
+type policyWAF struct {
+	Mode             string `json:"mode"`
+	OWASPCoreRuleSet *bool  `json:"owaspCoreRuleSet"`
+	ParanoiaLevel    int    `json:"paranoiaLevel"`
+	CustomRules      string `json:"customRules"`
+}
+
+type ingressPolicySpec struct {
+	Controllers    []string          `json:"controllers"`
+	IngressClasses []string          `json:"ingressClasses"`
+	RateLimits     []policyRateLimit `json:"rateLimits"`
+	IPAllowList    []string          `json:"ipAllowList"`
+	IPDenyList     []string          `json:"ipDenyList"`
+	WAF            *policyWAF        `json:"waf"`
+}
+
+type ingressPolicy struct {
+	Name  string
+	Spec  ingressPolicySpec
+	Error string
+}
+
+// controllerPolicies is the rendered form of all the policies attached to a controller.
+type controllerPolicies struct {
+	ControllerName    string              `json:"controllerName"`
+	Policies          []string            `json:"policies"`
+	RateLimits        []renderedLimit     `json:"rateLimits"`
+	RateLimitPolicies []string            `json:"rateLimitPolicies"`
+	IPDenyList        []renderedIPRule    `json:"ipDenyList"`
+	IPAllowList       []string            `json:"ipAllowList"`
+	IPAllowPolicies   []string            `json:"ipAllowPolicies"`
+	WAF               *renderedWAF        `json:"waf,omitempty"`
+	seenNetworks      map[string]struct{} `json:"-"`
+}
+
+type renderedLimit struct {
+	Zone  string `json:"zone"`
+	Key   string `json:"key"`
+	Rate  string `json:"rate"`
+	Burst int    `json:"burst"`
+}
+
+type renderedIPRule struct {
+	CIDR   string `json:"cidr"`
+	Policy string `json:"policy"`
+}
+
+type renderedWAF struct {
+	Mode             string          `json:"mode"`
+	OWASPCoreRuleSet bool            `json:"owaspCoreRuleSet"`
+	ParanoiaLevel    int             `json:"paranoiaLevel"`
+	BlockingPolicies []string        `json:"blockingPolicies"`
+	CustomRules      []renderedRules `json:"customRules"`
+}
+
+type renderedRules struct {
+	Policy string `json:"policy"`
+	Rules  string `json:"rules"`
+}
+
+func applyPolicyControllerFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	ingressClass, _, err := unstructured.NestedString(obj.Object, "spec", "ingressClass")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get ingressClass from ingress controller %s: %v", obj.GetName(), err)
+	}
+	if ingressClass == "" {
+		ingressClass = "nginx"
+	}
+
+	return policyController{Name: obj.GetName(), IngressClass: ingressClass}, nil
+}
+
+func applyIngressPolicyFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	policy := ingressPolicy{Name: obj.GetName()}
+
+	// A broken policy must not break the whole hook, it is reported by a metric instead.
+	var parsed struct {
+		Spec ingressPolicySpec `json:"spec"`
+	}
+	if err := sdk.FromUnstructured(obj, &parsed); err != nil {
+		policy.Error = fmt.Sprintf("cannot parse spec: %v", err)
+		return policy, nil
+	}
+	policy.Spec = parsed.Spec
+
+	if err := validateIngressPolicy(policy.Spec); err != nil {
+		policy.Error = err.Error()
+	}
+
+	return policy, nil
+}
+
+func validateIngressPolicy(spec ingressPolicySpec) error {
+	if len(spec.Controllers) == 0 && len(spec.IngressClasses) == 0 {
+		return fmt.Errorf("neither controllers nor ingressClasses are set")
+	}
+
+	limitNames := make(map[string]struct{}, len(spec.RateLimits))
+	for _, limit := range spec.RateLimits {
+		if _, ok := limitNames[limit.Name]; ok {
+			return fmt.Errorf("rate limit %q is defined more than once", limit.Name)
+		}
+		limitNames[limit.Name] = struct{}{}
+
+		if limit.Key == "Header" && limit.Header == "" {
+			return fmt.Errorf("rate limit %q: header is not set", limit.Name)
+		}
+	}
+
+	for _, cidr := range append(append([]string{}, spec.IPAllowList...), spec.IPDenyList...) {
+		if !isValidCIDR(cidr) {
+			return fmt.Errorf("%q is not a valid IP address or CIDR", cidr)
+		}
+	}
+
+	if spec.WAF != nil {
+		if strings.Contains(spec.WAF.CustomRules, "'") {
+			return fmt.Errorf("waf custom rules must not contain single quotes")
+		}
+		for _, line := range strings.Split(spec.WAF.CustomRules, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			if directive := strings.ToLower(fields[0]); directive == "include" || directive == "secruleengine" {
+				return fmt.Errorf("waf custom rules must not contain the %s directive", fields[0])
+			}
+		}
+	}
+
+	return nil
+}
+
+func isValidCIDR(s string) bool {
+	if strings.Contains(s, "/") {
+		_, _, err := net.ParseCIDR(s)
+		return err == nil
+	}
+	return net.ParseIP(s) != nil
+}
+
+// rateLimitKey returns the nginx variable requests are counted by.
+func rateLimitKey(limit policyRateLimit) string {
+	if limit.Key == "Header" {
+		return "$http_" + strings.ReplaceAll(strings.ToLower(limit.Header), "-", "_")
+	}
+	return "$binary_remote_addr"
+}
+
+func (c *controllerPolicies) add(name string, spec ingressPolicySpec) {
+	c.Policies = append(c.Policies, name)
+
+	for _, limit := range spec.RateLimits {
+		c.RateLimits = append(c.RateLimits, renderedLimit{
+			// Limit names can't contain dots, so zone names are unique.
+			Zone:  fmt.Sprintf("d8-policy.%s.%s", name, limit.Name),
+			Key:   rateLimitKey(limit),
+			Rate:  limit.Rate,
+			Burst: limit.Burst,
+		})
+	}
+	if len(spec.RateLimits) > 0 {
+		c.RateLimitPolicies = append(c.RateLimitPolicies, name)
+	}
+
+	for _, cidr := range spec.IPDenyList {
+		// The same network in several policies is attributed to the first one.
+		if _, ok := c.seenNetworks["deny/"+cidr]; ok {
+			continue
+		}
+		c.seenNetworks["deny/"+cidr] = struct{}{}
+		c.IPDenyList = append(c.IPDenyList, renderedIPRule{CIDR: cidr, Policy: name})
+	}
+
+	for _, cidr := range spec.IPAllowList {
+		if _, ok := c.seenNetworks["allow/"+cidr]; ok {
+			continue
+		}
+		c.seenNetworks["allow/"+cidr] = struct{}{}
+		c.IPAllowList = append(c.IPAllowList, cidr)
+	}
+	if len(spec.IPAllowList) > 0 {
+		c.IPAllowPolicies = append(c.IPAllowPolicies, name)
+	}
+
+	if spec.WAF == nil {
+		return
+	}
+	if c.WAF == nil {
+		c.WAF = &renderedWAF{Mode: "DetectionOnly", ParanoiaLevel: 1, BlockingPolicies: []string{}, CustomRules: []renderedRules{}}
+	}
+	if spec.WAF.Mode == "On" {
+		c.WAF.Mode = "On"
+		c.WAF.BlockingPolicies = append(c.WAF.BlockingPolicies, name)
+	}
+	if spec.WAF.OWASPCoreRuleSet == nil || *spec.WAF.OWASPCoreRuleSet {
+		c.WAF.OWASPCoreRuleSet = true
+	}
+	if spec.WAF.ParanoiaLevel > c.WAF.ParanoiaLevel {
+		c.WAF.ParanoiaLevel = spec.WAF.ParanoiaLevel
+	}
+	if rules := strings.TrimSpace(spec.WAF.CustomRules); rules != "" {
+		c.WAF.CustomRules = append(c.WAF.CustomRules, renderedRules{Policy: name, Rules: rules})
+	}
+}
+
+func setPolicyValues(input *go_hook.HookInput) error {
+	input.MetricsCollector.Expire(policyMetricsGroup)
+
+	policies := make([]ingressPolicy, 0, len(input.Snapshots["policies"]))
+	for _, snap := range input.Snapshots["policies"] {
+		policy := snap.(ingressPolicy)
+		if policy.Error != "" {
+			input.LogEntry.Warnf("IngressNginxPolicy %q is invalid and skipped: %s", policy.Name, policy.Error)
+			input.MetricsCollector.Set("d8_ingress_nginx_policy_invalid", 1, map[string]string{
+				"policy": policy.Name,
+				"error":  policy.Error,
+			}, metrics.WithGroup(policyMetricsGroup))
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+
+	controllers := make([]policyController, 0, len(input.Snapshots["controllers"]))
+	for _, snap := range input.Snapshots["controllers"] {
+		controllers = append(controllers, snap.(policyController))
+	}
+	sort.Slice(controllers, func(i, j int) bool { return controllers[i].Name < controllers[j].Name })
+
+	result := make([]controllerPolicies, 0)
+	for _, controller := range controllers {
+		attached := controllerPolicies{
+			ControllerName:    controller.Name,
+			Policies:          []string{},
+			RateLimits:        []renderedLimit{},
+			RateLimitPolicies: []string{},
+			IPDenyList:        []renderedIPRule{},
+			IPAllowList:       []string{},
+			IPAllowPolicies:   []string{},
+			seenNetworks:      make(map[string]struct{}),
+		}
+
+		for _, policy := range policies {
+			if !contains(policy.Spec.Controllers, controller.Name) && !contains(policy.Spec.IngressClasses, controller.IngressClass) {
+				continue
+			}
+			attached.add(policy.Name, policy.Spec)
+		}
+
+		if len(attached.Policies) > 0 {
+			result = append(result, attached)
+		}
+	}
+
+	input.Values.Set("ingressNginx.internal.policies", result)
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+var _ = Describe("ingress-nginx :: hooks :: get_ingress_policies ::", func() {
+	f := HookExecutionConfigInit(`{"ingressNginx":{"defaultControllerVersion": "1.1", "internal": {}}}`, "")
+	f.RegisterCRD("deckhouse.io", "v1", "IngressNginxController", false)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "IngressNginxPolicy", false)
+
+	Context("Fresh cluster", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(""))
+			f.RunHook()
+		})
+
+		It("Should set empty policies", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("ingressNginx.internal.policies").String()).To(MatchJSON(`[]`))
+		})
+	})
+
+	Context("With controllers and policies", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: IngressNginxController
+metadata:
+  name: main
+spec:
+  inlet: LoadBalancer
+---
+apiVersion: deckhouse.io/v1
+kind: IngressNginxController
+metadata:
+  name: internal
+spec:
+  ingressClass: internal
+  inlet: HostPort
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IngressNginxPolicy
+metadata:
+  name: api
+spec:
+  ingressClasses: [nginx]
+  rateLimits:
+  - name: per-client
+    key: ClientIP
+    rate: 10r/s
+    burst: 20
+  - name: per-token
+    key: Header
+    header: X-Api-Key
+    rate: 600r/m
+  ipDenyList:
+  - 203.0.113.0/24
+  waf:
+    mode: "On"
+    paranoiaLevel: 2
+    customRules: |
+      SecRuleRemoveById 920350
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IngressNginxPolicy
+metadata:
+  name: office
+spec:
+  controllers: [main, internal]
+  ipAllowList:
+  - 10.0.0.0/8
+  - 192.168.1.1
+  ipDenyList:
+  - 203.0.113.0/24
+  waf:
+    mode: DetectionOnly
+    owaspCoreRuleSet: false
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IngressNginxPolicy
+metadata:
+  name: broken
+spec:
+  controllers: [main]
+  ipDenyList:
+  - 10.0.0.300/8
+`))
+			f.RunHook()
+		})
+
+		It("Should merge valid policies per controller", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("ingressNginx.internal.policies").String()).To(MatchJSON(`[
+{
+  "controllerName": "internal",
+  "policies": ["office"],
+  "rateLimits": [],
+  "rateLimitPolicies": [],
+  "ipDenyList": [{"cidr": "203.0.113.0/24", "policy": "office"}],
+  "ipAllowList": ["10.0.0.0/8", "192.168.1.1"],
+  "ipAllowPolicies": ["office"],
+  "waf": {"mode": "DetectionOnly", "owaspCoreRuleSet": false, "paranoiaLevel": 1, "blockingPolicies": [], "customRules": []}
+},
+{
+  "controllerName": "main",
+  "policies": ["api", "office"],
+  "rateLimits": [
+    {"zone": "d8-policy.api.per-client", "key": "$binary_remote_addr", "rate": "10r/s", "burst": 20},
+    {"zone": "d8-policy.api.per-token", "key": "$http_x_api_key", "rate": "600r/m", "burst": 0}
+  ],
+  "rateLimitPolicies": ["api"],
+  "ipDenyList": [{"cidr": "203.0.113.0/24", "policy": "api"}],
+  "ipAllowList": ["10.0.0.0/8", "192.168.1.1"],
+  "ipAllowPolicies": ["office"],
+  "waf": {
+    "mode": "On",
+    "owaspCoreRuleSet": true,
+    "paranoiaLevel": 2,
+    "blockingPolicies": ["api"],
+    "customRules": [{"policy": "api", "rules": "SecRuleRemoveById 920350"}]
+  }
+}
+]`))
+		})
+
+		It("Should report the invalid policy", func() {
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(2))
+			Expect(m[0].Action).To(Equal("expire"))
+			Expect(m[1].Name).To(Equal("d8_ingress_nginx_policy_invalid"))
+			Expect(m[1].Labels).To(Equal(map[string]string{
+				"policy": "broken",
+				"error":  `"10.0.0.300/8" is not a valid IP address or CIDR`,
+			}))
+		})
+	})
+
+	Context("Policy with forbidden WAF directives", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: IngressNginxController
+metadata:
+  name: main
+spec:
+  inlet: LoadBalancer
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IngressNginxPolicy
+metadata:
+  name: waf
+spec:
+  controllers: [main]
+  waf:
+    customRules: |
+      SecRuleEngine Off
+`))
+			f.RunHook()
+		})
+
+		It("Should skip the policy", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("ingressNginx.internal.policies").String()).To(MatchJSON(`[]`))
+		})
+	})
+})
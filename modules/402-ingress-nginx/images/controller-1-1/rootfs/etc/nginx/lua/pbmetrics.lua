@@ -51,6 +51,11 @@ local geohash_encode = GeoHash.encode
 local buffer = new_tab(0, 100000)
 local debug_enabled = get_env("LUA_DEBUG")
 local use_geoip2 = get_env("LUA_USE_GEOIP2")
+local access_events_enabled = get_env("LUA_ACCESS_EVENTS")
+
+-- events are sent once per second, newer events are dropped if there are too many requests
+local _MAX_EVENTS = 10000
+local events = new_tab(_MAX_EVENTS, 0)
 
 -- setup protobuf
 local pb = require "pb"
@@ -86,11 +91,18 @@ message GaugeMessage {
     double Value = 3;
     map<string, string> Annotations = 4;
 }
+
+message EventMessage {
+    double Timestamp = 1;
+    map<string, string> Attributes = 2;
+    map<string, string> Annotations = 3;
+}
 ]])
 
 local _HISTOGRAM_TYPE = 1
 local _GAUGE_TYPE = 2
 local _COUNTER_TYPE = 3
+local _EVENT_TYPE = 4
 
 local function encode_buffer(buf, type, bytes)
   buf:pack("u", type)
@@ -112,6 +124,11 @@ local function protocounter(buf, value)
   encode_buffer(buf, _COUNTER_TYPE, bytes)
 end
 
+local function protoevent(buf, value)
+  local bytes = pb.encode("proto.EventMessage", value)
+  encode_buffer(buf, _EVENT_TYPE, bytes)
+end
+
 local function _extract_labels(line)
   local t = {}
   for token in gmatch(line, "[^#]+") do
@@ -199,6 +216,38 @@ local function _increment_geohash(overall_key, geoip_latitude, geoip_longitude,
   end
 end
 
+-- _policy_hit() returns the IngressNginxPolicy and the kind of its rule that rejected the request.
+-- The variables are set by the location-snippet rendered from the policies attached to the controller.
+local function _policy_hit(var_status, var_upstream_addr)
+  local denied_by = ngx.var.d8_policy_denied_by
+  if denied_by and denied_by ~= "" then
+    return denied_by, "ip-deny"
+  end
+
+  if ngx.var.d8_policy_allowed == "no" then
+    return ngx.var.d8_policy_allow_lists, "ip-allow"
+  end
+
+  local rate_limits = ngx.var.d8_policy_rate_limits
+  if rate_limits and rate_limits ~= "" and ngx.var.limit_req_status == "REJECTED" then
+    return rate_limits, "rate-limit"
+  end
+
+  -- ModSecurity blocks requests before they are proxied, so there is no upstream
+  local waf = ngx.var.d8_policy_waf
+  if waf and waf ~= "" and var_status == "403" and not var_upstream_addr then
+    return waf, "waf"
+  end
+end
+
+-- _add_event() saves the request as an access event to export it to OpenTelemetry
+local function _add_event(annotations, attributes)
+  if #events >= _MAX_EVENTS then
+    return
+  end
+  insert_tab(events, { Timestamp = ngx.req.start_time(), Attributes = attributes, Annotations = annotations })
+end
+
 -- fill_buffer() prepares metrics
 local function fill_buffer()
   local start_time = now()
@@ -365,6 +414,35 @@ local function fill_buffer()
     _increment_geohash(overall_key, ngx.var.geoip_latitude, ngx.var.geoip_longitude, ngx.var.geoip_city, ngx.var.geoip_region_name, ngx.var.geoip_city_country_code, var_annotations)
   end
 
+  -- policy hits
+  local policy, rule = _policy_hit(var_status, var_upstream_addr)
+  if policy then
+    _increment("c22#" .. var_namespace .. "#" .. var_server_name .. "#" .. policy .. "#" .. rule, var_annotations, 22)
+  end
+
+  if access_events_enabled then
+    _add_event(var_annotations, {
+      ["http.request.method"] = var_request_method,
+      ["url.scheme"] = var_scheme,
+      ["server.address"] = ngx.var.host,
+      ["url.path"] = ngx.var.uri,
+      ["http.response.status_code"] = var_status,
+      ["client.address"] = ngx.var.remote_addr,
+      ["user_agent.original"] = ngx.var.http_user_agent,
+      ["nginx.request_id"] = ngx.var.request_id,
+      ["nginx.request_length"] = var_request_length,
+      ["nginx.bytes_sent"] = var_bytes_sent,
+      ["nginx.request_time"] = ngx.var.request_time,
+      ["nginx.upstream_response_time"] = tostring(ngx.var.total_upstream_response_time),
+      ["k8s.namespace.name"] = var_namespace,
+      ["ingress.name"] = var_ingress_name,
+      ["ingress.service"] = var_service_name,
+      ["ingress.service_port"] = var_service_port,
+      ["ingress.location"] = var_location_path,
+      ["traceparent"] = ngx.var.http_traceparent,
+    })
+  end
+
   if debug_enabled then
     update_time()
     log(WARNING, format("lua parse seconds: %s", tostring(now() - start_time)))
@@ -373,7 +451,7 @@ end
 
 -- send() sends buffer data to protobuf exporter via tcp socket
 local function send(premature)
-  if nkeys(buffer) == 0 then
+  if nkeys(buffer) == 0 and #events == 0 then
     return
   end
 
@@ -392,6 +470,11 @@ local function send(premature)
   end
   clear_tab(buffer)
 
+  for _, event in ipairs(events) do
+    protoevent(pbbuff, event)
+  end
+  clear_tab(events)
+
 
   local sock = socket()
   sock:settimeout(10000)
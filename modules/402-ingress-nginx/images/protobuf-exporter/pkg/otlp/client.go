@@ -0,0 +1,219 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// initialBackoff is the delay before the first retry, it doubles after each attempt
+var initialBackoff = time.Second
+
+type client interface {
+	ExportMetrics(ctx context.Context, req *collectormetrics.ExportMetricsServiceRequest) error
+	ExportLogs(ctx context.Context, req *collectorlogs.ExportLogsServiceRequest) error
+	Close() error
+}
+
+// retryableError marks errors after which the request can be sent again, e.g. the collector is overloaded or restarting
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+func isRetryable(err error) bool {
+	var re *retryableError
+	return errors.As(err, &re)
+}
+
+func newClient(config Config) (client, error) {
+	switch config.Protocol {
+	case ProtocolGRPC:
+		return newGRPCClient(config)
+	case ProtocolHTTP:
+		return newHTTPClient(config), nil
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", config.Protocol)
+	}
+}
+
+type grpcClient struct {
+	conn    *grpc.ClientConn
+	metrics collectormetrics.MetricsServiceClient
+	logs    collectorlogs.LogsServiceClient
+	headers metadata.MD
+}
+
+func newGRPCClient(config Config) (*grpcClient, error) {
+	creds := credentials.NewTLS(&tls.Config{})
+	if config.Insecure {
+		creds = insecure.NewCredentials()
+	}
+
+	conn, err := grpc.Dial(config.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %v", config.Endpoint, err)
+	}
+
+	return &grpcClient{
+		conn:    conn,
+		metrics: collectormetrics.NewMetricsServiceClient(conn),
+		logs:    collectorlogs.NewLogsServiceClient(conn),
+		headers: metadata.New(config.Headers),
+	}, nil
+}
+
+func (c *grpcClient) ExportMetrics(ctx context.Context, req *collectormetrics.ExportMetricsServiceRequest) error {
+	_, err := c.metrics.Export(metadata.NewOutgoingContext(ctx, c.headers), req)
+	return grpcError(err)
+}
+
+func (c *grpcClient) ExportLogs(ctx context.Context, req *collectorlogs.ExportLogsServiceRequest) error {
+	_, err := c.logs.Export(metadata.NewOutgoingContext(ctx, c.headers), req)
+	return grpcError(err)
+}
+
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		return &retryableError{err: err}
+	}
+	return err
+}
+
+type httpClient struct {
+	client  *http.Client
+	baseURL string
+	headers map[string]string
+}
+
+func newHTTPClient(config Config) *httpClient {
+	scheme := "https"
+	if config.Insecure {
+		scheme = "http"
+	}
+
+	return &httpClient{
+		client:  &http.Client{Timeout: config.Timeout},
+		baseURL: scheme + "://" + config.Endpoint,
+		headers: config.Headers,
+	}
+}
+
+func (c *httpClient) ExportMetrics(ctx context.Context, req *collectormetrics.ExportMetricsServiceRequest) error {
+	return c.post(ctx, "/v1/metrics", req)
+}
+
+func (c *httpClient) ExportLogs(ctx context.Context, req *collectorlogs.ExportLogsServiceRequest) error {
+	return c.post(ctx, "/v1/logs", req)
+}
+
+func (c *httpClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func (c *httpClient) post(ctx context.Context, path string, message proto.Message) error {
+	body, err := proto.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	// Read the body to reuse the connection
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return &retryableError{err: fmt.Errorf("POST %s: %s", path, resp.Status)}
+	default:
+		return fmt.Errorf("POST %s: %s", path, resp.Status)
+	}
+}
+
+// withRetries calls send until it succeeds, fails with a permanent error or the retries are exhausted
+func withRetries(ctx context.Context, config Config, send func(ctx context.Context) error) error {
+	backoff := initialBackoff
+
+	for attempt := 0; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+		err := send(sendCtx)
+		cancel()
+
+		if err == nil || !isRetryable(err) || attempt >= config.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPClientRetries(t *testing.T) {
+	initialBackoff = time.Millisecond
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var req collectormetrics.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := Config{
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		Protocol: ProtocolHTTP,
+		Insecure: true,
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	}.WithDefaults()
+	c, err := newClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = withRetries(context.Background(), config, func(ctx context.Context) error {
+		return c.ExportMetrics(ctx, &collectormetrics.ExportMetricsServiceRequest{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestHTTPClientPermanentError(t *testing.T) {
+	initialBackoff = time.Millisecond
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	config := Config{Endpoint: strings.TrimPrefix(server.URL, "http://"), Protocol: ProtocolHTTP, Insecure: true}.WithDefaults()
+	c, err := newClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = withRetries(context.Background(), config, func(ctx context.Context) error {
+		return c.ExportLogs(ctx, logsRequest(nil, nil))
+	})
+	if err == nil || isRetryable(err) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("permanent errors should not be retried, got %d attempts", attempts)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{Endpoint: "collector:4317"}).WithDefaults().Validate(); err != nil {
+		t.Fatalf("default config should be valid: %v", err)
+	}
+	if err := (Config{}).WithDefaults().Validate(); err == nil {
+		t.Fatal("config without endpoint should be invalid")
+	}
+	if err := (Config{Endpoint: "collector:4317", Protocol: "udp"}).WithDefaults().Validate(); err == nil {
+		t.Fatal("config with unknown protocol should be invalid")
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"fmt"
+	"time"
+)
+
+type Protocol string
+
+const (
+	ProtocolGRPC Protocol = "grpc"
+	ProtocolHTTP Protocol = "http"
+)
+
+const (
+	defaultInterval   = 15 * time.Second
+	defaultTimeout    = 10 * time.Second
+	defaultBatchSize  = 1000
+	defaultMaxRetries = 5
+
+	// eventsQueueSize limits the number of access events waiting to be sent, newer events are dropped on overflow
+	eventsQueueSize = 10000
+)
+
+// Config describes the OpenTelemetry collector to send metrics and access events to
+type Config struct {
+	// Endpoint is the host:port of the collector, paths /v1/metrics and /v1/logs are used for the HTTP protocol
+	Endpoint string            `yaml:"endpoint"`
+	Protocol Protocol          `yaml:"protocol,omitempty"`
+	Insecure bool              `yaml:"insecure,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+
+	Interval   time.Duration `yaml:"interval,omitempty"`
+	Timeout    time.Duration `yaml:"timeout,omitempty"`
+	BatchSize  int           `yaml:"batchSize,omitempty"`
+	MaxRetries int           `yaml:"maxRetries,omitempty"`
+
+	AccessEvents bool `yaml:"accessEvents,omitempty"`
+}
+
+// WithDefaults returns a copy of the config with empty fields filled with default values
+func (c Config) WithDefaults() Config {
+	if c.Protocol == "" {
+		c.Protocol = ProtocolGRPC
+	}
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	return c
+}
+
+func (c Config) Validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("endpoint is required")
+	}
+	if c.Protocol != ProtocolGRPC && c.Protocol != ProtocolHTTP {
+		return fmt.Errorf("unknown protocol %q, expected %q or %q", c.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+	if c.Interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s, got %s", c.Interval)
+	}
+	if c.BatchSize < 0 || c.MaxRetries < 0 {
+		return fmt.Errorf("batchSize and maxRetries must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/hex"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	mproto "github.com/flant/protobuf_exporter/pkg/proto"
+	"github.com/flant/protobuf_exporter/pkg/vault"
+)
+
+const (
+	scopeName          = "protobuf_exporter"
+	defaultServiceName = "ingress-nginx"
+
+	traceparentAttribute = "traceparent"
+	statusCodeAttribute  = "http.response.status_code"
+)
+
+// intAttributes and doubleAttributes are sent by Lua as strings, they are converted back to numbers
+var (
+	intAttributes = map[string]struct{}{
+		statusCodeAttribute:    {},
+		"nginx.request_length": {},
+		"nginx.bytes_sent":     {},
+	}
+	doubleAttributes = map[string]struct{}{
+		"nginx.request_time":           {},
+		"nginx.upstream_response_time": {},
+	}
+)
+
+// resourceFromEnv builds the resource according to the OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES variables
+func resourceFromEnv(getenv func(string) string) *resourcepb.Resource {
+	attributes := make(map[string]string)
+	for _, pair := range strings.Split(getenv("OTEL_RESOURCE_ATTRIBUTES"), ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = unescaped
+		}
+		attributes[strings.TrimSpace(key)] = value
+	}
+
+	if name := getenv("OTEL_SERVICE_NAME"); name != "" {
+		attributes["service.name"] = name
+	}
+	if _, ok := attributes["service.name"]; !ok {
+		attributes["service.name"] = defaultServiceName
+	}
+
+	return &resourcepb.Resource{Attributes: stringAttributes(attributes)}
+}
+
+// metricsRequests converts stored metrics to export requests containing no more than batchSize data points each
+func metricsRequests(resource *resourcepb.Resource, snapshots []vault.MetricSnapshot, now time.Time, batchSize int) []*collectormetrics.ExportMetricsServiceRequest {
+	var (
+		requests []*collectormetrics.ExportMetricsServiceRequest
+		metrics  []*metricspb.Metric
+		points   int
+	)
+
+	flush := func() {
+		if len(metrics) == 0 {
+			return
+		}
+		requests = append(requests, &collectormetrics.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{{
+				Resource: resource,
+				ScopeMetrics: []*metricspb.ScopeMetrics{{
+					Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+					Metrics: metrics,
+				}},
+			}},
+		})
+		metrics, points = nil, 0
+	}
+
+	for _, snapshot := range snapshots {
+		samples := snapshot.Samples
+		for len(samples) > 0 {
+			n := batchSize - points
+			if n > len(samples) {
+				n = len(samples)
+			}
+			metrics = append(metrics, convertMetric(snapshot.Mapping, samples[:n], now))
+			points += n
+			samples = samples[n:]
+
+			if points >= batchSize {
+				flush()
+			}
+		}
+	}
+	flush()
+
+	return requests
+}
+
+func convertMetric(mapping vault.Mapping, samples []vault.Sample, now time.Time) *metricspb.Metric {
+	metric := &metricspb.Metric{Name: mapping.Name, Description: mapping.Help}
+
+	switch mapping.Type {
+	case vault.CounterMapping:
+		points := make([]*metricspb.NumberDataPoint, 0, len(samples))
+		for _, s := range samples {
+			points = append(points, &metricspb.NumberDataPoint{
+				Attributes:        labelAttributes(mapping.LabelNames, s.LabelValues),
+				StartTimeUnixNano: unixNano(s.StartTime),
+				TimeUnixNano:      unixNano(now),
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: int64(s.Value)},
+			})
+		}
+		metric.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	case vault.GaugeMapping:
+		points := make([]*metricspb.NumberDataPoint, 0, len(samples))
+		for _, s := range samples {
+			points = append(points, &metricspb.NumberDataPoint{
+				Attributes:   labelAttributes(mapping.LabelNames, s.LabelValues),
+				TimeUnixNano: unixNano(now),
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: s.Value},
+			})
+		}
+		metric.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+	case vault.HistogramMapping:
+		points := make([]*metricspb.HistogramDataPoint, 0, len(samples))
+		for _, s := range samples {
+			sum := s.Sum
+			points = append(points, &metricspb.HistogramDataPoint{
+				Attributes:        labelAttributes(mapping.LabelNames, s.LabelValues),
+				StartTimeUnixNano: unixNano(s.StartTime),
+				TimeUnixNano:      unixNano(now),
+				Count:             s.Count,
+				Sum:               &sum,
+				ExplicitBounds:    mapping.Buckets,
+				BucketCounts:      bucketCounts(mapping.Buckets, s.Buckets, s.Count),
+			})
+		}
+		metric.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}}
+	}
+
+	return metric
+}
+
+// bucketCounts converts cumulative Prometheus buckets to OTLP counts per bucket,
+// the last count is for values greater than the last bound
+func bucketCounts(bounds []float64, cumulative map[float64]uint64, count uint64) []uint64 {
+	counts := make([]uint64, len(bounds)+1)
+
+	var previous uint64
+	for i, bound := range bounds {
+		current := cumulative[bound]
+		if current > previous {
+			counts[i] = current - previous
+		}
+		previous = current
+	}
+	if count > previous {
+		counts[len(bounds)] = count - previous
+	}
+
+	return counts
+}
+
+// logRecord converts an access event to a log record, the W3C traceparent attribute links the record to a trace
+func logRecord(event *mproto.EventMessage, observed time.Time) *logspb.LogRecord {
+	seconds, fraction := math.Modf(event.Timestamp)
+	record := &logspb.LogRecord{
+		TimeUnixNano:         unixNano(time.Unix(int64(seconds), int64(fraction*1e9))),
+		ObservedTimeUnixNano: unixNano(observed),
+		SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:         "INFO",
+	}
+
+	status, _ := strconv.Atoi(event.Attributes[statusCodeAttribute])
+	switch {
+	case status >= 500:
+		record.SeverityNumber, record.SeverityText = logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "ERROR"
+	case status >= 400:
+		record.SeverityNumber, record.SeverityText = logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "WARN"
+	}
+
+	keys := make([]string, 0, len(event.Attributes))
+	for key := range event.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := event.Attributes[key]
+		if key == traceparentAttribute {
+			record.TraceId, record.SpanId, record.Flags = parseTraceparent(value)
+			continue
+		}
+		record.Attributes = append(record.Attributes, &commonpb.KeyValue{Key: key, Value: typedValue(key, value)})
+	}
+
+	record.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: strings.Join([]string{
+		event.Attributes["http.request.method"],
+		event.Attributes["server.address"] + event.Attributes["url.path"],
+		event.Attributes[statusCodeAttribute],
+	}, " ")}}
+
+	return record
+}
+
+func logsRequest(resource *resourcepb.Resource, records []*logspb.LogRecord) *collectorlogs.ExportLogsServiceRequest {
+	return &collectorlogs.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: scopeName},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+// parseTraceparent parses the header in the version-traceid-spanid-flags format, invalid values are ignored
+func parseTraceparent(value string) (traceID, spanID []byte, flags uint32) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, nil, 0
+	}
+
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, 0
+	}
+	spanID, err = hex.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, 0
+	}
+	traceFlags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return nil, nil, 0
+	}
+
+	return traceID, spanID, uint32(traceFlags)
+}
+
+func typedValue(key, value string) *commonpb.AnyValue {
+	if _, ok := intAttributes[key]; ok {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+	}
+	if _, ok := doubleAttributes[key]; ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}
+}
+
+func labelAttributes(names, values []string) []*commonpb.KeyValue {
+	attributes := make([]*commonpb.KeyValue, 0, len(names))
+	for i, name := range names {
+		if i >= len(values) {
+			break
+		}
+		attributes = append(attributes, &commonpb.KeyValue{
+			Key:   name,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: values[i]}},
+		})
+	}
+	return attributes
+}
+
+func stringAttributes(m map[string]string) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]*commonpb.KeyValue, 0, len(m))
+	for _, key := range keys {
+		attributes = append(attributes, &commonpb.KeyValue{
+			Key:   key,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: m[key]}},
+		})
+	}
+	return attributes
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
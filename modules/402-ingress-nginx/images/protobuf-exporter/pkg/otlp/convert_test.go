@@ -0,0 +1,163 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	mproto "github.com/flant/protobuf_exporter/pkg/proto"
+	"github.com/flant/protobuf_exporter/pkg/vault"
+)
+
+func TestBucketCounts(t *testing.T) {
+	bounds := []float64{0.1, 0.5, 1}
+	cumulative := map[float64]uint64{0.1: 2, 0.5: 5, 1: 5}
+
+	counts := bucketCounts(bounds, cumulative, 8)
+	expected := []uint64{2, 3, 0, 3}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("bucket counts %v, expected %v", counts, expected)
+	}
+}
+
+func TestMetricsRequests(t *testing.T) {
+	start := time.Unix(100, 0)
+	now := time.Unix(200, 0)
+
+	snapshots := []vault.MetricSnapshot{
+		{
+			Mapping: vault.Mapping{Type: vault.CounterMapping, Name: "requests_total", LabelNames: []string{"namespace", "vhost"}},
+			Samples: []vault.Sample{
+				{LabelValues: []string{"default", "a.example.com"}, StartTime: start, Value: 3},
+				{LabelValues: []string{"default", "b.example.com"}, StartTime: start, Value: 5},
+				{LabelValues: []string{"prod", "c.example.com"}, StartTime: start, Value: 7},
+			},
+		},
+		{
+			Mapping: vault.Mapping{Type: vault.HistogramMapping, Name: "request_seconds", LabelNames: []string{"namespace"}, Buckets: []float64{0.1, 1}},
+			Samples: []vault.Sample{
+				{LabelValues: []string{"default"}, StartTime: start, Count: 4, Sum: 1.5, Buckets: map[float64]uint64{0.1: 1, 1: 3}},
+			},
+		},
+		{
+			Mapping: vault.Mapping{Type: vault.GaugeMapping, Name: "retries", LabelNames: []string{"namespace"}},
+		},
+	}
+
+	requests := metricsRequests(resourceFromEnv(func(string) string { return "" }), snapshots, now, 2)
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	first := requests[0].ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(first) != 1 || len(first[0].GetSum().DataPoints) != 2 {
+		t.Fatalf("unexpected first batch %v", first)
+	}
+	point := first[0].GetSum().DataPoints[0]
+	if point.GetAsInt() != 3 || point.StartTimeUnixNano != uint64(start.UnixNano()) || point.TimeUnixNano != uint64(now.UnixNano()) {
+		t.Fatalf("unexpected counter point %v", point)
+	}
+	if point.Attributes[1].Key != "vhost" || point.Attributes[1].Value.GetStringValue() != "a.example.com" {
+		t.Fatalf("unexpected attributes %v", point.Attributes)
+	}
+
+	second := requests[1].ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(second) != 2 || second[0].Name != "requests_total" || second[1].Name != "request_seconds" {
+		t.Fatalf("unexpected second batch %v", second)
+	}
+	histogram := second[1].GetHistogram().DataPoints[0]
+	if histogram.Count != 4 || histogram.GetSum() != 1.5 || !reflect.DeepEqual(histogram.BucketCounts, []uint64{1, 2, 1}) {
+		t.Fatalf("unexpected histogram point %v", histogram)
+	}
+}
+
+func TestLogRecord(t *testing.T) {
+	event := &mproto.EventMessage{
+		Timestamp: 1700000000.25,
+		Attributes: map[string]string{
+			"http.request.method":       "GET",
+			"server.address":            "example.com",
+			"url.path":                  "/api",
+			"http.response.status_code": "503",
+			"nginx.request_time":        "0.125",
+			"traceparent":               "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+	}
+
+	record := logRecord(event, time.Unix(1700000001, 0))
+
+	if record.TimeUnixNano != 1700000000250000000 {
+		t.Fatalf("unexpected time %d", record.TimeUnixNano)
+	}
+	if record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		t.Fatalf("unexpected severity %s", record.SeverityNumber)
+	}
+	if hex.EncodeToString(record.TraceId) != "4bf92f3577b34da6a3ce929d0e0e4736" || hex.EncodeToString(record.SpanId) != "00f067aa0ba902b7" || record.Flags != 1 {
+		t.Fatalf("unexpected trace context %x %x %d", record.TraceId, record.SpanId, record.Flags)
+	}
+	if record.Body.GetStringValue() != "GET example.com/api 503" {
+		t.Fatalf("unexpected body %q", record.Body.GetStringValue())
+	}
+
+	for _, kv := range record.Attributes {
+		switch kv.Key {
+		case "traceparent":
+			t.Fatal("traceparent should not be exported as an attribute")
+		case "http.response.status_code":
+			if kv.Value.GetIntValue() != 503 {
+				t.Fatalf("status code should be an int, got %v", kv.Value)
+			}
+		case "nginx.request_time":
+			if kv.Value.GetDoubleValue() != 0.125 {
+				t.Fatalf("request time should be a double, got %v", kv.Value)
+			}
+		}
+	}
+}
+
+func TestParseTraceparentInvalid(t *testing.T) {
+	for _, value := range []string{"", "00-abc-def-01", "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01"} {
+		if traceID, spanID, _ := parseTraceparent(value); traceID != nil || spanID != nil {
+			t.Fatalf("traceparent %q should be ignored", value)
+		}
+	}
+}
+
+func TestResourceFromEnv(t *testing.T) {
+	env := map[string]string{
+		"OTEL_RESOURCE_ATTRIBUTES": "k8s.pod.name=controller-main-abcde, k8s.node.name=node%2D1,broken",
+	}
+	resource := resourceFromEnv(func(key string) string { return env[key] })
+
+	attributes := make(map[string]string)
+	for _, kv := range resource.Attributes {
+		attributes[kv.Key] = kv.Value.GetStringValue()
+	}
+	expected := map[string]string{
+		"k8s.pod.name":  "controller-main-abcde",
+		"k8s.node.name": "node-1",
+		"service.name":  "ingress-nginx",
+	}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Fatalf("resource attributes %v, expected %v", attributes, expected)
+	}
+}
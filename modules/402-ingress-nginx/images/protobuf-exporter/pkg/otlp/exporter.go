@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/common/log"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	mproto "github.com/flant/protobuf_exporter/pkg/proto"
+	"github.com/flant/protobuf_exporter/pkg/stats"
+	"github.com/flant/protobuf_exporter/pkg/vault"
+)
+
+// Exporter periodically sends the metrics from the vault to an OpenTelemetry collector,
+// access events are sent as log records in batches
+type Exporter struct {
+	config   Config
+	client   client
+	vault    *vault.MetricsVault
+	resource *resourcepb.Resource
+
+	events   chan *logspb.LogRecord
+	stopFunc context.CancelFunc
+	done     chan struct{}
+}
+
+func NewExporter(config Config, metricsVault *vault.MetricsVault) (*Exporter, error) {
+	config = config.WithDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("otlp config: %v", err)
+	}
+
+	c, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Exporter{
+		config:   config,
+		client:   c,
+		vault:    metricsVault,
+		resource: resourceFromEnv(os.Getenv),
+		events:   make(chan *logspb.LogRecord, eventsQueueSize),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (e *Exporter) Config() Config {
+	return e.config
+}
+
+func (e *Exporter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.stopFunc = cancel
+
+	log.Infof("Start exporting metrics to %s collector %q every %s", e.config.Protocol, e.config.Endpoint, e.config.Interval)
+	go e.run(ctx)
+}
+
+// Close stops the exporter, events that are not sent yet are lost
+func (e *Exporter) Close() {
+	if e.stopFunc != nil {
+		e.stopFunc()
+		<-e.done
+	}
+	_ = e.client.Close()
+}
+
+// AddEvent queues the access event, it never blocks the telemetry connection
+func (e *Exporter) AddEvent(event *mproto.EventMessage) {
+	if !e.config.AccessEvents {
+		return
+	}
+
+	select {
+	case e.events <- logRecord(event, time.Now()):
+	default:
+		stats.Errors.WithLabelValues("otlp-event-dropped").Inc()
+	}
+}
+
+func (e *Exporter) run(ctx context.Context) {
+	defer close(e.done)
+
+	tick := time.NewTicker(e.config.Interval)
+	defer tick.Stop()
+
+	records := make([]*logspb.LogRecord, 0, e.config.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case record := <-e.events:
+			records = append(records, record)
+			if len(records) >= e.config.BatchSize {
+				e.exportLogs(ctx, records)
+				records = records[:0]
+			}
+		case <-tick.C:
+			e.exportMetrics(ctx)
+			if len(records) > 0 {
+				e.exportLogs(ctx, records)
+				records = records[:0]
+			}
+		}
+	}
+}
+
+func (e *Exporter) exportMetrics(ctx context.Context) {
+	for _, req := range metricsRequests(e.resource, e.vault.Snapshot(), time.Now(), e.config.BatchSize) {
+		err := withRetries(ctx, e.config, func(ctx context.Context) error {
+			return e.client.ExportMetrics(ctx, req)
+		})
+		if err != nil {
+			log.Warnf("OTLP metrics export failed: %v", err)
+			stats.Errors.WithLabelValues("otlp-metrics-export").Inc()
+			return
+		}
+		stats.Exported.WithLabelValues("metrics").Inc()
+	}
+}
+
+func (e *Exporter) exportLogs(ctx context.Context, records []*logspb.LogRecord) {
+	req := logsRequest(e.resource, records)
+	err := withRetries(ctx, e.config, func(ctx context.Context) error {
+		return e.client.ExportLogs(ctx, req)
+	})
+	if err != nil {
+		log.Warnf("OTLP access events export failed, %d events are lost: %v", len(records), err)
+		stats.Errors.WithLabelValues("otlp-logs-export").Inc()
+		return
+	}
+	stats.Exported.WithLabelValues("logs").Inc()
+}
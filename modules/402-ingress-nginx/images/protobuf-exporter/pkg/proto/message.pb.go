@@ -243,6 +243,66 @@ func (m *GaugeMessage) GetAnnotations() map[string]string {
 	return nil
 }
 
+type EventMessage struct {
+	Timestamp   float64           `protobuf:"fixed64,1,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	Attributes  map[string]string `protobuf:"bytes,2,rep,name=Attributes,proto3" json:"Attributes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Annotations map[string]string `protobuf:"bytes,3,rep,name=Annotations,proto3" json:"Annotations,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *EventMessage) Reset()         { *m = EventMessage{} }
+func (m *EventMessage) String() string { return proto.CompactTextString(m) }
+func (*EventMessage) ProtoMessage()    {}
+func (*EventMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{3}
+}
+func (m *EventMessage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *EventMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_EventMessage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *EventMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EventMessage.Merge(m, src)
+}
+func (m *EventMessage) XXX_Size() int {
+	return m.Size()
+}
+func (m *EventMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_EventMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EventMessage proto.InternalMessageInfo
+
+func (m *EventMessage) GetTimestamp() float64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *EventMessage) GetAttributes() map[string]string {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *EventMessage) GetAnnotations() map[string]string {
+	if m != nil {
+		return m.Annotations
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*HistogramMessage)(nil), "proto.HistogramMessage")
 	proto.RegisterMapType((map[string]string)(nil), "proto.HistogramMessage.AnnotationsEntry")
@@ -251,34 +311,42 @@ func init() {
 	proto.RegisterMapType((map[string]string)(nil), "proto.CounterMessage.AnnotationsEntry")
 	proto.RegisterType((*GaugeMessage)(nil), "proto.GaugeMessage")
 	proto.RegisterMapType((map[string]string)(nil), "proto.GaugeMessage.AnnotationsEntry")
+	proto.RegisterType((*EventMessage)(nil), "proto.EventMessage")
+	proto.RegisterMapType((map[string]string)(nil), "proto.EventMessage.AnnotationsEntry")
+	proto.RegisterMapType((map[string]string)(nil), "proto.EventMessage.AttributesEntry")
 }
 
 func init() { proto.RegisterFile("message.proto", fileDescriptor_33c57e4bae7b9afd) }
 
 var fileDescriptor_33c57e4bae7b9afd = []byte{
-	// 349 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xcd, 0x4d, 0x2d, 0x2e,
-	0x4e, 0x4c, 0x4f, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x05, 0x53, 0x4a, 0xff, 0x98,
-	0xb8, 0x04, 0x3c, 0x32, 0x8b, 0x4b, 0xf2, 0xd3, 0x8b, 0x12, 0x73, 0x7d, 0x21, 0x2a, 0x84, 0x94,
-	0xb8, 0x78, 0x7c, 0x13, 0x0b, 0x0a, 0x32, 0xf3, 0xd2, 0x3d, 0xf3, 0x52, 0x52, 0x2b, 0x24, 0x18,
-	0x15, 0x18, 0x35, 0x58, 0x83, 0x50, 0xc4, 0x84, 0xc4, 0xb8, 0xd8, 0x7c, 0x12, 0x93, 0x52, 0x73,
-	0x8a, 0x25, 0x98, 0x14, 0x98, 0x35, 0x38, 0x83, 0xa0, 0x3c, 0x21, 0x3b, 0x2e, 0x76, 0xa7, 0xd2,
-	0xe4, 0xec, 0xd4, 0x92, 0x62, 0x09, 0x66, 0x05, 0x66, 0x0d, 0x6e, 0x23, 0x15, 0x88, 0x85, 0x7a,
-	0xe8, 0xb6, 0xe8, 0x41, 0x95, 0xb9, 0xe6, 0x95, 0x14, 0x55, 0x06, 0xc1, 0x34, 0x09, 0x09, 0x70,
-	0x31, 0x07, 0x97, 0xe6, 0x4a, 0xb0, 0x28, 0x30, 0x6a, 0x30, 0x06, 0x81, 0x98, 0x42, 0x22, 0x5c,
-	0xac, 0xce, 0xf9, 0xa5, 0x79, 0x25, 0x12, 0xac, 0x0a, 0x8c, 0x1a, 0x2c, 0x41, 0x10, 0x8e, 0x90,
-	0x17, 0x17, 0xb7, 0x63, 0x5e, 0x5e, 0x7e, 0x49, 0x62, 0x49, 0x66, 0x7e, 0x5e, 0xb1, 0x04, 0x1b,
-	0xd8, 0x2e, 0x0d, 0x5c, 0x76, 0x21, 0x29, 0x85, 0xd8, 0x87, 0xac, 0x59, 0xca, 0x8a, 0x8b, 0x07,
-	0xd9, 0x31, 0x20, 0x37, 0x64, 0xa7, 0x56, 0x82, 0xbd, 0xcd, 0x19, 0x04, 0x62, 0x82, 0xdc, 0x50,
-	0x96, 0x98, 0x53, 0x9a, 0x2a, 0xc1, 0x04, 0x71, 0x03, 0x98, 0x63, 0xc5, 0x64, 0xc1, 0x28, 0x65,
-	0xc7, 0x25, 0x80, 0x6e, 0x38, 0x21, 0xfd, 0x9c, 0x48, 0xfa, 0x95, 0xde, 0x30, 0x72, 0xf1, 0x81,
-	0x7d, 0x94, 0x5a, 0x44, 0x8d, 0xe0, 0x17, 0xe1, 0x62, 0x0d, 0x03, 0x5b, 0xc4, 0x0c, 0x71, 0x28,
-	0x98, 0x23, 0xe4, 0x81, 0x1a, 0x58, 0x2c, 0xe0, 0xc0, 0x52, 0x83, 0x06, 0x16, 0xaa, 0xed, 0x04,
-	0x82, 0x8a, 0x52, 0xef, 0xbe, 0x60, 0xe4, 0xe2, 0x71, 0x4f, 0x2c, 0x4d, 0x4f, 0xa5, 0xba, 0x67,
-	0x19, 0x61, 0x9e, 0x75, 0xc3, 0xe6, 0x59, 0x58, 0x2a, 0x44, 0xb6, 0x9b, 0xb6, 0x5e, 0x75, 0x92,
-	0x38, 0xf1, 0x48, 0x8e, 0xf1, 0xc2, 0x23, 0x39, 0xc6, 0x07, 0x8f, 0xe4, 0x18, 0x27, 0x3c, 0x96,
-	0x63, 0xb8, 0xf0, 0x58, 0x8e, 0xe1, 0xc6, 0x63, 0x39, 0x86, 0x24, 0x36, 0xb0, 0x5b, 0x8c, 0x01,
-	0x01, 0x00, 0x00, 0xff, 0xff, 0x51, 0x2e, 0xe8, 0xb4, 0x93, 0x03, 0x00, 0x00,
+	// 419 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x93, 0xc1, 0xaa, 0xd3, 0x40,
+	0x14, 0x86, 0x3b, 0x49, 0x53, 0xc9, 0x69, 0xd4, 0x32, 0x5c, 0x64, 0xb8, 0x48, 0x08, 0x55, 0x24,
+	0xab, 0x2c, 0xae, 0x1b, 0xb9, 0xe0, 0x85, 0xb6, 0x54, 0xab, 0xd8, 0x4d, 0x14, 0xf7, 0x53, 0x1d,
+	0x42, 0x68, 0x33, 0x09, 0xc9, 0xa4, 0xd8, 0xb7, 0xf0, 0x71, 0x7c, 0x04, 0x97, 0x5d, 0xba, 0x94,
+	0x76, 0xe3, 0xc2, 0x67, 0x10, 0xc9, 0x4c, 0x42, 0x93, 0xa0, 0x16, 0x69, 0xef, 0x2a, 0x73, 0x86,
+	0x73, 0xce, 0xff, 0x7f, 0x3f, 0x19, 0xb8, 0x1b, 0xb1, 0x2c, 0xa3, 0x01, 0xf3, 0x92, 0x34, 0x16,
+	0x31, 0x36, 0xe4, 0x67, 0xf8, 0x4b, 0x83, 0xc1, 0x2c, 0xcc, 0x44, 0x1c, 0xa4, 0x34, 0x9a, 0xab,
+	0x0e, 0x3c, 0x04, 0x6b, 0x4e, 0x93, 0x24, 0xe4, 0xc1, 0x2b, 0xfe, 0x91, 0x7d, 0x22, 0xc8, 0x41,
+	0xae, 0xe1, 0x37, 0xee, 0xf0, 0x03, 0xe8, 0xbd, 0xa1, 0x0b, 0xb6, 0xca, 0x88, 0xe6, 0xe8, 0xae,
+	0xe9, 0x97, 0x15, 0xbe, 0x81, 0x3b, 0xe3, 0xfc, 0xc3, 0x92, 0x89, 0x8c, 0xe8, 0x8e, 0xee, 0xf6,
+	0xaf, 0x1e, 0x2b, 0x41, 0xaf, 0xad, 0xe2, 0x95, 0x6d, 0x53, 0x2e, 0xd2, 0x8d, 0x5f, 0x0d, 0xe1,
+	0x01, 0xe8, 0x6f, 0xf3, 0x88, 0x74, 0x1d, 0xe4, 0x22, 0xbf, 0x38, 0xe2, 0x0b, 0x30, 0x26, 0x71,
+	0xce, 0x05, 0x31, 0x1c, 0xe4, 0x76, 0x7d, 0x55, 0xe0, 0xd7, 0xd0, 0x1f, 0x71, 0x1e, 0x0b, 0x2a,
+	0xc2, 0x98, 0x67, 0xa4, 0x27, 0xb5, 0xdc, 0xbf, 0x69, 0xd5, 0x5a, 0x95, 0x5e, 0x7d, 0xf8, 0xf2,
+	0x1a, 0xac, 0xba, 0x99, 0xc2, 0xc3, 0x92, 0x6d, 0x24, 0xb6, 0xe9, 0x17, 0xc7, 0xc2, 0xc3, 0x9a,
+	0xae, 0x72, 0x46, 0x34, 0xe5, 0x41, 0x16, 0xd7, 0xda, 0x33, 0x74, 0x79, 0x03, 0x83, 0xf6, 0xf2,
+	0x63, 0xf3, 0x66, 0x6d, 0x7e, 0xf8, 0x13, 0xc1, 0x3d, 0x49, 0xc4, 0xd2, 0x73, 0xc4, 0x7f, 0x01,
+	0xc6, 0x7b, 0x29, 0xa4, 0x2b, 0xa3, 0xb2, 0xc0, 0xb3, 0x66, 0x58, 0x5d, 0x19, 0xd6, 0x93, 0x32,
+	0xac, 0xa6, 0xfa, 0x91, 0xa8, 0x4e, 0xc5, 0xfd, 0x81, 0xc0, 0x7a, 0x49, 0xf3, 0x80, 0x9d, 0x1d,
+	0x16, 0x55, 0xb0, 0x2f, 0xfe, 0x04, 0x5b, 0xfd, 0x85, 0x75, 0xed, 0x5b, 0x46, 0xfd, 0xa2, 0x81,
+	0x35, 0x5d, 0x33, 0x2e, 0x2a, 0xd4, 0x87, 0x60, 0xbe, 0x0b, 0x23, 0x96, 0x09, 0x1a, 0x25, 0x72,
+	0x05, 0xf2, 0x0f, 0x17, 0x78, 0x02, 0x30, 0x12, 0x22, 0x0d, 0x17, 0xb9, 0x60, 0x0a, 0xb4, 0x7f,
+	0xf5, 0xa8, 0x74, 0x5d, 0x5f, 0xe3, 0x1d, 0xba, 0x94, 0xe9, 0xda, 0x58, 0x9b, 0xbd, 0xf9, 0x02,
+	0x9b, 0x5b, 0xfe, 0xc9, 0xfe, 0x1c, 0xee, 0xb7, 0x64, 0xfe, 0x07, 0xfd, 0xd4, 0xe8, 0xc6, 0xe4,
+	0xeb, 0xce, 0x46, 0xdb, 0x9d, 0x8d, 0xbe, 0xef, 0x6c, 0xf4, 0x79, 0x6f, 0x77, 0xb6, 0x7b, 0xbb,
+	0xf3, 0x6d, 0x6f, 0x77, 0x16, 0x3d, 0x89, 0xf2, 0xf4, 0xf7, 0x00, 0xe3, 0x2e, 0x17, 0x4f, 0xce,
+	0x04, 0x00, 0x00,
 }
 
 func (m *HistogramMessage) Marshal() (dAtA []byte, err error) {
@@ -488,6 +556,73 @@ func (m *GaugeMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *EventMessage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *EventMessage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EventMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Annotations) > 0 {
+		for k := range m.Annotations {
+			v := m.Annotations[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintMessage(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintMessage(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintMessage(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.Attributes) > 0 {
+		for k := range m.Attributes {
+			v := m.Attributes[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintMessage(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintMessage(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintMessage(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if m.Timestamp != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Timestamp))))
+		i--
+		dAtA[i] = 0x9
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintMessage(dAtA []byte, offset int, v uint64) int {
 	offset -= sovMessage(v)
 	base := offset
@@ -597,6 +732,34 @@ func (m *GaugeMessage) Size() (n int) {
 	return n
 }
 
+func (m *EventMessage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Timestamp != 0 {
+		n += 9
+	}
+	if len(m.Attributes) > 0 {
+		for k, v := range m.Attributes {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovMessage(uint64(len(k))) + 1 + len(v) + sovMessage(uint64(len(v)))
+			n += mapEntrySize + 1 + sovMessage(uint64(mapEntrySize))
+		}
+	}
+	if len(m.Annotations) > 0 {
+		for k, v := range m.Annotations {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovMessage(uint64(len(k))) + 1 + len(v) + sovMessage(uint64(len(v)))
+			n += mapEntrySize + 1 + sovMessage(uint64(mapEntrySize))
+		}
+	}
+	return n
+}
+
 func sovMessage(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
 }
@@ -1460,6 +1623,321 @@ func (m *GaugeMessage) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *EventMessage) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: EventMessage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: EventMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Timestamp = float64(math.Float64frombits(v))
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Attributes", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Attributes == nil {
+				m.Attributes = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowMessage
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowMessage
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthMessage
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthMessage
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowMessage
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthMessage
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthMessage
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipMessage(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthMessage
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Attributes[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Annotations", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Annotations == nil {
+				m.Annotations = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowMessage
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowMessage
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthMessage
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthMessage
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowMessage
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthMessage
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthMessage
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipMessage(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthMessage
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Annotations[mapkey] = mapvalue
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipMessage(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
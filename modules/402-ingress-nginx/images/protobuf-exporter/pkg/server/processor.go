@@ -18,24 +18,37 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
+	"reflect"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/prometheus/common/log"
 	"gopkg.in/yaml.v3"
+
+	"github.com/flant/protobuf_exporter/pkg/otlp"
+	mproto "github.com/flant/protobuf_exporter/pkg/proto"
+	"github.com/flant/protobuf_exporter/pkg/vault"
 )
 
 const (
 	telemetryConfigFile = "/var/files/telemetry_config.yml"
+	// otlpHeadersFile is mounted from a Secret since headers usually contain credentials
+	otlpHeadersFile = "/var/otlp/headers.yml"
 )
 
 type telemetryMessageProcessor struct {
 	discardProcessor *discardProcessor
+
+	vault        *vault.MetricsVault
+	exporterLock sync.RWMutex
+	otlpExporter *otlp.Exporter
 }
 
-func newTelemetryMessageProcessor() *telemetryMessageProcessor {
-	return &telemetryMessageProcessor{discardProcessor: newDiscardProcessor(nil)}
+func newTelemetryMessageProcessor(vault *vault.MetricsVault) *telemetryMessageProcessor {
+	return &telemetryMessageProcessor{discardProcessor: newDiscardProcessor(nil), vault: vault}
 }
 
 func (tmp *telemetryMessageProcessor) LoadConfig(ctx context.Context) error {
@@ -69,9 +82,89 @@ func (tmp *telemetryMessageProcessor) parseConfig() error {
 		tmp.discardProcessor = dp
 	}
 
+	if config.OTLP != nil {
+		config.OTLP.Headers, err = loadOTLPHeaders(otlpHeadersFile, config.OTLP.Headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tmp.configureExporter(config.OTLP)
+}
+
+// loadOTLPHeaders adds headers from the file to the headers from the config, the file is optional
+func loadOTLPHeaders(path string, headers map[string]string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return headers, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fileHeaders map[string]string
+	err = yaml.Unmarshal(data, &fileHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("parse OTLP headers from %q: %w", path, err)
+	}
+
+	if len(fileHeaders) == 0 {
+		return headers, nil
+	}
+
+	res := make(map[string]string, len(headers)+len(fileHeaders))
+	for name, value := range headers {
+		res[name] = value
+	}
+	for name, value := range fileHeaders {
+		res[name] = value
+	}
+	return res, nil
+}
+
+// configureExporter restarts the OTLP exporter if its config is changed, the exporter is stopped if the config is removed
+func (tmp *telemetryMessageProcessor) configureExporter(config *otlp.Config) error {
+	tmp.exporterLock.Lock()
+	defer tmp.exporterLock.Unlock()
+
+	if config != nil && tmp.otlpExporter != nil && reflect.DeepEqual(config.WithDefaults(), tmp.otlpExporter.Config()) {
+		return nil
+	}
+
+	if tmp.otlpExporter != nil {
+		log.Info("Stopping OTLP exporter")
+		tmp.otlpExporter.Close()
+		tmp.otlpExporter = nil
+	}
+
+	if config == nil {
+		return nil
+	}
+
+	exporter, err := otlp.NewExporter(*config, tmp.vault)
+	if err != nil {
+		return err
+	}
+	exporter.Start()
+	tmp.otlpExporter = exporter
+
 	return nil
 }
 
+// HandleEvent passes the access event to the OTLP exporter, events are dropped if the exporter is not configured
+func (tmp *telemetryMessageProcessor) HandleEvent(event *mproto.EventMessage) {
+	tmp.exporterLock.RLock()
+	defer tmp.exporterLock.RUnlock()
+
+	if tmp.otlpExporter != nil {
+		tmp.otlpExporter.AddEvent(event)
+	}
+}
+
+func (tmp *telemetryMessageProcessor) Close() {
+	_ = tmp.configureExporter(nil)
+}
+
 func (tmp *telemetryMessageProcessor) runConfigWatcher(ctx context.Context) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -84,6 +177,13 @@ func (tmp *telemetryMessageProcessor) runConfigWatcher(ctx context.Context) {
 		log.Fatalf("add watcher for file failed: %s", err)
 	}
 
+	if _, err := os.Stat(otlpHeadersFile); err == nil {
+		err = watcher.Add(otlpHeadersFile)
+		if err != nil {
+			log.Fatalf("add watcher for file failed: %s", err)
+		}
+	}
+
 	for {
 		select {
 		case event := <-watcher.Events:
@@ -96,7 +196,7 @@ func (tmp *telemetryMessageProcessor) runConfigWatcher(ctx context.Context) {
 					log.Fatal(err)
 				}
 				switch event.Name {
-				case telemetryConfigFile:
+				case telemetryConfigFile, otlpHeadersFile:
 					err := tmp.parseConfig()
 					if err != nil {
 						log.Fatalf("Config reload failed: %s", err)
@@ -115,4 +215,5 @@ func (tmp *telemetryMessageProcessor) runConfigWatcher(ctx context.Context) {
 
 type telemetryConfig struct {
 	Discard *discardConfig `yaml:"discard,omitempty"`
+	OTLP    *otlp.Config   `yaml:"otlp,omitempty"`
 }
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadOTLPHeaders(t *testing.T) {
+	dir := t.TempDir()
+
+	headers, err := loadOTLPHeaders(filepath.Join(dir, "missing.yml"), map[string]string{"X-Scope-OrgID": "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(headers, map[string]string{"X-Scope-OrgID": "main"}) {
+		t.Fatalf("headers %v, expected the config headers for a missing file", headers)
+	}
+
+	path := filepath.Join(dir, "headers.yml")
+	err = os.WriteFile(path, []byte("Authorization: Bearer token\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers, err = loadOTLPHeaders(path, map[string]string{"X-Scope-OrgID": "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"X-Scope-OrgID": "main", "Authorization": "Bearer token"}
+	if !reflect.DeepEqual(headers, expected) {
+		t.Fatalf("headers %v, expected %v", headers, expected)
+	}
+
+	err = os.WriteFile(path, []byte("{}\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err = loadOTLPHeaders(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 0 {
+		t.Fatalf("headers %v, expected no headers for an empty file", headers)
+	}
+
+	err = os.WriteFile(path, []byte("- bad\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadOTLPHeaders(path, nil); err == nil {
+		t.Fatal("expected an error for malformed headers")
+	}
+}
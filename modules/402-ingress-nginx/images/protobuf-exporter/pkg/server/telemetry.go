@@ -38,6 +38,7 @@ const (
 	HistogramMarker = byte(1)
 	GaugeMarker     = byte(2)
 	CounterMarker   = byte(3)
+	EventMarker     = byte(4)
 )
 
 type TelemetryServer struct {
@@ -54,7 +55,7 @@ func NewTelemetryServer(vault *vault.MetricsVault) *TelemetryServer {
 		ctx:              ctx,
 		stopFunc:         cancel,
 		vault:            vault,
-		messageProcessor: newTelemetryMessageProcessor(),
+		messageProcessor: newTelemetryMessageProcessor(vault),
 	}
 }
 
@@ -91,6 +92,7 @@ func (s *TelemetryServer) Start(address string, errorCh chan error) {
 
 func (s *TelemetryServer) Close() {
 	s.stopFunc()
+	s.messageProcessor.Close()
 }
 
 func (s *TelemetryServer) handleConn(c *net.TCPConn) {
@@ -163,6 +165,16 @@ func (s *TelemetryServer) handleConn(c *net.TCPConn) {
 			} else {
 				stats.Messages.WithLabelValues("histogram").Inc()
 			}
+		case EventMarker:
+			var message mproto.EventMessage
+			readMessage(readerCloser, &message)
+
+			if s.isMessagedDiscarded(message.Annotations) {
+				continue
+			}
+
+			s.messageProcessor.HandleEvent(&message)
+			stats.Messages.WithLabelValues("event").Inc()
 		default:
 			log.Warnf("protocol error: unknown metric marker: %v", marker)
 			stats.Errors.WithLabelValues("unknown-marker").Inc()
@@ -33,9 +33,17 @@ var (
 		},
 		[]string{"type"},
 	)
+	Exported = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "protobuf_exporter_otlp_requests_total",
+			Help: "The number of successful export requests to the OpenTelemetry collector.",
+		},
+		[]string{"signal"},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(Messages)
 	prometheus.MustRegister(Errors)
+	prometheus.MustRegister(Exported)
 }
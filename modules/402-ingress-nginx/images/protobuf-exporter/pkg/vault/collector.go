@@ -33,6 +33,7 @@ type ConstMetricCollector interface {
 	Collect(ch chan<- prometheus.Metric)
 	Store(labelsHash uint64, labels []string, timestamp time.Time, value interface{})
 	Clear(now time.Time)
+	Snapshot() MetricSnapshot
 }
 
 var (
@@ -46,6 +47,7 @@ type StampedHistogramMetric struct {
 	Sum         float64 // TODO: use uint64 like in prometheus for atomic operations
 	Buckets     map[float64]uint64
 	LabelValues []string
+	StartTime   time.Time
 	LastUpdate  time.Time
 }
 
@@ -61,12 +63,14 @@ func (s *StampedHistogramMetric) CopyBuckets() map[float64]uint64 {
 type StampedCounterMetric struct {
 	Value       uint64
 	LabelValues []string
+	StartTime   time.Time
 	LastUpdate  time.Time
 }
 
 type StampedGaugeMetric struct {
 	Value       float64
 	LabelValues []string
+	StartTime   time.Time
 	LastUpdate  time.Time
 }
 
@@ -120,7 +124,7 @@ func (c *ConstHistogramCollector) Store(labelsHash uint64, labels []string, time
 
 	storedMetric, ok := c.collection[labelsHash]
 	if !ok {
-		storedMetric = StampedHistogramMetric{Buckets: make(map[float64]uint64, len(c.mapping.Buckets)), LabelValues: labels}
+		storedMetric = StampedHistogramMetric{Buckets: make(map[float64]uint64, len(c.mapping.Buckets)), LabelValues: labels, StartTime: timestamp}
 	}
 
 	storedMetric.Count += histogramValue.Count
@@ -153,6 +157,24 @@ func (c *ConstHistogramCollector) Clear(now time.Time) {
 	}
 }
 
+func (c *ConstHistogramCollector) Snapshot() MetricSnapshot {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	samples := make([]Sample, 0, len(c.collection))
+	for _, s := range c.collection {
+		samples = append(samples, Sample{
+			LabelValues: s.LabelValues,
+			StartTime:   s.StartTime,
+			LastUpdate:  s.LastUpdate,
+			Count:       s.Count,
+			Sum:         s.Sum,
+			Buckets:     s.CopyBuckets(),
+		})
+	}
+	return MetricSnapshot{Mapping: c.mapping, Samples: samples}
+}
+
 type ConstCounterCollector struct {
 	mtx sync.RWMutex
 
@@ -196,7 +218,7 @@ func (c *ConstCounterCollector) Store(labelsHash uint64, labels []string, timest
 	counterValue := value.(uint64)
 	storedMetric, ok := c.collection[labelsHash]
 	if !ok {
-		storedMetric = StampedCounterMetric{Value: counterValue, LabelValues: labels}
+		storedMetric = StampedCounterMetric{Value: counterValue, LabelValues: labels, StartTime: timestamp}
 	} else {
 		atomic.AddUint64(&storedMetric.Value, counterValue)
 	}
@@ -220,6 +242,22 @@ func (c *ConstCounterCollector) Clear(now time.Time) {
 	}
 }
 
+func (c *ConstCounterCollector) Snapshot() MetricSnapshot {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	samples := make([]Sample, 0, len(c.collection))
+	for _, s := range c.collection {
+		samples = append(samples, Sample{
+			LabelValues: s.LabelValues,
+			StartTime:   s.StartTime,
+			LastUpdate:  s.LastUpdate,
+			Value:       float64(s.Value),
+		})
+	}
+	return MetricSnapshot{Mapping: c.mapping, Samples: samples}
+}
+
 type ConstGaugeCollector struct {
 	mtx sync.RWMutex
 
@@ -263,7 +301,7 @@ func (c *ConstGaugeCollector) Store(labelsHash uint64, labels []string, timestam
 	gaugeValue := value.(float64)
 	storedMetric, ok := c.collection[labelsHash]
 	if !ok {
-		storedMetric = StampedGaugeMetric{Value: gaugeValue, LabelValues: labels}
+		storedMetric = StampedGaugeMetric{Value: gaugeValue, LabelValues: labels, StartTime: timestamp}
 	}
 
 	storedMetric.Value = gaugeValue
@@ -285,3 +323,19 @@ func (c *ConstGaugeCollector) Clear(now time.Time) {
 		}
 	}
 }
+
+func (c *ConstGaugeCollector) Snapshot() MetricSnapshot {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	samples := make([]Sample, 0, len(c.collection))
+	for _, s := range c.collection {
+		samples = append(samples, Sample{
+			LabelValues: s.LabelValues,
+			StartTime:   s.StartTime,
+			LastUpdate:  s.LastUpdate,
+			Value:       s.Value,
+		})
+	}
+	return MetricSnapshot{Mapping: c.mapping, Samples: samples}
+}
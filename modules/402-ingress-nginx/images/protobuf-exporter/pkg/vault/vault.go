@@ -113,3 +113,32 @@ func (v *MetricsVault) RemoveStaleMetrics() {
 		m.Clear(currentTime)
 	}
 }
+
+// Sample is a copy of a stored metric, it is used to export metrics in formats other than Prometheus
+type Sample struct {
+	LabelValues []string
+	StartTime   time.Time
+	LastUpdate  time.Time
+
+	// Value is set for counters and gauges
+	Value float64
+
+	// Count, Sum and cumulative Buckets are set for histograms
+	Count   uint64
+	Sum     float64
+	Buckets map[float64]uint64
+}
+
+type MetricSnapshot struct {
+	Mapping Mapping
+	Samples []Sample
+}
+
+// Snapshot returns copies of all stored metrics in the order of mappings
+func (v *MetricsVault) Snapshot() []MetricSnapshot {
+	snapshots := make([]MetricSnapshot, 0, len(v.metrics))
+	for _, m := range v.metrics {
+		snapshots = append(snapshots, m.Snapshot())
+	}
+	return snapshots
+}
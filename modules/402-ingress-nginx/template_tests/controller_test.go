@@ -17,6 +17,7 @@ limitations under the License.
 package template_tests
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -308,6 +309,120 @@ memory: 500Mi`))
 			waitLbZeroDs := hec.KubernetesResource("DaemonSet", "d8-ingress-nginx", "controller-wait-lb-zero")
 			Expect(waitLbZeroDs.Exists()).To(BeTrue())
 			Expect(waitLbZeroDs.Field("spec.template.spec.containers.0.args").Array()).To(ContainElement(ContainSubstring(`--shutdown-grace-period=0`)))
+
+			telemetryConfig := hec.KubernetesResource("ConfigMap", "d8-ingress-nginx", "d8-ingress-telemetry-config")
+			Expect(telemetryConfig.Field(`data.telemetry_config\.yml`).String()).NotTo(ContainSubstring("otlp"))
+			Expect(testD.Field(`spec.template.spec.containers.#(name=="controller").env.#(name=="LUA_ACCESS_EVENTS")`).Exists()).To(BeFalse())
+		})
+
+		Context("OpenTelemetry export is configured", func() {
+			BeforeEach(func() {
+				hec.ValuesSetFromYaml("ingressNginx.openTelemetry", `
+endpoint: otel.example.com:4318
+protocol: HTTP
+headers:
+  Authorization: Bearer token
+accessEvents: true
+`)
+				hec.HelmRender()
+			})
+
+			It("should pass the collector settings to the protobuf exporter", func() {
+				Expect(hec.RenderError).ShouldNot(HaveOccurred())
+
+				telemetryConfig := hec.KubernetesResource("ConfigMap", "d8-ingress-nginx", "d8-ingress-telemetry-config")
+				Expect(telemetryConfig.Field(`data.telemetry_config\.yml`).String()).To(MatchYAML(`
+discard:
+  namespaces: []
+  ingresses: []
+otlp:
+  endpoint: otel.example.com:4318
+  protocol: http
+  insecure: false
+  interval: 15s
+  accessEvents: true
+`))
+
+				headers := hec.KubernetesResource("Secret", "d8-ingress-nginx", "d8-ingress-telemetry-otlp-headers")
+				Expect(headers.Exists()).To(BeTrue())
+				decodedHeaders, err := base64.StdEncoding.DecodeString(headers.Field(`data.headers\.yml`).String())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(string(decodedHeaders)).To(MatchYAML(`Authorization: Bearer token`))
+
+				testD := hec.KubernetesResource("DaemonSet", "d8-ingress-nginx", "controller-test")
+				Expect(testD.Field(`spec.template.spec.containers.#(name=="controller").env.#(name=="LUA_ACCESS_EVENTS").value`).String()).To(Equal("true"))
+				Expect(testD.Field(`spec.template.spec.containers.#(name=="protobuf-exporter").env.#(name=="OTEL_RESOURCE_ATTRIBUTES").value`).String()).To(ContainSubstring("ingress.controller=test"))
+			})
+		})
+
+		Context("IngressNginxPolicy is attached to the controller", func() {
+			BeforeEach(func() {
+				hec.ValuesSetFromYaml("ingressNginx.internal.policies", `
+- controllerName: test
+  policies: [api, office]
+  rateLimits:
+  - zone: d8-policy.api.per-client
+    key: $binary_remote_addr
+    rate: 10r/s
+    burst: 20
+  rateLimitPolicies: [api]
+  ipDenyList:
+  - cidr: 203.0.113.0/24
+    policy: api
+  ipAllowList: [10.0.0.0/8]
+  ipAllowPolicies: [office]
+  waf:
+    mode: "On"
+    owaspCoreRuleSet: true
+    paranoiaLevel: 2
+    blockingPolicies: [api]
+    customRules:
+    - policy: api
+      rules: SecRuleRemoveById 920350
+`)
+				hec.HelmRender()
+			})
+
+			It("should render policy rules to the controller config", func() {
+				Expect(hec.RenderError).ShouldNot(HaveOccurred())
+
+				cm := hec.KubernetesResource("ConfigMap", "d8-ingress-nginx", "test-config")
+				Expect(cm.Field(`data.http-snippet`).String()).To(Equal(`geo $d8_policy_denied_by {
+  default "";
+  "203.0.113.0/24" "api";
+}
+geo $d8_policy_allowed {
+  default "no";
+  "10.0.0.0/8" "yes";
+}
+limit_req_zone $binary_remote_addr zone=d8-policy.api.per-client:10m rate=10r/s;
+`))
+				Expect(cm.Field(`data.location-snippet`).String()).To(Equal(`set $d8_policy_rate_limits "api";
+set $d8_policy_allow_lists "office";
+set $d8_policy_waf "api";
+if ($d8_policy_denied_by != "") {
+  return 403;
+}
+if ($d8_policy_allowed = "no") {
+  return 403;
+}
+limit_req zone=d8-policy.api.per-client burst=20 nodelay;
+`))
+				Expect(cm.Field(`data.limit-req-status-code`).String()).To(Equal("429"))
+				Expect(cm.Field(`data.enable-modsecurity`).String()).To(Equal("true"))
+				Expect(cm.Field(`data.enable-owasp-modsecurity-crs`).String()).To(Equal("true"))
+				Expect(cm.Field(`data.modsecurity-snippet`).String()).To(Equal(`Include /etc/nginx/modsecurity/modsecurity.conf
+SecRuleEngine On
+SecAction "id:900000,phase:1,nolog,pass,t:none,setvar:tx.paranoia_level=2"
+# IngressNginxPolicy api
+SecRuleRemoveById 920350
+`))
+				Expect(cm.Field(`data.load-balance`).String()).To(Equal("ewma"))
+
+				otherCm := hec.KubernetesResource("ConfigMap", "d8-ingress-nginx", "test-lbwpp-config")
+				Expect(otherCm.Field(`data.location-snippet`).Exists()).To(BeFalse())
+				Expect(otherCm.Field(`data.enable-modsecurity`).Exists()).To(BeFalse())
+			})
 		})
 
 		Context("Vertical pod autoscaler CRD is disabled", func() {
@@ -38,7 +38,7 @@ type ClusterLogDestination struct {
 }
 
 type ClusterLogDestinationSpec struct {
-	// Type of cluster log source: Loki, Elasticsearch, Logstash, Vector
+	// Type of cluster log source: Loki, Elasticsearch, Logstash, Vector, Kafka, Splunk, S3, ClickHouse, Syslog, OTLP
 	Type string `json:"type,omitempty"`
 
 	// Loki describes spec for loki endpoint
@@ -59,6 +59,18 @@ type ClusterLogDestinationSpec struct {
 	// Vector spec for the Vector endpoint
 	Vector VectorSpec `json:"vector"`
 
+	// S3 spec for the S3-compatible object storage
+	S3 S3Spec `json:"s3"`
+
+	// ClickHouse spec for the ClickHouse endpoint
+	ClickHouse ClickHouseSpec `json:"clickhouse"`
+
+	// Syslog spec for the Syslog server
+	Syslog SyslogSpec `json:"syslog"`
+
+	// OTLP spec for the OpenTelemetry endpoint
+	OTLP OTLPSpec `json:"otlp"`
+
 	// Add extra labels for sources
 	ExtraLabels map[string]string `json:"extraLabels,omitempty"`
 
@@ -193,6 +205,89 @@ type SplunkSpec struct {
 	TLS CommonTLSSpec `json:"tls,omitempty"`
 }
 
+type S3Spec struct {
+	// Endpoint is set only for S3-compatible storages, AWS endpoints are resolved by the region.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Region string `json:"region,omitempty"`
+	Bucket string `json:"bucket,omitempty"`
+
+	// KeyPrefix is a template for object keys, e.g., {{ namespace }}/%F/
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+
+	Compression string `json:"compression,omitempty"`
+
+	Batch S3Batch `json:"batch,omitempty"`
+
+	Auth S3AuthSpec `json:"auth,omitempty"`
+
+	TLS CommonTLSSpec `json:"tls,omitempty"`
+}
+
+type S3Batch struct {
+	MaxBytes       uint32 `json:"maxBytes,omitempty"`
+	TimeoutSeconds uint32 `json:"timeoutSeconds,omitempty"`
+}
+
+type S3AuthSpec struct {
+	AccessKeyID     string `json:"accessKeyID,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+}
+
+type ClickHouseSpec struct {
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table,omitempty"`
+
+	Auth ClickHouseAuthSpec `json:"auth,omitempty"`
+
+	TLS CommonTLSSpec `json:"tls,omitempty"`
+}
+
+type ClickHouseAuthSpec struct {
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type SyslogMode = string
+
+const (
+	SyslogModeTCP SyslogMode = "TCP"
+	SyslogModeTLS SyslogMode = "TLS"
+)
+
+type SyslogSpec struct {
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Mode SyslogMode `json:"mode,omitempty"`
+
+	// Facility is the name of the syslog facility, e.g., Local0
+	Facility string `json:"facility,omitempty"`
+
+	// AppName overrides the APP-NAME field, which is the container or the systemd unit name by default.
+	AppName string `json:"appName,omitempty"`
+
+	TLS CommonTLSSpec `json:"tls,omitempty"`
+}
+
+type OTLPSpec struct {
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Headers map[string]string `json:"headers,omitempty"`
+
+	Auth OTLPAuthSpec `json:"auth,omitempty"`
+
+	TLS CommonTLSSpec `json:"tls,omitempty"`
+}
+
+type OTLPAuthSpec struct {
+	Password string `json:"password,omitempty"`
+	Strategy string `json:"strategy,omitempty"`
+	Token    string `json:"token,omitempty"`
+	User     string `json:"user,omitempty"`
+}
+
 type Buffer struct {
 	// The type of buffer to use.
 	Type BufferType `json:"type,omitempty"`
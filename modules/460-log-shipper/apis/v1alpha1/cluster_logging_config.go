@@ -38,7 +38,7 @@ type ClusterLoggingConfig struct {
 }
 
 type ClusterLoggingConfigSpec struct {
-	// Type of cluster log source: KubernetesPods, File
+	// Type of cluster log source: KubernetesPods, File, Journald, KubernetesEvents
 	Type string `json:"type,omitempty"`
 
 	// KubernetesPods describes spec for kubernetes pod source
@@ -47,6 +47,12 @@ type ClusterLoggingConfigSpec struct {
 	// File describes spec for file source
 	File FileSpec `json:"file,omitempty"`
 
+	// Journald describes spec for systemd journal source
+	Journald JournaldSpec `json:"journald,omitempty"`
+
+	// KubernetesEvents describes spec for kubernetes events source
+	KubernetesEvents KubernetesEventsSpec `json:"kubernetesEvents,omitempty"`
+
 	// Filters
 	LogFilters   []Filter `json:"logFilter,omitempty"`
 	LabelFilters []Filter `json:"labelFilter,omitempty"`
@@ -54,8 +60,25 @@ type ClusterLoggingConfigSpec struct {
 	// Multiline parsers
 	MultiLineParser MultiLineParser `json:"multilineParser,omitempty"`
 
+	// Masking of sensitive data
+	Masking Masking `json:"masking,omitempty"`
+
 	// DestinationRefs slice of ClusterLogDestination names
 	DestinationRefs []string `json:"destinationRefs,omitempty"`
+
+	// Routes filter events sent to particular destinations
+	Routes []Route `json:"routes,omitempty"`
+
+	// UnmatchedDestinationRef is a ClusterLogDestination name receiving events that match none of the routes
+	UnmatchedDestinationRef string `json:"unmatchedDestinationRef,omitempty"`
+}
+
+// Route restricts the events sent to a destination from DestinationRefs.
+type Route struct {
+	DestinationRef string `json:"destinationRef"`
+
+	LogFilters   []Filter `json:"logFilter,omitempty"`
+	LabelFilters []Filter `json:"labelFilter,omitempty"`
 }
 
 type ClusterLoggingConfigStatus struct {
@@ -79,3 +102,18 @@ type FileSpec struct {
 	Exclude       []string `json:"exclude,omitempty"`
 	LineDelimiter string   `json:"lineDelimiter,omitempty"`
 }
+
+type JournaldSpec struct {
+	IncludeUnits []string `json:"includeUnits,omitempty"`
+	ExcludeUnits []string `json:"excludeUnits,omitempty"`
+	// Priority is the least important priority of entries to collect, e.g., Warning.
+	Priority string `json:"priority,omitempty"`
+}
+
+type KubernetesEventsSpec struct {
+	// NamespaceSelector supports only matchNames and excludeNames, events are not selected by namespace labels.
+	NamespaceSelector NamespaceSelector `json:"namespaceSelector,omitempty"`
+
+	IncludeReasons []string `json:"includeReasons,omitempty"`
+	ExcludeReasons []string `json:"excludeReasons,omitempty"`
+}
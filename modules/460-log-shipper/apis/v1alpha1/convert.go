@@ -32,6 +32,7 @@ func NamespacedToCluster(namespaced PodLoggingConfig) ClusterLoggingConfig {
 			LabelFilters:    namespaced.Spec.LabelFilters,
 			LogFilters:      namespaced.Spec.LogFilters,
 			MultiLineParser: namespaced.Spec.MultiLineParser,
+			Masking:         namespaced.Spec.Masking,
 
 			KubernetesPods: KubernetesPodsSpec{
 				NamespaceSelector: NamespaceSelector{MatchNames: []string{namespaced.Namespace}},
@@ -0,0 +1,48 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Masking describes how sensitive data is hidden in log messages before they are sent to destinations.
+type Masking struct {
+	Detectors   []MaskingDetector  `json:"detectors,omitempty"`
+	CustomRules []MaskingRule      `json:"customRules,omitempty"`
+	Replacement MaskingReplacement `json:"replacement,omitempty"`
+	// Mask replaces matches if the replacement is Mask.
+	Mask string `json:"mask,omitempty"`
+}
+
+type MaskingRule struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+}
+
+type MaskingDetector string
+
+const (
+	MaskingDetectorJWT         MaskingDetector = "JWT"
+	MaskingDetectorBearerToken MaskingDetector = "BearerToken"
+	MaskingDetectorEmail       MaskingDetector = "Email"
+	MaskingDetectorCreditCard  MaskingDetector = "CreditCard"
+	MaskingDetectorIP          MaskingDetector = "IP"
+)
+
+type MaskingReplacement string
+
+const (
+	MaskingReplacementMask MaskingReplacement = "Mask"
+	MaskingReplacementHash MaskingReplacement = "Hash"
+)
@@ -46,6 +46,9 @@ type PodLoggingConfigSpec struct {
 	// Multiline parsers
 	MultiLineParser MultiLineParser `json:"multilineParser,omitempty"`
 
+	// Masking of sensitive data
+	Masking Masking `json:"masking,omitempty"`
+
 	// ClusterDestinationRefs slice of ClusterLogDestination names
 	ClusterDestinationRefs []string `json:"clusterDestinationRefs,omitempty"`
 }
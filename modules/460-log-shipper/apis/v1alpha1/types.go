@@ -23,9 +23,15 @@ const (
 	DestVector        = "Vector"
 	DestKafka         = "Kafka"
 	DestSplunk        = "Splunk"
+	DestS3            = "S3"
+	DestClickHouse    = "ClickHouse"
+	DestSyslog        = "Syslog"
+	DestOTLP          = "OTLP"
 )
 
 const (
-	SourceKubernetesPods = "KubernetesPods"
-	SourceFile           = "File"
+	SourceKubernetesPods   = "KubernetesPods"
+	SourceFile             = "File"
+	SourceJournald         = "Journald"
+	SourceKubernetesEvents = "KubernetesEvents"
 )
@@ -109,16 +109,19 @@ func generateConfig(input *go_hook.HookInput) error {
 	if len(input.Snapshots["namespace"]) < 1 {
 		// there is no namespace to manipulate the config map, the hook will create it later on afterHelm
 		input.Values.Set("logShipper.internal.activated", false)
+		input.Values.Set("logShipper.internal.collectKubernetesEvents", false)
 		return nil
 	}
 
-	configContent, err := composer.FromInput(input).Do()
+	c := composer.FromInput(input)
+	configContent, err := c.Do()
 	if err != nil {
 		return err
 	}
 
 	activated := len(configContent) != 0
 	input.Values.Set("logShipper.internal.activated", activated)
+	input.Values.Set("logShipper.internal.collectKubernetesEvents", activated && c.CollectsKubernetesEvents())
 
 	if !activated {
 		input.PatchCollector.Delete(
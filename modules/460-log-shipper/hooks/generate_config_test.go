@@ -85,6 +85,37 @@ spec:
 		})
 	})
 
+	Context("Kubernetes Events source", func() {
+		BeforeEach(func() {
+			manifests, err := os.ReadFile(filepath.Join("testdata", "events-to-elastic", "manifests.yaml"))
+			Expect(err).To(BeNil())
+
+			f.BindingContexts.Set(f.KubeStateSet(namespaceManifest + string(manifests)))
+			f.RunHook()
+		})
+
+		It("Should enable the events collector", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("logShipper.internal.collectKubernetesEvents").Bool()).To(BeTrue())
+		})
+	})
+
+	Context("Journald source", func() {
+		BeforeEach(func() {
+			manifests, err := os.ReadFile(filepath.Join("testdata", "journald-to-loki", "manifests.yaml"))
+			Expect(err).To(BeNil())
+
+			f.BindingContexts.Set(f.KubeStateSet(namespaceManifest + string(manifests)))
+			f.RunHook()
+		})
+
+		It("Should not enable the events collector", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("logShipper.internal.activated").Bool()).To(BeTrue())
+			Expect(f.ValuesGet("logShipper.internal.collectKubernetesEvents").Bool()).To(BeFalse())
+		})
+	})
+
 	DescribeTable("React to Custom Resources",
 		func(folder string) {
 			folder = filepath.Join("testdata", folder)
@@ -141,5 +172,14 @@ spec:
 		Entry("File to Splunk", "file-to-splunk"),
 		Entry("Two sources to single destination", "many-to-one"),
 		Entry("Throttle Transform with filter", "throttle-with-filter"),
+		Entry("Journald to Loki", "journald-to-loki"),
+		Entry("Kubernetes Events to Elasticsearch", "events-to-elastic"),
+		Entry("Pods to S3", "pods-to-s3"),
+		Entry("File to ClickHouse", "file-to-clickhouse"),
+		Entry("Pods to Syslog", "pods-to-syslog"),
+		Entry("Pods to OTLP", "pods-to-otlp"),
+		Entry("Masking sensitive data", "masking"),
+		Entry("Routing rules", "routing"),
+		Entry("Routing rules with unmatched events destination and masking", "routing-unmatched"),
 	)
 })
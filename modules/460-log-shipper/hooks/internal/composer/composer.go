@@ -30,6 +30,8 @@ import (
 type Composer struct {
 	Source []v1alpha1.ClusterLoggingConfig
 	Dest   []v1alpha1.ClusterLogDestination
+
+	collectsKubernetesEvents bool
 }
 
 func FromInput(input *go_hook.HookInput) *Composer {
@@ -83,10 +85,12 @@ func (c *Composer) Do() ([]byte, error) {
 	for _, s := range c.Source {
 		transforms, err := transform.CreateLogSourceTransforms(s.Name, &transform.LogSourceConfig{
 			SourceType:            s.Spec.Type,
+			KubernetesEvents:      s.Spec.KubernetesEvents,
 			MultilineType:         s.Spec.MultiLineParser.Type,
 			MultilineCustomConfig: s.Spec.MultiLineParser.Custom,
 			LabelFilter:           s.Spec.LabelFilters,
 			LogFilter:             s.Spec.LogFilters,
+			Masking:               s.Spec.Masking,
 		})
 		if err != nil {
 			return nil, err
@@ -99,12 +103,34 @@ func (c *Composer) Do() ([]byte, error) {
 
 		var destinations []PipelineDestination
 
+		routes := make(map[string]v1alpha1.Route, len(s.Spec.Routes))
+		for _, route := range s.Spec.Routes {
+			routes[route.DestinationRef] = route
+		}
+
 		for _, ref := range s.Spec.DestinationRefs {
 			dst := destinationRefs[destination.ComposeName(ref)]
 
-			if dst.Destination != nil {
-				destinations = append(destinations, dst)
+			if dst.Destination == nil {
+				continue
+			}
+
+			if route, ok := routes[ref]; ok {
+				dst.RouteTransforms, err = transform.CreateRouteTransforms(s.Name, route)
+				if err != nil {
+					return nil, err
+				}
 			}
+
+			destinations = append(destinations, dst)
+		}
+
+		unmatched, err := unmatchedDestination(s, routes, destinationRefs)
+		if err != nil {
+			return nil, err
+		}
+		if unmatched != nil {
+			destinations = append(destinations, *unmatched)
 		}
 
 		if len(destinations) > 0 {
@@ -115,12 +141,22 @@ func (c *Composer) Do() ([]byte, error) {
 			if err != nil {
 				return nil, err
 			}
+
+			if s.Spec.Type == v1alpha1.SourceKubernetesEvents {
+				c.collectsKubernetesEvents = true
+			}
 		}
 	}
 
 	return file.ConvertToJSON()
 }
 
+// CollectsKubernetesEvents reports whether the composed config has a pipeline for Kubernetes Events,
+// which requires the events-collector to be deployed. It is only valid after calling Do.
+func (c *Composer) CollectsKubernetesEvents() bool {
+	return c.collectsKubernetesEvents
+}
+
 func (c *Composer) composeDestinations() (map[string]PipelineDestination, error) {
 	destinationByName := make(map[string]PipelineDestination)
 
@@ -147,6 +183,10 @@ func newLogSource(typ, name string, spec v1alpha1.ClusterLoggingConfigSpec) apis
 		return source.NewFile(name, spec.File)
 	case v1alpha1.SourceKubernetesPods:
 		return source.NewKubernetes(name, spec.KubernetesPods, false)
+	case v1alpha1.SourceJournald:
+		return source.NewJournald(name, spec.Journald)
+	case v1alpha1.SourceKubernetesEvents:
+		return source.NewKubernetesEvents(name)
 	}
 	return nil
 }
@@ -165,6 +205,54 @@ func newLogDest(typ, name string, spec v1alpha1.ClusterLogDestinationSpec) apis.
 		return destination.NewKafka(name, spec)
 	case v1alpha1.DestSplunk:
 		return destination.NewSplunk(name, spec)
+	case v1alpha1.DestS3:
+		return destination.NewS3(name, spec)
+	case v1alpha1.DestClickHouse:
+		return destination.NewClickHouse(name, spec)
+	case v1alpha1.DestSyslog:
+		return destination.NewSyslog(name, spec)
+	case v1alpha1.DestOTLP:
+		return destination.NewOTLP(name, spec)
 	}
 	return nil
 }
+
+// unmatchedDestination returns the destination for events that match none of the routes of the source.
+// It is skipped if there are no routes or if the destination is already in destinationRefs and receives all events.
+func unmatchedDestination(s v1alpha1.ClusterLoggingConfig, routes map[string]v1alpha1.Route, destinationRefs map[string]PipelineDestination) (*PipelineDestination, error) {
+	ref := s.Spec.UnmatchedDestinationRef
+	if ref == "" {
+		return nil, nil
+	}
+
+	dst := destinationRefs[destination.ComposeName(ref)]
+	if dst.Destination == nil {
+		return nil, nil
+	}
+
+	// Only routes of existing destinations deliver events
+	activeRoutes := make([]v1alpha1.Route, 0, len(routes))
+	for _, destinationRef := range s.Spec.DestinationRefs {
+		if destinationRef == ref {
+			return nil, nil
+		}
+		if destinationRefs[destination.ComposeName(destinationRef)].Destination == nil {
+			continue
+		}
+		if route, ok := routes[destinationRef]; ok {
+			activeRoutes = append(activeRoutes, route)
+		}
+	}
+	if len(activeRoutes) == 0 {
+		return nil, nil
+	}
+
+	var err error
+	dst.RouteTransforms, err = transform.CreateUnmatchedTransforms(s.Name, activeRoutes)
+	if err != nil {
+		return nil, err
+	}
+	dst.RouteOutput = transform.UnmatchedOutput
+
+	return &dst, nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/hooks/internal/vector/transform"
+)
+
+type composedTransform struct {
+	Type      string            `json:"type"`
+	Inputs    []string          `json:"inputs"`
+	Source    string            `json:"source"`
+	Condition string            `json:"condition"`
+	Route     map[string]string `json:"route"`
+}
+
+type composedFile struct {
+	Transforms map[string]composedTransform `json:"transforms"`
+	Sinks      map[string]struct {
+		Inputs []string `json:"inputs"`
+	} `json:"sinks"`
+}
+
+func composeRouting(t *testing.T, spec v1alpha1.ClusterLoggingConfigSpec) composedFile {
+	c := &Composer{
+		Source: []v1alpha1.ClusterLoggingConfig{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pods"},
+			Spec:       spec,
+		}},
+		Dest: []v1alpha1.ClusterLogDestination{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "errors"},
+				Spec:       v1alpha1.ClusterLogDestinationSpec{Type: v1alpha1.DestLoki, Loki: v1alpha1.LokiSpec{Endpoint: "http://errors:3100"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "unrouted"},
+				Spec:       v1alpha1.ClusterLogDestinationSpec{Type: v1alpha1.DestLoki, Loki: v1alpha1.LokiSpec{Endpoint: "http://unrouted:3100"}},
+			},
+		},
+	}
+
+	data, err := c.Do()
+	require.NoError(t, err)
+
+	var file composedFile
+	require.NoError(t, json.Unmarshal(data, &file))
+	return file
+}
+
+// upstream returns the chain of transforms from the sink input to the source.
+func (f composedFile) upstream(input string) []string {
+	var chain []string
+	for {
+		name := strings.TrimSuffix(input, "."+transform.UnmatchedOutput)
+		tr, ok := f.Transforms[name]
+		if !ok {
+			return chain
+		}
+		chain = append(chain, name)
+		input = tr.Inputs[0]
+	}
+}
+
+func TestComposer_RouteLogFiltersWithMasking(t *testing.T) {
+	file := composeRouting(t, v1alpha1.ClusterLoggingConfigSpec{
+		Type:            v1alpha1.SourceKubernetesPods,
+		DestinationRefs: []string{"errors"},
+		// The source log filter parses the message before masking
+		LogFilters: []v1alpha1.Filter{{Field: "level", Operator: v1alpha1.FilterOpExists}},
+		Masking:    v1alpha1.Masking{Detectors: []v1alpha1.MaskingDetector{v1alpha1.MaskingDetectorEmail}},
+		Routes: []v1alpha1.Route{{
+			DestinationRef: "errors",
+			LogFilters:     []v1alpha1.Filter{{Field: "level", Operator: v1alpha1.FilterOpIn, Values: []interface{}{"error"}}},
+		}},
+	})
+
+	chain := file.upstream(file.Sinks["destination/cluster/errors"].Inputs[0])
+	require.NotEmpty(t, chain)
+
+	routeFilter := file.Transforms[chain[0]]
+	assert.Equal(t, "transform/route/pods/errors/01_log_filter", chain[0])
+	assert.Contains(t, routeFilter.Condition, ".parsed_data.level")
+
+	// Masking runs before the route and keeps the parsed data for the route filter, parsing the masked message
+	masking := -1
+	for i, name := range chain {
+		if strings.HasSuffix(name, "_masking") {
+			masking = i
+		}
+	}
+	require.Greater(t, masking, 0, "masking must precede the route filter: %v", chain)
+	maskingSource := file.Transforms[chain[masking]].Source
+	assert.Contains(t, maskingSource, "parse_json(message)")
+	assert.NotContains(t, maskingSource, "del(.parsed_data)")
+}
+
+func TestComposer_UnmatchedDestination(t *testing.T) {
+	routes := []v1alpha1.Route{{
+		DestinationRef: "errors",
+		LabelFilters:   []v1alpha1.Filter{{Field: "namespace", Operator: v1alpha1.FilterOpIn, Values: []interface{}{"shop"}}},
+		LogFilters:     []v1alpha1.Filter{{Field: "level", Operator: v1alpha1.FilterOpIn, Values: []interface{}{"error"}}},
+	}}
+
+	t.Run("unmatched events", func(t *testing.T) {
+		file := composeRouting(t, v1alpha1.ClusterLoggingConfigSpec{
+			Type:                    v1alpha1.SourceKubernetesPods,
+			DestinationRefs:         []string{"errors"},
+			UnmatchedDestinationRef: "unrouted",
+			Masking:                 v1alpha1.Masking{Detectors: []v1alpha1.MaskingDetector{v1alpha1.MaskingDetectorEmail}},
+			Routes:                  routes,
+		})
+
+		inputs := file.Sinks["destination/cluster/unrouted"].Inputs
+		assert.Equal(t, []string{"transform/route/pods/unmatched/01_unmatched._unmatched"}, inputs)
+
+		unmatched := file.Transforms["transform/route/pods/unmatched/01_unmatched"]
+		assert.Equal(t, "route", unmatched.Type)
+		require.Contains(t, unmatched.Route, "route_0")
+		assert.Contains(t, unmatched.Route["route_0"], ".namespace")
+		assert.Contains(t, unmatched.Route["route_0"], ".parsed_data.level")
+		assert.True(t, strings.HasSuffix(unmatched.Route["route_0"], "route_filter_0 == true && route_filter_1 == true"))
+
+		chain := file.upstream(inputs[0])
+		assert.Equal(t, "transform/route/pods/unmatched/00_parse_json", chain[1])
+		assert.Equal(t, "transform/source/pods/03_masking", chain[2])
+	})
+
+	t.Run("no routes", func(t *testing.T) {
+		file := composeRouting(t, v1alpha1.ClusterLoggingConfigSpec{
+			Type:                    v1alpha1.SourceKubernetesPods,
+			DestinationRefs:         []string{"errors"},
+			UnmatchedDestinationRef: "unrouted",
+		})
+		assert.NotContains(t, file.Sinks, "destination/cluster/unrouted")
+	})
+
+	t.Run("unmatched destination in destinationRefs", func(t *testing.T) {
+		file := composeRouting(t, v1alpha1.ClusterLoggingConfigSpec{
+			Type:                    v1alpha1.SourceKubernetesPods,
+			DestinationRefs:         []string{"errors", "unrouted"},
+			UnmatchedDestinationRef: "unrouted",
+			Routes:                  routes,
+		})
+		assert.NotContains(t, file.Transforms, "transform/route/pods/unmatched/01_unmatched")
+		assert.Equal(t, []string{"transform/source/pods/02_local_timezone"}, file.Sinks["destination/cluster/unrouted"].Inputs)
+	})
+}
@@ -43,6 +43,11 @@ type PipelineDestination struct {
 
 	Destination apis.LogDestination
 	Transforms  []apis.LogTransform
+
+	// RouteTransforms filter events of this pipeline only, they are placed before destination transforms.
+	RouteTransforms []apis.LogTransform
+	// RouteOutput is the named output of the last route transform, e.g., unmatched events.
+	RouteOutput string
 }
 
 // VectorFile is a vector config file corresponding golang structure.
@@ -114,6 +119,20 @@ func (v *VectorFile) AppendLogPipeline(pipeline *Pipeline) error {
 	for _, pipelineDest := range pipeline.Destinations {
 		dest := pipelineDest.Destination
 
+		inputs := destinationInputs
+		if len(pipelineDest.RouteTransforms) > 0 {
+			for _, trans := range pipelineDest.RouteTransforms {
+				v.Transforms[trans.GetName()] = trans
+			}
+
+			pipelineDest.RouteTransforms[0].SetInputs(destinationInputs)
+			input := pipelineDest.RouteTransforms[len(pipelineDest.RouteTransforms)-1].GetName()
+			if pipelineDest.RouteOutput != "" {
+				input += "." + pipelineDest.RouteOutput
+			}
+			inputs = []string{input}
+		}
+
 		if _, ok := v.Sinks[dest.GetName()]; !ok {
 			v.Sinks[dest.GetName()] = dest
 		}
@@ -125,13 +144,13 @@ func (v *VectorFile) AppendLogPipeline(pipeline *Pipeline) error {
 		}
 
 		if len(pipelineDest.Transforms) > 0 {
-			v.Transforms[pipelineDest.Transforms[0].GetName()].SetInputs(destinationInputs)
+			v.Transforms[pipelineDest.Transforms[0].GetName()].SetInputs(inputs)
 
 			v.Sinks[dest.GetName()].SetInputs([]string{
 				pipelineDest.Transforms[len(pipelineDest.Transforms)-1].GetName(),
 			})
 		} else {
-			v.Sinks[dest.GetName()].SetInputs(destinationInputs)
+			v.Sinks[dest.GetName()].SetInputs(inputs)
 		}
 	}
 
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+)
+
+type ClickHouse struct {
+	CommonSettings
+
+	Endpoint string `json:"endpoint"`
+
+	Database string `json:"database,omitempty"`
+
+	Table string `json:"table"`
+
+	Encoding Encoding `json:"encoding,omitempty"`
+
+	Compression string `json:"compression,omitempty"`
+
+	Auth ClickHouseAuth `json:"auth,omitempty"`
+
+	TLS CommonTLS `json:"tls"`
+
+	// Fields without a column in the table are ignored instead of failing the whole batch.
+	SkipUnknownFields bool `json:"skip_unknown_fields"`
+
+	DateTimeBestEffort bool `json:"date_time_best_effort"`
+}
+
+type ClickHouseAuth struct {
+	Strategy string `json:"strategy,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+func NewClickHouse(name string, cspec v1alpha1.ClusterLogDestinationSpec) *ClickHouse {
+	spec := cspec.ClickHouse
+
+	tls := CommonTLS{
+		CAFile:            decodeB64(spec.TLS.CAFile),
+		CertFile:          decodeB64(spec.TLS.CertFile),
+		KeyFile:           decodeB64(spec.TLS.KeyFile),
+		KeyPass:           decodeB64(spec.TLS.KeyPass),
+		VerifyCertificate: true,
+		VerifyHostname:    true,
+	}
+	if spec.TLS.VerifyCertificate != nil {
+		tls.VerifyCertificate = *spec.TLS.VerifyCertificate
+	}
+	if spec.TLS.VerifyHostname != nil {
+		tls.VerifyHostname = *spec.TLS.VerifyHostname
+	}
+
+	var auth ClickHouseAuth
+	if spec.Auth.User != "" {
+		auth = ClickHouseAuth{
+			Strategy: "basic",
+			User:     spec.Auth.User,
+			Password: decodeB64(spec.Auth.Password),
+		}
+	}
+
+	return &ClickHouse{
+		CommonSettings: CommonSettings{
+			Name:   ComposeName(name),
+			Type:   "clickhouse",
+			Inputs: set.New(),
+			Buffer: buildVectorBuffer(cspec.Buffer),
+		},
+		Auth: auth,
+		Encoding: Encoding{
+			TimestampFormat: "rfc3339",
+		},
+		TLS:                tls,
+		Endpoint:           spec.Endpoint,
+		Database:           spec.Database,
+		Table:              spec.Table,
+		Compression:        "gzip",
+		SkipUnknownFields:  true,
+		DateTimeBestEffort: true,
+	}
+}
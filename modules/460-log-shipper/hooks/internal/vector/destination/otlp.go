@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"strings"
+
+	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+)
+
+// OTLP sends log records using the OTLP/HTTP protocol with JSON encoding.
+//
+// Vector can not wrap a batch of events into a single OTLP request, so every event is converted
+// to the whole request by the destination transform (see vrl.OTLPRule) and sent separately.
+type OTLP struct {
+	CommonSettings
+
+	URI string `json:"uri"`
+
+	Method string `json:"method"`
+
+	Encoding Encoding `json:"encoding,omitempty"`
+
+	Framing OTLPFraming `json:"framing"`
+
+	Batch OTLPBatch `json:"batch"`
+
+	Compression string `json:"compression,omitempty"`
+
+	Auth OTLPAuth `json:"auth,omitempty"`
+
+	Request OTLPRequest `json:"request,omitempty"`
+
+	TLS CommonTLS `json:"tls"`
+}
+
+type OTLPFraming struct {
+	Method string `json:"method"`
+}
+
+type OTLPBatch struct {
+	MaxEvents uint32 `json:"max_events"`
+}
+
+type OTLPAuth struct {
+	Password string `json:"password,omitempty"`
+	Strategy string `json:"strategy,omitempty"`
+	Token    string `json:"token,omitempty"`
+	User     string `json:"user,omitempty"`
+}
+
+type OTLPRequest struct {
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func NewOTLP(name string, cspec v1alpha1.ClusterLogDestinationSpec) *OTLP {
+	spec := cspec.OTLP
+
+	tls := CommonTLS{
+		CAFile:            decodeB64(spec.TLS.CAFile),
+		CertFile:          decodeB64(spec.TLS.CertFile),
+		KeyFile:           decodeB64(spec.TLS.KeyFile),
+		KeyPass:           decodeB64(spec.TLS.KeyPass),
+		VerifyCertificate: true,
+		VerifyHostname:    true,
+	}
+	if spec.TLS.VerifyCertificate != nil {
+		tls.VerifyCertificate = *spec.TLS.VerifyCertificate
+	}
+	if spec.TLS.VerifyHostname != nil {
+		tls.VerifyHostname = *spec.TLS.VerifyHostname
+	}
+
+	var auth OTLPAuth
+	if spec.Auth.Strategy != "" {
+		auth = OTLPAuth{
+			User:     spec.Auth.User,
+			Token:    spec.Auth.Token,
+			Strategy: strings.ToLower(spec.Auth.Strategy),
+			Password: decodeB64(spec.Auth.Password),
+		}
+	}
+
+	return &OTLP{
+		CommonSettings: CommonSettings{
+			Name:   ComposeName(name),
+			Type:   "http",
+			Inputs: set.New(),
+			Buffer: buildVectorBuffer(cspec.Buffer),
+		},
+		Auth: auth,
+		Encoding: Encoding{
+			Codec: "json",
+		},
+		Framing: OTLPFraming{
+			Method: "bytes",
+		},
+		Batch: OTLPBatch{
+			MaxEvents: 1,
+		},
+		Request: OTLPRequest{
+			Headers: spec.Headers,
+		},
+		TLS:         tls,
+		URI:         spec.Endpoint,
+		Method:      "post",
+		Compression: "gzip",
+	}
+}
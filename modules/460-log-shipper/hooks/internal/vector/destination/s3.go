@@ -0,0 +1,120 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"strings"
+
+	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+)
+
+type S3 struct {
+	CommonSettings
+
+	Bucket string `json:"bucket"`
+
+	Region string `json:"region,omitempty"`
+
+	Endpoint string `json:"endpoint,omitempty"`
+
+	KeyPrefix string `json:"key_prefix,omitempty"`
+
+	Encoding Encoding `json:"encoding,omitempty"`
+
+	Compression string `json:"compression,omitempty"`
+
+	Batch S3Batch `json:"batch,omitempty"`
+
+	Auth S3Auth `json:"auth,omitempty"`
+
+	TLS CommonTLS `json:"tls"`
+}
+
+type S3Batch struct {
+	MaxBytes    uint32 `json:"max_bytes,omitempty"`
+	TimeoutSecs uint32 `json:"timeout_secs,omitempty"`
+}
+
+type S3Auth struct {
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+}
+
+func NewS3(name string, cspec v1alpha1.ClusterLogDestinationSpec) *S3 {
+	spec := cspec.S3
+
+	tls := CommonTLS{
+		CAFile:            decodeB64(spec.TLS.CAFile),
+		CertFile:          decodeB64(spec.TLS.CertFile),
+		KeyFile:           decodeB64(spec.TLS.KeyFile),
+		KeyPass:           decodeB64(spec.TLS.KeyPass),
+		VerifyCertificate: true,
+		VerifyHostname:    true,
+	}
+	if spec.TLS.VerifyCertificate != nil {
+		tls.VerifyCertificate = *spec.TLS.VerifyCertificate
+	}
+	if spec.TLS.VerifyHostname != nil {
+		tls.VerifyHostname = *spec.TLS.VerifyHostname
+	}
+
+	compression := "gzip"
+	if spec.Compression != "" {
+		compression = strings.ToLower(spec.Compression)
+	}
+
+	keyPrefix := spec.KeyPrefix
+	if keyPrefix == "" {
+		keyPrefix = "%F/"
+	}
+
+	batch := S3Batch{
+		MaxBytes:    10 * 1024 * 1024,
+		TimeoutSecs: 300,
+	}
+	if spec.Batch.MaxBytes > 0 {
+		batch.MaxBytes = spec.Batch.MaxBytes
+	}
+	if spec.Batch.TimeoutSeconds > 0 {
+		batch.TimeoutSecs = spec.Batch.TimeoutSeconds
+	}
+
+	return &S3{
+		CommonSettings: CommonSettings{
+			Name:   ComposeName(name),
+			Type:   "aws_s3",
+			Inputs: set.New(),
+			Buffer: buildVectorBuffer(cspec.Buffer),
+		},
+		Auth: S3Auth{
+			AccessKeyID:     decodeB64(spec.Auth.AccessKeyID),
+			SecretAccessKey: decodeB64(spec.Auth.SecretAccessKey),
+		},
+		Encoding: Encoding{
+			Codec:           "json",
+			TimestampFormat: "rfc3339",
+		},
+		TLS:         tls,
+		Bucket:      spec.Bucket,
+		Region:      spec.Region,
+		Endpoint:    spec.Endpoint,
+		KeyPrefix:   keyPrefix,
+		Compression: compression,
+		Batch:       batch,
+	}
+}
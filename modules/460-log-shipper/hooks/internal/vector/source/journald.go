@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"strconv"
+
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+)
+
+var _ apis.LogSource = (*Journald)(nil)
+
+// journaldPriorities are syslog priorities in the order of decreasing importance.
+var journaldPriorities = []string{"Emergency", "Alert", "Critical", "Error", "Warning", "Notice", "Info", "Debug"}
+
+// Journald represents `journald` vector source
+// https://vector.dev/docs/reference/configuration/sources/journald/
+type Journald struct {
+	commonSource
+
+	IncludeUnits   []string            `json:"include_units,omitempty"`
+	ExcludeUnits   []string            `json:"exclude_units,omitempty"`
+	IncludeMatches map[string][]string `json:"include_matches,omitempty"`
+}
+
+func NewJournald(name string, spec v1alpha1.JournaldSpec) *Journald {
+	j := &Journald{
+		commonSource: commonSource{
+			Name: "cluster_logging_config/" + name,
+			Type: "journald",
+		},
+		IncludeUnits: spec.IncludeUnits,
+		ExcludeUnits: spec.ExcludeUnits,
+	}
+
+	// Journal matches are ORed for the same field, so all the priorities up to the specified one are listed.
+	for i, priority := range journaldPriorities {
+		if priority == spec.Priority && i < len(journaldPriorities)-1 {
+			j.IncludeMatches = map[string][]string{"PRIORITY": priorityNumbers(i)}
+			break
+		}
+	}
+
+	return j
+}
+
+func priorityNumbers(upTo int) []string {
+	res := make([]string, 0, upTo+1)
+	for i := 0; i <= upTo; i++ {
+		res = append(res, strconv.Itoa(i))
+	}
+	return res
+}
+
+func (j *Journald) BuildSources() []apis.LogSource {
+	return []apis.LogSource{j}
+}
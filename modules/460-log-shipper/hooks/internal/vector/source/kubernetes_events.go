@@ -0,0 +1,56 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis"
+)
+
+const (
+	eventsCollectorNamespace = "d8-log-shipper"
+	eventsCollectorSelector  = "app=events-collector"
+)
+
+var _ apis.LogSource = (*KubernetesEvents)(nil)
+
+// KubernetesEvents represents a source for collecting Kubernetes Events.
+//
+// Vector does not have a source for Kubernetes Events, so the events-collector Deployment prints them as JSON lines,
+// and they are read as logs of the events-collector Pod. The agent on the node the Pod is running on sends them.
+// Events are parsed and filtered by source transforms.
+type KubernetesEvents struct {
+	rawKubernetesLogs
+}
+
+func NewKubernetesEvents(name string) *KubernetesEvents {
+	return &KubernetesEvents{
+		rawKubernetesLogs: rawKubernetesLogs{
+			commonSource: commonSource{
+				Name: "cluster_logging_config/" + name,
+				Type: "kubernetes_logs",
+			},
+			Fields:             "metadata.namespace=" + eventsCollectorNamespace,
+			Labels:             eventsCollectorSelector,
+			GlobCooldownMs:     defaultGlobCooldownMs,
+			UserAPIServerCache: true,
+		},
+	}
+}
+
+func (k *KubernetesEvents) BuildSources() []apis.LogSource {
+	return []apis.LogSource{k}
+}
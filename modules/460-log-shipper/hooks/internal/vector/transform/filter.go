@@ -41,12 +41,15 @@ func CreateParseDataTransforms() *DynamicTransform {
 
 type mutateFilter func(*v1alpha1.Filter, *vrl.Rule)
 
+// parsedDataFilter points log filters to the parsed message.
+func parsedDataFilter(filter *v1alpha1.Filter, _ *vrl.Rule) {
+	// parsed_data is a key for parsed json data from a message, we use it to quickly filter inputs
+	// "filter_field" -> "parsed_data.filter_field", "" -> "parsed_data"
+	filter.Field = strings.Join([]string{"parsed_data", filter.Field}, ".")
+}
+
 func CreateLogFilterTransforms(filters []v1alpha1.Filter) ([]apis.LogTransform, error) {
-	transforms, err := createFilterTransform("log_filter", filters, func(filter *v1alpha1.Filter, rule *vrl.Rule) {
-		// parsed_data is a key for parsed json data from a message, we use it to quickly filter inputs
-		// "filter_field" -> "parsed_data.filter_field", "" -> "parsed_data"
-		filter.Field = strings.Join([]string{"parsed_data", filter.Field}, ".")
-	})
+	transforms, err := createFilterTransform("log_filter", filters, parsedDataFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +64,30 @@ func CreateLabelFilterTransforms(filters []v1alpha1.Filter) ([]apis.LogTransform
 }
 
 func createFilterTransform(name string, filters []v1alpha1.Filter, mutate mutateFilter) ([]apis.LogTransform, error) {
-	transforms := make([]apis.LogTransform, 0)
+	conditions, err := filterConditions(filters, mutate)
+	if err != nil {
+		return nil, err
+	}
+
+	transforms := make([]apis.LogTransform, 0, len(conditions))
+	for _, condition := range conditions {
+		transforms = append(transforms, &DynamicTransform{
+			CommonTransform: CommonTransform{
+				Name:   name,
+				Type:   "filter",
+				Inputs: set.New(),
+			},
+			DynamicArgsMap: map[string]interface{}{
+				"condition": condition,
+			},
+		})
+	}
+	return transforms, nil
+}
+
+// filterConditions renders a VRL condition for every filter with a known operator.
+func filterConditions(filters []v1alpha1.Filter, mutate mutateFilter) ([]string, error) {
+	conditions := make([]string, 0, len(filters))
 
 	for _, filter := range filters {
 		rule := getRuleOutOfFilter(&filter)
@@ -77,19 +103,9 @@ func createFilterTransform(name string, filters []v1alpha1.Filter, mutate mutate
 		if err != nil {
 			return nil, err
 		}
-
-		transforms = append(transforms, &DynamicTransform{
-			CommonTransform: CommonTransform{
-				Name:   name,
-				Type:   "filter",
-				Inputs: set.New(),
-			},
-			DynamicArgsMap: map[string]interface{}{
-				"condition": condition,
-			},
-		})
+		conditions = append(conditions, condition)
 	}
-	return transforms, nil
+	return conditions, nil
 }
 
 func getRuleOutOfFilter(filter *v1alpha1.Filter) vrl.Rule {
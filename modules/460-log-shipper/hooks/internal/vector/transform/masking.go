@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"fmt"
+
+	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/hooks/internal/vrl"
+)
+
+const defaultMask = "******"
+
+type maskingPattern struct {
+	Pattern string
+	// KeepPrefix preserves the first capturing group of the pattern.
+	KeepPrefix bool
+}
+
+// maskingDetectors are regular expressions (in the Rust regex syntax) for built-in detectors.
+var maskingDetectors = map[v1alpha1.MaskingDetector][]maskingPattern{
+	v1alpha1.MaskingDetectorJWT: {
+		{Pattern: `eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`},
+	},
+	v1alpha1.MaskingDetectorBearerToken: {
+		{Pattern: `(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`, KeepPrefix: true},
+	},
+	v1alpha1.MaskingDetectorEmail: {
+		{Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	},
+	v1alpha1.MaskingDetectorCreditCard: {
+		// Visa, Mastercard, Discover, and American Express numbers, optionally separated by spaces or dashes.
+		{Pattern: `\b(?:4\d{3}|5[1-5]\d{2}|2[2-7]\d{2}|6011|65\d{2})(?:[ -]?\d{4}){3}\b`},
+		{Pattern: `\b3[47]\d{2}[ -]?\d{6}[ -]?\d{5}\b`},
+	},
+	v1alpha1.MaskingDetectorIP: {
+		{Pattern: `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`},
+		// Only full or "::"-compressed IPv6 addresses with groups on both sides of "::" to avoid matching
+		// timestamps like 12:30:45 and hex words like "add::".
+		{Pattern: `(?i)\b(?:[0-9a-f]{1,4}:){7}[0-9a-f]{1,4}\b`},
+		{Pattern: `(?i)\b(?:[0-9a-f]{1,4}:){1,6}:[0-9a-f]{1,4}(?::[0-9a-f]{1,4}){0,5}\b`},
+	},
+}
+
+// CreateMaskingTransforms hides sensitive data found by detectors and custom rules.
+func CreateMaskingTransforms(masking v1alpha1.Masking) ([]apis.LogTransform, error) {
+	patterns := make([]maskingPattern, 0)
+
+	for _, detector := range masking.Detectors {
+		patterns = append(patterns, maskingDetectors[detector]...)
+	}
+	for _, rule := range masking.CustomRules {
+		patterns = append(patterns, maskingPattern{Pattern: rule.Regex})
+	}
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	mask := masking.Mask
+	if mask == "" {
+		mask = defaultMask
+	}
+
+	source, err := vrl.MaskingRule.Render(vrl.Args{
+		"rules": patterns,
+		"hash":  masking.Replacement == v1alpha1.MaskingReplacementHash,
+		"mask":  mask,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("render masking rule: %w", err)
+	}
+
+	return []apis.LogTransform{&DynamicTransform{
+		CommonTransform: CommonTransform{
+			Name:   "masking",
+			Type:   "remap",
+			Inputs: set.New(),
+		},
+		DynamicArgsMap: map[string]interface{}{
+			"source":        source,
+			"drop_on_abort": false,
+		},
+	}}, nil
+}
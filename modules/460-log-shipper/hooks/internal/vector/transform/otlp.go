@@ -0,0 +1,69 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"fmt"
+
+	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/hooks/internal/vrl"
+)
+
+// otlpResourceAttributes maps metadata fields to the OpenTelemetry semantic conventions.
+var otlpResourceAttributes = [][2]string{
+	{"k8s.namespace.name", "namespace"},
+	{"k8s.pod.name", "pod"},
+	{"k8s.container.name", "container"},
+	{"k8s.node.name", "node"},
+	{"container.image.name", "image"},
+	{"host.name", "host"},
+}
+
+// OTLPTransform converts events to OTLP/HTTP JSON requests.
+func OTLPTransform(extraLabels map[string]string) (*DynamicTransform, error) {
+	resource := make([]map[string]string, 0, len(otlpResourceAttributes))
+	for _, attr := range otlpResourceAttributes {
+		resource = append(resource, map[string]string{"name": attr[0], "path": attr[1]})
+	}
+
+	attributes := []map[string]string{
+		{"name": "log.iostream", "path": "stream"},
+	}
+	for _, key := range mapKeys(extraLabels) {
+		attributes = append(attributes, map[string]string{"name": key, "path": escapeVectorString(key)})
+	}
+
+	source, err := vrl.OTLPRule.Render(vrl.Args{
+		"resource":   resource,
+		"attributes": attributes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("render otlp rule: %w", err)
+	}
+
+	return &DynamicTransform{
+		CommonTransform: CommonTransform{
+			Name:   "otlp",
+			Type:   "remap",
+			Inputs: set.New(),
+		},
+		DynamicArgsMap: map[string]interface{}{
+			"source":        source,
+			"drop_on_abort": false,
+		},
+	}, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+)
+
+// CreateRouteTransforms returns filters applied only to events sent from the source to the route destination.
+func CreateRouteTransforms(sourceName string, route v1alpha1.Route) ([]apis.LogTransform, error) {
+	var transforms []apis.LogTransform
+
+	labelFilterTransforms, err := CreateLabelFilterTransforms(route.LabelFilters)
+	if err != nil {
+		return nil, err
+	}
+	transforms = append(transforms, labelFilterTransforms...)
+
+	logFilterTransforms, err := CreateLogFilterTransforms(route.LogFilters)
+	if err != nil {
+		return nil, err
+	}
+	transforms = append(transforms, logFilterTransforms...)
+
+	if len(transforms) == 0 {
+		return nil, nil
+	}
+
+	return BuildFromMapSlice("route", sourceName+"/"+route.DestinationRef, transforms)
+}
+
+// UnmatchedOutput is the output of the route transform with events that match none of the routes.
+const UnmatchedOutput = "_unmatched"
+
+// CreateUnmatchedTransforms returns transforms selecting events that match none of the routes.
+// The unmatched events destination must read the UnmatchedOutput of the last transform.
+//
+// Filters are evaluated by a single Vector route transform. Events failing the evaluation of a condition
+// are also considered unmatched.
+func CreateUnmatchedTransforms(sourceName string, routes []v1alpha1.Route) ([]apis.LogTransform, error) {
+	var transforms []apis.LogTransform
+
+	conditions := make(map[string]string, len(routes))
+	for i, route := range routes {
+		condition, err := routeCondition(route)
+		if err != nil {
+			return nil, err
+		}
+		conditions[fmt.Sprintf("route_%d", i)] = condition
+
+		if len(route.LogFilters) > 0 && len(transforms) == 0 {
+			transforms = append(transforms, CreateParseDataTransforms())
+		}
+	}
+
+	transforms = append(transforms, &DynamicTransform{
+		CommonTransform: CommonTransform{
+			Name:   "unmatched",
+			Type:   "route",
+			Inputs: set.New(),
+		},
+		DynamicArgsMap: map[string]interface{}{
+			"route": conditions,
+		},
+	})
+
+	return BuildFromMapSlice("route", sourceName+"/unmatched", transforms)
+}
+
+// routeCondition combines all filters of the route into a single VRL program.
+func routeCondition(route v1alpha1.Route) (string, error) {
+	labelConditions, err := filterConditions(route.LabelFilters, nil)
+	if err != nil {
+		return "", err
+	}
+	logConditions, err := filterConditions(route.LogFilters, parsedDataFilter)
+	if err != nil {
+		return "", err
+	}
+
+	conditions := append(labelConditions, logConditions...)
+	if len(conditions) == 0 {
+		return "true", nil
+	}
+
+	var (
+		program strings.Builder
+		checks  = make([]string, 0, len(conditions))
+	)
+	for i, condition := range conditions {
+		// Filter conditions may return non-boolean values, they do not pass as in the filter transform
+		fmt.Fprintf(&program, "route_filter_%d = %s\n", i, strings.TrimSpace(condition))
+		checks = append(checks, fmt.Sprintf("route_filter_%d == true", i))
+	}
+	program.WriteString(strings.Join(checks, " && "))
+
+	return program.String(), nil
+}
@@ -67,23 +67,90 @@ func LocalTimezoneAfterSourceTransform() *DynamicTransform {
 	}
 }
 
+func JournaldSourceTransform() *DynamicTransform {
+	return &DynamicTransform{
+		CommonTransform: CommonTransform{
+			Name:   "journald",
+			Type:   "remap",
+			Inputs: set.New(),
+		},
+		DynamicArgsMap: map[string]interface{}{
+			"source":        vrl.JournaldRule.String(),
+			"drop_on_abort": false,
+		},
+	}
+}
+
+// KubernetesEventsSourceTransforms parses events printed by the events-collector and filters them.
+func KubernetesEventsSourceTransforms(spec v1alpha1.KubernetesEventsSpec) ([]apis.LogTransform, error) {
+	transforms := []apis.LogTransform{
+		&DynamicTransform{
+			CommonTransform: CommonTransform{
+				Name:   "kubernetes_event",
+				Type:   "remap",
+				Inputs: set.New(),
+			},
+			DynamicArgsMap: map[string]interface{}{
+				"source":        vrl.KubernetesEventRule.String(),
+				"drop_on_abort": true,
+			},
+		},
+	}
+
+	condition, err := vrl.KubernetesEventFilterRule.Render(vrl.Args{"spec": spec})
+	if err != nil {
+		return nil, err
+	}
+	if condition == "" {
+		return transforms, nil
+	}
+
+	transforms = append(transforms, &DynamicTransform{
+		CommonTransform: CommonTransform{
+			Name:   "kubernetes_event_filter",
+			Type:   "filter",
+			Inputs: set.New(),
+		},
+		DynamicArgsMap: map[string]interface{}{
+			"condition": condition,
+		},
+	})
+
+	return transforms, nil
+}
+
 type LogSourceConfig struct {
 	SourceType string
 
+	KubernetesEvents v1alpha1.KubernetesEventsSpec
+
 	MultilineType         v1alpha1.MultiLineParserType
 	MultilineCustomConfig v1alpha1.MultilineParserCustom
 	LabelFilter           []v1alpha1.Filter
 	LogFilter             []v1alpha1.Filter
+	Masking               v1alpha1.Masking
 }
 
 func CreateLogSourceTransforms(name string, cfg *LogSourceConfig) ([]apis.LogTransform, error) {
 	var transforms []apis.LogTransform
 
-	if cfg.SourceType == v1alpha1.SourceKubernetesPods {
+	switch cfg.SourceType {
+	case v1alpha1.SourceKubernetesPods:
 		transforms = append(transforms, OwnerReferenceSourceTransform())
+	case v1alpha1.SourceKubernetesEvents:
+		eventsTransforms, err := KubernetesEventsSourceTransforms(cfg.KubernetesEvents)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering kubernetes events transforms: %v", err)
+		}
+		transforms = append(transforms, eventsTransforms...)
 	}
 
 	transforms = append(transforms, CleanUpAfterSourceTransform())
+
+	if cfg.SourceType == v1alpha1.SourceJournald {
+		transforms = append(transforms, JournaldSourceTransform())
+	}
+
 	transforms = append(transforms, LocalTimezoneAfterSourceTransform())
 
 	multilineTransforms, err := CreateMultiLineTransforms(cfg.MultilineType, cfg.MultilineCustomConfig)
@@ -105,6 +172,12 @@ func CreateLogSourceTransforms(name string, cfg *LogSourceConfig) ([]apis.LogTra
 	}
 	transforms = append(transforms, logFilterTransforms...)
 
+	maskingTransforms, err := CreateMaskingTransforms(cfg.Masking)
+	if err != nil {
+		return nil, err
+	}
+	transforms = append(transforms, maskingTransforms...)
+
 	sTransforms, err := BuildFromMapSlice("source", name, transforms)
 	if err != nil {
 		return nil, fmt.Errorf("add source transforms: %v", err)
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/apis/v1alpha1"
+	"github.com/deckhouse/deckhouse/modules/460-log-shipper/hooks/internal/vrl"
+)
+
+// syslogFacilities are numerical codes of facilities from RFC5424.
+var syslogFacilities = map[string]int{
+	"Kern":   0,
+	"User":   1,
+	"Mail":   2,
+	"Daemon": 3,
+	"Auth":   4,
+	"Syslog": 5,
+	"Local0": 16,
+	"Local1": 17,
+	"Local2": 18,
+	"Local3": 19,
+	"Local4": 20,
+	"Local5": 21,
+	"Local6": 22,
+	"Local7": 23,
+}
+
+// validSDName matches labels that can be used as structured data parameter names.
+var validSDName = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,32}$`)
+
+// SyslogTransform formats messages for the Syslog destination.
+func SyslogTransform(spec v1alpha1.SyslogSpec, extraLabels map[string]string) (*DynamicTransform, error) {
+	facility, ok := syslogFacilities[spec.Facility]
+	if !ok {
+		facility = syslogFacilities["User"]
+	}
+
+	fields := make([]map[string]string, 0)
+	for _, name := range []string{"namespace", "container", "image", "pod_owner", "stream"} {
+		fields = append(fields, map[string]string{"name": name, "path": name})
+	}
+	for _, key := range mapKeys(extraLabels) {
+		if !validSDName.MatchString(key) {
+			continue
+		}
+		fields = append(fields, map[string]string{"name": key, "path": escapeVectorString(key)})
+	}
+
+	source, err := vrl.SyslogRule.Render(vrl.Args{
+		"facility": facility,
+		"appName":  spec.AppName,
+		"fields":   fields,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("render syslog rule: %w", err)
+	}
+
+	return &DynamicTransform{
+		CommonTransform: CommonTransform{
+			Name:   "syslog",
+			Type:   "remap",
+			Inputs: set.New(),
+		},
+		DynamicArgsMap: map[string]interface{}{
+			"source":        source,
+			"drop_on_abort": false,
+		},
+	}, nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vrl
+
+// MaskingRule replaces sensitive data in the message with a mask or with a truncated SHA-256 hash.
+// If a rule keeps the prefix, its first capturing group (e.g., "Bearer ") is not replaced.
+//
+// The parsed message contains unmasked data, thus it is parsed again from the masked message, so that
+// destination extra labels and route log filters keep working with masked values.
+const MaskingRule Rule = `
+if is_string(.message) {
+    message = string!(.message)
+{{- range $.rules }}
+{{- if $.hash }}
+    matches = parse_regex_all(message, r'{{ .Pattern }}', numeric_groups: true) ?? []
+    for_each(matches) -> |_index, m| {
+        secret = string(m."0") ?? ""
+{{- if .KeepPrefix }}
+        prefix = string(m."1") ?? ""
+{{- else }}
+        prefix = ""
+{{- end }}
+        if secret != "" {
+            message = replace(message, secret, prefix + "sha256:" + truncate(sha2(secret, variant: "SHA-256"), 16))
+        }
+    }
+{{- else }}
+    message = replace(message, r'{{ .Pattern }}', {{ if .KeepPrefix }}"${1}" + {{ end }}{{ $.mask | toJson }})
+{{- end }}
+{{- end }}
+    .message = message
+    if exists(.parsed_data) {
+        structured, err = parse_json(message)
+        if err == null {
+            .parsed_data = structured
+        } else {
+            .parsed_data = message
+        }
+    }
+}
+`
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vrl
+
+// OTLPRule replaces the event with the OTLP/HTTP JSON request containing the single log record.
+//
+// Kubernetes metadata is sent as resource attributes named according to the OpenTelemetry semantic conventions,
+// extra labels and the stream are sent as log record attributes.
+const OTLPRule Rule = `
+ts = parse_timestamp(.timestamp, format: "%+") ?? now()
+
+resource = []
+{{- range $.resource }}
+if exists(.{{ .path }}) {
+    resource = push(resource, {"key": {{ .name | toJson }}, "value": {"stringValue": string(.{{ .path }}) ?? encode_json(.{{ .path }})}})
+}
+{{- end }}
+labels = object(.pod_labels) ?? {}
+for_each(labels) -> |key, value| {
+    resource = push(resource, {"key": "k8s.pod.label." + key, "value": {"stringValue": string(value) ?? encode_json(value)}})
+}
+
+attributes = []
+{{- range $.attributes }}
+if exists(.{{ .path }}) {
+    attributes = push(attributes, {"key": {{ .name | toJson }}, "value": {"stringValue": string(.{{ .path }}) ?? encode_json(.{{ .path }})}})
+}
+{{- end }}
+
+record = {
+    "timeUnixNano": to_string(to_unix_timestamp(ts, unit: "nanoseconds")),
+    "body": {"stringValue": string(.message) ?? encode_json(.message)},
+    "attributes": attributes
+}
+
+. = {
+    "resourceLogs": [{
+        "resource": {"attributes": resource},
+        "scopeLogs": [{
+            "scope": {"name": "deckhouse.log-shipper"},
+            "logRecords": [record]
+        }]
+    }]
+}
+`
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vrl
+
+// JournaldRule moves the most useful journal fields to short names and drops the journal internals.
+const JournaldRule Rule = `
+if exists(._SYSTEMD_UNIT) {
+    .unit = del(._SYSTEMD_UNIT)
+}
+if exists(.host) {
+    .node = .host
+}
+del(.__CURSOR)
+del(.__MONOTONIC_TIMESTAMP)
+del(.__REALTIME_TIMESTAMP)
+`
+
+// KubernetesEventRule replaces the events-collector log line with the event it contains.
+// The message keeps the whole event, so that extra labels can refer to any of its fields.
+// Lines that are not events, e.g., errors of the collector itself, are dropped.
+const KubernetesEventRule Rule = `
+event, err = parse_json(.message)
+if err != null || !is_object(event) {
+    abort
+}
+message = .message
+. = object!(event)
+.message = message
+`
+
+// KubernetesEventFilterRule keeps only events from the selected namespaces and with the selected reasons.
+const KubernetesEventFilterRule Rule = `
+{{- $conditions := list }}
+{{- with $.spec.NamespaceSelector.MatchNames }}
+{{- $conditions = append $conditions (printf "includes(%s, .namespace)" (toJson .)) }}
+{{- end }}
+{{- with $.spec.NamespaceSelector.ExcludeNames }}
+{{- $conditions = append $conditions (printf "!includes(%s, .namespace)" (toJson .)) }}
+{{- end }}
+{{- with $.spec.IncludeReasons }}
+{{- $conditions = append $conditions (printf "includes(%s, .reason)" (toJson .)) }}
+{{- end }}
+{{- with $.spec.ExcludeReasons }}
+{{- $conditions = append $conditions (printf "!includes(%s, .reason)" (toJson .)) }}
+{{- end }}
+{{ join " && " $conditions }}
+`
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vrl
+
+// SyslogRule formats the message according to RFC5424.
+//
+// The severity is taken from the journald PRIORITY field if it is present, otherwise it is informational.
+// Kubernetes metadata and extra labels are sent as structured data, 32473 is the enterprise number reserved for documentation.
+const SyslogRule Rule = `
+ts = parse_timestamp(.timestamp, format: "%+") ?? now()
+
+severity = to_int(.PRIORITY) ?? 6
+if severity < 0 || severity > 7 {
+    severity = 6
+}
+
+hostname = string(.node) ?? string(.host) ?? ""
+{{- if $.appName }}
+app = {{ $.appName | toJson }}
+{{- else }}
+app = string(.container) ?? string(.unit) ?? ""
+{{- end }}
+procid = string(.pod) ?? ""
+
+hostname = truncate(replace(hostname, " ", "_"), 255)
+app = truncate(replace(app, " ", "_"), 48)
+procid = truncate(replace(procid, " ", "_"), 128)
+if hostname == "" {
+    hostname = "-"
+}
+if app == "" {
+    app = "-"
+}
+if procid == "" {
+    procid = "-"
+}
+
+sd = ""
+{{- range $.fields }}
+if exists(.{{ .path }}) {
+    value = string(.{{ .path }}) ?? encode_json(.{{ .path }})
+    sd = sd + " {{ .name }}=\"" + replace(value, r'([\\"\]])', "\\$1") + "\""
+}
+{{- end }}
+if sd == "" {
+    sd = "-"
+} else {
+    sd = "[k8s@32473" + sd + "]"
+}
+
+message = string(.message) ?? encode_json(.message)
+.message = "<" + to_string({{ $.facility }} * 8 + severity) + ">1 " + format_timestamp!(ts, format: "%Y-%m-%dT%H:%M:%S%.6f%:z") + " " + hostname + " " + app + " " + procid + " - " + sd + " " + message
+`
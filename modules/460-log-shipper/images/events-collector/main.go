@@ -0,0 +1,173 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// events-collector prints Kubernetes Events to stdout as JSON lines.
+//
+// Vector has no source for Kubernetes Events, so log-shipper agents read them
+// as logs of this Pod with the kubernetes_logs source.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Record is a single event log line.
+type Record struct {
+	Timestamp      time.Time      `json:"timestamp"`
+	Namespace      string         `json:"namespace,omitempty"`
+	Name           string         `json:"name"`
+	Type           string         `json:"type"`
+	Reason         string         `json:"reason"`
+	Message        string         `json:"message"`
+	Count          int32          `json:"count"`
+	Component      string         `json:"component,omitempty"`
+	Node           string         `json:"node,omitempty"`
+	InvolvedObject InvolvedObject `json:"involved_object"`
+}
+
+type InvolvedObject struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+}
+
+// lastSeen returns the time the event was observed for the last time.
+// Depending on the reporting client, only some of the fields are filled.
+func lastSeen(e *corev1.Event) time.Time {
+	switch {
+	case e.Series != nil && !e.Series.LastObservedTime.IsZero():
+		return e.Series.LastObservedTime.Time
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	case !e.FirstTimestamp.IsZero():
+		return e.FirstTimestamp.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+func count(e *corev1.Event) int32 {
+	if e.Series != nil && e.Series.Count > 0 {
+		return e.Series.Count
+	}
+	if e.Count > 0 {
+		return e.Count
+	}
+	return 1
+}
+
+func newRecord(e *corev1.Event) Record {
+	component := e.Source.Component
+	if component == "" {
+		component = e.ReportingController
+	}
+
+	return Record{
+		Timestamp: lastSeen(e).UTC(),
+		Namespace: e.Namespace,
+		Name:      e.Name,
+		Type:      e.Type,
+		Reason:    e.Reason,
+		Message:   e.Message,
+		Count:     count(e),
+		Component: component,
+		Node:      e.Source.Host,
+		InvolvedObject: InvolvedObject{
+			Kind:      e.InvolvedObject.Kind,
+			Namespace: e.InvolvedObject.Namespace,
+			Name:      e.InvolvedObject.Name,
+			UID:       string(e.InvolvedObject.UID),
+		},
+	}
+}
+
+func main() {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatalf("get in-cluster config: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("create client: %v", err)
+	}
+
+	// Events that happened before the start were printed by the previous instance,
+	// the informer lists them all on start.
+	startedAt := time.Now()
+	encoder := json.NewEncoder(os.Stdout)
+
+	emit := func(e *corev1.Event) {
+		if lastSeen(e).Before(startedAt) {
+			return
+		}
+		if err := encoder.Encode(newRecord(e)); err != nil {
+			log.Printf("encode event %s/%s: %v", e.Namespace, e.Name, err)
+		}
+	}
+
+	factory := informers.NewSharedInformerFactory(client, 0)
+	informer := factory.Core().V1().Events().Informer()
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if e, ok := obj.(*corev1.Event); ok {
+				emit(e)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldEvent, ok := oldObj.(*corev1.Event)
+			if !ok {
+				return
+			}
+			newEvent, ok := newObj.(*corev1.Event)
+			if !ok {
+				return
+			}
+			// Repeated events are updated with a new count, other updates are not interesting.
+			if count(newEvent) != count(oldEvent) {
+				emit(newEvent)
+			}
+		},
+	})
+	if err != nil {
+		log.Fatalf("add event handler: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		log.Fatal("events cache is not synced")
+	}
+	log.Println("watching events")
+
+	<-ctx.Done()
+	factory.Shutdown()
+}
@@ -70,6 +70,43 @@ resourcesRequests:
 			Expect(manVPA.Exists()).To(BeTrue())
 			Expect(manVPA.Field("spec.updatePolicy.updateMode").String()).To(Equal("Off"))
 			Expect(manVPA.Field("spec.resourcePolicy.containerPolicies").Exists()).To(BeFalse())
+
+			Expect(hec.KubernetesResource("Deployment", "d8-log-shipper", "events-collector").Exists()).To(BeFalse())
+		})
+	})
+
+	Context("With Kubernetes Events collected", func() {
+		BeforeEach(func() {
+			hec.ValuesSet("global.discovery.d8SpecificNodeCountByRole", map[string]interface{}{})
+			hec.ValuesSetFromYaml("logShipper", `
+debug: false
+internal:
+  activated: true
+  collectKubernetesEvents: true
+resourcesRequests:
+  mode: VPA
+  vpa:
+    cpu:
+      max: 500m
+      min: 50m
+    memory:
+      max: 2048Mi
+      min: 64Mi
+    mode: Initial
+`)
+			hec.HelmRender()
+		})
+		It("Should deploy the events collector", func() {
+			Expect(hec.RenderError).ShouldNot(HaveOccurred())
+
+			collector := hec.KubernetesResource("Deployment", "d8-log-shipper", "events-collector")
+			Expect(collector.Exists()).To(BeTrue())
+			Expect(collector.Field("spec.replicas").Int()).To(Equal(int64(1)))
+			Expect(collector.Field("spec.template.metadata.labels.app").String()).To(Equal("events-collector"))
+			Expect(collector.Field("spec.template.spec.serviceAccountName").String()).To(Equal("events-collector"))
+
+			Expect(hec.KubernetesResource("VerticalPodAutoscaler", "d8-log-shipper", "events-collector").Exists()).To(BeTrue())
+			Expect(hec.KubernetesGlobalResource("ClusterRoleBinding", "d8:log-shipper:events-collector").Exists()).To(BeTrue())
 		})
 	})
 
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/sdk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// This hook collects UpmeterProbe custom resources to internal values. The probes are passed to
+// upmeter server and agents in a ConfigMap, so that the components are restarted on changes.
+var _ = sdk.RegisterFunc(
+	&go_hook.HookConfig{
+		Queue: "/modules/upmeter/custom_probes",
+		Kubernetes: []go_hook.KubernetesConfig{
+			{
+				Name:       "upmeter_probes",
+				ApiVersion: "deckhouse.io/v1alpha1",
+				Kind:       "UpmeterProbe",
+				FilterFunc: filterCustomProbe,
+			},
+		},
+	},
+	collectCustomProbes,
+)
+
+// customProbe is the spec of UpmeterProbe with the probe name. The spec is passed as is, since it
+// is validated by the CRD schema and parsed by upmeter.
+type customProbe map[string]interface{}
+
+func filterCustomProbe(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get spec of UpmeterProbe %q: %v", obj.GetName(), err)
+	}
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+
+	probe := customProbe(spec)
+	probe["name"] = obj.GetName()
+	return probe, nil
+}
+
+func collectCustomProbes(input *go_hook.HookInput) error {
+	snapshot := input.Snapshots["upmeter_probes"]
+
+	probes := make([]customProbe, 0, len(snapshot))
+	for _, s := range snapshot {
+		probes = append(probes, s.(customProbe))
+	}
+	sort.Slice(probes, func(i, j int) bool {
+		return probes[i]["name"].(string) < probes[j]["name"].(string)
+	})
+
+	input.Values.Set("upmeter.internal.customProbes", probes)
+	return nil
+}
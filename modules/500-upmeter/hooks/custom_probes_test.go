@@ -0,0 +1,78 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+var _ = Describe("Modules :: upmeter :: hooks :: custom_probes ::", func() {
+	const initValues = `{"upmeter": { "internal": { "customProbes": [] } }}`
+
+	f := HookExecutionConfigInit(initValues, `{}`)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "UpmeterProbe", false)
+
+	Context("Empty cluster", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(""))
+			f.RunHook()
+		})
+
+		It("sets empty list", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("upmeter.internal.customProbes").String()).To(MatchJSON(`[]`))
+		})
+	})
+
+	Context("Cluster with probes", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: UpmeterProbe
+metadata:
+  name: shop
+spec:
+  group: payments
+  period: 30s
+  http:
+    url: https://shop.example.com/healthz
+    expectedStatusCodes: [200, 204]
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: UpmeterProbe
+metadata:
+  name: postgres
+spec:
+  tcp:
+    address: postgres.payments:5432
+`))
+			f.RunHook()
+		})
+
+		It("passes specs sorted by name", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("upmeter.internal.customProbes").String()).To(MatchJSON(`[
+  {"name": "postgres", "tcp": {"address": "postgres.payments:5432"}},
+  {"name": "shop", "group": "payments", "period": "30s", "http": {"url": "https://shop.example.com/healthz", "expectedStatusCodes": [200, 204]}}
+]`))
+		})
+	})
+})
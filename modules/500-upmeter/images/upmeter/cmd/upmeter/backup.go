@@ -0,0 +1,53 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"d8.io/upmeter/pkg/db"
+)
+
+// addBackupCommands adds commands to back up and restore the server database. They are meant to
+// be run in the server pod, e.g.
+//
+//	kubectl -n d8-upmeter exec upmeter-0 -c upmeter -- /upmeter backup > upmeter.sqlite
+//	kubectl -n d8-upmeter exec -i upmeter-0 -c upmeter -- /upmeter restore < upmeter.sqlite
+func addBackupCommands(app *kingpin.Application) {
+	var dbPath string
+
+	backupCommand := app.Command("backup", "Write the snapshot of the server SQLite database to stdout. The server can keep running.")
+	backupCommand.Flag("db-path", "SQLite file path.").
+		Envar("UPMETER_DB_PATH").
+		Default("upmeter.db").
+		StringVar(&dbPath)
+	backupCommand.Action(func(c *kingpin.ParseContext) error {
+		return db.Backup(context.Background(), dbPath, os.Stdout)
+	})
+
+	restoreCommand := app.Command("restore", "Replace the server SQLite database with the snapshot read from stdin. The server can keep running.")
+	restoreCommand.Flag("db-path", "SQLite file path.").
+		Envar("UPMETER_DB_PATH").
+		Default("upmeter.db").
+		StringVar(&dbPath)
+	restoreCommand.Action(func(c *kingpin.ParseContext) error {
+		return db.Restore(context.Background(), dbPath, os.Stdin)
+	})
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"d8.io/upmeter/pkg/monitor/downtime"
+	"d8.io/upmeter/pkg/server/api"
+)
+
+// downtimeClientConfig points to the upmeter server. The default address works from within the
+// server pod, e.g. `kubectl -n d8-upmeter exec upmeter-0 -c upmeter -- /upmeter downtime ...`
+type downtimeClientConfig struct {
+	ServerURL string
+	Token     string
+}
+
+func addDowntimeCommands(cmd *kingpin.CmdClause) {
+	config := &downtimeClientConfig{}
+
+	cmd.Flag("server", "Upmeter server URL.").
+		Envar("UPMETER_SERVER_URL").
+		Default("http://127.0.0.1:8091").
+		StringVar(&config.ServerURL)
+
+	cmd.Flag("token", "Bearer token to access upmeter server.").
+		Envar("UPMETER_TOKEN").
+		StringVar(&config.Token)
+
+	// Open
+
+	spec := downtime.Spec{}
+	openCommand := cmd.Command("open", "Open a downtime incident. It lasts until closed unless the end is specified.")
+	openCommand.Flag("type", "Downtime type.").
+		Required().
+		EnumVar(&spec.Type, downtime.Types...)
+	openCommand.Flag("affected", "Affected group, can be repeated.").
+		Required().
+		StringsVar(&spec.Affected)
+	openCommand.Flag("description", "Human readable incident information.").
+		StringVar(&spec.Description)
+	openCommand.Flag("start", "Start of the downtime (Unix time or RFC3339), now by default.").
+		StringVar(&spec.StartDate)
+	openCommand.Flag("end", "End of the downtime (Unix time or RFC3339).").
+		StringVar(&spec.EndDate)
+	openCommand.Action(func(c *kingpin.ParseContext) error {
+		body, err := json.Marshal(spec)
+		if err != nil {
+			return err
+		}
+		respBody, err := config.do(http.MethodPost, "/admin/downtimes", nil, body)
+		if err != nil {
+			return err
+		}
+
+		var resp api.OpenDowntimeResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return fmt.Errorf("cannot parse response: %v", err)
+		}
+		fmt.Println(resp.Name)
+		return nil
+	})
+
+	// Close
+
+	var name string
+	closeCommand := cmd.Command("close", "Close open incidents of the downtime.")
+	closeCommand.Arg("name", "Downtime name.").
+		Required().
+		StringVar(&name)
+	closeCommand.Action(func(c *kingpin.ParseContext) error {
+		body, err := json.Marshal(api.CloseDowntimeRequest{Name: name})
+		if err != nil {
+			return err
+		}
+		_, err = config.do(http.MethodPost, "/admin/downtimes/close", nil, body)
+		return err
+	})
+
+	// Export
+
+	var from, to, format, group string
+	exportCommand := cmd.Command("export", "Export incidents for the period, including expanded recurring maintenance.")
+	exportCommand.Flag("from", "Start of the period (Unix time or RFC3339), the beginning of the previous month by default.").
+		StringVar(&from)
+	exportCommand.Flag("to", "End of the period (Unix time or RFC3339), the beginning of the current month by default.").
+		StringVar(&to)
+	exportCommand.Flag("format", "Output format.").
+		Default("csv").
+		EnumVar(&format, "csv", "json")
+	exportCommand.Flag("group", "Export only incidents affecting the group.").
+		StringVar(&group)
+	exportCommand.Action(func(c *kingpin.ParseContext) error {
+		monthStart, prevMonthStart := lastMonth(time.Now().UTC())
+		if from == "" {
+			from = prevMonthStart.Format(time.RFC3339)
+		}
+		if to == "" {
+			to = monthStart.Format(time.RFC3339)
+		}
+
+		query := url.Values{}
+		query.Set("from", from)
+		query.Set("to", to)
+		query.Set("format", format)
+		if group != "" {
+			query.Set("group", group)
+		}
+
+		respBody, err := config.do(http.MethodGet, "/api/downtimes", query, nil)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(respBody)
+		return err
+	})
+}
+
+// lastMonth returns the beginning of the current and the previous months
+func lastMonth(now time.Time) (time.Time, time.Time) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return monthStart, monthStart.AddDate(0, -1, 0)
+}
+
+func (c *downtimeClientConfig) do(method, path string, query url.Values, body []byte) ([]byte, error) {
+	u := strings.TrimSuffix(c.ServerURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s", method, path, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
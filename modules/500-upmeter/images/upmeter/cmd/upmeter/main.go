@@ -126,6 +126,15 @@ func main() {
 		return nil
 	})
 
+	// Downtime management
+
+	downtimeCommand := app.Command("downtime", "Manage downtime incidents via upmeter server API")
+	addDowntimeCommands(downtimeCommand)
+
+	// Database backup
+
+	addBackupCommands(app)
+
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 }
 
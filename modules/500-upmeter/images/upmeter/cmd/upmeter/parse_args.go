@@ -40,11 +40,22 @@ func parseServerArgs(cmd *kingpin.CmdClause, config *server.Config) {
 		StringVar(&config.ListenPort)
 
 	// Database
-	cmd.Flag("db-path", "SQLite file path.").
+	cmd.Flag("db-path", "SQLite file path or PostgreSQL URL (postgres://...).").
 		Envar("UPMETER_DB_PATH").
 		Default("upmeter.db").
 		StringVar(&config.DatabasePath)
 
+	// Retention
+	cmd.Flag("retention-30s", "How long to keep 30s episodes, 0 to keep forever.").
+		Envar("UPMETER_RETENTION_30S").
+		Default("24h").
+		DurationVar(&config.Retention.Episodes30s)
+
+	cmd.Flag("retention-5m", "How long to keep 5m episodes, 0 to keep forever.").
+		Envar("UPMETER_RETENTION_5M").
+		Default("0").
+		DurationVar(&config.Retention.Episodes5m)
+
 	// Origins count
 	cmd.Flag("origins", "The expected number of origins, used for exporting episodes as metrics when they are fulfilled by this number of agents.").
 		Required().
@@ -64,12 +75,39 @@ func parseServerArgs(cmd *kingpin.CmdClause, config *server.Config) {
 	cmd.Flag("dynamic-probe-nodegroup", "Node Group name tracked by probes").
 		StringsVar(&config.DynamicProbes.NodeGroups)
 
+	// Probes declared by UpmeterProbe custom resources
+	cmd.Flag("custom-probes-path", "JSON file with the list of custom probes").
+		Envar("UPMETER_CUSTOM_PROBES_PATH").
+		StringVar(&config.DynamicProbes.CustomProbesPath)
+
 	// User-Agent
 	// TODO generate from CI?
 	cmd.Flag("user-agent", "User Agent for HTTP client").
 		Envar("UPMETER_USER_AGENT").
 		Default("Upmeter/1.0").
 		StringVar(&config.UserAgent)
+
+	// Federation, receiving episodes from other clusters
+	cmd.Flag("federation-tokens-dir", "Directory with tokens of clusters allowed to push episodes, file names are cluster names. Enables the central mode.").
+		Envar("UPMETER_FEDERATION_TOKENS_DIR").
+		StringVar(&config.Federation.TokensDir)
+
+	// Federation, pushing episodes to the central upmeter
+	cmd.Flag("federation-push-url", "URL of the central upmeter to push 5m episodes to.").
+		Envar("UPMETER_FEDERATION_PUSH_URL").
+		StringVar(&config.Federation.Push.URL)
+
+	cmd.Flag("federation-cluster", "The name of this cluster in the federation.").
+		Envar("UPMETER_FEDERATION_CLUSTER").
+		StringVar(&config.Federation.Push.Cluster)
+
+	cmd.Flag("federation-token", "The token to push episodes to the central upmeter.").
+		Envar("UPMETER_FEDERATION_TOKEN").
+		StringVar(&config.Federation.Push.Token)
+
+	cmd.Flag("federation-ca-path", "CA to verify the central upmeter certificate, system roots are used by default.").
+		Envar("UPMETER_FEDERATION_CA_PATH").
+		StringVar(&config.Federation.Push.CAPath)
 }
 
 func parseAgentArgs(cmd *kingpin.CmdClause, config *agent.Config) {
@@ -131,6 +169,11 @@ func parseAgentArgs(cmd *kingpin.CmdClause, config *agent.Config) {
 	cmd.Flag("dynamic-probe-known-zoneprefix", "A known zone prefix for current cloud provider").
 		StringVar(&config.DynamicProbes.ZonePrefix)
 
+	// Probes declared by UpmeterProbe custom resources
+	cmd.Flag("custom-probes-path", "JSON file with the list of custom probes").
+		Envar("UPMETER_CUSTOM_PROBES_PATH").
+		StringVar(&config.DynamicProbes.CustomProbesPath)
+
 	// User-Agent
 	// TODO generate from CI?
 	cmd.Flag("user-agent", "User Agent for HTTP client").
@@ -61,6 +61,7 @@ type DynamicProbesConfig struct {
 	NodeGroups         []string
 	Zones              []string
 	ZonePrefix         string
+	CustomProbesPath   string
 }
 
 func NewConfig() *Config {
@@ -88,11 +89,16 @@ func (a *Agent) Start(ctx context.Context) error {
 
 	// Probe registry
 	ftr := probe.NewProbeFilter(a.config.DisabledProbes)
+	customProbes, err := probe.ReadCustomProbes(a.config.DynamicProbes.CustomProbesPath)
+	if err != nil {
+		return fmt.Errorf("cannot load custom probes: %v", err)
+	}
 	dynamicConfig := probe.DynamicConfig{
 		IngressNginxControllers: a.config.DynamicProbes.IngressControllers,
 		NodeGroups:              a.config.DynamicProbes.NodeGroups,
 		Zones:                   a.config.DynamicProbes.Zones,
 		ZonePrefix:              a.config.DynamicProbes.ZonePrefix,
+		CustomProbes:            customProbes,
 	}
 
 	nodeMon := node.NewMonitor(kubeAccess.Kubernetes(), log.NewEntry(a.logger))
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	dbcontext "d8.io/upmeter/pkg/db/context"
+)
+
+// Vacuum rebuilds the SQLite database file to return the space freed by deleted rows. PostgreSQL
+// reclaims space by autovacuum, so nothing is done for it.
+func Vacuum(dbCtx *dbcontext.DbContext) error {
+	if dbCtx.IsPostgres() {
+		return nil
+	}
+
+	ctx := dbCtx.Start()
+	defer ctx.Stop()
+
+	_, err := ctx.StmtRunner().Exec("VACUUM")
+	return err
+}
+
+// Backup writes a consistent snapshot of the SQLite database to w. The database can be in use by
+// the server while the backup is running.
+func Backup(ctx context.Context, dbPath string, w io.Writer) error {
+	if dbcontext.IsPostgres(dbPath) {
+		return fmt.Errorf("backup is supported for SQLite only, use pg_dump for PostgreSQL")
+	}
+
+	tmp, err := os.CreateTemp("", "upmeter-backup-*.sqlite")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Close()
+
+	err = copySQLite(ctx, dbPath, tmp.Name())
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Restore replaces the content of the SQLite database with the backup read from r. The backup is
+// checked for integrity first. The database can be in use by the server, it sees the restored
+// data as soon as the restore is finished.
+func Restore(ctx context.Context, dbPath string, r io.Reader) error {
+	if dbcontext.IsPostgres(dbPath) {
+		return fmt.Errorf("restore is supported for SQLite only, use pg_restore for PostgreSQL")
+	}
+
+	tmp, err := os.CreateTemp("", "upmeter-restore-*.sqlite")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("cannot read backup: %v", err)
+	}
+
+	err = checkBackup(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("invalid backup: %v", err)
+	}
+
+	return copySQLite(ctx, tmp.Name(), dbPath)
+}
+
+// checkBackup verifies that the file is an intact upmeter server database
+func checkBackup(path string) error {
+	db, err := sql.Open("sqlite3", path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	err = db.QueryRow("PRAGMA integrity_check").Scan(&result)
+	if err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check: %s", result)
+	}
+
+	var count int
+	err = db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'episodes_5m'").Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("not an upmeter server database")
+	}
+	return nil
+}
+
+// copySQLite copies the database with SQLite online backup API. The destination is locked only
+// for the time of copying, and the copying is retried while the destination is busy.
+func copySQLite(ctx context.Context, srcPath, dstPath string) error {
+	srcDB, err := sql.Open("sqlite3", srcPath)
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+
+	dstDB, err := sql.Open("sqlite3", dstPath)
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %s: %v", srcPath, err)
+	}
+	defer srcConn.Close()
+
+	dstConn, err := dstDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %s: %v", dstPath, err)
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dst interface{}) error {
+		return srcConn.Raw(func(src interface{}) error {
+			return backupStep(ctx, dst.(*sqlite3.SQLiteConn), src.(*sqlite3.SQLiteConn))
+		})
+	})
+}
+
+func backupStep(ctx context.Context, dst, src *sqlite3.SQLiteConn) error {
+	bk, err := dst.Backup("main", src, "main")
+	if err != nil {
+		return fmt.Errorf("cannot start backup: %v", err)
+	}
+
+	for {
+		done, err := bk.Step(-1)
+		if done {
+			break
+		}
+		var sqliteErr sqlite3.Error
+		if err != nil && !(errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)) {
+			bk.Finish()
+			return fmt.Errorf("cannot copy database: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			bk.Finish()
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	return bk.Finish()
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/db"
+	"d8.io/upmeter/pkg/db/dao"
+	"d8.io/upmeter/pkg/db/migrations"
+)
+
+func Test_BackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	ref := check.ProbeRef{Group: "control-plane", Probe: "apiserver"}
+	slot := time.Unix(600, 0)
+
+	srcPath := filepath.Join(dir, "src.sqlite")
+	srcCtx := migrations.GetTestFileDatabase(t, srcPath, "./migrations/server")
+	err := dao.NewEpisodeDao5m(srcCtx.Start()).Insert(check.Episode{ProbeRef: ref, TimeSlot: slot, Up: 5 * time.Minute})
+	require.NoError(t, err)
+
+	var backup bytes.Buffer
+	require.NoError(t, db.Backup(context.Background(), srcPath, &backup))
+
+	// Restore into a live database that has different data
+	dstPath := filepath.Join(dir, "dst.sqlite")
+	dstCtx := migrations.GetTestFileDatabase(t, dstPath, "./migrations/server")
+	err = dao.NewEpisodeDao5m(dstCtx.Start()).Insert(check.Episode{ProbeRef: ref, TimeSlot: slot.Add(time.Hour), Down: 5 * time.Minute})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Restore(context.Background(), dstPath, &backup))
+
+	episodes, err := dao.NewEpisodeDao5m(dstCtx.Start()).ListEpisodesByRange(0, slot.Add(2*time.Hour).Unix(), ref)
+	require.NoError(t, err)
+	require.Len(t, episodes, 1)
+	assert.Equal(t, slot.Unix(), episodes[0].TimeSlot.Unix())
+	assert.Equal(t, 5*time.Minute, episodes[0].Up)
+}
+
+func Test_Restore_RejectsInvalidBackup(t *testing.T) {
+	dstPath := filepath.Join(t.TempDir(), "dst.sqlite")
+	migrations.GetTestFileDatabase(t, dstPath, "./migrations/server")
+
+	err := db.Restore(context.Background(), dstPath, bytes.NewBufferString("definitely not a database"))
+	assert.Error(t, err)
+}
+
+func Test_Backup_Postgres(t *testing.T) {
+	err := db.Backup(context.Background(), "postgres://localhost/upmeter", &bytes.Buffer{})
+	assert.Error(t, err)
+}
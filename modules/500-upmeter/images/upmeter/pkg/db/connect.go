@@ -17,6 +17,7 @@ limitations under the License.
 package db
 
 import (
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 
 	dbcontext "d8.io/upmeter/pkg/db/context"
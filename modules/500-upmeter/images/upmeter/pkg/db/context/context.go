@@ -32,6 +32,9 @@ type DbContext struct {
 	conns *pool
 	db    *sql.DB
 	tx    *sql.Tx
+
+	// postgres is set when the database is PostgreSQL rather than SQLite
+	postgres bool
 }
 
 func NewDbContext() *DbContext {
@@ -45,6 +48,7 @@ func (c *DbContext) Connect(path string) error {
 		return err
 	}
 	c.db = db
+	c.postgres = IsPostgres(path)
 	return nil
 }
 
@@ -68,6 +72,7 @@ func (c *DbContext) ConnectWithPool(path string, opts map[string]string) error {
 		size = defaultPoolSize
 	}
 	c.conns = newPool(size)
+	c.postgres = IsPostgres(path)
 
 	return c.conns.Connect(path, opts)
 }
@@ -76,44 +81,56 @@ func (c *DbContext) Handler() *sql.DB {
 	return c.db
 }
 
+// IsPostgres returns true if the context is connected to PostgreSQL
+func (c *DbContext) IsPostgres() bool {
+	return c.postgres
+}
+
 func (c *DbContext) Copy() *DbContext {
 	return &DbContext{
-		conns: c.conns,
-		db:    c.db,
-		tx:    c.tx,
+		conns:    c.conns,
+		db:       c.db,
+		tx:       c.tx,
+		postgres: c.postgres,
 	}
 }
 
 func (c *DbContext) StmtRunner() StmtRunner {
-	if c.tx != nil {
-		return c.tx
+	var runner StmtRunner
+	switch {
+	case c.tx != nil:
+		runner = c.tx
+	case c.db != nil:
+		runner = c.db
+	default:
+		panic("DB context is uninitialized")
 	}
 
-	if c.db != nil {
-		return c.db
+	if c.postgres {
+		return &postgresRunner{runner: runner}
 	}
-
-	panic("DB context is uninitialized")
+	return runner
 }
 
 // Start captures a connection from pool and returns a stoppable context.
 // If context is stoppable, returns non-stoppable db-only context.
 func (c *DbContext) Start() *DbContext {
 	if c.tx != nil {
-		return &DbContext{tx: c.tx}
+		return &DbContext{tx: c.tx, postgres: c.postgres}
 	}
 
 	// Do not copy pool if the db is already captured.
 	if c.db != nil {
-		return &DbContext{db: c.db}
+		return &DbContext{db: c.db, postgres: c.postgres}
 	}
 
 	// Capture connection from the pool if it is a "root" context.
 	if c.conns != nil && c.db == nil {
 		db := c.conns.Capture()
 		return &DbContext{
-			conns: c.conns,
-			db:    db,
+			conns:    c.conns,
+			db:       db,
+			postgres: c.postgres,
 		}
 	}
 
@@ -131,7 +148,7 @@ func (c *DbContext) BeginTransaction() (*DbContext, error) {
 	ctx := context.Background() // FIXME (e.shevchenko) pass the context from the outside
 
 	if c.tx != nil {
-		return &DbContext{tx: c.tx}, nil
+		return &DbContext{tx: c.tx, postgres: c.postgres}, nil
 	}
 
 	if c.db == nil {
@@ -143,7 +160,7 @@ func (c *DbContext) BeginTransaction() (*DbContext, error) {
 		return nil, err
 	}
 
-	return &DbContext{tx: tx}, nil
+	return &DbContext{tx: tx, postgres: c.postgres}, nil
 }
 
 func (c *DbContext) Rollback() error {
@@ -55,8 +55,18 @@ func DefaultConnectionOptions() map[string]string {
 	}
 }
 
-// open opens a sqlite database
+// open opens a sqlite database, or a PostgreSQL one if the path is a PostgreSQL URL. SQLite
+// connection options are not applicable to PostgreSQL and are ignored for it.
 func open(dbpath string, opts map[string]string) (*sql.DB, error) {
+	if IsPostgres(dbpath) {
+		db, err := sql.Open("postgres", dbpath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open postgres database: %v", err)
+		}
+		db.SetMaxOpenConns(MaxOpenConnections)
+		return db, nil
+	}
+
 	uri := buildUri(dbpath, opts)
 
 	db, err := sql.Open("sqlite3", uri)
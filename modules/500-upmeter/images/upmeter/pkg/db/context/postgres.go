@@ -0,0 +1,119 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package context
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IsPostgres returns true if the path is a PostgreSQL connection URL
+func IsPostgres(path string) bool {
+	return strings.HasPrefix(path, "postgres://") || strings.HasPrefix(path, "postgresql://")
+}
+
+// postgresRunner adapts queries written for SQLite to PostgreSQL. DAOs use "?" and "@name"
+// placeholders, while PostgreSQL driver supports only positional "$N" ones.
+type postgresRunner struct {
+	runner StmtRunner
+}
+
+func (r *postgresRunner) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	query, args, err := rebind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return r.runner.Query(query, args...)
+}
+
+func (r *postgresRunner) Exec(query string, args ...interface{}) (sql.Result, error) {
+	query, args, err := rebind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return r.runner.Exec(query, args...)
+}
+
+// rebind replaces "?" and "@name" placeholders with "$N" ones. Named arguments are converted to
+// positional ones in the order of their first appearance in the query. Placeholders are not
+// expected in string literals.
+func rebind(query string, args []interface{}) (string, []interface{}, error) {
+	named := make(map[string]interface{})
+	positional := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if n, ok := arg.(sql.NamedArg); ok {
+			named[n.Name] = n.Value
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	var (
+		b        strings.Builder
+		newArgs  = make([]interface{}, 0, len(args))
+		indexes  = make(map[string]int)
+		posIndex = 0
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '?':
+			if posIndex >= len(positional) {
+				return "", nil, fmt.Errorf("not enough arguments for placeholders in query")
+			}
+			newArgs = append(newArgs, positional[posIndex])
+			posIndex++
+			b.WriteString("$" + strconv.Itoa(len(newArgs)))
+
+		case c == '@' && i+1 < len(query) && isIdentChar(query[i+1]):
+			j := i + 1
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			i = j - 1
+
+			idx, ok := indexes[name]
+			if !ok {
+				value, found := named[name]
+				if !found {
+					return "", nil, fmt.Errorf("no value for the named argument @%s", name)
+				}
+				newArgs = append(newArgs, value)
+				idx = len(newArgs)
+				indexes[name] = idx
+			}
+			b.WriteString("$" + strconv.Itoa(idx))
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if posIndex != len(positional) {
+		return "", nil, fmt.Errorf("too many arguments for placeholders in query")
+	}
+
+	return b.String(), newArgs, nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/db"
+	dbcontext "d8.io/upmeter/pkg/db/context"
+	"d8.io/upmeter/pkg/server/ranges"
+)
+
+// ClusterEpisodeDao5m stores 5m episodes pushed by other clusters in the federation mode. The
+// cluster can be empty for the methods that are not bound to a cluster, e.g. ListClusters.
+type ClusterEpisodeDao5m struct {
+	DbCtx   *dbcontext.DbContext
+	Cluster string
+}
+
+func NewClusterEpisodeDao5m(dbCtx *dbcontext.DbContext, cluster string) *ClusterEpisodeDao5m {
+	return &ClusterEpisodeDao5m{
+		DbCtx:   dbCtx,
+		Cluster: cluster,
+	}
+}
+
+// ClusterInfo describes a cluster that pushed episodes
+type ClusterInfo struct {
+	Name string
+	// LastSlot is the latest 5m slot received from the cluster
+	LastSlot time.Time
+}
+
+// Save inserts episodes or replaces existing ones, since the pushing side sends the same slot until
+// it is complete.
+func (d *ClusterEpisodeDao5m) Save(episodes []check.Episode) error {
+	const query = `
+	INSERT INTO cluster_episodes_5m
+		(cluster, timeslot, group_name, probe_name,
+		 nano_up, nano_down, nano_unknown, nano_unmeasured)
+	VALUES
+		(@cluster, @timeslot, @group_name, @probe_name,
+		 @nano_up, @nano_down, @nano_unknown, @nano_unmeasured)
+	ON CONFLICT
+		(cluster, timeslot, group_name, probe_name)
+	DO UPDATE SET
+		nano_up         = @nano_up,
+		nano_down       = @nano_down,
+		nano_unknown    = @nano_unknown,
+		nano_unmeasured = @nano_unmeasured;
+	`
+
+	return db.WithTx(d.DbCtx, func(tx *dbcontext.DbContext) error {
+		for _, episode := range episodes {
+			_, err := tx.StmtRunner().Exec(
+				query,
+				sql.Named("cluster", d.Cluster),
+				sql.Named("timeslot", episode.TimeSlot.Unix()),
+
+				sql.Named("group_name", episode.ProbeRef.Group),
+				sql.Named("probe_name", episode.ProbeRef.Probe),
+
+				sql.Named("nano_up", episode.Up),
+				sql.Named("nano_down", episode.Down),
+				sql.Named("nano_unknown", episode.Unknown),
+				sql.Named("nano_unmeasured", episode.NoData),
+			)
+			if err != nil {
+				return fmt.Errorf("cannot save episode %s: %v", episode.ProbeRef.Id(), err)
+			}
+		}
+		return nil
+	})
+}
+
+// ListClusters returns all clusters that pushed episodes, sorted by name
+func (d *ClusterEpisodeDao5m) ListClusters() ([]ClusterInfo, error) {
+	const query = `
+	SELECT   cluster, max(timeslot)
+	FROM     cluster_episodes_5m
+	GROUP BY cluster
+	ORDER BY cluster
+	`
+
+	rows, err := d.DbCtx.StmtRunner().Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("cannot select clusters: %v", err)
+	}
+	defer rows.Close()
+
+	clusters := make([]ClusterInfo, 0)
+	for rows.Next() {
+		var (
+			info ClusterInfo
+			slot int64
+		)
+		err := rows.Scan(&info.Name, &slot)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse cluster: %v", err)
+		}
+		info.LastSlot = time.Unix(slot, 0)
+		clusters = append(clusters, info)
+	}
+	return clusters, rows.Err()
+}
+
+// ListProbeRefs returns groups and probes of the cluster seen since the slot, including group
+// aggregations.
+func (d *ClusterEpisodeDao5m) ListProbeRefs(since time.Time) ([]check.ProbeRef, error) {
+	const query = `
+	SELECT DISTINCT group_name, probe_name
+	FROM            cluster_episodes_5m
+	WHERE           cluster = ? AND timeslot >= ?
+	ORDER BY        group_name, probe_name
+	`
+
+	rows, err := d.DbCtx.StmtRunner().Query(query, d.Cluster, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("cannot select probes of cluster %q: %v", d.Cluster, err)
+	}
+	defer rows.Close()
+
+	refs := make([]check.ProbeRef, 0)
+	for rows.Next() {
+		var ref check.ProbeRef
+		err := rows.Scan(&ref.Group, &ref.Probe)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse probe ref: %v", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// ListEpisodeSumsForRanges returns sums of durations for each group_name+probe_name of the cluster
+// in each subrange. The signature matches EpisodeDao5m, so the cluster episodes are summarized the
+// same way as local ones.
+func (d *ClusterEpisodeDao5m) ListEpisodeSumsForRanges(rng ranges.StepRange, ref check.ProbeRef) ([]check.Episode, error) {
+	query := `
+	SELECT   sum(nano_up), sum(nano_down), sum(nano_unknown), sum(nano_unmeasured), group_name, probe_name
+	FROM     cluster_episodes_5m
+	WHERE    cluster = ? AND timeslot >= ? AND timeslot < ?
+	`
+
+	filterArgs := make([]interface{}, 0, 2)
+	if ref.Group != "" {
+		query += " AND group_name = ?"
+		filterArgs = append(filterArgs, ref.Group)
+	}
+	if !areAllProbesRequested(ref.Probe) {
+		query += " AND probe_name = ?"
+		filterArgs = append(filterArgs, ref.Probe)
+	}
+	query += " GROUP BY group_name, probe_name"
+
+	res := make([]check.Episode, 0)
+	for _, subrange := range rng.Subranges {
+		args := append([]interface{}{d.Cluster, subrange.From, subrange.To}, filterArgs...)
+		episodes, err := d.listSums(query, args)
+		if err != nil {
+			return nil, err
+		}
+		for _, ep := range episodes {
+			ep.TimeSlot = time.Unix(subrange.From, 0)
+			res = append(res, ep)
+		}
+	}
+
+	return res, nil
+}
+
+func (d *ClusterEpisodeDao5m) listSums(query string, args []interface{}) ([]check.Episode, error) {
+	rows, err := d.DbCtx.StmtRunner().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot select episode sums of cluster %q: %v", d.Cluster, err)
+	}
+	defer rows.Close()
+
+	episodes := make([]check.Episode, 0)
+	for rows.Next() {
+		var ep check.Episode
+		err := rows.Scan(&ep.Up, &ep.Down, &ep.Unknown, &ep.NoData, &ep.ProbeRef.Group, &ep.ProbeRef.Probe)
+		if err != nil {
+			return nil, fmt.Errorf("row to episode: %v", err)
+		}
+		episodes = append(episodes, ep)
+	}
+	return episodes, rows.Err()
+}
+
+// DeleteUpTo removes episodes of all clusters with timeslots up to the slot inclusively. It returns
+// the number of removed episodes.
+func (d *ClusterEpisodeDao5m) DeleteUpTo(slot time.Time) (int64, error) {
+	const query = `
+	DELETE FROM cluster_episodes_5m
+	WHERE timeslot <= ?
+	`
+	res, err := d.DbCtx.StmtRunner().Exec(query, slot.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
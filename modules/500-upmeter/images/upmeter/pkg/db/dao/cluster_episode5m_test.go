@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/server/ranges"
+)
+
+func Test_ClusterEpisodeDao5m_Save_ReplacesSlot(t *testing.T) {
+	g := NewWithT(t)
+
+	dbCtx := getTestDatabase(t)
+	daoCtx := dbCtx.Start()
+	defer daoCtx.Stop()
+
+	ref := check.ProbeRef{Group: "control-plane", Probe: "apiserver"}
+	slot := time.Unix(3000, 0)
+
+	dao := NewClusterEpisodeDao5m(daoCtx, "alpha")
+	err := dao.Save([]check.Episode{{ProbeRef: ref, TimeSlot: slot, Up: time.Minute, NoData: 4 * time.Minute}})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	err = dao.Save([]check.Episode{{ProbeRef: ref, TimeSlot: slot, Up: 4 * time.Minute, Down: time.Minute}})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	rng := ranges.New5MinStepRange(3000, 3300, 300)
+	episodes, err := dao.ListEpisodeSumsForRanges(rng, check.ProbeRef{Probe: ProbeEnumeration})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(episodes).To(HaveLen(1))
+	g.Expect(episodes[0].Up).To(Equal(4 * time.Minute))
+	g.Expect(episodes[0].Down).To(Equal(time.Minute))
+	g.Expect(episodes[0].NoData).To(BeZero())
+}
+
+func Test_ClusterEpisodeDao5m_KeepsClustersApart(t *testing.T) {
+	g := NewWithT(t)
+
+	dbCtx := getTestDatabase(t)
+	daoCtx := dbCtx.Start()
+	defer daoCtx.Stop()
+
+	ref := check.ProbeRef{Group: "synthetic", Probe: "access"}
+	total := check.ProbeRef{Group: "synthetic", Probe: GroupAggregation}
+
+	alpha := NewClusterEpisodeDao5m(daoCtx, "alpha")
+	err := alpha.Save([]check.Episode{
+		{ProbeRef: ref, TimeSlot: time.Unix(3000, 0), Up: 5 * time.Minute},
+		{ProbeRef: total, TimeSlot: time.Unix(3000, 0), Up: 5 * time.Minute},
+		{ProbeRef: ref, TimeSlot: time.Unix(3300, 0), Up: 5 * time.Minute},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	beta := NewClusterEpisodeDao5m(daoCtx, "beta")
+	err = beta.Save([]check.Episode{
+		{ProbeRef: ref, TimeSlot: time.Unix(3000, 0), Down: 5 * time.Minute},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	clusters, err := NewClusterEpisodeDao5m(daoCtx, "").ListClusters()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(clusters).To(Equal([]ClusterInfo{
+		{Name: "alpha", LastSlot: time.Unix(3300, 0)},
+		{Name: "beta", LastSlot: time.Unix(3000, 0)},
+	}))
+
+	refs, err := alpha.ListProbeRefs(time.Unix(3000, 0))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(refs).To(Equal([]check.ProbeRef{total, ref}))
+
+	// Two subranges, the episode of the second cluster does not leak
+	rng := ranges.New5MinStepRange(3000, 3600, 300)
+	episodes, err := alpha.ListEpisodeSumsForRanges(rng, ref)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(episodes).To(HaveLen(2))
+	for _, ep := range episodes {
+		g.Expect(ep.Up).To(Equal(5 * time.Minute))
+		g.Expect(ep.Down).To(BeZero())
+	}
+
+	deleted, err := alpha.DeleteUpTo(time.Unix(3000, 0))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(deleted).To(Equal(int64(3)))
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package dao
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -33,8 +34,12 @@ func getFileDatabase(t *testing.T, path string) *dbcontext.DbContext {
 	return migrations.GetTestFileDatabase(t, path, "../migrations/server")
 }
 
-// getTestDatabase bootstraps the server database in memory
+// getTestDatabase bootstraps the server database in memory. If UPMETER_TEST_POSTGRES_DSN is set, the
+// PostgreSQL database is used instead, so that all DAO tests run against it.
 func getTestDatabase(t *testing.T) *dbcontext.DbContext {
+	if os.Getenv(migrations.PostgresTestDSNEnv) != "" {
+		return migrations.GetTestPostgresDatabase(t, "../migrations/server-postgres")
+	}
 	return migrations.GetTestMemoryDatabase(t, "../migrations/server")
 }
 
@@ -125,6 +125,20 @@ func (d *EpisodeDao5m) Update(rowid int64, episode check.Episode) error {
 	return err
 }
 
+// DeleteUpTo removes episodes with timeslots up to the slot inclusively. It returns the number of
+// removed episodes.
+func (d *EpisodeDao5m) DeleteUpTo(slot time.Time) (int64, error) {
+	const query = `
+	DELETE FROM episodes_5m
+	WHERE timeslot <= ?
+	`
+	res, err := d.DbCtx.StmtRunner().Exec(query, slot.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // TODO (e.shevchenko): can be DRYed ? ?
 func (d *EpisodeDao5m) ListEpisodesByRange(from, to int64, ref check.ProbeRef) ([]check.Episode, error) {
 	query := selectEntityStmt + `
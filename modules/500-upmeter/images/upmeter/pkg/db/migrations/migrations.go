@@ -20,9 +20,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
@@ -79,8 +82,16 @@ func newMigrate(dbctx *dbcontext.DbContext, migrationsPath string) (*migrate.Mig
 	ctx := dbctx.Start()
 	defer ctx.Stop()
 
-	// no tx to be able to do vacuum
-	driver, err := sqlite3.WithInstance(ctx.Handler(), &sqlite3.Config{NoTxWrap: true})
+	var (
+		driver database.Driver
+		err    error
+	)
+	if ctx.IsPostgres() {
+		driver, err = postgres.WithInstance(ctx.Handler(), &postgres.Config{})
+	} else {
+		// no tx to be able to do vacuum
+		driver, err = sqlite3.WithInstance(ctx.Handler(), &sqlite3.Config{NoTxWrap: true})
+	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot init driver: %v", err)
 	}
@@ -113,6 +124,31 @@ func GetTestFileDatabase(t *testing.T, dbPath, migrationPath string) *dbcontext.
 	return dbctx
 }
 
+// PostgresTestDSNEnv is the environment variable with the URL of the PostgreSQL database for
+// tests. Tests for PostgreSQL are skipped if it is not set, "make test-postgres" sets it for a
+// disposable PostgreSQL container.
+const PostgresTestDSNEnv = "UPMETER_TEST_POSTGRES_DSN"
+
+// GetTestPostgresDatabase migrates the database from UPMETER_TEST_POSTGRES_DSN and cleans all its
+// tables, so that every test starts with an empty database.
+func GetTestPostgresDatabase(t *testing.T, migrationPath string) *dbcontext.DbContext {
+	dsn := os.Getenv(PostgresTestDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresTestDSNEnv)
+	}
+
+	dbctx, err := getTestDatabase(dsn, migrationPath)
+	if err != nil {
+		t.Fatalf("error running migrations from ground up: %v", err)
+	}
+
+	_, err = dbctx.StmtRunner().Exec(`TRUNCATE episodes_30s, episodes_5m, export_episodes, cluster_episodes_5m`)
+	if err != nil {
+		t.Fatalf("cannot clean tables: %v", err)
+	}
+	return dbctx
+}
+
 func getTestDatabase(dbPath, migrationPath string) (*dbcontext.DbContext, error) {
 	dbctx := dbcontext.NewDbContext()
 	err := dbctx.Connect(dbPath) // no connection pool
@@ -27,6 +27,10 @@ func Test_server_migrations(t *testing.T) {
 	GetTestMemoryDatabase(t, "./server")
 }
 
+func Test_server_postgres_migrations(t *testing.T) {
+	GetTestPostgresDatabase(t, "./server-postgres")
+}
+
 func Test_agent_migrations(t *testing.T) {
 	GetTestMemoryDatabase(t, "./agent")
 }
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS export_episodes;
+DROP TABLE IF EXISTS episodes_5m;
+DROP TABLE IF EXISTS episodes_30s;
+
+COMMIT;
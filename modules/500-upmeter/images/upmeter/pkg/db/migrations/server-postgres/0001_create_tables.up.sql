@@ -0,0 +1,55 @@
+/*
+
+This migration creates the schema for PostgreSQL backend. It is equivalent to the state of the SQLite schema after
+all server migrations, so there is no history to replay.
+
+"rowid" is created explicitly since it is implicit in SQLite and is used by DAOs.
+
+*/
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS episodes_30s
+(
+    rowid           BIGSERIAL PRIMARY KEY,
+    timeslot        BIGINT NOT NULL,
+    nano_up         BIGINT NOT NULL,
+    nano_down       BIGINT NOT NULL,
+    nano_unknown    BIGINT NOT NULL DEFAULT 0,
+    nano_unmeasured BIGINT NOT NULL DEFAULT 0,
+    group_name      TEXT   NOT NULL,
+    probe_name      TEXT   NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS episodes_5m
+(
+    rowid           BIGSERIAL PRIMARY KEY,
+    timeslot        BIGINT NOT NULL,
+    nano_up         BIGINT NOT NULL,
+    nano_down       BIGINT NOT NULL,
+    nano_unknown    BIGINT NOT NULL DEFAULT 0,
+    nano_unmeasured BIGINT NOT NULL DEFAULT 0,
+    group_name      TEXT   NOT NULL,
+    probe_name      TEXT   NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS episodes_30s_time_group_probe ON episodes_30s (timeslot, group_name, probe_name);
+CREATE UNIQUE INDEX IF NOT EXISTS episodes_5m_time_group_probe  ON episodes_5m  (timeslot, group_name, probe_name);
+
+CREATE TABLE IF NOT EXISTS export_episodes
+(
+    sync_id         TEXT    NOT NULL,
+    timeslot        BIGINT  NOT NULL,
+    group_name      TEXT    NOT NULL,
+    probe_name      TEXT    NOT NULL,
+    nano_up         BIGINT  NOT NULL,
+    nano_down       BIGINT  NOT NULL,
+    nano_unknown    BIGINT  NOT NULL,
+    nano_unmeasured BIGINT  NOT NULL,
+    origins         TEXT    NOT NULL,
+    origins_count   INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS sync_id_sorted ON export_episodes (sync_id, timeslot, group_name, probe_name);
+
+COMMIT;
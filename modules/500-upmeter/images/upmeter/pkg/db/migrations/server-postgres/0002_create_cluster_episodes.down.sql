@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS cluster_episodes_5m_cluster_time_group_probe;
+DROP TABLE IF EXISTS cluster_episodes_5m;
+
+COMMIT;
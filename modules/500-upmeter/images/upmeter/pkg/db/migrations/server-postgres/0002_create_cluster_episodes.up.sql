@@ -0,0 +1,23 @@
+/*
+
+This migration creates the table for 5m episodes received from other clusters in the federation mode.
+
+*/
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS cluster_episodes_5m
+(
+    cluster         TEXT   NOT NULL,
+    timeslot        BIGINT NOT NULL,
+    nano_up         BIGINT NOT NULL,
+    nano_down       BIGINT NOT NULL,
+    nano_unknown    BIGINT NOT NULL DEFAULT 0,
+    nano_unmeasured BIGINT NOT NULL DEFAULT 0,
+    group_name      TEXT   NOT NULL,
+    probe_name      TEXT   NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS cluster_episodes_5m_cluster_time_group_probe ON cluster_episodes_5m (cluster, timeslot, group_name, probe_name);
+
+COMMIT;
@@ -0,0 +1,24 @@
+/*
+
+This migration creates the table for 5m episodes received from other clusters in the federation mode. The central
+upmeter keeps them apart from its own episodes, the "cluster" field is the name the episodes were pushed with.
+
+*/
+
+BEGIN IMMEDIATE;
+
+CREATE TABLE IF NOT EXISTS cluster_episodes_5m
+(
+    cluster         TEXT    NOT NULL,
+    timeslot        INTEGER NOT NULL,
+    nano_up         INTEGER NOT NULL,
+    nano_down       INTEGER NOT NULL,
+    nano_unknown    INTEGER NOT NULL DEFAULT 0,
+    nano_unmeasured INTEGER NOT NULL DEFAULT 0,
+    group_name      TEXT    NOT NULL,
+    probe_name      TEXT    NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS cluster_episodes_5m_cluster_time_group_probe ON cluster_episodes_5m (cluster, timeslot, group_name, probe_name);
+
+COMMIT;
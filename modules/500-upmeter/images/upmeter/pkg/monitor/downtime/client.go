@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kube "github.com/flant/kube-client/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var gvr = schema.GroupVersionResource{
+	Group:    "deckhouse.io",
+	Version:  "v1alpha1",
+	Resource: "downtimes",
+}
+
+// Types are the allowed types of downtime incidents
+var Types = []string{"Accident", "Maintenance", "InfrastructureMaintenance", "InfrastructureAccident"}
+
+var ErrNotOpen = errors.New("downtime has no open incidents")
+
+// Client opens and closes incidents in real time by managing Downtime custom resources
+type Client struct {
+	kubeClient kube.Client
+}
+
+func NewClient(kubeClient kube.Client) *Client {
+	return &Client{kubeClient: kubeClient}
+}
+
+// Open creates a Downtime with a single incident. The incident starts now unless the start date is
+// specified, and it is open until closed unless the end date is specified. The name of the
+// created object is returned.
+func (c *Client) Open(ctx context.Context, spec Spec, now time.Time) (string, error) {
+	if err := ValidateIncident(spec); err != nil {
+		return "", err
+	}
+	if spec.StartDate == "" {
+		spec.StartDate = now.UTC().Format(time.RFC3339)
+	}
+
+	obj := &Downtime{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: gvr.GroupVersion().String(),
+			Kind:       "Downtime",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "upmeter-",
+			Labels:       map[string]string{"heritage": "upmeter"},
+		},
+		Spec: []Spec{spec},
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", fmt.Errorf("cannot convert Downtime to unstructured: %v", err)
+	}
+
+	created, err := c.kubeClient.Dynamic().Resource(gvr).Create(ctx, &unstructured.Unstructured{Object: content}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("cannot create Downtime: %v", err)
+	}
+	return created.GetName(), nil
+}
+
+// Close sets the end date of all open incidents in the Downtime. ErrNotOpen is returned if there
+// are no open incidents.
+func (c *Client) Close(ctx context.Context, name string, now time.Time) error {
+	client := c.kubeClient.Dynamic().Resource(gvr)
+
+	unstrObj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("cannot get Downtime %q: %w", name, err)
+	}
+	obj, err := convert(unstrObj)
+	if err != nil {
+		return err
+	}
+
+	closed := 0
+	for i := range obj.Spec {
+		if obj.Spec[i].IsOpen() {
+			obj.Spec[i].EndDate = now.UTC().Format(time.RFC3339)
+			closed++
+		}
+	}
+	if closed == 0 {
+		return ErrNotOpen
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return fmt.Errorf("cannot convert Downtime to unstructured: %v", err)
+	}
+	_, err = client.Update(ctx, &unstructured.Unstructured{Object: content}, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("cannot update Downtime %q: %w", name, err)
+	}
+	return nil
+}
+
+// ValidateIncident checks the spec of a single incident
+func ValidateIncident(spec Spec) error {
+	if spec.IsRecurring() {
+		return fmt.Errorf("recurring downtimes are managed in Downtime objects")
+	}
+	if !isKnownType(spec.Type) {
+		return fmt.Errorf("unknown type %q, expected one of %v", spec.Type, Types)
+	}
+	if len(spec.Affected) == 0 {
+		return fmt.Errorf("affected groups are required")
+	}
+	if spec.StartDate != "" {
+		if _, err := DateToSeconds(spec.StartDate); err != nil {
+			return fmt.Errorf("convert startDate '%s': %v", spec.StartDate, err)
+		}
+	}
+	if spec.EndDate != "" {
+		if _, err := DateToSeconds(spec.EndDate); err != nil {
+			return fmt.Errorf("convert endDate '%s': %v", spec.EndDate, err)
+		}
+	}
+	return nil
+}
+
+func isKnownType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
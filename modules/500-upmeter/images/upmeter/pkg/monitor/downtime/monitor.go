@@ -26,7 +26,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/tools/cache"
 
@@ -42,11 +41,6 @@ type Monitor struct {
 
 func NewMonitor(kubeClient kube.Client, logger *log.Entry) *Monitor {
 	var (
-		gvr = schema.GroupVersionResource{
-			Group:    "deckhouse.io",
-			Version:  "v1alpha1",
-			Resource: "downtimes",
-		}
 		indexers     = cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
 		resyncPeriod = 5 * time.Minute
 
@@ -79,20 +73,22 @@ func (m *Monitor) Stop() {
 	close(m.stopCh)
 }
 
-func (m *Monitor) List() ([]check.DowntimeIncident, error) {
+// List returns incidents that intersect with the range [from, to) in Unix seconds
+func (m *Monitor) List(from, to int64) ([]check.DowntimeIncident, error) {
+	now := time.Now()
 	res := make([]check.DowntimeIncident, 0)
 	for _, obj := range m.informer.GetStore().List() {
-		incs, err := convert(obj)
+		downtime, err := convert(obj)
 		if err != nil {
 			return nil, err
 		}
 
-		res = append(res, incs...)
+		res = append(res, downtime.GetDowntimeIncidents(from, to, now)...)
 	}
 	return res, nil
 }
 
-func convert(o interface{}) ([]check.DowntimeIncident, error) {
+func convert(o interface{}) (*Downtime, error) {
 	unstrObj, ok := o.(*unstructured.Unstructured)
 	if !ok {
 		return nil, fmt.Errorf("cannot convert object to *unstructured.Unstructured: %v", o)
@@ -102,5 +98,5 @@ func convert(o interface{}) ([]check.DowntimeIncident, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot convert unstructured to Downtime: %v", err)
 	}
-	return incidentObj.GetDowntimeIncidents(), nil
+	return &incidentObj, nil
 }
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downtime
+
+import (
+	"fmt"
+	"math"
+	"time"
+	// The image has no system time zone database
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxOccurrences protects from expanding a too frequent schedule over a long range
+const maxOccurrences = 10000
+
+// Schedule is a parsed recurring downtime
+type Schedule struct {
+	cron     cron.Schedule
+	duration time.Duration
+	location *time.Location
+
+	// bounds of the recurrence in Unix seconds
+	notBefore int64
+	notAfter  int64
+}
+
+// Occurrence is a single downtime produced by a schedule, in Unix seconds
+type Occurrence struct {
+	Start int64
+	End   int64
+}
+
+// ParseSchedule parses the standard 5-field cron expression of the spec. The expression is
+// evaluated in the time zone of the spec, UTC by default.
+func ParseSchedule(spec Spec) (*Schedule, error) {
+	sched, err := cron.ParseStandard(spec.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", spec.Schedule, err)
+	}
+
+	if spec.Duration == "" {
+		return nil, fmt.Errorf("duration is required for the schedule")
+	}
+	duration, err := time.ParseDuration(spec.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q: %v", spec.Duration, err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive, got %q", spec.Duration)
+	}
+
+	location := time.UTC
+	if spec.TimeZone != "" {
+		location, err = time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %v", spec.TimeZone, err)
+		}
+	}
+
+	s := &Schedule{
+		cron:      sched,
+		duration:  duration,
+		location:  location,
+		notBefore: math.MinInt64,
+		notAfter:  math.MaxInt64,
+	}
+	if spec.StartDate != "" {
+		s.notBefore, err = DateToSeconds(spec.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("convert startDate '%s': %v", spec.StartDate, err)
+		}
+	}
+	if spec.EndDate != "" {
+		s.notAfter, err = DateToSeconds(spec.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("convert endDate '%s': %v", spec.EndDate, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Occurrences returns downtimes that intersect with the range [from, to). An occurrence is
+// clipped by the bounds of the recurrence.
+//
+// At most maxOccurrences are expanded. If the schedule has more of them in the range, the
+// expanded ones are returned along with an error.
+func (s *Schedule) Occurrences(from, to int64) ([]Occurrence, error) {
+	res := make([]Occurrence, 0)
+
+	// An occurrence that started before the range can still last in it
+	cursor := time.Unix(from, 0).Add(-s.duration).Add(-time.Second).In(s.location)
+	for i := 0; ; i++ {
+		start := s.cron.Next(cursor)
+		if start.IsZero() || start.Unix() >= to || start.Unix() >= s.notAfter {
+			break
+		}
+		if i == maxOccurrences {
+			return res, fmt.Errorf("more than %d occurrences in the range, the rest are ignored", maxOccurrences)
+		}
+		cursor = start
+
+		occ := Occurrence{
+			Start: max(start.Unix(), s.notBefore),
+			End:   min(start.Add(s.duration).Unix(), s.notAfter),
+		}
+		if occ.End <= from || occ.Start >= occ.End {
+			continue
+		}
+		res = append(res, occ)
+	}
+
+	return res, nil
+}
+
+func min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
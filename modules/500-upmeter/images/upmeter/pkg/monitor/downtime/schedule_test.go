@@ -0,0 +1,113 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unix(t *testing.T, s string) int64 {
+	tm, err := time.Parse(time.RFC3339, s)
+	require.NoError(t, err)
+	return tm.Unix()
+}
+
+func TestParseSchedule_Errors(t *testing.T) {
+	specs := []Spec{
+		{Schedule: "not a cron", Duration: "1h"},
+		{Schedule: "0 3 * * SAT"},
+		{Schedule: "0 3 * * SAT", Duration: "-1h"},
+		{Schedule: "0 3 * * SAT", Duration: "1h", TimeZone: "Mars/Olympus"},
+		{Schedule: "0 3 * * SAT", Duration: "1h", StartDate: "yesterday"},
+	}
+	for _, spec := range specs {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, "spec %+v", spec)
+	}
+}
+
+func TestSchedule_Occurrences(t *testing.T) {
+	t.Run("weekly in time zone", func(t *testing.T) {
+		// Saturdays 03:00 in Moscow is 00:00 UTC
+		s, err := ParseSchedule(Spec{Schedule: "0 3 * * SAT", Duration: "2h", TimeZone: "Europe/Moscow"})
+		require.NoError(t, err)
+
+		got, err := s.Occurrences(unix(t, "2023-03-01T00:00:00Z"), unix(t, "2023-03-15T00:00:00Z"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []Occurrence{
+			{Start: unix(t, "2023-03-04T00:00:00Z"), End: unix(t, "2023-03-04T02:00:00Z")},
+			{Start: unix(t, "2023-03-11T00:00:00Z"), End: unix(t, "2023-03-11T02:00:00Z")},
+		}, got)
+	})
+
+	t.Run("occurrence started before the range is included", func(t *testing.T) {
+		s, err := ParseSchedule(Spec{Schedule: "0 23 * * *", Duration: "3h"})
+		require.NoError(t, err)
+
+		got, err := s.Occurrences(unix(t, "2023-03-02T00:00:00Z"), unix(t, "2023-03-02T12:00:00Z"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []Occurrence{
+			{Start: unix(t, "2023-03-01T23:00:00Z"), End: unix(t, "2023-03-02T02:00:00Z")},
+		}, got)
+	})
+
+	t.Run("recurrence is bounded by dates", func(t *testing.T) {
+		s, err := ParseSchedule(Spec{
+			Schedule:  "0 0 * * *",
+			Duration:  "1h",
+			StartDate: "2023-03-02T00:30:00Z",
+			EndDate:   "2023-03-03T12:00:00Z",
+		})
+		require.NoError(t, err)
+
+		got, err := s.Occurrences(unix(t, "2023-03-01T00:00:00Z"), unix(t, "2023-03-10T00:00:00Z"))
+		require.NoError(t, err)
+
+		assert.Equal(t, []Occurrence{
+			{Start: unix(t, "2023-03-02T00:30:00Z"), End: unix(t, "2023-03-02T01:00:00Z")},
+			{Start: unix(t, "2023-03-03T00:00:00Z"), End: unix(t, "2023-03-03T01:00:00Z")},
+		}, got)
+	})
+	t.Run("too many occurrences are truncated with an error", func(t *testing.T) {
+		s, err := ParseSchedule(Spec{Schedule: "* * * * *", Duration: "30s"})
+		require.NoError(t, err)
+
+		// a minute schedule has 525600 occurrences in a year
+		got, err := s.Occurrences(unix(t, "2023-01-01T00:00:00Z"), unix(t, "2024-01-01T00:00:00Z"))
+
+		assert.Error(t, err)
+		assert.Len(t, got, maxOccurrences)
+		assert.Equal(t, Occurrence{Start: unix(t, "2023-01-01T00:00:00Z"), End: unix(t, "2023-01-01T00:00:30Z")}, got[0])
+	})
+
+	t.Run("exactly the limit of occurrences is not an error", func(t *testing.T) {
+		s, err := ParseSchedule(Spec{Schedule: "* * * * *", Duration: "30s"})
+		require.NoError(t, err)
+
+		from := unix(t, "2023-01-01T00:00:00Z")
+		got, err := s.Occurrences(from, from+maxOccurrences*60)
+
+		require.NoError(t, err)
+		assert.Len(t, got, maxOccurrences)
+	})
+}
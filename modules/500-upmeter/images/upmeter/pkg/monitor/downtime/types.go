@@ -33,6 +33,22 @@ type Spec struct {
 	Type        string   `json:"type"`
 	Description string   `json:"description"`
 	Affected    []string `json:"affected"`
+
+	// Schedule makes the downtime recurring, e.g. "0 3 * * SAT" for weekly maintenance. Each
+	// occurrence lasts for Duration. StartDate and EndDate, if set, bound the recurrence.
+	Schedule string `json:"schedule,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// IsRecurring returns true if the spec is a schedule rather than a single incident
+func (s Spec) IsRecurring() bool {
+	return s.Schedule != ""
+}
+
+// IsOpen returns true for an incident that has started but not finished yet
+func (s Spec) IsOpen() bool {
+	return !s.IsRecurring() && s.EndDate == ""
 }
 
 // Downtime is the Schema for the downtime incidents
@@ -50,30 +66,58 @@ type DowntimeList struct {
 	Items           []Downtime `json:"items"`
 }
 
+// GetDowntimeIncidents returns incidents that intersect with the range [from, to). Recurring
+// downtimes are expanded into separate incidents. Open incidents last until now.
+//
 // TODO use FilterFunc and store an array of DowntimeIncidents in a filterResult object.
-func (d Downtime) GetDowntimeIncidents() []check.DowntimeIncident {
+func (d Downtime) GetDowntimeIncidents(from, to int64, now time.Time) []check.DowntimeIncident {
 	res := make([]check.DowntimeIncident, 0)
 	for _, obj := range d.Spec {
+		newIncident := func(start, end int64) check.DowntimeIncident {
+			return check.DowntimeIncident{
+				Start:        start,
+				End:          end,
+				Duration:     0,
+				Type:         obj.Type,
+				Description:  obj.Description,
+				Affected:     obj.Affected,
+				DowntimeName: d.Name,
+			}
+		}
+
+		if obj.IsRecurring() {
+			schedule, err := ParseSchedule(obj)
+			if err != nil {
+				log.Errorf("parse schedule in %s: %v", d.Name, err)
+				continue
+			}
+			occurrences, err := schedule.Occurrences(from, to)
+			if err != nil {
+				log.Warnf("expand schedule %q in %s: %v", obj.Schedule, d.Name, err)
+			}
+			for _, occ := range occurrences {
+				res = append(res, newIncident(occ.Start, occ.End))
+			}
+			continue
+		}
+
 		start, err := DateToSeconds(obj.StartDate)
 		if err != nil {
 			log.Errorf("convert startDate '%s' in %s: %v", obj.StartDate, d.Name, err)
 			continue
 		}
-		end, err := DateToSeconds(obj.EndDate)
-		if err != nil {
-			log.Errorf("convert endDate '%s' in %s: %v", obj.EndDate, d.Name, err)
-			continue
+		end := now.Unix()
+		if !obj.IsOpen() {
+			end, err = DateToSeconds(obj.EndDate)
+			if err != nil {
+				log.Errorf("convert endDate '%s' in %s: %v", obj.EndDate, d.Name, err)
+				continue
+			}
 		}
-		inc := check.DowntimeIncident{
-			Start:        start,
-			End:          end,
-			Duration:     0,
-			Type:         obj.Type,
-			Description:  obj.Description,
-			Affected:     obj.Affected,
-			DowntimeName: d.Name,
+		if start >= to || end <= from {
+			continue
 		}
-		res = append(res, inc)
+		res = append(res, newIncident(start, end))
 	}
 
 	return res
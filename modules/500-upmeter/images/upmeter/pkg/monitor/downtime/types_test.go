@@ -0,0 +1,85 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDowntime_GetDowntimeIncidents(t *testing.T) {
+	now := time.Unix(unix(t, "2023-03-10T12:00:00Z"), 0)
+	d := Downtime{
+		ObjectMeta: metav1.ObjectMeta{Name: "dt"},
+		Spec: []Spec{
+			{
+				StartDate: "2023-03-01T10:00:00Z",
+				EndDate:   "2023-03-01T11:00:00Z",
+				Type:      "Accident",
+				Affected:  []string{"control-plane"},
+			},
+			{
+				// open
+				StartDate: "2023-03-10T11:00:00Z",
+				Type:      "Accident",
+				Affected:  []string{"nginx"},
+			},
+			{
+				Schedule: "0 4 * * *",
+				Duration: "30m",
+				Type:     "Maintenance",
+				Affected: []string{"nodegroups"},
+			},
+			{
+				// out of range
+				StartDate: "2023-02-01T10:00:00Z",
+				EndDate:   "2023-02-01T11:00:00Z",
+				Type:      "Accident",
+			},
+		},
+	}
+
+	got := d.GetDowntimeIncidents(unix(t, "2023-03-01T00:00:00Z"), unix(t, "2023-03-11T00:00:00Z"), now)
+
+	byType := map[string]int{}
+	for _, inc := range got {
+		assert.Equal(t, "dt", inc.DowntimeName)
+		byType[inc.Type]++
+	}
+	assert.Equal(t, map[string]int{"Accident": 2, "Maintenance": 10}, byType)
+
+	// the open incident lasts until now
+	assert.Equal(t, unix(t, "2023-03-10T11:00:00Z"), got[1].Start)
+	assert.Equal(t, now.Unix(), got[1].End)
+}
+
+func TestValidateIncident(t *testing.T) {
+	assert.NoError(t, ValidateIncident(Spec{Type: "Accident", Affected: []string{"nginx"}}))
+
+	specs := []Spec{
+		{Type: "Outage", Affected: []string{"nginx"}},
+		{Type: "Accident"},
+		{Type: "Accident", Affected: []string{"nginx"}, StartDate: "now"},
+		{Type: "Maintenance", Affected: []string{"nginx"}, Schedule: "0 3 * * *", Duration: "1h"},
+	}
+	for _, spec := range specs {
+		assert.Error(t, ValidateIncident(spec), "spec %+v", spec)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slo
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/db/dao"
+)
+
+const DefaultWindowDays = 30
+
+// Spec is the spec in the UpmeterSLO CRD
+type Spec struct {
+	Group string `json:"group"`
+	Probe string `json:"probe,omitempty"`
+	// Objective is the target availability in percent, e.g. 99.9
+	Objective  float64 `json:"objective"`
+	WindowDays int     `json:"windowDays,omitempty"`
+}
+
+// UpmeterSLO is the Schema for the service level objectives
+type UpmeterSLO struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec Spec `json:"spec,omitempty"`
+}
+
+// UpmeterSLOList contains a list of UpmeterSLO objects
+type UpmeterSLOList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []UpmeterSLO `json:"items"`
+}
+
+// Objective is the SLO prepared for evaluation
+type Objective struct {
+	Name string
+	// Ref points to the group total if the probe is not specified in the spec
+	Ref check.ProbeRef
+	// Target is the availability fraction of 1, e.g. 0.999
+	Target float64
+	Window time.Duration
+}
+
+// ErrorBudget is the allowed fraction of downtime, e.g. 0.001
+func (o Objective) ErrorBudget() float64 {
+	return 1 - o.Target
+}
+
+func (s UpmeterSLO) Objective() (Objective, error) {
+	if s.Spec.Group == "" {
+		return Objective{}, fmt.Errorf("group is empty")
+	}
+	if s.Spec.Objective <= 0 || s.Spec.Objective >= 100 {
+		return Objective{}, fmt.Errorf("objective %v is out of range (0, 100)", s.Spec.Objective)
+	}
+
+	days := s.Spec.WindowDays
+	if days == 0 {
+		days = DefaultWindowDays
+	}
+	if days < 0 {
+		return Objective{}, fmt.Errorf("windowDays %d is negative", days)
+	}
+
+	probe := s.Spec.Probe
+	if probe == "" {
+		probe = dao.GroupAggregation
+	}
+
+	return Objective{
+		Name:   s.Name,
+		Ref:    check.ProbeRef{Group: s.Spec.Group, Probe: probe},
+		Target: s.Spec.Objective / 100,
+		Window: time.Duration(days) * 24 * time.Hour,
+	}, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/db/dao"
+)
+
+func TestUpmeterSLO_Objective(t *testing.T) {
+	newSLO := func(spec Spec) UpmeterSLO {
+		return UpmeterSLO{ObjectMeta: metav1.ObjectMeta{Name: "slo"}, Spec: spec}
+	}
+
+	t.Run("defaults to the group aggregation and 30 days", func(t *testing.T) {
+		obj, err := newSLO(Spec{Group: "control-plane", Objective: 99.9}).Objective()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "slo", obj.Name)
+		assert.Equal(t, check.ProbeRef{Group: "control-plane", Probe: dao.GroupAggregation}, obj.Ref)
+		assert.InDelta(t, 0.999, obj.Target, 1e-9)
+		assert.InDelta(t, 0.001, obj.ErrorBudget(), 1e-9)
+		assert.Equal(t, 30*24*time.Hour, obj.Window)
+	})
+
+	t.Run("probe and window are respected", func(t *testing.T) {
+		obj, err := newSLO(Spec{Group: "control-plane", Probe: "apiserver", Objective: 99, WindowDays: 7}).Objective()
+
+		assert.NoError(t, err)
+		assert.Equal(t, check.ProbeRef{Group: "control-plane", Probe: "apiserver"}, obj.Ref)
+		assert.Equal(t, 7*24*time.Hour, obj.Window)
+	})
+
+	t.Run("invalid specs", func(t *testing.T) {
+		specs := []Spec{
+			{Objective: 99},
+			{Group: "g", Objective: 0},
+			{Group: "g", Objective: 100},
+			{Group: "g", Objective: 99, WindowDays: -1},
+		}
+		for _, spec := range specs {
+			_, err := newSLO(spec).Objective()
+			assert.Error(t, err, "spec %+v", spec)
+		}
+	})
+}
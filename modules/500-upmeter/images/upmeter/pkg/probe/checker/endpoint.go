@@ -0,0 +1,263 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"d8.io/upmeter/pkg/check"
+)
+
+// maxResponseBodySize limits the amount of the response body read for verification
+const maxResponseBodySize = 1 << 20
+
+// HTTPEndpointAvailable is a checker constructor and configurator for arbitrary HTTP(S) endpoints
+type HTTPEndpointAvailable struct {
+	URL                string
+	Method             string
+	Headers            map[string]string
+	InsecureSkipVerify bool
+	UserAgent          string
+
+	// ExpectedStatusCodes defaults to 200 OK
+	ExpectedStatusCodes []int
+	// BodyRegex is optional
+	BodyRegex *regexp.Regexp
+	// LatencyThreshold is optional, the zero value disables the latency check
+	LatencyThreshold time.Duration
+
+	Timeout time.Duration
+}
+
+func (c HTTPEndpointAvailable) Checker() check.Checker {
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	codes := c.ExpectedStatusCodes
+	if len(codes) == 0 {
+		codes = []int{http.StatusOK}
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify},
+		},
+		Timeout: c.Timeout,
+	}
+
+	return &httpEndpointChecker{
+		client:           client,
+		url:              c.URL,
+		method:           method,
+		headers:          c.Headers,
+		userAgent:        c.UserAgent,
+		statusCodes:      codes,
+		bodyRegex:        c.BodyRegex,
+		latencyThreshold: c.LatencyThreshold,
+	}
+}
+
+type httpEndpointChecker struct {
+	client    *http.Client
+	url       string
+	method    string
+	headers   map[string]string
+	userAgent string
+
+	statusCodes      []int
+	bodyRegex        *regexp.Regexp
+	latencyThreshold time.Duration
+}
+
+func (c *httpEndpointChecker) Check() check.Error {
+	req, err := http.NewRequest(c.method, c.url, nil)
+	if err != nil {
+		return check.ErrUnknown("cannot create request: %v", err)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	if c.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return check.ErrFail("cannot dial %q: %v", c.url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if err != nil {
+		return check.ErrFail("cannot read response body: %v", err)
+	}
+	latency := time.Since(start)
+
+	if !hasStatusCode(c.statusCodes, resp.StatusCode) {
+		return check.ErrFail("HTTP: %s %s returned status %d, expected one of %v", c.method, c.url, resp.StatusCode, c.statusCodes)
+	}
+	if c.bodyRegex != nil && !c.bodyRegex.Match(body) {
+		return check.ErrFail("HTTP: %s %s response body does not match %q", c.method, c.url, c.bodyRegex.String())
+	}
+	return verifyLatency(latency, c.latencyThreshold)
+}
+
+func hasStatusCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// TCPEndpointAvailable is a checker constructor and configurator for TCP connection establishment
+type TCPEndpointAvailable struct {
+	Address          string
+	LatencyThreshold time.Duration
+	Timeout          time.Duration
+}
+
+func (c TCPEndpointAvailable) Checker() check.Checker {
+	return &tcpEndpointChecker{
+		address:          c.Address,
+		timeout:          c.Timeout,
+		latencyThreshold: c.LatencyThreshold,
+	}
+}
+
+type tcpEndpointChecker struct {
+	address          string
+	timeout          time.Duration
+	latencyThreshold time.Duration
+}
+
+func (c *tcpEndpointChecker) Check() check.Error {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+	if err != nil {
+		return check.ErrFail("cannot connect to %q: %v", c.address, err)
+	}
+	latency := time.Since(start)
+	_ = conn.Close()
+
+	return verifyLatency(latency, c.latencyThreshold)
+}
+
+// DNSRecordResolvable is a checker constructor and configurator for DNS name resolution
+type DNSRecordResolvable struct {
+	Name string
+	// Server is the optional "host:port" of the DNS server, the system resolver is used by default
+	Server string
+	// ExpectedAddresses is optional, all of them must be present in the response
+	ExpectedAddresses []string
+	LatencyThreshold  time.Duration
+	Timeout           time.Duration
+}
+
+func (c DNSRecordResolvable) Checker() check.Checker {
+	resolver := net.DefaultResolver
+	if c.Server != "" {
+		server := c.Server
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	return &dnsRecordChecker{
+		resolver:         resolver,
+		name:             c.Name,
+		expected:         c.ExpectedAddresses,
+		timeout:          c.Timeout,
+		latencyThreshold: c.LatencyThreshold,
+	}
+}
+
+type dnsRecordChecker struct {
+	resolver         *net.Resolver
+	name             string
+	expected         []string
+	timeout          time.Duration
+	latencyThreshold time.Duration
+}
+
+func (c *dnsRecordChecker) Check() check.Error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := c.resolver.LookupHost(ctx, c.name)
+	if err != nil {
+		return check.ErrFail("cannot resolve %q: %v", c.name, err)
+	}
+	latency := time.Since(start)
+
+	if len(addrs) == 0 {
+		return check.ErrFail("no addresses resolved for %q", c.name)
+	}
+	if missing := missingAddresses(c.expected, addrs); len(missing) > 0 {
+		sort.Strings(addrs)
+		return check.ErrFail("%q resolved to [%s], missing expected [%s]", c.name, strings.Join(addrs, ", "), strings.Join(missing, ", "))
+	}
+	return verifyLatency(latency, c.latencyThreshold)
+}
+
+func missingAddresses(expected, got []string) []string {
+	gotIPs := make(map[string]struct{}, len(got))
+	for _, a := range got {
+		gotIPs[normalizeIP(a)] = struct{}{}
+	}
+
+	var missing []string
+	for _, a := range expected {
+		if _, ok := gotIPs[normalizeIP(a)]; !ok {
+			missing = append(missing, a)
+		}
+	}
+	return missing
+}
+
+func normalizeIP(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
+	}
+	return addr
+}
+
+// verifyLatency returns the fail error if the threshold is set and exceeded
+func verifyLatency(latency, threshold time.Duration) check.Error {
+	if threshold > 0 && latency > threshold {
+		return check.ErrFail("latency %s exceeds the threshold %s", latency.Round(time.Millisecond), threshold)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"d8.io/upmeter/pkg/check"
+	k8saccess "d8.io/upmeter/pkg/kubernetes"
+)
+
+func Test_HTTPEndpointAvailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte(`{"status":"green"}`))
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"status":"green"}`))
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		config HTTPEndpointAvailable
+		status check.Status
+	}{
+		{
+			name:   "200 by default",
+			config: HTTPEndpointAvailable{URL: server.URL + "/ok"},
+			status: check.Up,
+		},
+		{
+			name:   "unexpected status code",
+			config: HTTPEndpointAvailable{URL: server.URL + "/missing"},
+			status: check.Down,
+		},
+		{
+			name:   "expected status code",
+			config: HTTPEndpointAvailable{URL: server.URL + "/created", ExpectedStatusCodes: []int{200, 201}},
+			status: check.Up,
+		},
+		{
+			name:   "body matches",
+			config: HTTPEndpointAvailable{URL: server.URL + "/ok", BodyRegex: regexp.MustCompile(`"status":\s*"green"`)},
+			status: check.Up,
+		},
+		{
+			name:   "body does not match",
+			config: HTTPEndpointAvailable{URL: server.URL + "/ok", BodyRegex: regexp.MustCompile(`red`)},
+			status: check.Down,
+		},
+		{
+			name:   "latency exceeds the threshold",
+			config: HTTPEndpointAvailable{URL: server.URL + "/slow", LatencyThreshold: 10 * time.Millisecond},
+			status: check.Down,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Timeout = time.Second
+			assertCheckStatus(t, tt.status, tt.config.Checker().Check())
+		})
+	}
+}
+
+func Test_TCPEndpointAvailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	addr := listener.Addr().String()
+
+	up := TCPEndpointAvailable{Address: addr, Timeout: time.Second}
+	assertCheckStatus(t, check.Up, up.Checker().Check())
+
+	_ = listener.Close()
+
+	down := TCPEndpointAvailable{Address: addr, Timeout: time.Second}
+	assertCheckStatus(t, check.Down, down.Checker().Check())
+}
+
+func Test_DNSRecordResolvable(t *testing.T) {
+	resolved := DNSRecordResolvable{Name: "localhost", Timeout: time.Second}
+	assertCheckStatus(t, check.Up, resolved.Checker().Check())
+
+	missing := DNSRecordResolvable{Name: "localhost", ExpectedAddresses: []string{"192.0.2.1"}, Timeout: time.Second}
+	assertCheckStatus(t, check.Down, missing.Checker().Check())
+}
+
+func Test_queryResultVerifier(t *testing.T) {
+	min, max := 1.0, 10.0
+
+	tests := []struct {
+		name   string
+		body   string
+		status check.Status
+	}{
+		{
+			name:   "vector in bounds",
+			body:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1613143228.991,"5"]}]}}`,
+			status: check.Up,
+		},
+		{
+			name:   "scalar in bounds",
+			body:   `{"status":"success","data":{"resultType":"scalar","result":[1613143228.991,"1"]}}`,
+			status: check.Up,
+		},
+		{
+			name:   "lower than min",
+			body:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1613143228.991,"0.5"]}]}}`,
+			status: check.Down,
+		},
+		{
+			name:   "greater than max",
+			body:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1613143228.991,"11"]}]}}`,
+			status: check.Down,
+		},
+		{
+			name:   "empty vector",
+			body:   `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			status: check.Down,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &queryResultVerifier{query: "q", min: &min, max: &max}
+			assertCheckStatus(t, tt.status, v.Verify([]byte(tt.body)))
+		})
+	}
+}
+
+func Test_PrometheusQueryResultInBounds_Credentials(t *testing.T) {
+	var authorized bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, authorized = r.Header["Authorization"]
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1613143228.991,"1"]}}`))
+	}))
+	defer server.Close()
+
+	for _, authorize := range []bool{false, true} {
+		c := PrometheusQueryResultInBounds{
+			Access:    k8saccess.FakeAccessor(),
+			Timeout:   time.Second,
+			Endpoint:  server.URL + "/api/v1/query",
+			Query:     "up",
+			Authorize: authorize,
+		}
+		assertCheckStatus(t, check.Up, c.Checker().Check())
+		assert.Equal(t, authorize, authorized)
+	}
+}
+
+func Test_PrometheusQueryResultInBounds_InvalidEndpoint(t *testing.T) {
+	c := PrometheusQueryResultInBounds{
+		Access:   k8saccess.FakeAccessor(),
+		Timeout:  time.Second,
+		Endpoint: "http://%zz/api/v1/query",
+		Query:    "up",
+	}
+	assertCheckStatus(t, check.Down, c.Checker().Check())
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package checker
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/tidwall/gjson"
@@ -168,3 +170,108 @@ func addMetricQuery(baseUrl, metricName string) string {
 
 	return endpoint.String()
 }
+
+// PrometheusQueryResultInBounds is a checker constructor and configurator. It runs the instant
+// query and verifies the first value of the result against the bounds.
+type PrometheusQueryResultInBounds struct {
+	Access   kubernetes.Access
+	Timeout  time.Duration
+	Endpoint string
+	Query    string
+
+	// Authorize adds the ServiceAccount token to the request. It must be set only for the in-cluster
+	// Prometheus, since the endpoint is user-defined.
+	Authorize bool
+
+	// Min and Max are optional inclusive bounds
+	Min *float64
+	Max *float64
+}
+
+func (c PrometheusQueryResultInBounds) Checker() check.Checker {
+	req, err := newQueryRequest(c.Endpoint, c.Query, c.Access.UserAgent())
+	if err != nil {
+		return &failingChecker{err: check.ErrFail("invalid endpoint %q: %v", c.Endpoint, err)}
+	}
+	if c.Authorize {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Access.ServiceAccountToken()))
+	}
+
+	verifier := &queryResultVerifier{
+		req:   req,
+		query: c.Query,
+		min:   c.Min,
+		max:   c.Max,
+	}
+	checker := newHTTPChecker(newInsecureClient(3*c.Timeout), verifier)
+	return withTimeout(checker, c.Timeout)
+}
+
+type queryResultVerifier struct {
+	req      *http.Request
+	query    string
+	min, max *float64
+}
+
+func (v *queryResultVerifier) Request() *http.Request {
+	return v.req.Clone(context.Background())
+}
+
+// Verify checks the value of the first element of the vector or the scalar result
+func (v *queryResultVerifier) Verify(body []byte) check.Error {
+	resultType := gjson.GetBytes(body, "data.resultType").String()
+
+	var raw gjson.Result
+	switch resultType {
+	case "scalar":
+		raw = gjson.GetBytes(body, "data.result.1")
+	case "vector":
+		raw = gjson.GetBytes(body, "data.result.0.value.1")
+	default:
+		return check.ErrFail("unexpected result type %q of query %q", resultType, v.query)
+	}
+	if !raw.Exists() {
+		return check.ErrFail("empty result of query %q", v.query)
+	}
+
+	value, err := strconv.ParseFloat(raw.String(), 64)
+	if err != nil {
+		return check.ErrFail("cannot parse value %q of query %q: %v", raw.String(), v.query, err)
+	}
+	if v.min != nil && value < *v.min {
+		return check.ErrFail("query %q returned %v, lower than %v", v.query, value, *v.min)
+	}
+	if v.max != nil && value > *v.max {
+		return check.ErrFail("query %q returned %v, greater than %v", v.query, value, *v.max)
+	}
+	return nil
+}
+
+// newQueryRequest prepares the instant query request without credentials
+func newQueryRequest(baseUrl, promql, userAgent string) (*http.Request, error) {
+	endpoint, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	query := make(url.Values)
+	query.Set("query", promql)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// failingChecker always returns the same error. It is used when the checker cannot be
+// configured, so that the probe fails instead of crashing the agent.
+type failingChecker struct {
+	err check.Error
+}
+
+func (c *failingChecker) Check() check.Error {
+	return c.err
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"d8.io/upmeter/pkg/kubernetes"
+	"d8.io/upmeter/pkg/probe/checker"
+)
+
+const (
+	DefaultCustomGroup = "custom"
+
+	defaultCustomProbePeriod   = 30 * time.Second
+	defaultCustomProbeTimeout  = 5 * time.Second
+	minCustomProbePeriod       = 5 * time.Second
+	defaultPrometheusQueryHost = "https://prometheus.d8-monitoring:9090"
+)
+
+// builtinGroups cannot be used for custom probes, so that users do not mix their checks into the
+// availability of the cluster components
+var builtinGroups = map[string]struct{}{
+	"control-plane":              {},
+	"deckhouse":                  {},
+	"extensions":                 {},
+	"load-balancing":             {},
+	"monitoring-and-autoscaling": {},
+	"nginx":                      {},
+	"nodegroups":                 {},
+	"synthetic":                  {},
+}
+
+// CustomProbe is the probe declared by the UpmeterProbe custom resource. The name of the resource
+// becomes the probe name. Exactly one of HTTP, TCP, DNS, or Prometheus must be set.
+type CustomProbe struct {
+	Name    string `json:"name"`
+	Group   string `json:"group,omitempty"`
+	Period  string `json:"period,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+
+	HTTP       *CustomHTTPProbe       `json:"http,omitempty"`
+	TCP        *CustomTCPProbe        `json:"tcp,omitempty"`
+	DNS        *CustomDNSProbe        `json:"dns,omitempty"`
+	Prometheus *CustomPrometheusProbe `json:"prometheus,omitempty"`
+}
+
+type CustomHTTPProbe struct {
+	URL                 string            `json:"url"`
+	Method              string            `json:"method,omitempty"`
+	Headers             map[string]string `json:"headers,omitempty"`
+	InsecureSkipVerify  bool              `json:"insecureSkipVerify,omitempty"`
+	ExpectedStatusCodes []int             `json:"expectedStatusCodes,omitempty"`
+	BodyRegex           string            `json:"bodyRegex,omitempty"`
+	LatencyThreshold    string            `json:"latencyThreshold,omitempty"`
+}
+
+type CustomTCPProbe struct {
+	Address          string `json:"address"`
+	LatencyThreshold string `json:"latencyThreshold,omitempty"`
+}
+
+type CustomDNSProbe struct {
+	Name              string   `json:"name"`
+	Server            string   `json:"server,omitempty"`
+	ExpectedAddresses []string `json:"expectedAddresses,omitempty"`
+	LatencyThreshold  string   `json:"latencyThreshold,omitempty"`
+}
+
+type CustomPrometheusProbe struct {
+	Endpoint string   `json:"endpoint,omitempty"`
+	Query    string   `json:"query"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+}
+
+// ReadCustomProbes reads the list of custom probes from JSON file. Empty path means no custom probes.
+func ReadCustomProbes(path string) ([]CustomProbe, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading custom probes: %v", err)
+	}
+
+	var probes []CustomProbe
+	if err := json.Unmarshal(raw, &probes); err != nil {
+		return nil, fmt.Errorf("parsing custom probes: %v", err)
+	}
+	return probes, nil
+}
+
+// initCustom creates runner configs for custom probes. Invalid probes are skipped, so that a
+// mistake in a single resource does not stop the agent.
+func initCustom(access kubernetes.Access, probes []CustomProbe, logger *logrus.Logger) []runnerConfig {
+	configs := make([]runnerConfig, 0, len(probes))
+	for _, p := range probes {
+		rc, err := customRunnerConfig(access, p)
+		if err != nil {
+			logger.Errorf("Skipping custom probe %q: %v", p.Name, err)
+			continue
+		}
+		configs = append(configs, rc)
+	}
+	return configs
+}
+
+func customRunnerConfig(access kubernetes.Access, p CustomProbe) (runnerConfig, error) {
+	rc := runnerConfig{
+		group: p.Group,
+		probe: p.Name,
+	}
+
+	if rc.probe == "" {
+		return rc, fmt.Errorf("name is empty")
+	}
+	if rc.group == "" {
+		rc.group = DefaultCustomGroup
+	}
+	if _, reserved := builtinGroups[rc.group]; reserved {
+		return rc, fmt.Errorf("group %q is reserved for built-in probes", rc.group)
+	}
+
+	var err error
+	rc.period, err = parseDurationOrDefault(p.Period, defaultCustomProbePeriod)
+	if err != nil {
+		return rc, fmt.Errorf("period: %v", err)
+	}
+	if rc.period < minCustomProbePeriod {
+		return rc, fmt.Errorf("period %s is less than %s", rc.period, minCustomProbePeriod)
+	}
+	timeout, err := parseDurationOrDefault(p.Timeout, defaultCustomProbeTimeout)
+	if err != nil {
+		return rc, fmt.Errorf("timeout: %v", err)
+	}
+	if timeout > rc.period {
+		return rc, fmt.Errorf("timeout %s is greater than period %s", timeout, rc.period)
+	}
+
+	specified := 0
+	for _, set := range []bool{p.HTTP != nil, p.TCP != nil, p.DNS != nil, p.Prometheus != nil} {
+		if set {
+			specified++
+		}
+	}
+	if specified != 1 {
+		return rc, fmt.Errorf("exactly one of http, tcp, dns, or prometheus must be specified, got %d", specified)
+	}
+
+	switch {
+	case p.HTTP != nil:
+		rc.check = "http"
+		rc.config, err = customHTTPConfig(access, p.HTTP, timeout)
+	case p.TCP != nil:
+		rc.check = "tcp"
+		rc.config, err = customTCPConfig(p.TCP, timeout)
+	case p.DNS != nil:
+		rc.check = "dns"
+		rc.config, err = customDNSConfig(p.DNS, timeout)
+	case p.Prometheus != nil:
+		rc.check = "prometheus-query"
+		rc.config, err = customPrometheusConfig(access, p.Prometheus, timeout)
+	}
+	if err != nil {
+		return rc, fmt.Errorf("%s: %v", rc.check, err)
+	}
+	return rc, nil
+}
+
+func customHTTPConfig(access kubernetes.Access, p *CustomHTTPProbe, timeout time.Duration) (checker.Config, error) {
+	if p.URL == "" {
+		return nil, fmt.Errorf("url is empty")
+	}
+	latency, err := parseDurationOrDefault(p.LatencyThreshold, 0)
+	if err != nil {
+		return nil, fmt.Errorf("latencyThreshold: %v", err)
+	}
+	var bodyRegex *regexp.Regexp
+	if p.BodyRegex != "" {
+		bodyRegex, err = regexp.Compile(p.BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("bodyRegex: %v", err)
+		}
+	}
+
+	return checker.HTTPEndpointAvailable{
+		URL:                 p.URL,
+		Method:              p.Method,
+		Headers:             p.Headers,
+		InsecureSkipVerify:  p.InsecureSkipVerify,
+		UserAgent:           access.UserAgent(),
+		ExpectedStatusCodes: p.ExpectedStatusCodes,
+		BodyRegex:           bodyRegex,
+		LatencyThreshold:    latency,
+		Timeout:             timeout,
+	}, nil
+}
+
+func customTCPConfig(p *CustomTCPProbe, timeout time.Duration) (checker.Config, error) {
+	if p.Address == "" {
+		return nil, fmt.Errorf("address is empty")
+	}
+	latency, err := parseDurationOrDefault(p.LatencyThreshold, 0)
+	if err != nil {
+		return nil, fmt.Errorf("latencyThreshold: %v", err)
+	}
+
+	return checker.TCPEndpointAvailable{
+		Address:          p.Address,
+		LatencyThreshold: latency,
+		Timeout:          timeout,
+	}, nil
+}
+
+func customDNSConfig(p *CustomDNSProbe, timeout time.Duration) (checker.Config, error) {
+	if p.Name == "" {
+		return nil, fmt.Errorf("name is empty")
+	}
+	latency, err := parseDurationOrDefault(p.LatencyThreshold, 0)
+	if err != nil {
+		return nil, fmt.Errorf("latencyThreshold: %v", err)
+	}
+
+	return checker.DNSRecordResolvable{
+		Name:              p.Name,
+		Server:            p.Server,
+		ExpectedAddresses: p.ExpectedAddresses,
+		LatencyThreshold:  latency,
+		Timeout:           timeout,
+	}, nil
+}
+
+func customPrometheusConfig(access kubernetes.Access, p *CustomPrometheusProbe, timeout time.Duration) (checker.Config, error) {
+	if p.Query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+	if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+		return nil, fmt.Errorf("min %v is greater than max %v", *p.Min, *p.Max)
+	}
+	host := p.Endpoint
+	if host == "" {
+		host = defaultPrometheusQueryHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("endpoint %q must be an absolute http(s) URL", host)
+	}
+
+	return checker.PrometheusQueryResultInBounds{
+		Access:    access,
+		Timeout:   timeout,
+		Endpoint:  strings.TrimSuffix(host, "/") + "/api/v1/query",
+		Query:     p.Query,
+		Authorize: isInClusterPrometheus(u),
+		Min:       p.Min,
+		Max:       p.Max,
+	}, nil
+}
+
+// isInClusterPrometheus tells whether the endpoint is the Deckhouse Prometheus service. Only it
+// receives the agent ServiceAccount token, other endpoints are controlled by the probe author.
+func isInClusterPrometheus(u *url.URL) bool {
+	if u.Scheme != "https" || u.Port() != "9090" {
+		return false
+	}
+	switch u.Hostname() {
+	case "prometheus.d8-monitoring", "prometheus.d8-monitoring.svc":
+		return true
+	}
+	return false
+}
+
+func parseDurationOrDefault(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probe
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"d8.io/upmeter/pkg/kubernetes"
+	"d8.io/upmeter/pkg/probe/checker"
+)
+
+func TestReadCustomProbes(t *testing.T) {
+	probes, err := ReadCustomProbes("")
+	assert.NoError(t, err)
+	assert.Empty(t, probes)
+
+	path := filepath.Join(t.TempDir(), "probes.json")
+	content := `[
+  {"name": "shop", "period": "1m", "http": {"url": "https://shop.example.com/healthz", "expectedStatusCodes": [200, 204]}},
+  {"name": "orders", "group": "databases", "prometheus": {"query": "pg_up{db=\"orders\"}", "min": 1}}
+]`
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	probes, err = ReadCustomProbes(path)
+	assert.NoError(t, err)
+	if assert.Len(t, probes, 2) {
+		assert.Equal(t, "shop", probes[0].Name)
+		assert.Equal(t, []int{200, 204}, probes[0].HTTP.ExpectedStatusCodes)
+		assert.Equal(t, "databases", probes[1].Group)
+		assert.Equal(t, 1.0, *probes[1].Prometheus.Min)
+	}
+}
+
+func Test_customRunnerConfig(t *testing.T) {
+	one, zero := 1.0, 0.0
+
+	tests := []struct {
+		name    string
+		probe   CustomProbe
+		group   string
+		check   string
+		period  time.Duration
+		wantErr bool
+	}{
+		{
+			name:   "http with defaults",
+			probe:  CustomProbe{Name: "shop", HTTP: &CustomHTTPProbe{URL: "https://shop.example.com"}},
+			group:  DefaultCustomGroup,
+			check:  "http",
+			period: defaultCustomProbePeriod,
+		},
+		{
+			name:   "tcp in own group",
+			probe:  CustomProbe{Name: "pg", Group: "databases", Period: "10s", TCP: &CustomTCPProbe{Address: "pg:5432"}},
+			group:  "databases",
+			check:  "tcp",
+			period: 10 * time.Second,
+		},
+		{
+			name:   "dns",
+			probe:  CustomProbe{Name: "resolver", DNS: &CustomDNSProbe{Name: "example.com"}},
+			group:  DefaultCustomGroup,
+			check:  "dns",
+			period: defaultCustomProbePeriod,
+		},
+		{
+			name:   "prometheus",
+			probe:  CustomProbe{Name: "queue", Prometheus: &CustomPrometheusProbe{Query: "up", Min: &one}},
+			group:  DefaultCustomGroup,
+			check:  "prometheus-query",
+			period: defaultCustomProbePeriod,
+		},
+		{
+			name:    "built-in group",
+			probe:   CustomProbe{Name: "x", Group: "synthetic", TCP: &CustomTCPProbe{Address: "pg:5432"}},
+			wantErr: true,
+		},
+		{
+			name:    "no checks",
+			probe:   CustomProbe{Name: "x"},
+			wantErr: true,
+		},
+		{
+			name:    "two checks",
+			probe:   CustomProbe{Name: "x", TCP: &CustomTCPProbe{Address: "pg:5432"}, DNS: &CustomDNSProbe{Name: "pg"}},
+			wantErr: true,
+		},
+		{
+			name:    "too frequent",
+			probe:   CustomProbe{Name: "x", Period: "1s", TCP: &CustomTCPProbe{Address: "pg:5432"}},
+			wantErr: true,
+		},
+		{
+			name:    "timeout longer than period",
+			probe:   CustomProbe{Name: "x", Period: "10s", Timeout: "20s", TCP: &CustomTCPProbe{Address: "pg:5432"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid regex",
+			probe:   CustomProbe{Name: "x", HTTP: &CustomHTTPProbe{URL: "https://example.com", BodyRegex: "("}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid prometheus endpoint",
+			probe:   CustomProbe{Name: "x", Prometheus: &CustomPrometheusProbe{Endpoint: "http://%zz", Query: "up"}},
+			wantErr: true,
+		},
+		{
+			name:    "min greater than max",
+			probe:   CustomProbe{Name: "x", Prometheus: &CustomPrometheusProbe{Query: "up", Min: &one, Max: &zero}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := customRunnerConfig(kubernetes.FakeAccessor(), tt.probe)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.group, rc.group)
+			assert.Equal(t, tt.probe.Name, rc.probe)
+			assert.Equal(t, tt.check, rc.check)
+			assert.Equal(t, tt.period, rc.period)
+			assert.NotNil(t, rc.config.Checker())
+		})
+	}
+}
+
+func Test_customPrometheusConfig_Authorize(t *testing.T) {
+	tests := []struct {
+		endpoint  string
+		authorize bool
+	}{
+		{endpoint: "", authorize: true},
+		{endpoint: "https://prometheus.d8-monitoring:9090", authorize: true},
+		{endpoint: "https://prometheus.d8-monitoring.svc:9090/", authorize: true},
+		{endpoint: "http://prometheus.d8-monitoring:9090", authorize: false},
+		{endpoint: "https://prometheus.d8-monitoring.example.com:9090", authorize: false},
+		{endpoint: "https://victoria.example.com", authorize: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			config, err := customPrometheusConfig(kubernetes.FakeAccessor(), &CustomPrometheusProbe{Endpoint: tt.endpoint, Query: "up"}, time.Second)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.authorize, config.(checker.PrometheusQueryResultInBounds).Authorize)
+		})
+	}
+}
@@ -68,6 +68,7 @@ type DynamicConfig struct {
 	NodeGroups              []string
 	Zones                   []string
 	ZonePrefix              string
+	CustomProbes            []CustomProbe
 }
 
 func (l *Loader) Load() []*check.Runner {
@@ -148,6 +149,7 @@ func (l *Loader) collectConfigs() []runnerConfig {
 	l.configs = append(l.configs, initDeckhouse(l.access, l.preflight, l.logger)...)
 	l.configs = append(l.configs, initNginx(l.access, l.preflight, l.dynamic.IngressNginxControllers)...)
 	l.configs = append(l.configs, initNodeGroups(l.access, l.nodeLister, l.preflight, l.dynamic.NodeGroups, l.dynamic.Zones, l.dynamic.ZonePrefix)...)
+	l.configs = append(l.configs, initCustom(l.access, l.dynamic.CustomProbes, l.logger)...)
 
 	return l.configs
 }
@@ -0,0 +1,275 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/monitor/downtime"
+)
+
+// IncidentRecord is a downtime incident in the export for SLA reports
+type IncidentRecord struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Affected    []string `json:"affected"`
+	Start       string   `json:"start"`
+	End         string   `json:"end"`
+	// Duration is the number of seconds of the incident within the requested period
+	Duration int64 `json:"duration"`
+}
+
+type OpenDowntimeResponse struct {
+	Name string `json:"name"`
+}
+
+type CloseDowntimeRequest struct {
+	Name string `json:"name"`
+}
+
+// DowntimesHandler exports incidents for a period
+type DowntimesHandler struct {
+	DowntimeMonitor *downtime.Monitor
+}
+
+func (h *DowntimesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Infoln("Downtimes", r.RemoteAddr, r.RequestURI)
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "%d GET is required\n", http.StatusMethodNotAllowed)
+		return
+	}
+	h.export(w, r)
+}
+
+func (h *DowntimesHandler) export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := downtime.DateToSeconds(query.Get("from"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d 'from' is invalid: %s\n", http.StatusBadRequest, err)
+		return
+	}
+	to, err := downtime.DateToSeconds(query.Get("to"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d 'to' is invalid: %s\n", http.StatusBadRequest, err)
+		return
+	}
+	if from >= to {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d 'from' must be before 'to'\n", http.StatusBadRequest)
+		return
+	}
+
+	incidents, err := h.DowntimeMonitor.List(from, to)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+	if group := query.Get("group"); group != "" {
+		incidents = filterIncidents(incidents, incidentAffectsGroup(group))
+	}
+	records := newIncidentRecords(incidents, from, to)
+
+	switch format := query.Get("format"); format {
+	case "", "json":
+		out, err := json.Marshal(records)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write(out)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="downtimes.csv"`)
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if err := writeIncidentsCSV(w, records); err != nil {
+			log.Errorf("cannot write downtimes CSV: %v", err)
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d unknown format %q, expected json or csv\n", http.StatusBadRequest, format)
+	}
+}
+
+// OpenDowntimeHandler opens a new incident. It changes Downtime objects, thus it must not be
+// exposed along with the read-only API.
+type OpenDowntimeHandler struct {
+	DowntimeClient *downtime.Client
+}
+
+func (h *OpenDowntimeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Infoln("OpenDowntime", r.RemoteAddr, r.RequestURI)
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "%d POST is required\n", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d application/json is required\n", http.StatusBadRequest)
+		return
+	}
+
+	var spec downtime.Spec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusBadRequest, err)
+		return
+	}
+
+	if err := downtime.ValidateIncident(spec); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusBadRequest, err)
+		return
+	}
+
+	name, err := h.DowntimeClient.Open(r.Context(), spec, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+	log.Infof("Opened downtime %s", name)
+
+	out, _ := json.Marshal(OpenDowntimeResponse{Name: name})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(out)
+}
+
+// CloseDowntimeHandler closes open incidents of a Downtime
+type CloseDowntimeHandler struct {
+	DowntimeClient *downtime.Client
+}
+
+func (h *CloseDowntimeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Infoln("CloseDowntime", r.RemoteAddr, r.RequestURI)
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "%d POST is required\n", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d application/json is required\n", http.StatusBadRequest)
+		return
+	}
+
+	var req CloseDowntimeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d the name of the downtime is required\n", http.StatusBadRequest)
+		return
+	}
+
+	err := h.DowntimeClient.Close(r.Context(), req.Name, time.Now())
+	switch {
+	case err == nil:
+	case apierrors.IsNotFound(err):
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%d downtime %q not found\n", http.StatusNotFound, req.Name)
+		return
+	case errors.Is(err, downtime.ErrNotOpen):
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusConflict, err)
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+	log.Infof("Closed downtime %s", req.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{}")
+}
+
+func newIncidentRecords(incidents []check.DowntimeIncident, from, to int64) []IncidentRecord {
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidents[i].Start < incidents[j].Start
+	})
+
+	records := make([]IncidentRecord, 0, len(incidents))
+	for _, inc := range incidents {
+		start, end := inc.Start, inc.End
+		if start < from {
+			start = from
+		}
+		if end > to {
+			end = to
+		}
+
+		records = append(records, IncidentRecord{
+			Name:        inc.DowntimeName,
+			Type:        inc.Type,
+			Description: inc.Description,
+			Affected:    inc.Affected,
+			Start:       time.Unix(inc.Start, 0).UTC().Format(time.RFC3339),
+			End:         time.Unix(inc.End, 0).UTC().Format(time.RFC3339),
+			Duration:    end - start,
+		})
+	}
+	return records
+}
+
+func writeIncidentsCSV(w io.Writer, records []IncidentRecord) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"name", "type", "start", "end", "duration", "affected", "description"})
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		err = cw.Write([]string{
+			r.Name,
+			r.Type,
+			r.Start,
+			r.End,
+			strconv.FormatInt(r.Duration, 10),
+			strings.Join(r.Affected, " "),
+			r.Description,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
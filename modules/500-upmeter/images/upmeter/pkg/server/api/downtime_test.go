@@ -0,0 +1,65 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"d8.io/upmeter/pkg/check"
+)
+
+func Test_newIncidentRecords(t *testing.T) {
+	g := NewWithT(t)
+
+	incidents := []check.DowntimeIncident{
+		{Start: 1000, End: 1600, Type: "Maintenance", Affected: []string{"a", "b"}, DowntimeName: "second"},
+		{Start: 100, End: 700, Type: "Accident", Description: "boom, crash", Affected: []string{"a"}, DowntimeName: "first"},
+	}
+
+	records := newIncidentRecords(incidents, 400, 1300)
+
+	g.Expect(records).To(HaveLen(2))
+	g.Expect(records[0].Name).To(Equal("first"))
+	g.Expect(records[0].Start).To(Equal("1970-01-01T00:01:40Z"))
+	g.Expect(records[0].Duration).To(BeEquivalentTo(300), "clipped by the period start")
+	g.Expect(records[1].Name).To(Equal("second"))
+	g.Expect(records[1].Duration).To(BeEquivalentTo(300), "clipped by the period end")
+
+	var buf bytes.Buffer
+	g.Expect(writeIncidentsCSV(&buf, records)).To(Succeed())
+	g.Expect(buf.String()).To(Equal(
+		"name,type,start,end,duration,affected,description\n" +
+			"first,Accident,1970-01-01T00:01:40Z,1970-01-01T00:11:40Z,300,a,\"boom, crash\"\n" +
+			"second,Maintenance,1970-01-01T00:16:40Z,1970-01-01T00:26:40Z,300,a b,\n",
+	))
+}
+
+func Test_DowntimesHandler_IsReadOnly(t *testing.T) {
+	g := NewWithT(t)
+
+	h := &DowntimesHandler{}
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/api/downtimes", bytes.NewBufferString("{}")))
+		g.Expect(w.Code).To(Equal(http.StatusMethodNotAllowed), method)
+	}
+}
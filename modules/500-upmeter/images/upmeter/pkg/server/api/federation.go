@@ -0,0 +1,379 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"d8.io/upmeter/pkg/check"
+	dbcontext "d8.io/upmeter/pkg/db/context"
+	"d8.io/upmeter/pkg/db/dao"
+	"d8.io/upmeter/pkg/monitor/downtime"
+	"d8.io/upmeter/pkg/server/entity"
+)
+
+// StatusUnknown is the status of a cluster that has not pushed episodes recently
+const StatusUnknown PublicStatus = "Unknown"
+
+// ClusterAuthenticator returns the name of the cluster that owns the token
+type ClusterAuthenticator interface {
+	Authenticate(token string) (string, error)
+}
+
+// FederationEpisodesHandler receives 5m episodes pushed by upmeter servers of other clusters. The
+// payload is the same as for agents, the origin must be the name of the cluster the token belongs to.
+type FederationEpisodesHandler struct {
+	DbCtx         *dbcontext.DbContext
+	Authenticator ClusterAuthenticator
+}
+
+func (h *FederationEpisodesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Infoln("FederationEpisodes", r.RemoteAddr, r.RequestURI)
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "%d POST is required\n", http.StatusMethodNotAllowed)
+		return
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, "%d bearer token is required\n", http.StatusUnauthorized)
+		return
+	}
+	cluster, err := h.Authenticator.Authenticate(strings.TrimPrefix(auth, "Bearer "))
+	if err != nil {
+		log.Warnf("Federation: rejected episodes from %s: %v", r.RemoteAddr, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, "%d invalid token\n", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d application/json is required\n", http.StatusBadRequest)
+		return
+	}
+
+	var data EpisodesPayload
+	err = json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusBadRequest, err)
+		return
+	}
+	if data.Origin != cluster {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "%d the token does not belong to cluster %q\n", http.StatusForbidden, data.Origin)
+		return
+	}
+	err = validateClusterEpisodes(data.Episodes)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusBadRequest, err)
+		return
+	}
+
+	daoCtx := h.DbCtx.Start()
+	defer daoCtx.Stop()
+
+	err = dao.NewClusterEpisodeDao5m(daoCtx, cluster).Save(data.Episodes)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{}")
+}
+
+func validateClusterEpisodes(episodes []check.Episode) error {
+	for _, ep := range episodes {
+		if ep.ProbeRef.Group == "" || ep.ProbeRef.Probe == "" {
+			return fmt.Errorf("episode without group or probe in slot %d", ep.TimeSlot.Unix())
+		}
+		if ep.TimeSlot.Unix()%300 != 0 {
+			return fmt.Errorf("episode %s is not aligned to 5m: slot %d", ep.ProbeRef.Id(), ep.TimeSlot.Unix())
+		}
+	}
+	return nil
+}
+
+type FederationStatusResponse struct {
+	Status   PublicStatus    `json:"status"`
+	Clusters []ClusterStatus `json:"clusters"`
+}
+
+type ClusterStatus struct {
+	Cluster string       `json:"cluster"`
+	Status  PublicStatus `json:"status"`
+	// LastSlot is the latest 5m slot pushed by the cluster, Unix seconds
+	LastSlot int64         `json:"lastSlot"`
+	Rows     []GroupStatus `json:"rows"`
+}
+
+// FederationStatusHandler serves the current status of all clusters pushing episodes, in the same
+// form as the public status of a single cluster. Downtimes are not pushed, so they are not muted.
+type FederationStatusHandler struct {
+	DbCtx *dbcontext.DbContext
+}
+
+func (h *FederationStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Infoln("FederationStatus", r.RemoteAddr, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		message := fmt.Sprintf("%s not allowed, use GET\n", r.Method)
+		fmt.Fprint(w, jsonError(message))
+		return
+	}
+
+	clusters, err := h.getClusterStatusList(time.Now())
+	if err != nil {
+		log.Errorf("Cannot get federation status: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, jsonError(err.Error()))
+		return
+	}
+
+	// Skipping the error because the JSON structure is defined in advance.
+	out, _ := json.Marshal(&FederationStatusResponse{
+		Status:   calculateFederationStatus(clusters),
+		Clusters: clusters,
+	})
+	w.Write(out)
+}
+
+func (h *FederationStatusHandler) getClusterStatusList(now time.Time) ([]ClusterStatus, error) {
+	daoCtx := h.DbCtx.Start()
+	defer daoCtx.Stop()
+
+	infos, err := dao.NewClusterEpisodeDao5m(daoCtx, "").ListClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	rng := new15MinutesStepRange(now)
+	statuses := make([]ClusterStatus, 0, len(infos))
+	for _, info := range infos {
+		status := ClusterStatus{
+			Cluster:  info.Name,
+			Status:   StatusUnknown,
+			LastSlot: info.LastSlot.Unix(),
+			Rows:     []GroupStatus{},
+		}
+		if info.LastSlot.Unix() < rng.From {
+			statuses = append(statuses, status)
+			continue
+		}
+
+		lister := dao.NewClusterEpisodeDao5m(daoCtx, info.Name)
+		refs, err := lister.ListProbeRefs(time.Unix(rng.From, 0))
+		if err != nil {
+			return nil, err
+		}
+		public := &PublicStatusHandler{ProbeLister: newRefProbeLister(refs)}
+		rows, err := public.calcStatuses(rng, lister, pickGroupProbeSummary)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: %v", info.Name, err)
+		}
+
+		status.Rows = rows
+		status.Status = calculateTotalStatus(rows)
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// calculateFederationStatus returns the worst status of clusters, clusters without fresh data make the
+// federation degraded.
+func calculateFederationStatus(clusters []ClusterStatus) PublicStatus {
+	rows := make([]GroupStatus, 0, len(clusters))
+	for _, c := range clusters {
+		status := c.Status
+		if status == StatusUnknown {
+			status = StatusDegraded
+		}
+		rows = append(rows, GroupStatus{Status: status})
+	}
+	return calculateTotalStatus(rows)
+}
+
+type ClusterAvailability struct {
+	Cluster string              `json:"cluster"`
+	From    int64               `json:"from"`
+	To      int64               `json:"to"`
+	Groups  []GroupAvailability `json:"groups"`
+}
+
+type GroupAvailability struct {
+	Group string `json:"group"`
+	// Availability is the fraction of 1, it is -1 if there is no data
+	Availability float64 `json:"availability"`
+}
+
+// FederationAvailabilityHandler serves the availability of groups in each cluster for a period, the
+// last 30 days by default.
+type FederationAvailabilityHandler struct {
+	DbCtx *dbcontext.DbContext
+}
+
+func (h *FederationAvailabilityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Infoln("FederationAvailability", r.RemoteAddr, r.RequestURI)
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "%d GET is required\n", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	to := time.Now().Truncate(5 * time.Minute)
+	from := to.Add(-30 * 24 * time.Hour)
+	if arg := query.Get("from"); arg != "" {
+		seconds, err := downtime.DateToSeconds(arg)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "%d 'from' is invalid: %s\n", http.StatusBadRequest, err)
+			return
+		}
+		from = time.Unix(seconds, 0).Truncate(5 * time.Minute)
+	}
+	if arg := query.Get("to"); arg != "" {
+		seconds, err := downtime.DateToSeconds(arg)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "%d 'to' is invalid: %s\n", http.StatusBadRequest, err)
+			return
+		}
+		to = time.Unix(seconds, 0).Truncate(5 * time.Minute)
+	}
+	if !from.Before(to) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%d 'from' must be before 'to'\n", http.StatusBadRequest)
+		return
+	}
+
+	daoCtx := h.DbCtx.Start()
+	defer daoCtx.Stop()
+
+	avails, err := calcClusterAvailabilities(daoCtx, query.Get("cluster"), from, to)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+
+	out, err := json.Marshal(avails)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(out)
+}
+
+func calcClusterAvailabilities(daoCtx *dbcontext.DbContext, cluster string, from, to time.Time) ([]ClusterAvailability, error) {
+	infos, err := dao.NewClusterEpisodeDao5m(daoCtx, "").ListClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	avails := make([]ClusterAvailability, 0, len(infos))
+	for _, info := range infos {
+		if cluster != "" && info.Name != cluster {
+			continue
+		}
+
+		lister := dao.NewClusterEpisodeDao5m(daoCtx, info.Name)
+		refs, err := lister.ListProbeRefs(from)
+		if err != nil {
+			return nil, err
+		}
+
+		avail := ClusterAvailability{
+			Cluster: info.Name,
+			From:    from.Unix(),
+			To:      to.Unix(),
+			Groups:  make([]GroupAvailability, 0),
+		}
+		for _, group := range newRefProbeLister(refs).Groups() {
+			ref := check.ProbeRef{Group: group, Probe: dao.GroupAggregation}
+			summary, err := summarizeWindow(lister, nil, ref, to, to.Sub(from))
+			if err != nil {
+				return nil, fmt.Errorf("cluster %q, group %q: %v", info.Name, group, err)
+			}
+			avail.Groups = append(avail.Groups, GroupAvailability{
+				Group:        group,
+				Availability: calculateAvailability([]entity.EpisodeSummary{summary}),
+			})
+		}
+		avails = append(avails, avail)
+	}
+
+	return avails, nil
+}
+
+// refProbeLister lists groups and probes found in episodes, it is used for clusters which probe
+// registry is unknown to the central upmeter.
+type refProbeLister struct {
+	groups []string
+	probes []check.ProbeRef
+}
+
+func newRefProbeLister(refs []check.ProbeRef) *refProbeLister {
+	groupSet := make(map[string]struct{})
+	probes := make([]check.ProbeRef, 0, len(refs))
+	for _, ref := range refs {
+		groupSet[ref.Group] = struct{}{}
+		if ref.Probe != dao.GroupAggregation {
+			probes = append(probes, ref)
+		}
+	}
+
+	groups := make([]string, 0, len(groupSet))
+	for group := range groupSet {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	return &refProbeLister{groups: groups, probes: probes}
+}
+
+func (l *refProbeLister) Groups() []string {
+	return l.groups
+}
+
+func (l *refProbeLister) Probes() []check.ProbeRef {
+	return l.probes
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/onsi/gomega"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/db/dao"
+	"d8.io/upmeter/pkg/db/migrations"
+)
+
+type fakeAuthenticator map[string]string
+
+func (a fakeAuthenticator) Authenticate(token string) (string, error) {
+	if cluster, ok := a[token]; ok {
+		return cluster, nil
+	}
+	return "", errors.New("unknown token")
+}
+
+func postClusterEpisodes(h http.Handler, token string, payload EpisodesPayload) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/api/federation/episodes", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_FederationEpisodesHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	dbCtx := migrations.GetTestMemoryDatabase(t, "../../db/migrations/server")
+	handler := &FederationEpisodesHandler{
+		DbCtx:         dbCtx,
+		Authenticator: fakeAuthenticator{"alpha-token": "alpha"},
+	}
+
+	ref := check.ProbeRef{Group: "synthetic", Probe: "access"}
+	episodes := []check.Episode{{ProbeRef: ref, TimeSlot: time.Unix(3000, 0), Up: 5 * time.Minute}}
+
+	rec := postClusterEpisodes(handler, "", EpisodesPayload{Origin: "alpha", Episodes: episodes})
+	g.Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+	rec = postClusterEpisodes(handler, "wrong", EpisodesPayload{Origin: "alpha", Episodes: episodes})
+	g.Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+	rec = postClusterEpisodes(handler, "alpha-token", EpisodesPayload{Origin: "beta", Episodes: episodes})
+	g.Expect(rec.Code).To(Equal(http.StatusForbidden), "a cluster cannot push for another one")
+
+	unaligned := []check.Episode{{ProbeRef: ref, TimeSlot: time.Unix(3030, 0), Up: 30 * time.Second}}
+	rec = postClusterEpisodes(handler, "alpha-token", EpisodesPayload{Origin: "alpha", Episodes: unaligned})
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest), "30s episodes are not accepted")
+
+	rec = postClusterEpisodes(handler, "alpha-token", EpisodesPayload{Origin: "alpha", Episodes: episodes})
+	g.Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+
+	daoCtx := dbCtx.Start()
+	defer daoCtx.Stop()
+	clusters, err := dao.NewClusterEpisodeDao5m(daoCtx, "").ListClusters()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(clusters).To(Equal([]dao.ClusterInfo{{Name: "alpha", LastSlot: time.Unix(3000, 0)}}))
+}
+
+func Test_FederationStatusHandler_getClusterStatusList(t *testing.T) {
+	g := NewWithT(t)
+
+	dbCtx := migrations.GetTestMemoryDatabase(t, "../../db/migrations/server")
+	daoCtx := dbCtx.Start()
+	defer daoCtx.Stop()
+
+	now := time.Now()
+	current := now.Truncate(5 * time.Minute)
+
+	probe := check.ProbeRef{Group: "synthetic", Probe: "access"}
+	total := check.ProbeRef{Group: "synthetic", Probe: dao.GroupAggregation}
+	fresh := make([]check.Episode, 0)
+	for _, slot := range []time.Time{current.Add(-10 * time.Minute), current.Add(-5 * time.Minute), current} {
+		fresh = append(fresh,
+			check.Episode{ProbeRef: probe, TimeSlot: slot, Up: 5 * time.Minute},
+			check.Episode{ProbeRef: total, TimeSlot: slot, Up: 5 * time.Minute},
+		)
+	}
+	g.Expect(dao.NewClusterEpisodeDao5m(daoCtx, "alpha").Save(fresh)).To(Succeed())
+
+	stale := []check.Episode{{ProbeRef: total, TimeSlot: current.Add(-time.Hour), Up: 5 * time.Minute}}
+	g.Expect(dao.NewClusterEpisodeDao5m(daoCtx, "beta").Save(stale)).To(Succeed())
+
+	handler := &FederationStatusHandler{DbCtx: dbCtx}
+	statuses, err := handler.getClusterStatusList(now)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(statuses).To(HaveLen(2))
+
+	g.Expect(statuses[0].Cluster).To(Equal("alpha"))
+	g.Expect(statuses[0].Status).To(Equal(StatusOperational))
+	g.Expect(statuses[0].Rows).To(HaveLen(1))
+	g.Expect(statuses[0].Rows[0].Group).To(Equal("synthetic"))
+	g.Expect(statuses[0].Rows[0].Probes).To(Equal([]ProbeAvailability{
+		{Probe: "access", Availability: 1, Status: StatusOperational},
+	}))
+
+	g.Expect(statuses[1].Cluster).To(Equal("beta"))
+	g.Expect(statuses[1].Status).To(Equal(StatusUnknown))
+
+	g.Expect(calculateFederationStatus(statuses)).To(Equal(StatusDegraded), "stale clusters degrade the federation")
+	g.Expect(calculateFederationStatus(statuses[:1])).To(Equal(StatusOperational))
+}
+
+func Test_calcClusterAvailabilities(t *testing.T) {
+	g := NewWithT(t)
+
+	dbCtx := migrations.GetTestMemoryDatabase(t, "../../db/migrations/server")
+	daoCtx := dbCtx.Start()
+	defer daoCtx.Stop()
+
+	total := check.ProbeRef{Group: "control-plane", Probe: dao.GroupAggregation}
+	episodes := []check.Episode{
+		{ProbeRef: total, TimeSlot: time.Unix(3000, 0), Up: 5 * time.Minute},
+		{ProbeRef: total, TimeSlot: time.Unix(3300, 0), Up: 4 * time.Minute, Down: time.Minute},
+	}
+	g.Expect(dao.NewClusterEpisodeDao5m(daoCtx, "alpha").Save(episodes)).To(Succeed())
+	g.Expect(dao.NewClusterEpisodeDao5m(daoCtx, "beta").Save(episodes[:1])).To(Succeed())
+
+	avails, err := calcClusterAvailabilities(daoCtx, "alpha", time.Unix(3000, 0), time.Unix(3600, 0))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(avails).To(Equal([]ClusterAvailability{{
+		Cluster: "alpha",
+		From:    3000,
+		To:      3600,
+		Groups:  []GroupAvailability{{Group: "control-plane", Availability: 0.9}},
+	}}))
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"d8.io/upmeter/pkg/check"
+	dbcontext "d8.io/upmeter/pkg/db/context"
+	"d8.io/upmeter/pkg/db/dao"
+	"d8.io/upmeter/pkg/monitor/downtime"
+	"d8.io/upmeter/pkg/monitor/slo"
+	"d8.io/upmeter/pkg/server/entity"
+	"d8.io/upmeter/pkg/server/ranges"
+)
+
+// BurnRateWindows are the windows to calculate burn rates for. They are paired in alerts, e.g.
+// 1h with 5m, and 6h with 30m, to page on fast burns and to reset quickly after the recovery.
+var BurnRateWindows = []time.Duration{
+	5 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	3 * 24 * time.Hour,
+}
+
+type SLOStatus struct {
+	Name  string `json:"name"`
+	Group string `json:"group"`
+	Probe string `json:"probe"`
+
+	// Objective is the target availability as a fraction of 1
+	Objective float64 `json:"objective"`
+	Window    string  `json:"window"`
+	From      int64   `json:"from"`
+	To        int64   `json:"to"`
+
+	// Availability is the fraction of 1 in the window, it is -1 if there is no data
+	Availability float64     `json:"availability"`
+	ErrorBudget  ErrorBudget `json:"errorBudget"`
+	BurnRates    []BurnRate  `json:"burnRates"`
+}
+
+type ErrorBudget struct {
+	// TotalSeconds is the allowed downtime for the measured time in the window
+	TotalSeconds float64 `json:"totalSeconds"`
+	// ConsumedSeconds is the observed downtime in the window
+	ConsumedSeconds float64 `json:"consumedSeconds"`
+	// Remaining is the fraction of the budget left, it is negative when the budget is exhausted
+	Remaining float64 `json:"remaining"`
+}
+
+// BurnRate is the ratio of the observed error rate to the allowed one. The rate of 1 exhausts the
+// budget exactly by the end of the SLO window.
+type BurnRate struct {
+	Window string  `json:"window"`
+	Rate   float64 `json:"rate"`
+}
+
+type SLOHandler struct {
+	DbCtx           *dbcontext.DbContext
+	DowntimeMonitor *downtime.Monitor
+	SLOMonitor      *slo.Monitor
+}
+
+func (h *SLOHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Infoln("SLO", r.RemoteAddr, r.RequestURI)
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "%d GET is required\n", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	objectives := make([]slo.Objective, 0)
+	for _, obj := range h.SLOMonitor.List() {
+		if name == "" || obj.Name == name {
+			objectives = append(objectives, obj)
+		}
+	}
+	if name != "" && len(objectives) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%d SLO %q not found\n", http.StatusNotFound, name)
+		return
+	}
+
+	daoCtx := h.DbCtx.Start()
+	defer daoCtx.Stop()
+
+	statuses, err := EvaluateSLOs(dao.NewEpisodeDao5m(daoCtx), h.DowntimeMonitor, objectives, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+
+	out, err := json.Marshal(statuses)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "%d Error: %s\n", http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(out)
+}
+
+func EvaluateSLOs(lister entity.RangeEpisodeLister, monitor *downtime.Monitor, objectives []slo.Objective, now time.Time) ([]SLOStatus, error) {
+	statuses := make([]SLOStatus, 0, len(objectives))
+	for _, obj := range objectives {
+		status, err := evaluateSLO(lister, monitor, obj, now)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating SLO %q: %v", obj.Name, err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// evaluateSLO calculates the availability and the error budget for the SLO window, and burn rates
+// for shorter windows. Windows end at the last complete 5m slot. Downtimes are muted the same way
+// as on the status page.
+func evaluateSLO(lister entity.RangeEpisodeLister, monitor *downtime.Monitor, obj slo.Objective, now time.Time) (SLOStatus, error) {
+	to := now.Truncate(5 * time.Minute)
+
+	status := SLOStatus{
+		Name:         obj.Name,
+		Group:        obj.Ref.Group,
+		Probe:        obj.Ref.Probe,
+		Objective:    obj.Target,
+		Window:       formatWindow(obj.Window),
+		From:         to.Add(-obj.Window).Unix(),
+		To:           to.Unix(),
+		Availability: -1,
+		BurnRates:    make([]BurnRate, 0, len(BurnRateWindows)),
+	}
+
+	summary, err := summarizeWindow(lister, monitor, obj.Ref, to, obj.Window)
+	if err != nil {
+		return status, err
+	}
+	if measured := summary.Up + summary.Unknown + summary.Down; measured > 0 {
+		status.Availability = float64(summary.Up+summary.Unknown) / float64(measured)
+		status.ErrorBudget = calcErrorBudget(summary, obj.ErrorBudget())
+	}
+
+	for _, window := range BurnRateWindows {
+		if window > obj.Window {
+			break
+		}
+		summary, err := summarizeWindow(lister, monitor, obj.Ref, to, window)
+		if err != nil {
+			return status, err
+		}
+		rate, ok := calcBurnRate(summary, obj.ErrorBudget())
+		if !ok {
+			continue
+		}
+		status.BurnRates = append(status.BurnRates, BurnRate{Window: formatWindow(window), Rate: rate})
+	}
+
+	return status, nil
+}
+
+func calcErrorBudget(summary entity.EpisodeSummary, budget float64) ErrorBudget {
+	measured := summary.Up + summary.Unknown + summary.Down
+	total := budget * measured.Seconds()
+	consumed := summary.Down.Seconds()
+
+	remaining := 1.0
+	if total > 0 {
+		remaining = 1 - consumed/total
+	}
+
+	return ErrorBudget{
+		TotalSeconds:    total,
+		ConsumedSeconds: consumed,
+		Remaining:       remaining,
+	}
+}
+
+// calcBurnRate returns false if there is no data in the summary
+func calcBurnRate(summary entity.EpisodeSummary, budget float64) (float64, bool) {
+	measured := summary.Up + summary.Unknown + summary.Down
+	if measured == 0 {
+		return 0, false
+	}
+	errorRate := float64(summary.Down) / float64(measured)
+	return errorRate / budget, true
+}
+
+// summarizeWindow sums episodes in the window ending at `to`. Unlike the status range, the window
+// is not aligned to its own length, so the single subrange is built explicitly.
+func summarizeWindow(lister entity.RangeEpisodeLister, monitor *downtime.Monitor, ref check.ProbeRef, to time.Time, window time.Duration) (entity.EpisodeSummary, error) {
+	from := to.Add(-window).Unix()
+	rng := ranges.StepRange{
+		From:      from,
+		To:        to.Unix(),
+		Step:      to.Unix() - from,
+		Subranges: []ranges.Range{{From: from, To: to.Unix()}},
+	}
+
+	filter := &statusFilter{
+		stepRange: rng,
+		probeRef:  ref,
+		muteDowntimeTypes: []string{
+			"Maintenance",
+			"InfrastructureMaintenance",
+			"InfrastructureAccident",
+		},
+	}
+
+	resp, err := getStatusSummary(lister, monitor, filter)
+	if err != nil {
+		return entity.EpisodeSummary{}, err
+	}
+
+	var total entity.EpisodeSummary
+	for _, s := range resp.Statuses[ref.Group][ref.Probe] {
+		if s.TimeSlot == -1 {
+			// the total column
+			continue
+		}
+		total.Up += s.Up
+		total.Down += s.Down
+		total.Unknown += s.Unknown
+		total.Muted += s.Muted
+		total.NoData += s.NoData
+	}
+	return total, nil
+}
+
+// formatWindow renders days and hours in the Prometheus style, e.g. 30d, 6h, 5m
+func formatWindow(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
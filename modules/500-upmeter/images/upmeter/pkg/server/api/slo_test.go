@@ -0,0 +1,91 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"d8.io/upmeter/pkg/server/entity"
+)
+
+func Test_calcErrorBudget(t *testing.T) {
+	cases := []struct {
+		name    string
+		summary entity.EpisodeSummary
+		budget  float64
+		want    ErrorBudget
+	}{
+		{
+			name:    "no data leaves the whole budget",
+			summary: entity.EpisodeSummary{NoData: time.Hour},
+			budget:  0.01,
+			want:    ErrorBudget{Remaining: 1},
+		},
+		{
+			name:    "half of the budget is consumed",
+			summary: entity.EpisodeSummary{Up: 990 * time.Second, Down: 5 * time.Second, Unknown: 5 * time.Second},
+			budget:  0.01,
+			want:    ErrorBudget{TotalSeconds: 10, ConsumedSeconds: 5, Remaining: 0.5},
+		},
+		{
+			name:    "exhausted budget goes negative",
+			summary: entity.EpisodeSummary{Up: 980 * time.Second, Down: 20 * time.Second},
+			budget:  0.01,
+			want:    ErrorBudget{TotalSeconds: 10, ConsumedSeconds: 20, Remaining: -1},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got := calcErrorBudget(c.summary, c.budget)
+
+			g.Expect(got.TotalSeconds).To(BeNumerically("~", c.want.TotalSeconds, 1e-9))
+			g.Expect(got.ConsumedSeconds).To(BeNumerically("~", c.want.ConsumedSeconds, 1e-9))
+			g.Expect(got.Remaining).To(BeNumerically("~", c.want.Remaining, 1e-9))
+		})
+	}
+}
+
+func Test_calcBurnRate(t *testing.T) {
+	g := NewWithT(t)
+
+	_, ok := calcBurnRate(entity.EpisodeSummary{NoData: time.Hour}, 0.001)
+	g.Expect(ok).To(BeFalse())
+
+	// 1% of errors against the budget of 0.1% burns 10 times faster than allowed
+	rate, ok := calcBurnRate(entity.EpisodeSummary{Up: 99 * time.Minute, Down: time.Minute}, 0.001)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(rate).To(BeNumerically("~", 10, 1e-9))
+
+	rate, ok = calcBurnRate(entity.EpisodeSummary{Up: time.Hour}, 0.001)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(rate).To(BeZero())
+}
+
+func Test_formatWindow(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(formatWindow(5 * time.Minute)).To(Equal("5m"))
+	g.Expect(formatWindow(90 * time.Minute)).To(Equal("90m"))
+	g.Expect(formatWindow(6 * time.Hour)).To(Equal("6h"))
+	g.Expect(formatWindow(30 * 24 * time.Hour)).To(Equal("30d"))
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"d8.io/upmeter/pkg/agent/sender"
+	"d8.io/upmeter/pkg/check"
+	dbcontext "d8.io/upmeter/pkg/db/context"
+	"d8.io/upmeter/pkg/db/dao"
+	"d8.io/upmeter/pkg/server/api"
+)
+
+const (
+	EpisodesPath = "/api/federation/episodes"
+
+	// backfill limits the amount of episodes sent after the central upmeter was unavailable
+	backfill = 6 * time.Hour
+)
+
+type PusherConfig struct {
+	// URL of the central upmeter, e.g. https://upmeter-federation.example.com
+	URL string
+	// Cluster is the name of this cluster in the federation
+	Cluster string
+	Token   string
+	// CAPath is the CA to verify the central upmeter certificate, system roots are used if empty
+	CAPath string
+
+	UserAgent string
+	Period    time.Duration
+	Timeout   time.Duration
+}
+
+// Pusher sends 5m episodes of this cluster to the central upmeter
+type Pusher struct {
+	config *PusherConfig
+	dbCtx  *dbcontext.DbContext
+	client *http.Client
+	logger *log.Entry
+
+	// pushed is the slot to start the next push from
+	pushed time.Time
+}
+
+func NewPusher(config *PusherConfig, dbCtx *dbcontext.DbContext, logger *log.Entry) (*Pusher, error) {
+	if config.Cluster == "" || config.Token == "" {
+		return nil, fmt.Errorf("cluster name and token are required to push episodes")
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: config.Timeout,
+	}
+	if config.CAPath != "" {
+		caCertBytes, err := ioutil.ReadFile(config.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate from '%s': %v", config.CAPath, err)
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCertBytes)
+		transport.TLSClientConfig = &tls.Config{RootCAs: caCertPool}
+	}
+
+	client := &http.Client{
+		Transport: sender.NewKubeBearerTransport(transport, config.Token),
+		Timeout:   config.Timeout,
+	}
+
+	return &Pusher{
+		config: config,
+		dbCtx:  dbCtx,
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+func (p *Pusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := p.push(time.Now())
+			if err != nil {
+				p.logger.Errorf("cannot push episodes to the central upmeter: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pusher) push(now time.Time) error {
+	current := now.Truncate(5 * time.Minute)
+
+	from := p.pushed
+	if oldest := current.Add(-backfill); from.Before(oldest) {
+		from = oldest
+	}
+	to := current.Add(5 * time.Minute)
+
+	episodes, err := p.listEpisodes(from, to)
+	if err != nil {
+		return err
+	}
+	if len(episodes) == 0 {
+		return nil
+	}
+
+	err = p.send(api.EpisodesPayload{Origin: p.config.Cluster, Episodes: episodes})
+	if err != nil {
+		return err
+	}
+
+	// The current slot is still being filled, and the previous one can be updated by late agents,
+	// so both are sent again the next time.
+	p.pushed = current.Add(-5 * time.Minute)
+	return nil
+}
+
+func (p *Pusher) listEpisodes(from, to time.Time) ([]check.Episode, error) {
+	daoCtx := p.dbCtx.Start()
+	defer daoCtx.Stop()
+
+	ref := check.ProbeRef{Probe: dao.ProbeEnumeration}
+	episodes, err := dao.NewEpisodeDao5m(daoCtx).ListEpisodesByRange(from.Unix(), to.Unix(), ref)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list 5m episodes: %v", err)
+	}
+	return episodes, nil
+}
+
+func (p *Pusher) send(payload api.EpisodesPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshalling episodes: %v", err)
+	}
+
+	url := strings.TrimSuffix(p.config.URL, "/") + EpisodesPath
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("preparing POST request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", p.config.UserAgent)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: status=%d, body=%q", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package federation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/db/dao"
+	"d8.io/upmeter/pkg/db/migrations"
+	"d8.io/upmeter/pkg/server/api"
+)
+
+func Test_Pusher_push(t *testing.T) {
+	g := NewWithT(t)
+
+	var (
+		payloads []api.EpisodesPayload
+		auth     string
+	)
+	central := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.URL.Path).To(Equal(EpisodesPath))
+		auth = r.Header.Get("Authorization")
+
+		var payload api.EpisodesPayload
+		g.Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+		payloads = append(payloads, payload)
+		w.Write([]byte("{}"))
+	}))
+	defer central.Close()
+
+	dbCtx := migrations.GetTestMemoryDatabase(t, "../../db/migrations/server")
+	daoCtx := dbCtx.Start()
+	defer daoCtx.Stop()
+
+	now := time.Now()
+	current := now.Truncate(5 * time.Minute)
+	ref := check.ProbeRef{Group: "synthetic", Probe: "access"}
+	episodeDao := dao.NewEpisodeDao5m(daoCtx)
+	for _, slot := range []time.Time{
+		current.Add(-7 * time.Hour), // beyond backfill
+		current.Add(-time.Hour),
+		current.Add(-5 * time.Minute),
+		current,
+	} {
+		g.Expect(episodeDao.Insert(check.Episode{ProbeRef: ref, TimeSlot: slot, Up: time.Minute})).To(Succeed())
+	}
+
+	pusher, err := NewPusher(&PusherConfig{
+		URL:     central.URL + "/",
+		Cluster: "alpha",
+		Token:   "secret",
+		Timeout: 5 * time.Second,
+	}, dbCtx, log.NewEntry(log.New()))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Expect(pusher.push(now)).To(Succeed())
+	g.Expect(auth).To(Equal("Bearer secret"))
+	g.Expect(payloads).To(HaveLen(1))
+	g.Expect(payloads[0].Origin).To(Equal("alpha"))
+	g.Expect(payloads[0].Episodes).To(HaveLen(3), "the episode beyond the backfill is not sent")
+
+	// The previous and the current slots are sent again
+	g.Expect(pusher.push(now)).To(Succeed())
+	g.Expect(payloads).To(HaveLen(2))
+	g.Expect(payloads[1].Episodes).To(HaveLen(2))
+}
+
+func Test_NewPusher_RequiresIdentity(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewPusher(&PusherConfig{URL: "https://central", Cluster: "alpha"}, nil, nil)
+	g.Expect(err).Should(HaveOccurred())
+
+	_, err = NewPusher(&PusherConfig{URL: "https://central", Token: "secret"}, nil, nil)
+	g.Expect(err).Should(HaveOccurred())
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package federation
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnauthorized = errors.New("unknown token")
+
+// TokenDir authenticates clusters by tokens stored as files in the directory: the file name is the
+// cluster name, and the content is its token. It fits a Secret mounted as a volume. The directory is
+// read on every call, so that tokens are rotated without restarts.
+type TokenDir struct {
+	path string
+}
+
+func NewTokenDir(path string) *TokenDir {
+	return &TokenDir{path: path}
+}
+
+// Authenticate returns the name of the cluster that owns the token
+func (d *TokenDir) Authenticate(token string) (string, error) {
+	if token == "" {
+		return "", ErrUnauthorized
+	}
+
+	tokens, err := d.read()
+	if err != nil {
+		return "", err
+	}
+
+	for cluster, clusterToken := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(clusterToken)) == 1 {
+			return cluster, nil
+		}
+	}
+	return "", ErrUnauthorized
+}
+
+func (d *TokenDir) read() (map[string]string, error) {
+	files, err := ioutil.ReadDir(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tokens directory: %v", err)
+	}
+
+	tokens := make(map[string]string)
+	for _, f := range files {
+		// Secret volumes contain hidden service entries like "..data"
+		if strings.HasPrefix(f.Name(), ".") || f.IsDir() {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(d.path, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read token of cluster %q: %v", f.Name(), err)
+		}
+		token := strings.TrimSpace(string(content))
+		if token == "" {
+			continue
+		}
+		tokens[f.Name()] = token
+	}
+	return tokens, nil
+}
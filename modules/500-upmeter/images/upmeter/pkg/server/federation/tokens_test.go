@@ -0,0 +1,64 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package federation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_TokenDir_Authenticate(t *testing.T) {
+	g := NewWithT(t)
+
+	dir, err := ioutil.TempDir("", "upmeter-tokens")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	// The layout of a Secret volume
+	g.Expect(os.Mkdir(filepath.Join(dir, "..2023_01_01"), 0o755)).To(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "..data"), []byte("hidden"), 0o600)).To(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "alpha"), []byte("alpha-token\n"), 0o600)).To(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "beta"), []byte("beta-token"), 0o600)).To(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "empty"), []byte(""), 0o600)).To(Succeed())
+
+	tokens := NewTokenDir(dir)
+
+	cluster, err := tokens.Authenticate("alpha-token")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(cluster).To(Equal("alpha"))
+
+	cluster, err = tokens.Authenticate("beta-token")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(cluster).To(Equal("beta"))
+
+	for _, token := range []string{"", "hidden", "unknown"} {
+		_, err = tokens.Authenticate(token)
+		g.Expect(err).To(Equal(ErrUnauthorized), "token %q", token)
+	}
+
+	// Rotation is picked up without restarts
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "alpha"), []byte("rotated"), 0o600)).To(Succeed())
+	_, err = tokens.Authenticate("alpha-token")
+	g.Expect(err).To(Equal(ErrUnauthorized))
+	cluster, err = tokens.Authenticate("rotated")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(cluster).To(Equal("alpha"))
+}
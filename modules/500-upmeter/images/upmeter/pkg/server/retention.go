@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"d8.io/upmeter/pkg/db"
+	dbcontext "d8.io/upmeter/pkg/db/context"
+	"d8.io/upmeter/pkg/db/dao"
+)
+
+// RetentionConfig defines how long episodes are stored. Zero means forever.
+type RetentionConfig struct {
+	// Episodes30s are only needed to fill 5m episodes and to show the recent state, so they are
+	// kept for a short time.
+	Episodes30s time.Duration
+	// Episodes5m are the SLA history
+	Episodes5m time.Duration
+}
+
+const (
+	retention30sPeriod = 30 * time.Second
+	retention5mPeriod  = time.Hour
+	// Vacuum rewrites the whole SQLite file, so it is not done more often
+	compactionPeriod = 24 * time.Hour
+)
+
+// runRetention deletes outdated episodes and compacts the database after 5m episodes are deleted.
+func runRetention(ctx context.Context, dbCtx *dbcontext.DbContext, config RetentionConfig) {
+	ticker30s := time.NewTicker(retention30sPeriod)
+	defer ticker30s.Stop()
+
+	ticker5m := time.NewTicker(retention5mPeriod)
+	defer ticker5m.Stop()
+
+	var lastCompaction time.Time
+	for {
+		select {
+		case <-ticker30s.C:
+			if config.Episodes30s <= 0 {
+				continue
+			}
+			deadline := time.Now().Truncate(retention30sPeriod).Add(-config.Episodes30s)
+			err := delete30sEpisodes(dbCtx, deadline)
+			if err != nil {
+				log.Errorf("cannot clean old 30s episodes: %v", err)
+			}
+
+		case <-ticker5m.C:
+			if config.Episodes5m <= 0 {
+				continue
+			}
+			deadline := time.Now().Truncate(5 * time.Minute).Add(-config.Episodes5m)
+			deleted, err := delete5mEpisodes(dbCtx, deadline)
+			if err != nil {
+				log.Errorf("cannot clean old 5m episodes: %v", err)
+				continue
+			}
+			if deleted == 0 || time.Since(lastCompaction) < compactionPeriod {
+				continue
+			}
+
+			log.Infof("Deleted %d 5m episodes older than %s, compacting the database", deleted, deadline.Format(time.RFC3339))
+			lastCompaction = time.Now()
+			err = db.Vacuum(dbCtx)
+			if err != nil {
+				log.Errorf("cannot compact the database: %v", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func delete30sEpisodes(dbCtx *dbcontext.DbContext, deadline time.Time) error {
+	conn := dbCtx.Start()
+	defer conn.Stop()
+
+	return dao.NewEpisodeDao30s(conn).DeleteUpTo(deadline)
+}
+
+func delete5mEpisodes(dbCtx *dbcontext.DbContext, deadline time.Time) (int64, error) {
+	conn := dbCtx.Start()
+	defer conn.Stop()
+
+	deleted, err := dao.NewEpisodeDao5m(conn).DeleteUpTo(deadline)
+	if err != nil {
+		return 0, err
+	}
+
+	// Episodes received from other clusters in the federation mode
+	deletedFederated, err := dao.NewClusterEpisodeDao5m(conn, "").DeleteUpTo(deadline)
+	if err != nil {
+		return deleted, err
+	}
+
+	return deleted + deletedFederated, nil
+}
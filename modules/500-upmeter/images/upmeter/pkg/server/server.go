@@ -26,18 +26,21 @@ import (
 
 	kube "github.com/flant/kube-client/client"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 
 	"d8.io/upmeter/pkg/db"
 	dbcontext "d8.io/upmeter/pkg/db/context"
-	"d8.io/upmeter/pkg/db/dao"
 	"d8.io/upmeter/pkg/kubernetes"
 	"d8.io/upmeter/pkg/monitor/downtime"
+	"d8.io/upmeter/pkg/monitor/slo"
 	"d8.io/upmeter/pkg/probe"
 	"d8.io/upmeter/pkg/probe/calculated"
 	"d8.io/upmeter/pkg/probe/checker"
 	"d8.io/upmeter/pkg/registry"
 	"d8.io/upmeter/pkg/server/api"
+	"d8.io/upmeter/pkg/server/federation"
 	"d8.io/upmeter/pkg/server/remotewrite"
 )
 
@@ -56,6 +59,7 @@ type Server struct {
 
 	server                *http.Server
 	downtimeMonitor       *downtime.Monitor
+	sloMonitor            *slo.Monitor
 	remoteWriteController *remotewrite.Controller
 }
 
@@ -65,21 +69,36 @@ type Config struct {
 	UserAgent  string
 
 	DatabasePath string
+	Retention    RetentionConfig
 
 	OriginsCount int
 
 	DisabledProbes []string
 	DynamicProbes  *DynamicProbesConfig
+
+	Federation *FederationConfig
+}
+
+// FederationConfig enables pushing episodes to the central upmeter and receiving them from other
+// clusters. Both are optional and independent.
+type FederationConfig struct {
+	// TokensDir contains tokens of clusters allowed to push episodes here, the file name is the
+	// cluster name
+	TokensDir string
+	// Push is used when the URL is set
+	Push federation.PusherConfig
 }
 
 type DynamicProbesConfig struct {
 	IngressControllers []string
 	NodeGroups         []string
+	CustomProbesPath   string
 }
 
 func NewConfig() *Config {
 	return &Config{
 		DynamicProbes: &DynamicProbesConfig{},
+		Federation:    &FederationConfig{},
 	}
 }
 
@@ -111,6 +130,12 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("cannot start downtimes.deckhouse.io monitor: %v", err)
 	}
 
+	// UpmeterSLO CR monitor
+	s.sloMonitor, err = initSLOMonitor(ctx, kubeClient, s.logger)
+	if err != nil {
+		return fmt.Errorf("cannot start upmeterslos.deckhouse.io monitor: %v", err)
+	}
+
 	// Metrics controller
 	s.remoteWriteController, err = initRemoteWriteController(ctx, dbctx, kubeClient, s.config.OriginsCount, s.logger, s.config.UserAgent)
 	if err != nil {
@@ -118,15 +143,37 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("cannot start remote_write controller: %v", err)
 	}
 
-	go cleanOld30sEpisodes(ctx, dbctx)
+	go runRetention(ctx, dbctx, s.config.Retention)
+
+	// Pushing episodes to the central upmeter
+	if s.config.Federation.Push.URL != "" {
+		pushConfig := s.config.Federation.Push
+		pushConfig.UserAgent = s.config.UserAgent
+		pushConfig.Period = time.Minute
+		pushConfig.Timeout = 30 * time.Second
+		pusher, err := federation.NewPusher(&pushConfig, dbctx, log.NewEntry(s.logger))
+		if err != nil {
+			return fmt.Errorf("cannot init federation pusher: %v", err)
+		}
+		go pusher.Run(ctx)
+	}
+
+	// SLO metrics for alerting
+	metricsRegistry := prometheus.NewRegistry()
+	go evaluateSLOs(ctx, dbctx, s.downtimeMonitor, s.sloMonitor, newSLOMetrics(metricsRegistry))
 
 	// Probe lister that can only list groups and probes
-	probeLister := newProbeLister(s.config.DisabledProbes, s.config.DynamicProbes)
+	customProbes, err := probe.ReadCustomProbes(s.config.DynamicProbes.CustomProbesPath)
+	if err != nil {
+		return fmt.Errorf("cannot load custom probes: %v", err)
+	}
+	probeLister := newProbeLister(s.config.DisabledProbes, s.config.DynamicProbes, customProbes)
 
 	// Start http server. It blocks, that's why it is the last here.
 	s.logger.Debugf("starting HTTP server")
 	listenAddr := s.config.ListenHost + ":" + s.config.ListenPort
-	s.server = initHttpServer(dbctx, s.downtimeMonitor, s.remoteWriteController, probeLister, listenAddr)
+	downtimeClient := downtime.NewClient(kubeClient)
+	s.server = initHttpServer(dbctx, s.downtimeMonitor, downtimeClient, s.sloMonitor, s.remoteWriteController, probeLister, metricsRegistry, s.config.Federation.TokensDir, listenAddr)
 
 	err = s.server.ListenAndServe()
 	if err == http.ErrServerClosed {
@@ -143,37 +190,22 @@ func (s *Server) Stop() error {
 	}
 	s.remoteWriteController.Stop()
 	s.downtimeMonitor.Stop()
+	s.sloMonitor.Stop()
 
 	return nil
 }
 
-func cleanOld30sEpisodes(ctx context.Context, dbCtx *dbcontext.DbContext) {
-	dayBack := -24 * time.Hour
-	period := 30 * time.Second
-
-	conn := dbCtx.Start()
-	defer conn.Stop()
-
-	storage := dao.NewEpisodeDao30s(conn)
-
-	ticker := time.NewTicker(period)
-
-	for {
-		select {
-		case <-ticker.C:
-			deadline := time.Now().Truncate(period).Add(dayBack)
-			err := storage.DeleteUpTo(deadline)
-			if err != nil {
-				log.Errorf("cannot clean old episodes: %v", err)
-			}
-		case <-ctx.Done():
-			ticker.Stop()
-			return
-		}
-	}
-}
-
-func initHttpServer(dbCtx *dbcontext.DbContext, downtimeMonitor *downtime.Monitor, controller *remotewrite.Controller, probeLister registry.ProbeLister, addr string) *http.Server {
+func initHttpServer(
+	dbCtx *dbcontext.DbContext,
+	downtimeMonitor *downtime.Monitor,
+	downtimeClient *downtime.Client,
+	sloMonitor *slo.Monitor,
+	controller *remotewrite.Controller,
+	probeLister registry.ProbeLister,
+	metricsGatherer prometheus.Gatherer,
+	federationTokensDir string,
+	addr string,
+) *http.Server {
 	mux := http.NewServeMux()
 
 	// API handlers
@@ -182,6 +214,20 @@ func initHttpServer(dbCtx *dbcontext.DbContext, downtimeMonitor *downtime.Monito
 	mux.Handle("/public/api/status", &api.PublicStatusHandler{DbCtx: dbCtx, DowntimeMonitor: downtimeMonitor, ProbeLister: probeLister})
 	mux.Handle("/downtime", &api.AddEpisodesHandler{DbCtx: dbCtx, RemoteWrite: controller})
 	mux.Handle("/stats", &api.StatsHandler{DbCtx: dbCtx})
+	mux.Handle("/api/downtimes", &api.DowntimesHandler{DowntimeMonitor: downtimeMonitor})
+	mux.Handle("/api/slo", &api.SLOHandler{DbCtx: dbCtx, DowntimeMonitor: downtimeMonitor, SLOMonitor: sloMonitor})
+	// Downtime management is kept out of /api, which is exposed by the webui ingress, and is authorized
+	// separately by kube-rbac-proxy
+	mux.Handle("/admin/downtimes", &api.OpenDowntimeHandler{DowntimeClient: downtimeClient})
+	mux.Handle("/admin/downtimes/close", &api.CloseDowntimeHandler{DowntimeClient: downtimeClient})
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsGatherer, promhttp.HandlerOpts{}))
+	// Federation, the central upmeter
+	if federationTokensDir != "" {
+		authenticator := federation.NewTokenDir(federationTokensDir)
+		mux.Handle(federation.EpisodesPath, &api.FederationEpisodesHandler{DbCtx: dbCtx, Authenticator: authenticator})
+		mux.Handle("/api/federation/availability", &api.FederationAvailabilityHandler{DbCtx: dbCtx})
+		mux.Handle("/public/api/federation/status", &api.FederationStatusHandler{DbCtx: dbCtx})
+	}
 	// Kubernetes probes
 	mux.HandleFunc("/healthz", writeOk)
 	mux.HandleFunc("/ready", writeOk)
@@ -219,13 +265,19 @@ func initDowntimeMonitor(ctx context.Context, kubeClient kube.Client, logger *lo
 	return m, m.Start(ctx)
 }
 
-func newProbeLister(disabled []string, dynamic *DynamicProbesConfig) *registry.RegistryProbeLister {
+func initSLOMonitor(ctx context.Context, kubeClient kube.Client, logger *log.Logger) (*slo.Monitor, error) {
+	m := slo.NewMonitor(kubeClient, log.NewEntry(logger))
+	return m, m.Start(ctx)
+}
+
+func newProbeLister(disabled []string, dynamic *DynamicProbesConfig, customProbes []probe.CustomProbe) *registry.RegistryProbeLister {
 	noLogger := newDummyLogger()
 	noFilter := probe.NewProbeFilter(disabled)
 	noAccess := kubernetes.FakeAccessor()
 	dynamicConfig := probe.DynamicConfig{
 		IngressNginxControllers: dynamic.IngressControllers,
 		NodeGroups:              dynamic.NodeGroups,
+		CustomProbes:            customProbes,
 	}
 	dummyDoer := checker.NoopDoer{}
 	runLoader := probe.NewLoader(noFilter, noAccess, nil, dynamicConfig, dummyDoer, noLogger)
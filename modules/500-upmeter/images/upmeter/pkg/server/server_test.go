@@ -22,11 +22,12 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"d8.io/upmeter/pkg/check"
+	"d8.io/upmeter/pkg/probe"
 )
 
 // Test how all the known probes and groups are presented
 func Test_newProbeLister(t *testing.T) {
-	pl := newProbeLister([]string{}, &DynamicProbesConfig{})
+	pl := newProbeLister([]string{}, &DynamicProbesConfig{}, nil)
 
 	allProbesSorted := []check.ProbeRef{
 		{Group: "control-plane", Probe: "apiserver"},
@@ -71,11 +72,15 @@ func Test_newProbeLister(t *testing.T) {
 	assert.Equal(t, allGroupsSorted, pl.Groups())
 }
 
-// Test how all the known probes and groups are presented including dynamic probes
+// Test how all the known probes and groups are presented including dynamic and custom probes
 func Test_newProbeLister_with_dynamic(t *testing.T) {
 	pl := newProbeLister([]string{}, &DynamicProbesConfig{
 		IngressControllers: []string{"main", "main-w-pp"},
 		NodeGroups:         []string{"system", "frontend", "worker"},
+	}, []probe.CustomProbe{
+		{Name: "shop", HTTP: &probe.CustomHTTPProbe{URL: "https://shop.example.com"}},
+		{Name: "postgres", Group: "databases", TCP: &probe.CustomTCPProbe{Address: "postgres.db:5432"}},
+		{Name: "broken", Group: "synthetic", TCP: &probe.CustomTCPProbe{Address: "postgres.db:5432"}},
 	})
 
 	allProbesSorted := []check.ProbeRef{
@@ -85,6 +90,8 @@ func Test_newProbeLister_with_dynamic(t *testing.T) {
 		{Group: "control-plane", Probe: "controller-manager"},
 		{Group: "control-plane", Probe: "namespace"},
 		{Group: "control-plane", Probe: "scheduler"},
+		{Group: "custom", Probe: "shop"},
+		{Group: "databases", Probe: "postgres"},
 		{Group: "deckhouse", Probe: "cluster-configuration"},
 		{Group: "extensions", Probe: "cluster-autoscaler"},
 		{Group: "extensions", Probe: "cluster-scaling"},
@@ -115,6 +122,8 @@ func Test_newProbeLister_with_dynamic(t *testing.T) {
 
 	allGroupsSorted := []string{
 		"control-plane",
+		"custom",
+		"databases",
 		"deckhouse",
 		"extensions",
 		"load-balancing",
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	dbcontext "d8.io/upmeter/pkg/db/context"
+	"d8.io/upmeter/pkg/db/dao"
+	"d8.io/upmeter/pkg/monitor/downtime"
+	"d8.io/upmeter/pkg/monitor/slo"
+	"d8.io/upmeter/pkg/server/api"
+)
+
+// sloMetrics exposes the evaluated SLOs for alerting on error budget burn rates
+type sloMetrics struct {
+	objective       *prometheus.GaugeVec
+	availability    *prometheus.GaugeVec
+	budgetRemaining *prometheus.GaugeVec
+	burnRate        *prometheus.GaugeVec
+}
+
+func newSLOMetrics(reg prometheus.Registerer) *sloMetrics {
+	labels := []string{"slo", "group", "probe", "slo_window"}
+
+	m := &sloMetrics{
+		objective: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upmeter_slo_objective_ratio",
+			Help: "Target availability of the SLO as a fraction of 1.",
+		}, labels),
+		availability: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upmeter_slo_availability_ratio",
+			Help: "Observed availability in the SLO window as a fraction of 1.",
+		}, labels),
+		budgetRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upmeter_slo_error_budget_remaining_ratio",
+			Help: "Fraction of the error budget left in the SLO window, negative when exhausted.",
+		}, labels),
+		burnRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upmeter_slo_error_budget_burn_rate",
+			Help: "Ratio of the observed error rate to the allowed one in the window.",
+		}, append(labels, "window")),
+	}
+
+	reg.MustRegister(m.objective, m.availability, m.budgetRemaining, m.burnRate)
+	return m
+}
+
+// set replaces all series, so that deleted SLOs disappear
+func (m *sloMetrics) set(statuses []api.SLOStatus) {
+	m.objective.Reset()
+	m.availability.Reset()
+	m.budgetRemaining.Reset()
+	m.burnRate.Reset()
+
+	for _, s := range statuses {
+		labels := prometheus.Labels{"slo": s.Name, "group": s.Group, "probe": s.Probe, "slo_window": s.Window}
+
+		m.objective.With(labels).Set(s.Objective)
+		if s.Availability < 0 {
+			// no data
+			continue
+		}
+		m.availability.With(labels).Set(s.Availability)
+		m.budgetRemaining.With(labels).Set(s.ErrorBudget.Remaining)
+
+		for _, br := range s.BurnRates {
+			brLabels := prometheus.Labels{"window": br.Window}
+			for k, v := range labels {
+				brLabels[k] = v
+			}
+			m.burnRate.With(brLabels).Set(br.Rate)
+		}
+	}
+}
+
+// evaluateSLOs periodically updates SLO metrics. 5m episodes are updated by agents every 30
+// seconds, so evaluating more often is pointless.
+func evaluateSLOs(ctx context.Context, dbCtx *dbcontext.DbContext, downtimeMonitor *downtime.Monitor, sloMonitor *slo.Monitor, metrics *sloMetrics) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		conn := dbCtx.Start()
+		statuses, err := api.EvaluateSLOs(dao.NewEpisodeDao5m(conn), downtimeMonitor, sloMonitor.List(), time.Now())
+		conn.Stop()
+		if err != nil {
+			log.Errorf("cannot evaluate SLOs: %v", err)
+		} else {
+			metrics.set(statuses)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
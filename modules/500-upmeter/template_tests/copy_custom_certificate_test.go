@@ -86,6 +86,11 @@ internal:
 smokeMini: { auth: {} }
 smokeMiniDisabled: false
 statusPageAuthDisabled: false
+storage:
+  retention:
+    episodes30sDays: 1
+    episodes5mDays: 0
+federation: {}
 `
 
 var _ = Describe("Module :: upmeter :: helm template :: custom-certificate", func() {
@@ -266,7 +266,8 @@ var DefaultImagesDigests = map[string]interface{}{
 		"localPathProvisioner": "imageHash-localPathProvisioner-localPathProvisioner",
 	},
 	"logShipper": map[string]interface{}{
-		"vector": "imageHash-logShipper-vector",
+		"eventsCollector": "imageHash-logShipper-eventsCollector",
+		"vector":          "imageHash-logShipper-vector",
 	},
 	"loki": map[string]interface{}{
 		"loki": "imageHash-loki-loki",